@@ -17,6 +17,7 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -94,6 +95,133 @@ type IncludedItem struct {
 	ExpectedAction ActionType `json:"expected,omitempty"`
 }
 
+// BaselineSource selects the approved connectivity baseline
+type BaselineSource struct {
+	// ConfigMap is the name of a ConfigMap in the namespace of the Kubesonde object
+	ConfigMap string `json:"configMap"`
+	// Key is the ConfigMap key holding the baseline, defaults to baseline.yaml
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// Webhook posts the findings to an HTTP endpoint
+type Webhook struct {
+	// Name identifies the webhook in the logs and the metrics
+	Name string `json:"name"`
+	// URL is the endpoint the findings are posted to
+	// +optional
+	URL string `json:"url,omitempty"`
+	// URLSecretRef reads the URL from a Secret in the namespace of the
+	// Kubesonde object, e.g. for Slack incoming webhooks
+	// +optional
+	URLSecretRef *corev1.SecretKeySelector `json:"urlSecretRef,omitempty"`
+	// Format is the payload: generic, a JSON document listing the findings, or
+	// slack, a message for Slack incoming webhooks. Defaults to generic.
+	// +kubebuilder:validation:Enum=generic;slack
+	// +optional
+	Format string `json:"format,omitempty"`
+	// Template is a Go template rendering the payload instead of the format
+	// +optional
+	Template string `json:"template,omitempty"`
+	// Findings are the kinds of findings posted, all of them when empty
+	// +optional
+	Findings []string `json:"findings,omitempty"`
+	// SigningSecretRef is the key signing the payloads with HMAC-SHA256
+	// +optional
+	SigningSecretRef *corev1.SecretKeySelector `json:"signingSecretRef,omitempty"`
+	// BatchInterval is the period the findings are batched over, defaults to 10s
+	// +optional
+	BatchInterval *metav1.Duration `json:"batchInterval,omitempty"`
+	// MaxBatchSize posts the batch as soon as it holds that many findings, defaults to 50
+	// +optional
+	MaxBatchSize int32 `json:"maxBatchSize,omitempty"`
+	// MaxRetries is the number of retries of a failed delivery, defaults to 5
+	// +optional
+	MaxRetries *int32 `json:"maxRetries,omitempty"`
+}
+
+// CloudEventsSink receives the probe results and the findings as CloudEvents
+type CloudEventsSink struct {
+	// URL is the endpoint the events are posted to, e.g. a Knative broker
+	URL string `json:"url"`
+	// Mode is the HTTP content mode: binary, the attributes in ce- headers and
+	// the data in the body, or structured, the whole event in the body.
+	// Defaults to binary.
+	// +kubebuilder:validation:Enum=binary;structured
+	// +optional
+	Mode string `json:"mode,omitempty"`
+	// Types are the types of events sent, all of them when empty
+	// +optional
+	Types []string `json:"types,omitempty"`
+	// MaxRetries is the number of retries of a failed delivery, defaults to 5
+	// +optional
+	MaxRetries *int32 `json:"maxRetries,omitempty"`
+}
+
+// Security profiles of the debug containers
+const (
+	// NET_RAW_PROFILE drops all the capabilities but NET_RAW of the debugger,
+	// which nmap needs for the UDP, SCTP and SYN scans. The debugger runs as
+	// root: without privilege escalation, a non-root user cannot get NET_RAW.
+	NET_RAW_PROFILE = "netRaw"
+	// RESTRICTED_PROFILE runs both containers without capabilities as a
+	// non-root user, as required by the restricted Pod Security Standard
+	RESTRICTED_PROFILE = "restricted"
+	// PRIVILEGED_PROFILE runs both containers privileged
+	PRIVILEGED_PROFILE = "privileged"
+)
+
+// DebugContainers configures the ephemeral containers installed in the probed pods
+type DebugContainers struct {
+	// Profile is the security profile of the containers: netRaw, restricted or
+	// privileged. Both containers drop all the capabilities, run with the
+	// RuntimeDefault seccomp profile and the monitor runs as a non-root user.
+	// netRaw adds NET_RAW to the debugger, which runs as root since a non-root
+	// user cannot get capabilities without privilege escalation. restricted
+	// also runs the debugger as a non-root user: the TCP probes then use
+	// connect scans and the UDP and SCTP probes are skipped. The namespaces
+	// enforcing the baseline or restricted Pod Security level use
+	// restricted. Defaults to netRaw.
+	// +kubebuilder:validation:Enum=netRaw;restricted;privileged
+	// +optional
+	Profile string `json:"profile,omitempty"`
+	// RunAsUser is the UID of the non-root containers, defaults to 65534
+	// +optional
+	RunAsUser *int64 `json:"runAsUser,omitempty"`
+	// ResourceHints are the cpu and memory the containers should use. Ephemeral
+	// containers cannot have resources, the hints are passed to the monitor as
+	// GOMAXPROCS and GOMEMLIMIT.
+	// +optional
+	ResourceHints corev1.ResourceList `json:"resourceHints,omitempty"`
+	// ImagePullSecrets pull the images of the containers. The image pull
+	// secrets of a running pod cannot change: the pods that do not reference
+	// them, directly or through their service account, are not instrumented.
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+}
+
+// SkipPolicy selects the pods that are not instrumented and are only probe
+// destinations.
+// The pods and namespaces with the kubesonde.io/skip or
+// kubesonde.io/no-instrument annotation or label are always skipped.
+type SkipPolicy struct {
+	// KubeSystem skips the pods of the kube-system namespace, defaults to true
+	// +optional
+	KubeSystem *bool `json:"kubeSystem,omitempty"`
+	// StaticPods skips the static pods and their mirror pods, defaults to true
+	// +optional
+	StaticPods *bool `json:"staticPods,omitempty"`
+	// HostNetwork skips the pods using the network of their node, defaults to true
+	// +optional
+	HostNetwork *bool `json:"hostNetwork,omitempty"`
+	// OwnNamespace skips the pods of the namespace Kubesonde runs in, defaults to true
+	// +optional
+	OwnNamespace *bool `json:"ownNamespace,omitempty"`
+	// Namespaces are further namespaces whose pods are skipped
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
 // KubesondeSpec defines the desired state of Kubesonde
 type KubesondeSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
@@ -107,6 +235,10 @@ type KubesondeSpec struct {
 	// +optional
 	MonitorImage string `json:"monitorImage,omitempty"`
 
+	// DebugContainers configures the security of the debugger and monitor containers
+	// +optional
+	DebugContainers *DebugContainers `json:"debugContainers,omitempty"`
+
 	// Namespace indicates the target namespace for the probe
 	Namespace string `json:"namespace,omitempty"`
 	// Probe describes if the default behavior is to probe all or none
@@ -118,6 +250,48 @@ type KubesondeSpec struct {
 	// Include is the set of probes to be included
 	// +optional
 	Include []IncludedItem `json:"include,omitempty"`
+	// Skip selects the pods that are not instrumented and are only probe destinations
+	// +optional
+	Skip *SkipPolicy `json:"skip,omitempty"`
+	// Baseline is the approved connectivity the probes are compared to
+	// +optional
+	Baseline *BaselineSource `json:"baseline,omitempty"`
+	// Webhooks are notified of the findings
+	// +optional
+	Webhooks []Webhook `json:"webhooks,omitempty"`
+	// CloudEvents is the sink of the probe results and the findings
+	// +optional
+	CloudEvents *CloudEventsSink `json:"cloudEvents,omitempty"`
+}
+
+// DriftStatus compares the probes to the baseline
+type DriftStatus struct {
+	// LastCheckTime is the last time the probes were compared to the baseline
+	// +optional
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+	// Unexpected is the number of allowed edges missing from the baseline
+	Unexpected int `json:"unexpected"`
+	// Missing is the number of edges of the baseline that are denied or were not probed
+	Missing int `json:"missing"`
+	// Edges lists the first drifted edges, e.g. "+ shop/frontend -> shop/db 5432/TCP"
+	// +optional
+	Edges []string `json:"edges,omitempty"`
+	// Error explains why the baseline could not be loaded
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// InstallationFailure is a pod the debug containers do not run in
+type InstallationFailure struct {
+	// Pod is the namespace/name of the pod
+	Pod string `json:"pod"`
+	// Reason is a CamelCase reason, e.g. ImagePullBackOff
+	Reason string `json:"reason"`
+	// Message explains the failure
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Time is when the failure was observed
+	Time metav1.Time `json:"time"`
 }
 
 // KubesondeStatus defines the observed state of Kubesonde
@@ -129,9 +303,16 @@ type KubesondeStatus struct {
 	// Information when was the last time the probe was run.
 	// +optional
 	LastProbeTime *metav1.Time `json:"lastProbeTime,omitempty"`
+	// Drift compares the probes to the baseline of the spec
+	// +optional
+	Drift *DriftStatus `json:"drift,omitempty"`
+	// InstallationFailures lists the pods the debug containers do not run in
+	// +optional
+	InstallationFailures []InstallationFailure `json:"installationFailures,omitempty"`
 }
 
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
 
 // Kubesonde is the Schema for the Kubesondes API
 type Kubesonde struct {
@@ -45,6 +45,32 @@ func (item ProbeOutputItem) ToComparableProbe() ComparableProbeOutputItem {
 
 }
 
+// Connection identifies the connection probed by the item, whatever its verdict
+func (item ProbeOutputItem) Connection() ComparableProbeOutputItem {
+	connection := item.ToComparableProbe()
+	connection.ExpectedAction = ""
+	connection.ResultingAction = ""
+	return connection
+}
+
+// LatestProbes keeps the latest item of every connection, by timestamp. The
+// probes of a connection that was allowed and then denied, or the other way
+// around, differ only by their verdict.
+func LatestProbes(items []ProbeOutputItem) []ProbeOutputItem {
+	positions := map[ComparableProbeOutputItem]int{}
+	latest := []ProbeOutputItem{}
+	for _, item := range items {
+		position, found := positions[item.Connection()]
+		if !found {
+			positions[item.Connection()] = len(latest)
+			latest = append(latest, item)
+		} else if item.Timestamp >= latest[position].Timestamp {
+			latest[position] = item
+		}
+	}
+	return latest
+}
+
 type ProbeOutputItem struct {
 	Type ProbeOutputItemType `json:"type"`
 	// ExpectedAction is the expected outcome of the probe. It might have values "allow" or "deny"
@@ -80,6 +106,18 @@ type ProbeEndpointInfo struct {
 	Labels         string `json:"labels,omitempty"`
 }
 
+// WorkloadName returns the name of the workload the endpoint belongs to: its
+// deployment, its replica set or the endpoint itself
+func (endpoint ProbeEndpointInfo) WorkloadName() string {
+	if endpoint.DeploymentName != "" {
+		return endpoint.DeploymentName
+	}
+	if endpoint.ReplicaSetName != "" {
+		return endpoint.ReplicaSetName
+	}
+	return endpoint.Name
+}
+
 type ProbeOutputError struct {
 	Value  ProbeOutputItem `json:"value,omitempty"`
 	Reason string          `json:"reason,omitempty"`
@@ -89,6 +127,18 @@ type PodNetworkingInfo struct {
 	PodName string `json:"podName"`
 	Netstat string `json:"netstat"`
 }
+
+// SkippedPod is a pod that is not instrumented and is only a probe destination
+type SkippedPod struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Reason is a CamelCase reason, e.g. HostNetwork
+	Reason string `json:"reason"`
+	// Message explains the reason
+	Message   string `json:"message,omitempty"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+}
+
 type ProbeOutput struct {
 	Items                      []ProbeOutputItem   `json:"items"`
 	Errors                     []ProbeOutputError  `json:"errors"`
@@ -97,6 +147,8 @@ type ProbeOutput struct {
 	PodConfigurationNetworking PodNetworkingInfoV2 `json:"podConfigurationNetworking"`
 	Start                      string              `json:"start,omitempty"` // TODO: This may be unuseful
 	End                        string              `json:"end,omitempty"`   // TODO: This may be unuseful
+	// Skipped are the pods the skip policy of the spec excludes
+	Skipped []SkippedPod `json:"skipped,omitempty"`
 }
 
 type PodNetworkingItem struct {
@@ -0,0 +1,359 @@
+// Package client is a typed client of the results server of the Kubesonde
+// controller, see /openapi.json for the contract.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/pkg/apitypes"
+)
+
+// DEFAULT_PAGE_SIZE is the number of probes fetched per request by GetProbes
+const DEFAULT_PAGE_SIZE = 1000
+
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	token      string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient replaces http.DefaultClient, e.g. to trust the certificate of the server
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithBearerToken authenticates the requests, required when the server runs with --probes-auth
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// New returns a client of the server at baseURL, e.g. http://localhost:2709
+func New(baseURL string, options ...Option) (*Client, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %s", baseURL)
+	}
+	c := &Client{baseURL: parsed, httpClient: http.DefaultClient}
+	for _, option := range options {
+		option(c)
+	}
+	return c, nil
+}
+
+// APIError is returned when the server answers with an error status
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("kubesonde: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// ProbeQuery filters the probes, the zero value selects all of them
+type ProbeQuery struct {
+	Namespace   string
+	Source      string
+	Destination string
+	// SourceLabels and DestinationLabels are label selectors, e.g. app=web
+	SourceLabels      string
+	DestinationLabels string
+	Workload          string
+	Port              string
+	Protocol          string
+	Verdict           v1.ActionType
+	Type              v1.ProbeOutputItemType
+	Since             time.Time
+	Until             time.Time
+}
+
+// Values returns the query parameters of the /probes filters
+func (q ProbeQuery) Values() url.Values {
+	values := url.Values{}
+	set := func(name string, value string) {
+		if value != "" {
+			values.Set(name, value)
+		}
+	}
+	set("namespace", q.Namespace)
+	set("source", q.Source)
+	set("destination", q.Destination)
+	set("sourceLabels", q.SourceLabels)
+	set("destinationLabels", q.DestinationLabels)
+	set("workload", q.Workload)
+	set("port", q.Port)
+	set("protocol", q.Protocol)
+	set("verdict", strings.ToLower(string(q.Verdict)))
+	set("type", strings.ToLower(string(q.Type)))
+	if !q.Since.IsZero() {
+		values.Set("since", q.Since.Format(time.RFC3339))
+	}
+	if !q.Until.IsZero() {
+		values.Set("until", q.Until.Format(time.RFC3339))
+	}
+	return values
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body any, result any) (http.Header, error) {
+	endpoint := c.baseURL.JoinPath(path)
+	endpoint.RawQuery = query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, endpoint.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		return nil, &APIError{StatusCode: response.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+	if raw, ok := result.(*[]byte); ok {
+		*raw = data
+	} else if result != nil {
+		if err := json.Unmarshal(data, result); err != nil {
+			return nil, fmt.Errorf("failed to decode %s %s: %w", method, path, err)
+		}
+	}
+	return response.Header, nil
+}
+
+// GetProbesPage returns at most limit probes starting at cursor, and the
+// cursor of the next page, empty on the last page
+func (c *Client) GetProbesPage(ctx context.Context, query ProbeQuery, limit int, cursor string) (v1.ProbeOutput, string, error) {
+	values := query.Values()
+	values.Set("limit", strconv.Itoa(limit))
+	if cursor != "" {
+		values.Set("cursor", cursor)
+	}
+	var output v1.ProbeOutput
+	header, err := c.do(ctx, http.MethodGet, apitypes.GET_PROBES_PATH, values, nil, &output)
+	if err != nil {
+		return v1.ProbeOutput{}, "", err
+	}
+	return output, header.Get(apitypes.NEXT_CURSOR_HEADER), nil
+}
+
+// GetProbes returns all the probes matching the query, fetched in pages
+func (c *Client) GetProbes(ctx context.Context, query ProbeQuery) (v1.ProbeOutput, error) {
+	output, cursor, err := c.GetProbesPage(ctx, query, DEFAULT_PAGE_SIZE, "")
+	for err == nil && cursor != "" {
+		var page v1.ProbeOutput
+		page, cursor, err = c.GetProbesPage(ctx, query, DEFAULT_PAGE_SIZE, cursor)
+		output.Items = append(output.Items, page.Items...)
+	}
+	return output, err
+}
+
+// ClearProbes deletes the probe results
+func (c *Client) ClearProbes(ctx context.Context) error {
+	_, err := c.do(ctx, http.MethodPost, apitypes.POST_PROBES_CLEAR_PATH, nil, nil, nil)
+	return err
+}
+
+// RunProbes queues a round of the probes of the scope ahead of the running
+// one and returns how many were queued, the zero scope selects all the probes
+func (c *Client) RunProbes(ctx context.Context, scope apitypes.Scope) (int, error) {
+	values := url.Values{}
+	for name, value := range map[string]string{"namespace": scope.Namespace, "workload": scope.Workload, "pod": scope.Pod} {
+		if value != "" {
+			values.Set(name, value)
+		}
+	}
+	var response apitypes.RunProbesResponse
+	_, err := c.do(ctx, http.MethodPost, apitypes.POST_PROBES_RUN_PATH, values, nil, &response)
+	return response.Queued, err
+}
+
+// AdhocProbe runs a single probe synchronously and returns its outcome
+func (c *Client) AdhocProbe(ctx context.Context, probe apitypes.AdhocProbe) (v1.ProbeOutputItem, error) {
+	var item v1.ProbeOutputItem
+	_, err := c.do(ctx, http.MethodPost, apitypes.POST_PROBES_ADHOC_PATH, nil, probe, &item)
+	return item, err
+}
+
+// GetMismatches returns the probes whose outcome differs from the one predicted by the NetworkPolicies
+func (c *Client) GetMismatches(ctx context.Context) ([]apitypes.Mismatch, error) {
+	var mismatches []apitypes.Mismatch
+	_, err := c.do(ctx, http.MethodGet, apitypes.GET_MISMATCHES_PATH, nil, nil, &mismatches)
+	return mismatches, err
+}
+
+// Explain lists the NetworkPolicies and rules allowing or blocking a connection.
+// Source and destination are `namespace/name`, a pod name or an IP address.
+func (c *Client) Explain(ctx context.Context, source string, destination string, port int32, protocol string) (apitypes.Explanation, error) {
+	values := url.Values{
+		"source":      {source},
+		"destination": {destination},
+		"port":        {strconv.Itoa(int(port))},
+	}
+	if protocol != "" {
+		values.Set("protocol", protocol)
+	}
+	var explanation apitypes.Explanation
+	_, err := c.do(ctx, http.MethodGet, apitypes.GET_EXPLAIN_PATH, values, nil, &explanation)
+	return explanation, err
+}
+
+// GetPolicies returns the least-privilege NetworkPolicies generated from the
+// observed traffic, an empty namespace selects all of them
+func (c *Client) GetPolicies(ctx context.Context, namespace string) ([]networkingv1.NetworkPolicy, error) {
+	values := url.Values{"format": {"json"}}
+	if namespace != "" {
+		values.Set("namespace", namespace)
+	}
+	var policies []networkingv1.NetworkPolicy
+	_, err := c.do(ctx, http.MethodGet, apitypes.GET_POLICIES_PATH, values, nil, &policies)
+	return policies, err
+}
+
+// DryRunPolicies compares the generated NetworkPolicies with the existing ones
+func (c *Client) DryRunPolicies(ctx context.Context, namespace string) ([]apitypes.PolicyDiff, error) {
+	values := url.Values{"dryRun": {"true"}}
+	if namespace != "" {
+		values.Set("namespace", namespace)
+	}
+	var diffs []apitypes.PolicyDiff
+	_, err := c.do(ctx, http.MethodGet, apitypes.GET_POLICIES_PATH, values, nil, &diffs)
+	return diffs, err
+}
+
+// GetGraph returns the probes matching the query aggregated at the given level
+func (c *Client) GetGraph(ctx context.Context, level apitypes.GraphLevel, query ProbeQuery) (apitypes.Graph, error) {
+	values := query.Values()
+	if level != "" {
+		values.Set("level", string(level))
+	}
+	var g apitypes.Graph
+	_, err := c.do(ctx, http.MethodGet, apitypes.GET_GRAPH_PATH, values, nil, &g)
+	return g, err
+}
+
+// GetQueue returns the state of the probe dispatcher
+func (c *Client) GetQueue(ctx context.Context) (apitypes.QueueStats, error) {
+	var stats apitypes.QueueStats
+	_, err := c.do(ctx, http.MethodGet, apitypes.GET_QUEUE_PATH, nil, nil, &stats)
+	return stats, err
+}
+
+// GetPlan returns the probes known to the controller with the time they last ran
+func (c *Client) GetPlan(ctx context.Context) ([]apitypes.PlanItem, error) {
+	var plan []apitypes.PlanItem
+	_, err := c.do(ctx, http.MethodGet, apitypes.GET_PLAN_PATH, nil, nil, &plan)
+	return plan, err
+}
+
+// GetDrift compares the probes to the baseline of the Kubesonde object
+func (c *Client) GetDrift(ctx context.Context) (apitypes.DriftResponse, error) {
+	var drift apitypes.DriftResponse
+	_, err := c.do(ctx, http.MethodGet, apitypes.GET_DRIFT_PATH, nil, nil, &drift)
+	return drift, err
+}
+
+// GetSARIF returns the security findings of the probes matching the query
+func (c *Client) GetSARIF(ctx context.Context, query ProbeQuery) (apitypes.SarifLog, error) {
+	values := query.Values()
+	values.Set("format", apitypes.SARIF_FORMAT)
+	var sarif apitypes.SarifLog
+	_, err := c.do(ctx, http.MethodGet, apitypes.GET_REPORT_PATH, values, nil, &sarif)
+	return sarif, err
+}
+
+// GetJUnit returns the JUnit XML report of the edges of the probes matching
+// the query that have an expected action or belong to the baseline
+func (c *Client) GetJUnit(ctx context.Context, query ProbeQuery) ([]byte, error) {
+	values := query.Values()
+	values.Set("format", apitypes.JUNIT_FORMAT)
+	var data []byte
+	_, err := c.do(ctx, http.MethodGet, apitypes.GET_REPORT_PATH, values, nil, &data)
+	return data, err
+}
+
+// CreateSnapshot freezes the current probe results
+func (c *Client) CreateSnapshot(ctx context.Context, label string) (apitypes.SnapshotSummary, error) {
+	var summary apitypes.SnapshotSummary
+	_, err := c.do(ctx, http.MethodPost, apitypes.SNAPSHOTS_PATH, nil, apitypes.CreateSnapshotRequest{Label: label}, &summary)
+	return summary, err
+}
+
+// ListSnapshots returns the snapshots kept by the server
+func (c *Client) ListSnapshots(ctx context.Context) ([]apitypes.SnapshotSummary, error) {
+	var summaries []apitypes.SnapshotSummary
+	_, err := c.do(ctx, http.MethodGet, apitypes.SNAPSHOTS_PATH, nil, nil, &summaries)
+	return summaries, err
+}
+
+// Diff compares two snapshots referenced by ID or label, an empty `to` is the
+// current results
+func (c *Client) Diff(ctx context.Context, from string, to string) (apitypes.SnapshotDiff, error) {
+	values := url.Values{"from": {from}}
+	if to != "" {
+		values.Set("to", to)
+	}
+	var diff apitypes.SnapshotDiff
+	_, err := c.do(ctx, http.MethodGet, apitypes.GET_DIFF_PATH, values, nil, &diff)
+	return diff, err
+}
+
+// GetAudit returns the audit records of the commands run in the pods that
+// match the query, oldest first
+func (c *Client) GetAudit(ctx context.Context, query apitypes.AuditQuery) ([]apitypes.AuditRecord, error) {
+	values := url.Values{}
+	if query.Kubesonde != "" {
+		values.Set("kubesonde", query.Kubesonde)
+	}
+	if query.Pod != "" {
+		values.Set("pod", query.Pod)
+	}
+	if query.After > 0 {
+		values.Set("after", strconv.FormatUint(query.After, 10))
+	}
+	if query.Limit > 0 {
+		values.Set("limit", strconv.Itoa(query.Limit))
+	}
+	var records []apitypes.AuditRecord
+	_, err := c.do(ctx, http.MethodGet, apitypes.GET_AUDIT_PATH, values, nil, &records)
+	return records, err
+}
@@ -0,0 +1,276 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/audit"
+	"kubesonde.io/controllers/baseline"
+	"kubesonde.io/controllers/dispatcher"
+	"kubesonde.io/controllers/graph"
+	"kubesonde.io/controllers/inner"
+	networkpolicy "kubesonde.io/controllers/network-policy"
+	"kubesonde.io/controllers/probe_command"
+	recursiveprobing "kubesonde.io/controllers/recursive-probing"
+	"kubesonde.io/controllers/report"
+	"kubesonde.io/controllers/snapshot"
+	"kubesonde.io/controllers/state"
+	restapis "kubesonde.io/rest_apis"
+)
+
+var _ = Describe("Client", func() {
+	var stateManager *state.StateManager
+	var server *httptest.Server
+	var c *Client
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		stateManager = state.NewStateManager()
+		src := v1.ProbeEndpointInfo{Type: v1.POD, Name: "src-1", Namespace: "default", DeploymentName: "src", Labels: "app=src;"}
+		dst := v1.ProbeEndpointInfo{Type: v1.POD, Name: "dst", Namespace: "default", Labels: "app=dst;"}
+		items := []v1.ProbeOutputItem{}
+		for _, port := range []string{"80", "443", "8080"} {
+			items = append(items, v1.ProbeOutputItem{Type: v1.PROBE, ResultingAction: v1.ALLOW, Source: src, Destination: dst, Port: port, Protocol: "TCP"})
+		}
+		items = append(items, v1.ProbeOutputItem{Type: v1.PROBE, ResultingAction: v1.DENY, Source: dst, Destination: src, Port: "80", Protocol: "TCP"})
+		Expect(stateManager.AppendProbes(&items)).To(Succeed())
+		Expect(stateManager.SetNetInfoV2("dst", &[]v1.PodNetworkingItem{{Port: "80", IP: "0.0.0.0", Protocol: "TCP"}})).To(Succeed())
+
+		pods := []corev1.Pod{
+			{ObjectMeta: metav1.ObjectMeta{Name: "src-1", Namespace: "default"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "dst", Namespace: "default"}},
+		}
+		evaluator := func() (*networkpolicy.Evaluator, error) {
+			return networkpolicy.NewEvaluator(pods, nil, nil, nil), nil
+		}
+		existing := func(string) ([]networkingv1.NetworkPolicy, error) { return nil, nil }
+		store := snapshot.NewStore(snapshot.MAX_SNAPSHOTS)
+
+		mux := http.NewServeMux()
+		mux.Handle(restapis.GET_PROBES_PATH, restapis.GetProbesHandlerWithManager(stateManager))
+		mux.Handle(restapis.POST_PROBES_CLEAR_PATH, restapis.PostProbesClearHandlerWithManager(stateManager))
+		mux.Handle(restapis.POST_PROBES_RUN_PATH, restapis.PostProbesRunHandlerWithRunner(func(scope recursiveprobing.Scope) int {
+			return lo.Ternary(scope.Namespace == "default", 4, 0)
+		}))
+		mux.Handle(restapis.POST_PROBES_ADHOC_PATH, restapis.PostProbesAdhocHandlerWithRunner(
+			fake.NewSimpleClientset(&pods[0], &pods[1]),
+			func(_ kubernetes.Interface, command probe_command.KubesondeCommand) (v1.ProbeOutputItem, error) {
+				return v1.ProbeOutputItem{Type: v1.PROBE, ResultingAction: v1.ALLOW, Port: command.DestinationPort, Protocol: command.Protocol}, nil
+			},
+		))
+		mux.Handle(restapis.GET_MISMATCHES_PATH, restapis.GetMismatchesHandlerWithManager(stateManager, evaluator))
+		mux.Handle(restapis.GET_EXPLAIN_PATH, restapis.GetExplainHandlerWithManager(stateManager, evaluator))
+		mux.Handle(restapis.GET_POLICIES_PATH, restapis.GetPoliciesHandlerWithManager(stateManager, existing))
+		mux.Handle(restapis.GET_GRAPH_PATH, restapis.GetGraphHandlerWithManager(stateManager))
+		mux.Handle(restapis.GET_QUEUE_PATH, restapis.GetQueueHandlerWithStats(func() dispatcher.QueueStats {
+			return dispatcher.QueueStats{Pending: 2, ByNamespace: map[string]int{"default": 2}}
+		}))
+		mux.Handle(restapis.GET_PLAN_PATH, restapis.GetPlanHandlerWithStorage(
+			func() []probe_command.KubesondeCommand {
+				return []probe_command.KubesondeCommand{{SourcePodName: "src-1", Namespace: "default", Destination: "dst", DestinationPort: "80"}}
+			},
+			func(probe_command.KubesondeCommand) (time.Time, bool) { return time.Unix(1700000000, 0), true },
+		))
+		current := func() (baseline.Current, bool) {
+			return baseline.Current{
+				Kubesonde: "default/kubesonde",
+				Source:    "default/approved/baseline.yaml",
+				Baseline:  baseline.Baseline{Edges: []baseline.Edge{{From: "default/src", To: "default/dst", Ports: []string{"80/TCP", "443/TCP"}}}},
+			}, true
+		}
+		mux.Handle(restapis.GET_DRIFT_PATH, restapis.GetDriftHandlerWithManager(stateManager, current))
+		mux.Handle(restapis.GET_REPORT_PATH, restapis.GetReportHandlerWithManager(stateManager, current))
+		mux.Handle(restapis.SNAPSHOTS_PATH, restapis.SnapshotsHandlerWithManager(stateManager, store))
+		mux.Handle(restapis.GET_DIFF_PATH, restapis.GetDiffHandlerWithManager(stateManager, store))
+		auditLog := audit.NewLog(nil)
+		for _, pod := range []string{"src-1", "dst"} {
+			_, err := auditLog.Append(audit.Record{Pod: "default/" + pod, Container: "debugger", Argv: []string{"nc", "-z", "dst", "80"}, Result: audit.SUCCEEDED})
+			Expect(err).To(BeNil())
+		}
+		mux.Handle(restapis.GET_AUDIT_PATH, restapis.GetAuditHandlerWithLog(func() *audit.Log { return auditLog }))
+		server = httptest.NewServer(mux)
+
+		var err error
+		c, err = New(server.URL)
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("Rejects invalid base URLs", func() {
+		_, err := New("localhost:2709")
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("Fetches all the pages of probes", func() {
+		output, next, err := c.GetProbesPage(ctx, ProbeQuery{}, 2, "")
+		Expect(err).To(BeNil())
+		Expect(output.Items).To(HaveLen(2))
+		Expect(next).NotTo(BeEmpty())
+
+		output, err = c.GetProbes(ctx, ProbeQuery{})
+		Expect(err).To(BeNil())
+		Expect(output.Items).To(HaveLen(4))
+	})
+
+	It("Filters the probes", func() {
+		output, err := c.GetProbes(ctx, ProbeQuery{Verdict: v1.DENY, Namespace: "default"})
+		Expect(err).To(BeNil())
+		Expect(output.Items).To(HaveLen(1))
+		Expect(output.Items[0].Source.Name).To(Equal("dst"))
+	})
+
+	It("Returns the server errors", func() {
+		_, err := c.GetProbes(ctx, ProbeQuery{Port: "http"})
+		var apiError *APIError
+		Expect(err).To(BeAssignableToTypeOf(apiError))
+		Expect(err.(*APIError).StatusCode).To(Equal(400))
+		Expect(err.(*APIError).Message).To(Equal("invalid port http"))
+	})
+
+	It("Clears the probes", func() {
+		Expect(c.ClearProbes(ctx)).To(Succeed())
+		Expect(stateManager.GetProbeState().Items).To(BeEmpty())
+	})
+
+	It("Runs probes on demand", func() {
+		queued, err := c.RunProbes(ctx, recursiveprobing.Scope{Namespace: "default"})
+		Expect(err).To(BeNil())
+		Expect(queued).To(Equal(4))
+
+		item, err := c.AdhocProbe(ctx, inner.AdhocProbe{Source: "default/src-1", Destination: "dst", Port: 53, Protocol: "UDP"})
+		Expect(err).To(BeNil())
+		Expect(item.ResultingAction).To(Equal(v1.ALLOW))
+		Expect(item.Port).To(Equal("53"))
+		Expect(item.Protocol).To(Equal("UDP"))
+
+		_, err = c.AdhocProbe(ctx, inner.AdhocProbe{Source: "default/unknown", Destination: "dst", Port: 53})
+		Expect(err.(*APIError).StatusCode).To(Equal(404))
+	})
+
+	It("Fetches the analyses", func() {
+		g, err := c.GetGraph(ctx, graph.WORKLOAD_LEVEL, ProbeQuery{})
+		Expect(err).To(BeNil())
+		Expect(g.Level).To(Equal(graph.WORKLOAD_LEVEL))
+		Expect(g.Edges).To(HaveLen(2))
+
+		mismatches, err := c.GetMismatches(ctx)
+		Expect(err).To(BeNil())
+		Expect(mismatches).To(HaveLen(1))
+		Expect(mismatches[0].PredictedAction).To(Equal(v1.ALLOW))
+
+		explanation, err := c.Explain(ctx, "default/src-1", "dst", 80, "")
+		Expect(err).To(BeNil())
+		Expect(explanation.Action).To(Equal(v1.ALLOW))
+		Expect(explanation.ObservedAction).To(Equal(v1.ALLOW))
+
+		policies, err := c.GetPolicies(ctx, "default")
+		Expect(err).To(BeNil())
+		Expect(policies).NotTo(BeEmpty())
+
+		diffs, err := c.DryRunPolicies(ctx, "default")
+		Expect(err).To(BeNil())
+		Expect(diffs).To(HaveLen(len(policies)))
+	})
+
+	It("Inspects the dispatcher", func() {
+		stats, err := c.GetQueue(ctx)
+		Expect(err).To(BeNil())
+		Expect(stats.Pending).To(Equal(2))
+		Expect(stats.ByNamespace).To(Equal(map[string]int{"default": 2}))
+
+		plan, err := c.GetPlan(ctx)
+		Expect(err).To(BeNil())
+		Expect(plan).To(Equal([]restapis.PlanItem{{Source: "default/src-1", Destination: "dst", DestinationPort: "80", LastExecution: 1700000000}}))
+	})
+
+	It("Compares the probes to the baseline", func() {
+		drift, err := c.GetDrift(ctx)
+		Expect(err).To(BeNil())
+		Expect(drift.Source).To(Equal("default/approved/baseline.yaml"))
+		Expect(drift.Unexpected).To(Equal([]baseline.DriftEdge{
+			{Kind: baseline.UNEXPECTED, From: "default/src", To: "default/dst", Port: "8080", Protocol: "TCP", Action: v1.ALLOW},
+		}))
+		Expect(drift.Missing).To(BeEmpty())
+	})
+
+	It("Fetches the audit records", func() {
+		records, err := c.GetAudit(ctx, audit.Query{})
+		Expect(err).To(BeNil())
+		Expect(records).To(HaveLen(2))
+
+		records, err = c.GetAudit(ctx, audit.Query{Pod: "default/dst"})
+		Expect(err).To(BeNil())
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].Sequence).To(Equal(uint64(2)))
+		Expect(records[0].Argv).To(Equal([]string{"nc", "-z", "dst", "80"}))
+	})
+
+	It("Fetches the reports", func() {
+		sarif, err := c.GetSARIF(ctx, ProbeQuery{})
+		Expect(err).To(BeNil())
+		Expect(sarif.Version).To(Equal(report.SARIF_VERSION))
+		Expect(sarif.Runs[0].Tool.Driver.Rules).To(Equal(report.RULES))
+
+		junit, err := c.GetJUnit(ctx, ProbeQuery{Port: "8080"})
+		Expect(err).To(BeNil())
+		Expect(string(junit)).To(ContainSubstring(`<testcase classname="default/src" name="default/dst 8080/TCP">`))
+		Expect(string(junit)).To(ContainSubstring(`<failure message="allowed, not in the baseline">`))
+	})
+
+	It("Compares snapshots", func() {
+		summary, err := c.CreateSnapshot(ctx, "before")
+		Expect(err).To(BeNil())
+		Expect(summary.Label).To(Equal("before"))
+		Expect(summary.Items).To(Equal(4))
+
+		summaries, err := c.ListSnapshots(ctx)
+		Expect(err).To(BeNil())
+		Expect(summaries).To(Equal([]snapshot.Summary{summary}))
+
+		Expect(c.ClearProbes(ctx)).To(Succeed())
+		diff, err := c.Diff(ctx, "before", "")
+		Expect(err).To(BeNil())
+		Expect(diff.To).To(Equal(restapis.CURRENT_SNAPSHOT))
+		Expect(diff.Workloads).NotTo(BeEmpty())
+		Expect(diff.Workloads[0].Removed).NotTo(BeEmpty())
+
+		_, err = c.Diff(ctx, "unknown", "")
+		Expect(err.(*APIError).StatusCode).To(Equal(404))
+	})
+
+	It("Sends the bearer token", func() {
+		authenticated := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`[]`))
+		}))
+		defer authenticated.Close()
+
+		anonymous, err := New(authenticated.URL)
+		Expect(err).To(BeNil())
+		_, err = anonymous.ListSnapshots(ctx)
+		Expect(err.(*APIError).StatusCode).To(Equal(401))
+
+		withToken, err := New(authenticated.URL, WithBearerToken("secret"), WithHTTPClient(authenticated.Client()))
+		Expect(err).To(BeNil())
+		summaries, err := withToken.ListSnapshots(ctx)
+		Expect(err).To(BeNil())
+		Expect(summaries).To(BeEmpty())
+	})
+})
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"kubesonde.io/controllers/analysis"
+	"kubesonde.io/rest_apis/openapi"
+)
+
+func formatCounts(counts analysis.VerdictCounts) string {
+	return fmt.Sprintf("%d allowed, %d denied, %d mixed", counts.Allowed, counts.Denied, counts.Mixed)
+}
+
+// writeSummary prints the summary for humans
+func writeSummary(w io.Writer, summary analysis.Summary) {
+	fmt.Fprintf(w, "Probes: %d, errors: %d, pods: %d\n", summary.Probes, summary.Errors, summary.Pods)
+	fmt.Fprintf(w, "Edges: %s\n", formatCounts(summary.Edges))
+	fmt.Fprintf(w, "Connections: %s\n", formatCounts(summary.Connections))
+	fmt.Fprintf(w, "Pods reaching the Internet: %d\n", len(summary.InternetExposed))
+	for _, exposure := range summary.InternetExposed {
+		fmt.Fprintf(w, "  %s -> %s %s\n", exposure.Pod, exposure.Destination, strings.Join(exposure.Ports, ", "))
+	}
+	fmt.Fprintf(w, "Undeclared listening ports: %d\n", len(summary.UndeclaredPorts))
+	for _, port := range summary.UndeclaredPorts {
+		fmt.Fprintf(w, "  %s %s/%s on %s\n", port.Pod, port.Port, port.Protocol, port.IP)
+	}
+}
+
+// analyze summarizes a probe result file without cluster access
+func (c *cli) analyze(_ context.Context, args []string) error {
+	var filters queryOptions
+	flags := c.flagSet("analyze", "<results.json>")
+	filters.bindFlags(flags)
+	filters.bindNamespaceFlags(flags)
+	output := flags.String("o", "text", "Output format: text or json.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected one result file, got %d arguments", flags.NArg())
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid output %s, expected text or json", *output)
+	}
+	query, err := filters.parse()
+	if err != nil {
+		return err
+	}
+	results, err := c.readOutput(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if results, err = filter(results, query); err != nil {
+		return err
+	}
+
+	summary := analysis.Summarize(results)
+	if *output == "text" {
+		writeSummary(c.stdout, summary)
+		return nil
+	}
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.stdout, string(data))
+	return err
+}
+
+// validate checks that result files match the schema of GET /probes
+func (c *cli) validate(_ context.Context, args []string) error {
+	flags := c.flagSet("validate", "<results.json>...")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected at least one result file")
+	}
+
+	invalid := 0
+	for _, path := range flags.Args() {
+		data, err := c.readFile(path)
+		if err == nil {
+			err = openapi.ValidateProbeOutput(data)
+		}
+		if err != nil {
+			invalid++
+			fmt.Fprintf(c.stdout, "%s: %v\n", path, err)
+			continue
+		}
+		fmt.Fprintf(c.stdout, "%s: valid\n", path)
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d files are invalid", invalid, flags.NArg())
+	}
+	return nil
+}
+
+// readFile reads a file, - is the standard input
+func (c *cli) readFile(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(c.stdin)
+	}
+	return os.ReadFile(path)
+}
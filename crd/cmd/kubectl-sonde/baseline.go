@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	securityv1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/baseline"
+)
+
+// baseline approves the allowed workload edges of a result file, or of the
+// results of the controller
+func (c *cli) baseline(ctx context.Context, args []string) error {
+	var options kubeOptions
+	var controller controllerOptions
+	var filters queryOptions
+	flags := c.flagSet("baseline", "[results.json]")
+	options.bindFlags(flags)
+	controller.bindFlags(flags)
+	filters.bindFlags(flags)
+	output := flags.String("o", "yaml", "Output format: yaml or json.")
+	configMap := flags.String("configmap", "", "Name of a ConfigMap wrapping the baseline, to reference it from a Kubesonde object.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return fmt.Errorf("expected at most one result file, got %d arguments", flags.NArg())
+	}
+	if *output != "yaml" && *output != "json" {
+		return fmt.Errorf("invalid output %s, expected yaml or json", *output)
+	}
+	query, err := filters.parse()
+	if err != nil {
+		return err
+	}
+	query.Namespace = options.namespace
+
+	var results securityv1.ProbeOutput
+	if flags.NArg() == 1 {
+		if results, err = c.readOutput(flags.Arg(0)); err != nil {
+			return err
+		}
+		results, err = filter(results, query)
+	} else {
+		results, err = c.fetchProbes(ctx, options, controller, query)
+	}
+	if err != nil {
+		return err
+	}
+
+	var value any = baseline.FromOutput(results)
+	if *configMap != "" {
+		data, err := yaml.Marshal(value)
+		if err != nil {
+			return err
+		}
+		value = &corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: *configMap},
+			Data:       map[string]string{baseline.DEFAULT_KEY: string(data)},
+		}
+	}
+	var data []byte
+	if *output == "yaml" {
+		data, err = yaml.Marshal(value)
+	} else {
+		data, err = json.MarshalIndent(value, "", "  ")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return err
+	}
+	_, err = c.stdout.Write(data)
+	return err
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	securityv1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/export"
+	"kubesonde.io/controllers/snapshot"
+)
+
+// readOutput reads a probe result file, - is the standard input
+func (c *cli) readOutput(path string) (securityv1.ProbeOutput, error) {
+	var reader io.Reader = c.stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return securityv1.ProbeOutput{}, err
+		}
+		defer file.Close()
+		reader = file
+	}
+	var output securityv1.ProbeOutput
+	if err := json.NewDecoder(reader).Decode(&output); err != nil {
+		return securityv1.ProbeOutput{}, fmt.Errorf("failed to read the probe results of %s: %w", path, err)
+	}
+	return output, nil
+}
+
+func formatEndpoint(endpoint snapshot.Endpoint) string {
+	if endpoint.Namespace == "" {
+		return endpoint.Name
+	}
+	return endpoint.Namespace + "/" + endpoint.Name
+}
+
+func formatEdge(edge snapshot.Edge) string {
+	return fmt.Sprintf("%s -> %s %s/%s", formatEndpoint(edge.Source), formatEndpoint(edge.Destination), edge.Port, edge.Protocol)
+}
+
+func formatPort(port securityv1.PodNetworkingItem) string {
+	return fmt.Sprintf("%s/%s", port.Port, port.Protocol)
+}
+
+// writeDiff prints one line per change, grouped by workload
+func writeDiff(w io.Writer, diff snapshot.Diff) {
+	if len(diff.Workloads) == 0 {
+		fmt.Fprintln(w, "No changes")
+		return
+	}
+	for _, workload := range diff.Workloads {
+		fmt.Fprintln(w, formatEndpoint(snapshot.Endpoint{Namespace: workload.Namespace, Name: workload.Workload}))
+		for _, edge := range workload.Added {
+			fmt.Fprintf(w, "  + %s %s\n", formatEdge(edge), edge.Action)
+		}
+		for _, edge := range workload.Removed {
+			fmt.Fprintf(w, "  - %s %s\n", formatEdge(edge), edge.Action)
+		}
+		for _, edge := range workload.Changed {
+			fmt.Fprintf(w, "  ~ %s %s -> %s\n", formatEdge(edge.SnapshotEdge), edge.PreviousAction, edge.Action)
+		}
+		for _, port := range workload.OpenedPorts {
+			fmt.Fprintf(w, "  + listening on %s\n", formatPort(port))
+		}
+		for _, port := range workload.ClosedPorts {
+			fmt.Fprintf(w, "  - listening on %s\n", formatPort(port))
+		}
+	}
+}
+
+// diff compares two probe result files, like GET /diff does for snapshots
+func (c *cli) diff(_ context.Context, args []string) error {
+	flags := c.flagSet("diff", "<from.json> <to.json>")
+	output := flags.String("o", "text", "Output format: text or json.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return fmt.Errorf("expected two result files, got %d arguments", flags.NArg())
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid output %s, expected text or json", *output)
+	}
+	from, err := c.readOutput(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	to, err := c.readOutput(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	diff := snapshot.Diff{From: flags.Arg(0), To: flags.Arg(1), Workloads: snapshot.Compare(from, to)}
+	if *output == "text" {
+		writeDiff(c.stdout, diff)
+		return nil
+	}
+	data, err := json.MarshalIndent(diff, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.stdout, string(data))
+	return err
+}
+
+// export filters a probe result file and converts it, e.g. to draw it with GraphViz
+func (c *cli) export(_ context.Context, args []string) error {
+	var render renderOptions
+	var filters queryOptions
+	flags := c.flagSet("export", "<results.json>")
+	render.bindFlags(flags, export.DOT)
+	filters.bindFlags(flags)
+	filters.bindNamespaceFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected one result file, got %d arguments", flags.NArg())
+	}
+	query, err := filters.parse()
+	if err != nil {
+		return err
+	}
+	output, err := c.readOutput(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if output, err = filter(output, query); err != nil {
+		return err
+	}
+	return c.render(output, render)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	securityv1 "kubesonde.io/api/v1"
+	"kubesonde.io/pkg/apitypes"
+)
+
+const (
+	DEFAULT_CONTROLLER_NAMESPACE = "kubesonde-system"
+	DEFAULT_CONTROLLER_SELECTOR  = "control-plane=controller-manager"
+)
+
+// kubeOptions are the flags selecting the cluster, as in kubectl
+type kubeOptions struct {
+	kubeconfig string
+	context    string
+	namespace  string
+}
+
+func (o *kubeOptions) bindFlags(flags *flag.FlagSet) {
+	flags.StringVar(&o.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file, defaults to the kubectl one.")
+	flags.StringVar(&o.context, "context", "", "The kubeconfig context to use.")
+	flags.StringVar(&o.namespace, "namespace", "", "The namespace, defaults to the one of the context.")
+	flags.StringVar(&o.namespace, "n", "", "Shorthand for --namespace.")
+}
+
+// kubeClients are the clients of the selected cluster
+type kubeClients struct {
+	config    *rest.Config
+	clientset kubernetes.Interface
+	crd       client.Client
+	// namespace is the --namespace flag or the namespace of the context
+	namespace string
+}
+
+func newScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	lo.Must0(clientgoscheme.AddToScheme(scheme))
+	lo.Must0(securityv1.AddToScheme(scheme))
+	return scheme
+}
+
+// newKubeClients loads the kubeconfig like kubectl does
+func newKubeClients(options kubeOptions) (kubeClients, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = options.kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: options.context}
+	overrides.Context.Namespace = options.namespace
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+	config, err := loader.ClientConfig()
+	if err != nil {
+		return kubeClients{}, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	namespace, _, err := loader.Namespace()
+	if err != nil {
+		return kubeClients{}, fmt.Errorf("failed to read the namespace of the context: %w", err)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return kubeClients{}, err
+	}
+	crd, err := client.New(config, client.Options{Scheme: newScheme()})
+	if err != nil {
+		return kubeClients{}, err
+	}
+	return kubeClients{config: config, clientset: clientset, crd: crd, namespace: namespace}, nil
+}
+
+// portForward forwards a random local port to the results server of a running
+// controller pod. The forward stops when stop is closed.
+func portForward(ctx context.Context, clients kubeClients, namespace string, selector string, stop chan struct{}) (string, error) {
+	pods, err := clients.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return "", fmt.Errorf("failed to list the controller pods: %w", err)
+	}
+	pod, found := lo.Find(pods.Items, func(pod corev1.Pod) bool { return pod.Status.Phase == corev1.PodRunning })
+	if !found {
+		return "", fmt.Errorf("no running controller pod matches %s in namespace %s", selector, namespace)
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(clients.config)
+	if err != nil {
+		return "", err
+	}
+	target := clients.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(pod.Namespace).Name(pod.Name).SubResource("portforward").URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, target)
+
+	ready := make(chan struct{})
+	remotePort := apitypes.DEFAULT_PORT
+	forwarder, err := portforward.New(dialer, []string{"0:" + remotePort}, stop, ready, io.Discard, io.Discard)
+	if err != nil {
+		return "", err
+	}
+	errs := make(chan error, 1)
+	go func() { errs <- forwarder.ForwardPorts() }()
+	select {
+	case <-ready:
+	case err := <-errs:
+		return "", fmt.Errorf("failed to forward to pod %s: %w", pod.Name, err)
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	ports, err := forwarder.GetPorts()
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: serverScheme(pod), Host: fmt.Sprintf("localhost:%d", ports[0].Local)}).String(), nil
+}
+
+// serverScheme is https unless the manager in the pod runs with --probes-secure=false
+func serverScheme(pod corev1.Pod) string {
+	for _, container := range pod.Spec.Containers {
+		args := append(append([]string{}, container.Command...), container.Args...)
+		if lo.Contains(args, "--probes-secure=false") || lo.Contains(args, "-probes-secure=false") {
+			return "http"
+		}
+	}
+	return "https"
+}
+
+// bearerToken is the token of the kubeconfig user, empty when the user
+// authenticates otherwise, e.g. with a client certificate or an exec plugin
+func bearerToken(config *rest.Config) (string, error) {
+	if config == nil {
+		return "", nil
+	}
+	if config.BearerToken != "" || config.BearerTokenFile == "" {
+		return config.BearerToken, nil
+	}
+	token, err := os.ReadFile(config.BearerTokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the token of the kubeconfig: %w", err)
+	}
+	return strings.TrimSpace(string(token)), nil
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	securityv1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/analysis"
+	"kubesonde.io/controllers/baseline"
+	"kubesonde.io/controllers/report"
+	"kubesonde.io/controllers/snapshot"
+	"kubesonde.io/controllers/state"
+	restapis "kubesonde.io/rest_apis"
+)
+
+var _ = Describe("kubectl-sonde", func() {
+	var crd client.Client
+	var stdout *bytes.Buffer
+	var c *cli
+	ctx := context.Background()
+
+	endpoint := func(name string) securityv1.ProbeEndpointInfo {
+		return securityv1.ProbeEndpointInfo{Type: securityv1.POD, Name: name, Namespace: "default"}
+	}
+	probe := func(source string, destination string, action securityv1.ActionType) securityv1.ProbeOutputItem {
+		return securityv1.ProbeOutputItem{Type: securityv1.PROBE, Source: endpoint(source), Destination: endpoint(destination), Port: "80", Protocol: "TCP", ResultingAction: action}
+	}
+	writeOutput := func(output securityv1.ProbeOutput) string {
+		data, err := json.Marshal(output)
+		Expect(err).To(BeNil())
+		path := filepath.Join(GinkgoT().TempDir(), "results.json")
+		Expect(os.WriteFile(path, data, 0o600)).To(Succeed())
+		return path
+	}
+
+	BeforeEach(func() {
+		lastProbe := metav1.Unix(1700000000, 0)
+		crd = fake.NewClientBuilder().WithScheme(newScheme()).WithObjects(&securityv1.Kubesonde{
+			ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "default"},
+			Spec:       securityv1.KubesondeSpec{Namespace: "default", Probe: "all"},
+			Status:     securityv1.KubesondeStatus{LastProbeTime: &lastProbe},
+		}).Build()
+		stdout = &bytes.Buffer{}
+		c = &cli{
+			stdin:  strings.NewReader(""),
+			stdout: stdout,
+			stderr: GinkgoWriter,
+			newKubeClients: func(options kubeOptions) (kubeClients, error) {
+				namespace := options.namespace
+				if namespace == "" {
+					namespace = "default"
+				}
+				return kubeClients{crd: crd, namespace: namespace}, nil
+			},
+		}
+	})
+
+	It("Rejects unknown commands", func() {
+		Expect(c.run(ctx, []string{"unknown"})).NotTo(Succeed())
+		Expect(c.run(ctx, []string{"scan", "-h"})).To(Succeed())
+	})
+
+	It("Creates a Kubesonde object", func() {
+		Expect(c.run(ctx, []string{"scan", "-n", "shop", "--probe", "none",
+			"--include", "from=frontend,to=backend,port=8080,expected=allow",
+			"--exclude", "from=frontend,to=db"})).To(Succeed())
+		Expect(stdout.String()).To(Equal("kubesonde.security.kubesonde.io/kubesonde-shop created\n"))
+
+		var created securityv1.Kubesonde
+		Expect(crd.Get(ctx, client.ObjectKey{Namespace: "shop", Name: "kubesonde-shop"}, &created)).To(Succeed())
+		Expect(created.Spec.Namespace).To(Equal("shop"))
+		Expect(created.Spec.Probe).To(Equal("none"))
+		Expect(created.Spec.Include).To(Equal([]securityv1.IncludedItem{
+			{FromPodSelector: "frontend", ToPodSelector: "backend", Port: "8080", ExpectedAction: securityv1.ALLOW},
+		}))
+		Expect(created.Spec.Exclude).To(Equal([]securityv1.ExcludedItem{{FromPodSelector: "frontend", ToPodSelector: "db"}}))
+	})
+
+	It("Validates the scan flags", func() {
+		Expect(c.run(ctx, []string{"scan", "--include", "source=frontend"})).NotTo(Succeed())
+		Expect(c.run(ctx, []string{"scan", "--include", "from=a,expected=maybe"})).NotTo(Succeed())
+		Expect(c.run(ctx, []string{"scan", "--probe", "some"})).NotTo(Succeed())
+	})
+
+	It("Prints the object on dry runs", func() {
+		c.newKubeClients = func(kubeOptions) (kubeClients, error) {
+			Fail("a dry run with a namespace should not connect to the cluster")
+			return kubeClients{}, nil
+		}
+		Expect(c.run(ctx, []string{"scan", "-n", "shop", "--dry-run", "probe"})).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("kind: Kubesonde"))
+		Expect(stdout.String()).To(ContainSubstring("name: probe"))
+	})
+
+	It("Shows the status", func() {
+		Expect(c.run(ctx, []string{"status"})).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("LAST PROBE"))
+		Expect(stdout.String()).To(MatchRegexp(`default\s+existing\s+default\s+all\s+0\s+0\s+2023-11-14T22:13:20Z`))
+
+		stdout.Reset()
+		Expect(c.run(ctx, []string{"status", "-o", "json", "existing"})).To(Succeed())
+		var items []securityv1.Kubesonde
+		Expect(json.Unmarshal(stdout.Bytes(), &items)).To(Succeed())
+		Expect(items).To(HaveLen(1))
+
+		Expect(c.run(ctx, []string{"status", "missing"})).NotTo(Succeed())
+	})
+
+	It("Fetches the filtered results", func() {
+		stateManager := state.NewStateManager()
+		Expect(stateManager.AppendProbes(&[]securityv1.ProbeOutputItem{
+			probe("a", "b", securityv1.ALLOW),
+			probe("a", "c", securityv1.DENY),
+		})).To(Succeed())
+		server := httptest.NewServer(restapis.GetProbesHandlerWithManager(stateManager))
+		defer server.Close()
+
+		Expect(c.run(ctx, []string{"results", "--server", server.URL + "/", "--verdict", "deny"})).To(Succeed())
+		var output securityv1.ProbeOutput
+		Expect(json.Unmarshal(stdout.Bytes(), &output)).To(Succeed())
+		Expect(output.Items).To(HaveLen(1))
+		Expect(output.Items[0].Destination.Name).To(Equal("c"))
+
+		stdout.Reset()
+		Expect(c.run(ctx, []string{"results", "--server", server.URL, "-o", "csv"})).To(Succeed())
+		Expect(strings.Split(strings.TrimSpace(stdout.String()), "\n")).To(HaveLen(3))
+
+		Expect(c.run(ctx, []string{"results", "--server", server.URL, "--since", "yesterday"})).NotTo(Succeed())
+	})
+
+	It("Reports the results server errors", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		}))
+		defer server.Close()
+		err := c.run(ctx, []string{"results", "--server", server.URL})
+		Expect(err).To(MatchError(ContainSubstring("403")))
+	})
+
+	It("Asks for a token when --server requires authentication", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		}))
+		defer server.Close()
+		err := c.run(ctx, []string{"results", "--server", server.URL})
+		Expect(err).To(MatchError(ContainSubstring("use --token")))
+	})
+
+	It("Fetches the results over TLS without sending the token of the kubeconfig to --server", func() {
+		stateManager := state.NewStateManager()
+		Expect(stateManager.AppendProbes(&[]securityv1.ProbeOutputItem{probe("a", "b", securityv1.ALLOW)})).To(Succeed())
+		var authorization string
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+			restapis.GetProbesHandlerWithManager(stateManager).ServeHTTP(w, r)
+		}))
+		defer server.Close()
+		c.newKubeClients = func(kubeOptions) (kubeClients, error) {
+			return kubeClients{config: &rest.Config{BearerToken: "kubeconfig-token"}}, nil
+		}
+
+		Expect(c.run(ctx, []string{"results", "--server", server.URL})).To(MatchError(ContainSubstring("--insecure-skip-tls-verify")))
+
+		Expect(c.run(ctx, []string{"results", "--server", server.URL, "--insecure-skip-tls-verify"})).To(Succeed())
+		Expect(authorization).To(BeEmpty())
+
+		ca := filepath.Join(GinkgoT().TempDir(), "ca.crt")
+		Expect(os.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600)).To(Succeed())
+		stdout.Reset()
+		Expect(c.run(ctx, []string{"results", "--server", server.URL, "--certificate-authority", ca, "--token", "flag-token"})).To(Succeed())
+		Expect(authorization).To(Equal("Bearer flag-token"))
+		Expect(stdout.String()).To(ContainSubstring(`"name": "b"`))
+	})
+
+	It("Trusts the certificate of the controller through the port-forward", func() {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+
+		forwarded, err := controllerOptions{}.httpClient(true)
+		Expect(err).NotTo(HaveOccurred())
+		response, err := forwarded.Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.Body.Close()).To(Succeed())
+
+		direct, err := controllerOptions{}.httpClient(false)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = direct.Get(server.URL)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Uses https unless the controller serves plain HTTP", func() {
+		manager := func(args ...string) corev1.Pod {
+			return corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "manager", Command: []string{"/manager"}, Args: args}}}}
+		}
+		Expect(serverScheme(manager("--leader-elect"))).To(Equal("https"))
+		Expect(serverScheme(manager("--leader-elect", "--probes-secure=true"))).To(Equal("https"))
+		Expect(serverScheme(manager("--leader-elect", "--probes-secure=false"))).To(Equal("http"))
+	})
+
+	It("Compares result files", func() {
+		from := writeOutput(securityv1.ProbeOutput{Items: []securityv1.ProbeOutputItem{probe("a", "b", securityv1.ALLOW)}})
+		to := writeOutput(securityv1.ProbeOutput{Items: []securityv1.ProbeOutputItem{probe("a", "b", securityv1.DENY)}})
+
+		Expect(c.run(ctx, []string{"diff", from, to})).To(Succeed())
+		Expect(stdout.String()).To(Equal("default/a\n  ~ default/a -> default/b 80/TCP Allow -> Deny\n"))
+
+		stdout.Reset()
+		Expect(c.run(ctx, []string{"diff", "-o", "json", from, from})).To(Succeed())
+		var diff snapshot.Diff
+		Expect(json.Unmarshal(stdout.Bytes(), &diff)).To(Succeed())
+		Expect(diff.Workloads).To(BeEmpty())
+
+		Expect(c.run(ctx, []string{"diff", from})).NotTo(Succeed())
+	})
+
+	It("Exports result files", func() {
+		path := writeOutput(securityv1.ProbeOutput{Items: []securityv1.ProbeOutputItem{probe("a", "b", securityv1.ALLOW)}})
+		Expect(c.run(ctx, []string{"export", path})).To(Succeed())
+		Expect(stdout.String()).To(HavePrefix("digraph"))
+
+		stdout.Reset()
+		c.stdin = strings.NewReader(`{"items": []}`)
+		Expect(c.run(ctx, []string{"export", "-o", "mermaid", "-"})).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("flowchart"))
+
+		Expect(c.run(ctx, []string{"export", filepath.Join(GinkgoT().TempDir(), "missing.json")})).NotTo(Succeed())
+	})
+
+	It("Filters result files", func() {
+		path := writeOutput(securityv1.ProbeOutput{Items: []securityv1.ProbeOutputItem{
+			probe("a", "b", securityv1.ALLOW),
+			probe("a", "c", securityv1.DENY),
+		}})
+		Expect(c.run(ctx, []string{"export", "-o", "json", "--verdict", "deny", "-n", "default", path})).To(Succeed())
+		var output securityv1.ProbeOutput
+		Expect(json.Unmarshal(stdout.Bytes(), &output)).To(Succeed())
+		Expect(output.Items).To(HaveLen(1))
+		Expect(output.Items[0].Destination.Name).To(Equal("c"))
+
+		Expect(c.run(ctx, []string{"export", "--source-labels", "app in (", path})).NotTo(Succeed())
+	})
+
+	It("Generates a baseline", func() {
+		path := writeOutput(securityv1.ProbeOutput{Items: []securityv1.ProbeOutputItem{
+			probe("a", "b", securityv1.ALLOW),
+			probe("a", "c", securityv1.DENY),
+		}})
+		Expect(c.run(ctx, []string{"baseline", path})).To(Succeed())
+		Expect(stdout.String()).To(Equal("edges:\n- from: default/a\n  ports:\n  - 80/TCP\n  to: default/b\n"))
+		approved, err := baseline.Parse(stdout.Bytes())
+		Expect(err).To(BeNil())
+		Expect(approved.Edges).To(HaveLen(1))
+
+		stdout.Reset()
+		Expect(c.run(ctx, []string{"baseline", "--configmap", "approved", "-o", "json", path})).To(Succeed())
+		var configMap corev1.ConfigMap
+		Expect(json.Unmarshal(stdout.Bytes(), &configMap)).To(Succeed())
+		Expect(configMap.Name).To(Equal("approved"))
+		Expect(configMap.Data).To(HaveKey(baseline.DEFAULT_KEY))
+
+		stateManager := state.NewStateManager()
+		Expect(stateManager.AppendProbes(&[]securityv1.ProbeOutputItem{probe("a", "c", securityv1.ALLOW)})).To(Succeed())
+		server := httptest.NewServer(restapis.GetProbesHandlerWithManager(stateManager))
+		defer server.Close()
+		stdout.Reset()
+		Expect(c.run(ctx, []string{"baseline", "--server", server.URL})).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("to: default/c"))
+	})
+
+	It("Renders reports", func() {
+		expected := probe("a", "c", securityv1.ALLOW)
+		expected.ExpectedAction = securityv1.DENY
+		path := writeOutput(securityv1.ProbeOutput{Items: []securityv1.ProbeOutputItem{probe("a", "b", securityv1.ALLOW), expected}})
+
+		Expect(c.run(ctx, []string{"report", path})).To(Succeed())
+		var sarif report.SarifLog
+		Expect(json.Unmarshal(stdout.Bytes(), &sarif)).To(Succeed())
+		Expect(sarif.Runs[0].Results).To(HaveLen(1))
+		Expect(sarif.Runs[0].Results[0].RuleID).To(Equal(report.EXPECTED_DENY_VIOLATION_RULE))
+
+		approved := filepath.Join(GinkgoT().TempDir(), "baseline.yaml")
+		Expect(os.WriteFile(approved, []byte("edges:\n- from: default/a\n  to: default/b\n  ports: [80/TCP]\n"), 0o600)).To(Succeed())
+		stdout.Reset()
+		Expect(c.run(ctx, []string{"report", "-o", "junit", "--baseline", approved, path})).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring(`<testsuites name="kubesonde" tests="2" failures="2">`))
+		Expect(stdout.String()).To(ContainSubstring(`<failure message="expected Deny, got Allow">`))
+		Expect(stdout.String()).To(ContainSubstring(`<failure message="allowed, not in the baseline">`))
+
+		Expect(c.run(ctx, []string{"report", "-o", "html", path})).To(MatchError(ContainSubstring("invalid output html")))
+	})
+
+	It("Summarizes result files", func() {
+		internet := securityv1.ProbeEndpointInfo{Type: securityv1.INTERNET, Name: "Google"}
+		path := writeOutput(securityv1.ProbeOutput{
+			Items: []securityv1.ProbeOutputItem{
+				probe("a", "b", securityv1.ALLOW),
+				{Type: securityv1.PROBE, Source: endpoint("a"), Destination: internet, Port: "443", Protocol: "TCP", ResultingAction: securityv1.ALLOW},
+			},
+			PodNetworkingV2: securityv1.PodNetworkingInfoV2{"b": {{Port: "9090", IP: "0.0.0.0", Protocol: "TCP"}}},
+		})
+
+		Expect(c.run(ctx, []string{"analyze", path})).To(Succeed())
+		Expect(stdout.String()).To(Equal(strings.Join([]string{
+			"Probes: 2, errors: 0, pods: 2",
+			"Edges: 2 allowed, 0 denied, 0 mixed",
+			"Connections: 2 allowed, 0 denied, 0 mixed",
+			"Pods reaching the Internet: 1",
+			"  default/a -> Google 443/TCP",
+			"Undeclared listening ports: 1",
+			"  default/b 9090/TCP on 0.0.0.0",
+			"",
+		}, "\n")))
+
+		stdout.Reset()
+		Expect(c.run(ctx, []string{"analyze", "-o", "json", "--destination", "b", path})).To(Succeed())
+		var summary analysis.Summary
+		Expect(json.Unmarshal(stdout.Bytes(), &summary)).To(Succeed())
+		Expect(summary.Probes).To(Equal(1))
+		Expect(summary.InternetExposed).To(BeEmpty())
+	})
+
+	It("Validates result files", func() {
+		examples, err := filepath.Glob("../../../examples/*.json")
+		Expect(err).To(BeNil())
+		Expect(examples).NotTo(BeEmpty())
+		Expect(c.run(ctx, append([]string{"validate"}, examples...))).To(Succeed())
+
+		invalid := filepath.Join(GinkgoT().TempDir(), "invalid.json")
+		Expect(os.WriteFile(invalid, []byte(`{"items": [{"type": "Probe", "verdict": "allow"}]}`), 0o600)).To(Succeed())
+		stdout.Reset()
+		Expect(c.run(ctx, []string{"validate", examples[0], invalid})).To(MatchError("1 of 2 files are invalid"))
+		Expect(stdout.String()).To(ContainSubstring(examples[0] + ": valid"))
+		Expect(stdout.String()).To(ContainSubstring(invalid + ": $: missing required property errors"))
+	})
+})
@@ -0,0 +1,95 @@
+// kubectl-sonde installs, runs and queries Kubesonde. Installed on the PATH it
+// is available as `kubectl sonde`.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+)
+
+type command struct {
+	summary string
+	run     func(c *cli, ctx context.Context, args []string) error
+}
+
+var commands = map[string]command{
+	"scan":     {"Create a Kubesonde object probing a namespace", (*cli).scan},
+	"status":   {"Show the status of the Kubesonde objects", (*cli).status},
+	"results":  {"Fetch the probe results from the controller", (*cli).results},
+	"diff":     {"Compare two probe result files", (*cli).diff},
+	"export":   {"Filter a probe result file and convert it to another format", (*cli).export},
+	"analyze":  {"Summarize a probe result file", (*cli).analyze},
+	"validate": {"Check that probe result files match the schema", (*cli).validate},
+	"baseline": {"Generate a connectivity baseline from the probe results", (*cli).baseline},
+	"report":   {"Render the probe results as SARIF findings or a JUnit report", (*cli).report},
+}
+
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	// newKubeClients connects to the cluster selected by the flags
+	newKubeClients func(kubeOptions) (kubeClients, error)
+}
+
+func (c *cli) usage() {
+	fmt.Fprintln(c.stderr, "Usage: kubectl sonde <command> [flags] [arguments]")
+	fmt.Fprintln(c.stderr, "\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(c.stderr, "  %-8s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(c.stderr, "\nRun `kubectl sonde <command> -h` for the flags of a command.")
+}
+
+// flagSet returns the flags of a command, reporting errors to the caller
+func (c *cli) flagSet(name string, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: kubectl sonde %s [flags] %s\n\nFlags:\n", name, arguments)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+func (c *cli) run(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		c.usage()
+		return nil
+	}
+	cmd, found := commands[args[0]]
+	if !found {
+		c.usage()
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	err := cmd.run(c, ctx, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	return err
+}
+
+func main() {
+	c := &cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr, newKubeClients: newKubeClients}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := c.run(ctx, os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		stop()
+		os.Exit(1)
+	}
+}
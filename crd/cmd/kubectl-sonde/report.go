@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	securityv1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/baseline"
+	"kubesonde.io/controllers/report"
+)
+
+// report renders the findings of a result file, or of the results of the
+// controller, for security tooling
+func (c *cli) report(ctx context.Context, args []string) error {
+	var options kubeOptions
+	var controller controllerOptions
+	var filters queryOptions
+	flags := c.flagSet("report", "[results.json]")
+	options.bindFlags(flags)
+	controller.bindFlags(flags)
+	filters.bindFlags(flags)
+	output := flags.String("o", "sarif", "Output format: sarif, the security findings, or junit, a test case per edge with an expected action or in the baseline.")
+	baselinePath := flags.String("baseline", "", "Baseline or result file whose edges are added to the junit report.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return fmt.Errorf("expected at most one result file, got %d arguments", flags.NArg())
+	}
+	if *output != "sarif" && *output != "junit" {
+		return fmt.Errorf("invalid output %s, expected sarif or junit", *output)
+	}
+	var approved *baseline.Baseline
+	if *baselinePath != "" {
+		data, err := os.ReadFile(*baselinePath)
+		if err != nil {
+			return err
+		}
+		parsed, err := baseline.Parse(data)
+		if err != nil {
+			return fmt.Errorf("failed to read the baseline %s: %w", *baselinePath, err)
+		}
+		approved = &parsed
+	}
+	query, err := filters.parse()
+	if err != nil {
+		return err
+	}
+	query.Namespace = options.namespace
+
+	var results securityv1.ProbeOutput
+	if flags.NArg() == 1 {
+		if results, err = c.readOutput(flags.Arg(0)); err != nil {
+			return err
+		}
+		results, err = filter(results, query)
+	} else {
+		results, err = c.fetchProbes(ctx, options, controller, query)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *output == "junit" {
+		return report.WriteJUnit(c.stdout, "kubesonde", report.Cases(results, approved))
+	}
+	data, err := json.MarshalIndent(report.SARIF(results), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.stdout, string(data))
+	return err
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	securityv1 "kubesonde.io/api/v1"
+	"kubesonde.io/client"
+	"kubesonde.io/controllers/export"
+	probequery "kubesonde.io/controllers/probe-query"
+)
+
+// renderOptions are the flags choosing how probe results are printed
+type renderOptions struct {
+	format   string
+	grouping string
+}
+
+func (o *renderOptions) bindFlags(flags *flag.FlagSet, format export.Format) {
+	flags.StringVar(&o.format, "o", string(format), "Output format: json, csv, dot, mermaid, graphml or cytoscape.")
+	flags.StringVar(&o.grouping, "group", string(export.POD_GROUPING), "Draw one node per pod or per deployment: pod or deployment.")
+}
+
+// render prints the probe output in the chosen format
+func (c *cli) render(output securityv1.ProbeOutput, options renderOptions) error {
+	format, err := export.ParseFormat(options.format)
+	if err != nil {
+		return err
+	}
+	grouping, err := export.ParseGrouping(options.grouping)
+	if err != nil {
+		return err
+	}
+	var data []byte
+	if format == export.JSON {
+		data, err = json.MarshalIndent(output, "", "  ")
+		data = append(data, '\n')
+	} else {
+		data, err = export.Render(output, format, grouping)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = c.stdout.Write(data)
+	return err
+}
+
+// parseTime accepts RFC 3339 dates and durations before now, e.g. 1h
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-duration), nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %s, expected an RFC 3339 date or a duration", value)
+	}
+	return parsed, nil
+}
+
+func parseItemType(value string) (securityv1.ProbeOutputItemType, error) {
+	switch strings.ToLower(value) {
+	case "":
+		return "", nil
+	case "probe":
+		return securityv1.PROBE, nil
+	case "information":
+		return securityv1.INFO, nil
+	}
+	return "", fmt.Errorf("invalid type %s, expected probe or information", value)
+}
+
+// queryOptions are the flags of the /probes filters
+type queryOptions struct {
+	query    client.ProbeQuery
+	verdict  string
+	itemType string
+	since    string
+	until    string
+}
+
+func (o *queryOptions) bindFlags(flags *flag.FlagSet) {
+	flags.StringVar(&o.query.Source, "source", "", "Name or IP address of the source.")
+	flags.StringVar(&o.query.Destination, "destination", "", "Name or IP address of the destination.")
+	flags.StringVar(&o.query.SourceLabels, "source-labels", "", "Label selector of the source, e.g. app=web.")
+	flags.StringVar(&o.query.DestinationLabels, "destination-labels", "", "Label selector of the destination.")
+	flags.StringVar(&o.query.Workload, "workload", "", "Deployment or replica set of the source or the destination.")
+	flags.StringVar(&o.query.Port, "port", "", "Probed port.")
+	flags.StringVar(&o.query.Protocol, "protocol", "", "Probed protocol.")
+	flags.StringVar(&o.verdict, "verdict", "", "Outcome of the probes: allow or deny.")
+	flags.StringVar(&o.itemType, "type", "", "Type of the items: probe or information.")
+	flags.StringVar(&o.since, "since", "", "Oldest item, an RFC 3339 date or a duration before now, e.g. 1h.")
+	flags.StringVar(&o.until, "until", "", "Newest item, an RFC 3339 date or a duration before now.")
+}
+
+// bindNamespaceFlags binds the namespace filter for the commands without
+// cluster access, where --namespace does not select the kubeconfig namespace
+func (o *queryOptions) bindNamespaceFlags(flags *flag.FlagSet) {
+	flags.StringVar(&o.query.Namespace, "namespace", "", "Namespace of the source or the destination.")
+	flags.StringVar(&o.query.Namespace, "n", "", "Shorthand for --namespace.")
+}
+
+func (o *queryOptions) parse() (client.ProbeQuery, error) {
+	query := o.query
+	var err error
+	if query.Verdict, err = parseAction(o.verdict); err != nil {
+		return query, err
+	}
+	if query.Type, err = parseItemType(o.itemType); err != nil {
+		return query, err
+	}
+	if query.Since, err = parseTime(o.since); err != nil {
+		return query, err
+	}
+	if query.Until, err = parseTime(o.until); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+// filter applies the query to a probe output read from a file
+func filter(output securityv1.ProbeOutput, query client.ProbeQuery) (securityv1.ProbeOutput, error) {
+	parsed, err := probequery.ParseFilter(query.Values())
+	if err != nil {
+		return output, err
+	}
+	return parsed.Apply(output), nil
+}
+
+// controllerOptions are the flags reaching the results server of the controller
+type controllerOptions struct {
+	namespace             string
+	selector              string
+	server                string
+	token                 string
+	insecureSkipTLSVerify bool
+	certificateAuthority  string
+}
+
+func (o *controllerOptions) bindFlags(flags *flag.FlagSet) {
+	flags.StringVar(&o.namespace, "controller-namespace", DEFAULT_CONTROLLER_NAMESPACE, "Namespace of the Kubesonde controller.")
+	flags.StringVar(&o.selector, "selector", DEFAULT_CONTROLLER_SELECTOR, "Label selector of the Kubesonde controller pods.")
+	flags.StringVar(&o.server, "server", "", "URL of the results server, e.g. https://localhost:2709. Skips the port-forward.")
+	flags.StringVar(&o.token, "token", "", "Bearer token sent to the results server. Defaults to the token of the kubeconfig user "+
+		"through the port-forward, required with --server when the controller authenticates the requests.")
+	flags.BoolVar(&o.insecureSkipTLSVerify, "insecure-skip-tls-verify", false,
+		"Do not verify the certificate of --server, e.g. the self-signed one generated by the controller. "+
+			"The certificate is not verified through the port-forward unless --certificate-authority is set.")
+	flags.StringVar(&o.certificateAuthority, "certificate-authority", "", "Path to the CA certificate of the results server.")
+}
+
+// httpClient trusts the certificate of the results server. Through the
+// port-forward, the API server already authenticates the controller pod at the
+// other end of the tunnel, so its certificate, self-signed by default, is
+// trusted unless --certificate-authority is given.
+func (o controllerOptions) httpClient(forwarded bool) (*http.Client, error) {
+	skipVerify := o.insecureSkipTLSVerify || (forwarded && o.certificateAuthority == "")
+	if !skipVerify && o.certificateAuthority == "" {
+		return http.DefaultClient, nil
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: skipVerify} // #nosec G402 -- opt-in flag or port-forward
+	if o.certificateAuthority != "" {
+		pem, err := os.ReadFile(o.certificateAuthority)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the certificate authority: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificate in %s", o.certificateAuthority)
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	return &http.Client{Transport: transport}, nil
+}
+
+// fetchProbes port-forwards to the controller, unless a server is given, and
+// fetches the filtered results. The token of the kubeconfig is only sent
+// through the port-forward, that goes through the API server, never to an
+// arbitrary --server.
+func (c *cli) fetchProbes(ctx context.Context, options kubeOptions, controller controllerOptions, query client.ProbeQuery) (securityv1.ProbeOutput, error) {
+	baseURL := controller.server
+	token := controller.token
+	if baseURL == "" {
+		clients, err := c.newKubeClients(options)
+		if err != nil {
+			return securityv1.ProbeOutput{}, err
+		}
+		if token == "" {
+			if token, err = bearerToken(clients.config); err != nil {
+				return securityv1.ProbeOutput{}, err
+			}
+		}
+		stop := make(chan struct{})
+		defer close(stop)
+		if baseURL, err = portForward(ctx, clients, controller.namespace, controller.selector, stop); err != nil {
+			return securityv1.ProbeOutput{}, err
+		}
+	}
+	httpClient, err := controller.httpClient(controller.server == "")
+	if err != nil {
+		return securityv1.ProbeOutput{}, err
+	}
+	clientOptions := []client.Option{client.WithHTTPClient(httpClient)}
+	if token != "" {
+		clientOptions = append(clientOptions, client.WithBearerToken(token))
+	}
+	results, err := client.New(baseURL, clientOptions...)
+	if err != nil {
+		return securityv1.ProbeOutput{}, err
+	}
+	output, err := results.GetProbes(ctx, query)
+	var unknownAuthority x509.UnknownAuthorityError
+	if errors.As(err, &unknownAuthority) {
+		return securityv1.ProbeOutput{}, fmt.Errorf("failed to fetch the results: %w, "+
+			"the controller generates a self-signed certificate unless --probes-cert-path is set: "+
+			"use --certificate-authority, --insecure-skip-tls-verify or the port-forward", err)
+	}
+	var apiError *client.APIError
+	if controller.server != "" && token == "" && errors.As(err, &apiError) && apiError.StatusCode == http.StatusUnauthorized {
+		return securityv1.ProbeOutput{}, fmt.Errorf("failed to fetch the results: %w, "+
+			"the token of the kubeconfig is not sent to --server: use --token", err)
+	}
+	if err != nil {
+		return securityv1.ProbeOutput{}, fmt.Errorf("failed to fetch the results: %w", err)
+	}
+	return output, nil
+}
+
+// results port-forwards to the controller and fetches the filtered results
+func (c *cli) results(ctx context.Context, args []string) error {
+	var options kubeOptions
+	var controller controllerOptions
+	var render renderOptions
+	var filters queryOptions
+	flags := c.flagSet("results", "")
+	options.bindFlags(flags)
+	controller.bindFlags(flags)
+	render.bindFlags(flags, export.JSON)
+	filters.bindFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return fmt.Errorf("unexpected arguments %v", flags.Args())
+	}
+	query, err := filters.parse()
+	if err != nil {
+		return err
+	}
+	// --namespace filters the results instead of selecting the namespace of the context
+	query.Namespace = options.namespace
+
+	output, err := c.fetchProbes(ctx, options, controller, query)
+	if err != nil {
+		return err
+	}
+	return c.render(output, render)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	securityv1 "kubesonde.io/api/v1"
+)
+
+// parseAction accepts the verdicts in any case, e.g. allow or Allow
+func parseAction(value string) (securityv1.ActionType, error) {
+	switch strings.ToLower(value) {
+	case "":
+		return "", nil
+	case "allow":
+		return securityv1.ALLOW, nil
+	case "deny":
+		return securityv1.DENY, nil
+	}
+	return "", fmt.Errorf("invalid verdict %s, expected allow or deny", value)
+}
+
+// parseFields parses `key=value` pairs separated by commas, e.g.
+// `from=frontend,to=backend,port=80`
+func parseFields(value string, keys ...string) (map[string]string, error) {
+	fields := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		key, fieldValue, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || !lo.Contains(keys, key) {
+			return nil, fmt.Errorf("invalid field %q, expected one of %s", pair, strings.Join(keys, ", "))
+		}
+		fields[key] = fieldValue
+	}
+	return fields, nil
+}
+
+// includeFlag collects the repeated --include flags
+type includeFlag []securityv1.IncludedItem
+
+func (f *includeFlag) String() string { return fmt.Sprint(*f) }
+
+func (f *includeFlag) Set(value string) error {
+	fields, err := parseFields(value, "from", "to", "port", "protocol", "expected")
+	if err != nil {
+		return err
+	}
+	expected, err := parseAction(fields["expected"])
+	if err != nil {
+		return err
+	}
+	*f = append(*f, securityv1.IncludedItem{
+		FromPodSelector: fields["from"],
+		ToPodSelector:   fields["to"],
+		Port:            fields["port"],
+		Protocol:        fields["protocol"],
+		ExpectedAction:  expected,
+	})
+	return nil
+}
+
+// excludeFlag collects the repeated --exclude flags
+type excludeFlag []securityv1.ExcludedItem
+
+func (f *excludeFlag) String() string { return fmt.Sprint(*f) }
+
+func (f *excludeFlag) Set(value string) error {
+	fields, err := parseFields(value, "from", "to", "port", "protocol")
+	if err != nil {
+		return err
+	}
+	*f = append(*f, securityv1.ExcludedItem{
+		FromPodSelector: fields["from"],
+		ToPodSelector:   fields["to"],
+		Port:            fields["port"],
+		Protocol:        fields["protocol"],
+	})
+	return nil
+}
+
+// scan creates a Kubesonde object probing the selected namespace
+func (c *cli) scan(ctx context.Context, args []string) error {
+	var options kubeOptions
+	var include includeFlag
+	var exclude excludeFlag
+	flags := c.flagSet("scan", "[name]")
+	options.bindFlags(flags)
+	probe := flags.String("probe", string(securityv1.ALL), "Probe all the pods (all) or only the included probes (none).")
+	flags.Var(&include, "include", "Probe to run, e.g. from=frontend,to=backend,port=80,protocol=TCP,expected=allow. Repeatable.")
+	flags.Var(&exclude, "exclude", "Probe to skip, e.g. from=frontend,to=db,port=5432,protocol=TCP. Repeatable.")
+	debuggerImage := flags.String("debugger-image", "", "Image of the debug containers, defaults to the controller one.")
+	monitorImage := flags.String("monitor-image", "", "Image of the monitor containers, defaults to the controller one.")
+	dryRun := flags.Bool("dry-run", false, "Print the object instead of creating it.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return fmt.Errorf("expected at most one name, got %d arguments", flags.NArg())
+	}
+	if *probe != string(securityv1.ALL) && *probe != string(securityv1.NONE) {
+		return fmt.Errorf("invalid --probe %s, expected all or none", *probe)
+	}
+
+	// A dry run with an explicit namespace does not need a cluster
+	namespace := options.namespace
+	var clients kubeClients
+	if !*dryRun || namespace == "" {
+		var err error
+		if clients, err = c.newKubeClients(options); err != nil {
+			return err
+		}
+		namespace = clients.namespace
+	}
+	name := lo.Ternary(flags.NArg() == 1, flags.Arg(0), "kubesonde-"+namespace)
+
+	kubesonde := &securityv1.Kubesonde{
+		TypeMeta:   metav1.TypeMeta{APIVersion: securityv1.GroupVersion.String(), Kind: "Kubesonde"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: securityv1.KubesondeSpec{
+			DebuggerImage: *debuggerImage,
+			MonitorImage:  *monitorImage,
+			Namespace:     namespace,
+			Probe:         *probe,
+			Include:       include,
+			Exclude:       exclude,
+		},
+	}
+	if *dryRun {
+		data, err := yaml.Marshal(kubesonde)
+		if err != nil {
+			return err
+		}
+		_, err = c.stdout.Write(data)
+		return err
+	}
+	if err := clients.crd.Create(ctx, kubesonde); err != nil {
+		return fmt.Errorf("failed to create Kubesonde %s/%s: %w", namespace, name, err)
+	}
+	fmt.Fprintf(c.stdout, "kubesonde.%s/%s created\n", securityv1.GroupVersion.Group, name)
+	return nil
+}
+
+// status lists the Kubesonde objects of the namespace, or the given one
+func (c *cli) status(ctx context.Context, args []string) error {
+	var options kubeOptions
+	flags := c.flagSet("status", "[name]")
+	options.bindFlags(flags)
+	allNamespaces := flags.Bool("all-namespaces", false, "List the Kubesonde objects of all the namespaces.")
+	flags.BoolVar(allNamespaces, "A", false, "Shorthand for --all-namespaces.")
+	output := flags.String("o", "table", "Output format: table or json.")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *output != "table" && *output != "json" {
+		return fmt.Errorf("invalid output %s, expected table or json", *output)
+	}
+	clients, err := c.newKubeClients(options)
+	if err != nil {
+		return err
+	}
+
+	var list securityv1.KubesondeList
+	switch {
+	case flags.NArg() == 1:
+		var kubesonde securityv1.Kubesonde
+		if err := clients.crd.Get(ctx, client.ObjectKey{Namespace: clients.namespace, Name: flags.Arg(0)}, &kubesonde); err != nil {
+			return fmt.Errorf("failed to get Kubesonde %s/%s: %w", clients.namespace, flags.Arg(0), err)
+		}
+		list.Items = []securityv1.Kubesonde{kubesonde}
+	case flags.NArg() == 0:
+		listOptions := lo.Ternary[[]client.ListOption](*allNamespaces, nil, []client.ListOption{client.InNamespace(clients.namespace)})
+		if err := clients.crd.List(ctx, &list, listOptions...); err != nil {
+			return fmt.Errorf("failed to list Kubesondes: %w", err)
+		}
+	default:
+		flags.Usage()
+		return fmt.Errorf("expected at most one name, got %d arguments", flags.NArg())
+	}
+
+	if *output == "json" {
+		data, err := json.MarshalIndent(list.Items, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(c.stdout, string(data))
+		return err
+	}
+	if len(list.Items) == 0 {
+		fmt.Fprintln(c.stdout, "No Kubesonde objects found")
+		return nil
+	}
+	writer := tabwriter.NewWriter(c.stdout, 0, 4, 3, ' ', 0)
+	fmt.Fprintln(writer, "NAMESPACE\tNAME\tTARGET\tPROBE\tINCLUDED\tEXCLUDED\tLAST PROBE")
+	for _, kubesonde := range list.Items {
+		lastProbe := "never"
+		if kubesonde.Status.LastProbeTime != nil {
+			lastProbe = kubesonde.Status.LastProbeTime.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
+			kubesonde.Namespace, kubesonde.Name, kubesonde.Spec.Namespace, lo.CoalesceOrEmpty(kubesonde.Spec.Probe, string(securityv1.NONE)),
+			len(kubesonde.Spec.Include), len(kubesonde.Spec.Exclude), lastProbe)
+	}
+	return writer.Flush()
+}
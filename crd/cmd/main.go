@@ -17,9 +17,11 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -33,10 +35,19 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	securityv1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/audit"
+	"kubesonde.io/controllers/cloudevents"
+	"kubesonde.io/controllers/delivery"
+	"kubesonde.io/controllers/gate"
+	"kubesonde.io/controllers/state"
+	"kubesonde.io/controllers/tracing"
+	"kubesonde.io/controllers/utils"
+	kubesondewebhook "kubesonde.io/controllers/webhook"
 	"kubesonde.io/internal/controller"
 	//+kubebuilder:scaffold:imports
 )
@@ -54,11 +65,26 @@ func init() {
 }
 
 func main() {
+	os.Exit(run())
+}
+
+// run sets up and runs the manager and returns the exit code of the process,
+// the deferred calls, e.g. closing the audit log, run before the exit
+func run() int {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var probesAddr string
+	var secureProbes bool
+	var probesAuth bool
+	var probesCertPath, probesCertName, probesCertKey string
+	var oneShot bool
+	var gateOptions gate.Options
+	var tracingOptions tracing.Options
+	var auditPath, auditKeySecret string
+	var auditMaxSize, auditMaxBackups int
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -68,6 +94,38 @@ func main() {
 		"If set the metrics endpoint is served securely")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&probesAddr, "probes-bind-address", kubesondeHTTPServer.DEFAULT_BIND_ADDRESS,
+		"The address the probes results endpoint binds to.")
+	flag.BoolVar(&secureProbes, "probes-secure", true,
+		"If set the probes results endpoint is served via HTTPS, --probes-secure=false serves plain HTTP")
+	flag.BoolVar(&probesAuth, "probes-auth", true,
+		"If set the probes results endpoint requires a bearer token authorized for the request path and verb, "+
+			"--probes-auth=false lets anyone reaching the endpoint read the results and run probes")
+	flag.StringVar(&probesCertPath, "probes-cert-path", "",
+		"The directory that contains the probes results server certificate. A self-signed certificate is used when empty.")
+	flag.StringVar(&probesCertName, "probes-cert-name", "tls.crt", "The name of the probes results server certificate file.")
+	flag.StringVar(&probesCertKey, "probes-cert-key", "tls.key", "The name of the probes results server key file.")
+	flag.BoolVar(&oneShot, "one-shot", false,
+		"If set, run a single round of all the probes, write the reports and exit with 1 on violations and 2 on errors")
+	flag.DurationVar(&gateOptions.Settle, "one-shot-settle", 30*time.Second,
+		"How long the probes must not change before the one-shot round starts.")
+	flag.DurationVar(&gateOptions.Timeout, "one-shot-timeout", 30*time.Minute,
+		"The maximum duration of the one-shot mode, including the instrumentation of the pods.")
+	flag.StringVar(&gateOptions.BaselinePath, "baseline", "",
+		"A probe results file the one-shot round must match. Allowed edges missing from it are violations.")
+	flag.StringVar(&gateOptions.ReportDir, "report-dir", ".", "The directory the one-shot reports are written to.")
+	flag.StringVar(&tracingOptions.Endpoint, "otlp-endpoint", "",
+		"The host:port of the OTLP gRPC collector the probe pipeline spans are exported to. "+
+			"Tracing is disabled when empty, unless OTEL_EXPORTER_OTLP_ENDPOINT is set.")
+	flag.BoolVar(&tracingOptions.Insecure, "otlp-insecure", false, "If set the spans are exported without TLS")
+	flag.StringVar(&auditPath, "audit-log", "",
+		"The file the commands run in the pods are appended to as JSON lines, - for stdout. "+
+			"The records are only served by GET /audit when empty.")
+	flag.IntVar(&auditMaxSize, "audit-log-max-size", 100, "The size in megabytes the audit log file is rotated at, 0 disables the rotation.")
+	flag.IntVar(&auditMaxBackups, "audit-log-max-backups", 5, "The number of rotated audit log files kept, 0 keeps all of them.")
+	flag.StringVar(&auditKeySecret, "audit-log-key-secret", "kubesonde-audit-key",
+		"The secret of the namespace of the controller holding the HMAC key the audit records are signed with, "+
+			"created with a random key when missing. Empty to only hash the records.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -121,7 +179,7 @@ func main() {
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
-		os.Exit(1)
+		return 1
 	}
 
 	// Create the Kubernetes client
@@ -129,7 +187,39 @@ func main() {
 	kubernetesClient, err := kubernetes.NewForConfig(clusterConfig)
 	if err != nil {
 		setupLog.Error(err, "unable to create kubernetes client")
-		os.Exit(1)
+		return 1
+	}
+
+	var auditKey []byte
+	switch {
+	case auditKeySecret == "":
+		setupLog.Info("The audit records are not signed, they can be rewritten with a valid chain")
+	case utils.OwnNamespace() == "":
+		setupLog.Info("The controller does not run in a cluster, the audit records are not signed")
+	default:
+		auditKey, err = audit.LoadKey(context.Background(), kubernetesClient, utils.OwnNamespace(), auditKeySecret)
+		if err != nil {
+			setupLog.Error(err, "unable to load the audit key")
+			return 1
+		}
+	}
+	switch auditPath {
+	case "":
+		audit.SetDefaultLog(audit.NewKeyedLog(nil, auditKey))
+	case "-":
+		audit.SetDefaultLog(audit.NewKeyedLog(os.Stdout, auditKey))
+	default:
+		auditLog, auditFile, err := audit.Open(auditPath, auditKey, int64(auditMaxSize)<<20, auditMaxBackups)
+		if err != nil {
+			setupLog.Error(err, "unable to open the audit log")
+			return 1
+		}
+		defer func() {
+			if err := auditFile.Close(); err != nil {
+				setupLog.Error(err, "unable to close the audit log")
+			}
+		}()
+		audit.SetDefaultLog(auditLog)
 	}
 
 	if err = (&controller.KubesondeReconciler{
@@ -138,24 +228,106 @@ func main() {
 		KubernetesClient: kubernetesClient,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Kubesonde")
-		os.Exit(1)
+		return 1
+	}
+	if err = (&controller.BaselineReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("baseline"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Baseline")
+		return 1
+	}
+	if err = (&controller.InstallationReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("installation"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Installation")
+		return 1
+	}
+	// The findings are detected once for the webhooks and the CloudEvents sinks
+	notifier := kubesondewebhook.NewNotifier()
+	publisher := cloudevents.NewPublisher()
+	if err := mgr.Add(delivery.NewFeed(state.GetDefaultManager(), notifier, publisher)); err != nil {
+		setupLog.Error(err, "unable to add the delivery feed")
+		return 1
+	}
+	if err = (&controller.WebhookReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("webhook"),
+		Notifier: notifier,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Webhook")
+		return 1
+	}
+	if err = (&controller.CloudEventsReconciler{
+		Client:    mgr.GetClient(),
+		Log:       ctrl.Log.WithName("cloudevents"),
+		Publisher: publisher,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "CloudEvents")
+		return 1
 	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
-		os.Exit(1)
+		return 1
 	}
 	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up ready check")
-		os.Exit(1)
+		return 1
 	}
 	setupLog.Info("starting Kubesonde API server")
-	kubesondeHTTPServer.ServeHTTP()
+	probesServerOptions := kubesondeHTTPServer.ServerOptions{
+		BindAddress:   probesAddr,
+		SecureServing: secureProbes,
+		CertDir:       probesCertPath,
+		CertName:      probesCertName,
+		KeyName:       probesCertKey,
+		TLSOpts:       tlsOpts,
+		RestConfig:    clusterConfig,
+	}
+	if !probesAuth {
+		setupLog.Info("the probes results endpoint does not authenticate the requests, anyone reaching it can read the results and run probes")
+	} else {
+		if !secureProbes {
+			setupLog.Info("bearer tokens of the probes results endpoint are sent over plain HTTP, consider --probes-secure")
+		}
+		probesServerOptions.FilterProvider = filters.WithAuthenticationAndAuthorization
+	}
+	if err := kubesondeHTTPServer.ServeHTTP(kubernetesClient, probesServerOptions); err != nil {
+		setupLog.Error(err, "unable to start Kubesonde API server")
+		return 1
+	}
+
+	ctx := ctrl.SetupSignalHandler()
+	shutdownTracing, err := tracing.Setup(ctx, tracingOptions)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		return 1
+	}
+	exitCode := make(chan int, 1)
+	if oneShot {
+		// The manager stops once the round is evaluated
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		go func() {
+			exitCode <- gate.Run(ctx, gateOptions)
+			cancel()
+		}()
+	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctx)
+	if err := shutdownTracing(context.Background()); err != nil {
+		setupLog.Error(err, "unable to flush the spans")
+	}
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
-		os.Exit(1)
+		return 1
+	}
+	if oneShot {
+		return <-exitCode
 	}
+	return 0
 }
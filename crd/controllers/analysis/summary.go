@@ -0,0 +1,149 @@
+// The analysis module summarizes a probe output without cluster access, e.g.
+// to review archived scans
+package analysis
+
+import (
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/graph"
+)
+
+// VerdictCounts counts edges or ports by verdict
+type VerdictCounts struct {
+	Allowed int `json:"allowed"`
+	Denied  int `json:"denied"`
+	// Mixed counts the ones both allowed and denied by different probes
+	Mixed int `json:"mixed"`
+}
+
+func (c *VerdictCounts) add(verdict graph.Verdict) {
+	switch verdict {
+	case graph.ALLOWED:
+		c.Allowed++
+	case graph.DENIED:
+		c.Denied++
+	default:
+		c.Mixed++
+	}
+}
+
+// InternetExposure is a pod that reached an external destination
+type InternetExposure struct {
+	// Pod is the namespace/name of the pod
+	Pod         string `json:"pod"`
+	Destination string `json:"destination"`
+	// Ports are the allowed port/protocol pairs
+	Ports []string `json:"ports"`
+}
+
+// UndeclaredPort is a port a pod listens on without declaring it in its spec
+type UndeclaredPort struct {
+	// Pod is the namespace/name of the pod, its name when the namespace is unknown
+	Pod      string `json:"pod"`
+	Port     string `json:"port"`
+	Protocol string `json:"protocol"`
+	IP       string `json:"ip"`
+}
+
+// Summary describes a probe output
+type Summary struct {
+	Probes int `json:"probes"`
+	Errors int `json:"errors"`
+	Pods   int `json:"pods"`
+	// Edges counts the pairs of probed endpoints by verdict
+	Edges VerdictCounts `json:"edges"`
+	// Connections counts the probed ports of the edges by verdict
+	Connections     VerdictCounts      `json:"connections"`
+	InternetExposed []InternetExposure `json:"internetExposed"`
+	UndeclaredPorts []UndeclaredPort   `json:"undeclaredPorts"`
+}
+
+func formatPort(port string, protocol string) string {
+	return port + "/" + lo.Ternary(protocol != "", protocol, "TCP")
+}
+
+// podNamespaces maps the names of the probed pods to their namespace, the
+// listening ports are keyed by pod name only
+func podNamespaces(output v1.ProbeOutput) map[string]string {
+	namespaces := map[string]string{}
+	for _, item := range output.Items {
+		for _, endpoint := range []v1.ProbeEndpointInfo{item.Source, item.Destination} {
+			if endpoint.Type == v1.POD && endpoint.Name != "" {
+				namespaces[endpoint.Name] = endpoint.Namespace
+			}
+		}
+	}
+	return namespaces
+}
+
+// UndeclaredPorts returns the ports found by netstat that are not container
+// ports of the pod spec. Loopback listeners are not reachable from other pods
+// and are skipped.
+func UndeclaredPorts(output v1.ProbeOutput) []UndeclaredPort {
+	namespaces := podNamespaces(output)
+	undeclared := []UndeclaredPort{}
+	for pod, listening := range output.PodNetworkingV2 {
+		declared := output.PodConfigurationNetworking[pod]
+		name := lo.Ternary(namespaces[pod] != "", namespaces[pod]+"/"+pod, pod)
+		for _, item := range listening {
+			if ip := net.ParseIP(item.IP); ip != nil && ip.IsLoopback() {
+				continue
+			}
+			isDeclared := lo.ContainsBy(declared, func(d v1.PodNetworkingItem) bool {
+				return d.Port == item.Port && strings.EqualFold(d.Protocol, item.Protocol)
+			})
+			if !isDeclared {
+				undeclared = append(undeclared, UndeclaredPort{Pod: name, Port: item.Port, Protocol: item.Protocol, IP: item.IP})
+			}
+		}
+	}
+	undeclared = lo.Uniq(undeclared)
+	sort.Slice(undeclared, func(i, j int) bool {
+		a, b := undeclared[i], undeclared[j]
+		if a.Pod != b.Pod {
+			return a.Pod < b.Pod
+		}
+		if a.Port != b.Port {
+			return a.Port < b.Port
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.IP < b.IP
+	})
+	return undeclared
+}
+
+// Summarize counts the probes and the edges by verdict and lists the pods
+// reaching the Internet and the undeclared listening ports
+func Summarize(output v1.ProbeOutput) Summary {
+	g := graph.Build(output, graph.POD_LEVEL)
+	nodes := lo.KeyBy(g.Nodes, func(n graph.Node) string { return n.ID })
+	summary := Summary{
+		Probes:          lo.CountBy(output.Items, func(item v1.ProbeOutputItem) bool { return item.Type == v1.PROBE }),
+		Errors:          len(output.Errors),
+		Pods:            lo.CountBy(g.Nodes, func(n graph.Node) bool { return n.Type == v1.POD }),
+		InternetExposed: []InternetExposure{},
+		UndeclaredPorts: UndeclaredPorts(output),
+	}
+	for _, edge := range g.Edges {
+		summary.Edges.add(edge.Verdict)
+		for _, port := range edge.Ports {
+			summary.Connections.add(port.Verdict)
+		}
+		if nodes[edge.Target].Type != v1.INTERNET || edge.Allowed == 0 {
+			continue
+		}
+		allowed := lo.Filter(edge.Ports, func(port graph.Port, _ int) bool { return port.Allowed > 0 })
+		summary.InternetExposed = append(summary.InternetExposed, InternetExposure{
+			Pod:         edge.Source,
+			Destination: edge.Target,
+			Ports:       lo.Map(allowed, func(port graph.Port, _ int) string { return formatPort(port.Port, port.Protocol) }),
+		})
+	}
+	return summary
+}
@@ -0,0 +1,86 @@
+package analysis
+
+import (
+	"encoding/json"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "kubesonde.io/api/v1"
+)
+
+var _ = Describe("Summarize", func() {
+	pod := func(name string) v1.ProbeEndpointInfo {
+		return v1.ProbeEndpointInfo{Type: v1.POD, Name: name, Namespace: "default"}
+	}
+	internet := v1.ProbeEndpointInfo{Type: v1.INTERNET, Name: "Google", IPAddress: "google.com"}
+	probe := func(source v1.ProbeEndpointInfo, destination v1.ProbeEndpointInfo, port string, action v1.ActionType) v1.ProbeOutputItem {
+		return v1.ProbeOutputItem{Type: v1.PROBE, Source: source, Destination: destination, Port: port, Protocol: "TCP", ResultingAction: action}
+	}
+
+	It("Counts the edges by verdict", func() {
+		output := v1.ProbeOutput{
+			Items: []v1.ProbeOutputItem{
+				probe(pod("a"), pod("b"), "80", v1.ALLOW),
+				probe(pod("a"), pod("b"), "443", v1.DENY),
+				probe(pod("a"), pod("c"), "80", v1.DENY),
+				probe(pod("b"), pod("c"), "80", v1.ALLOW),
+				{Type: v1.INFO, Source: pod("a")},
+			},
+			Errors: []v1.ProbeOutputError{{Reason: "timeout"}},
+		}
+
+		summary := Summarize(output)
+		Expect(summary.Probes).To(Equal(4))
+		Expect(summary.Errors).To(Equal(1))
+		Expect(summary.Pods).To(Equal(3))
+		Expect(summary.Edges).To(Equal(VerdictCounts{Allowed: 1, Denied: 1, Mixed: 1}))
+		Expect(summary.Connections).To(Equal(VerdictCounts{Allowed: 2, Denied: 2}))
+		Expect(summary.InternetExposed).To(BeEmpty())
+	})
+
+	It("Lists the pods reaching the Internet", func() {
+		output := v1.ProbeOutput{Items: []v1.ProbeOutputItem{
+			probe(pod("a"), internet, "80", v1.ALLOW),
+			probe(pod("a"), internet, "443", v1.DENY),
+			probe(pod("b"), internet, "80", v1.DENY),
+		}}
+
+		Expect(Summarize(output).InternetExposed).To(Equal([]InternetExposure{
+			{Pod: "default/a", Destination: "Google", Ports: []string{"80/TCP"}},
+		}))
+	})
+
+	It("Lists the undeclared listening ports", func() {
+		output := v1.ProbeOutput{
+			Items: []v1.ProbeOutputItem{probe(pod("a"), pod("b"), "80", v1.ALLOW)},
+			PodNetworkingV2: v1.PodNetworkingInfoV2{
+				"a": {
+					{Port: "80", IP: "0.0.0.0", Protocol: "TCP"},
+					{Port: "9090", IP: "0.0.0.0", Protocol: "TCP"},
+					{Port: "6060", IP: "127.0.0.1", Protocol: "TCP"},
+				},
+				"unknown": {{Port: "53", IP: "::", Protocol: "UDP"}},
+			},
+			PodConfigurationNetworking: v1.PodNetworkingInfoV2{
+				"a": {{Port: "80", IP: "0.0.0.0", Protocol: "tcp"}},
+			},
+		}
+
+		Expect(Summarize(output).UndeclaredPorts).To(Equal([]UndeclaredPort{
+			{Pod: "default/a", Port: "9090", Protocol: "TCP", IP: "0.0.0.0"},
+			{Pod: "unknown", Port: "53", Protocol: "UDP", IP: "::"},
+		}))
+	})
+
+	It("Summarizes the archived scans", func() {
+		data, err := os.ReadFile("../../../examples/wordpress.json")
+		Expect(err).To(BeNil())
+		var output v1.ProbeOutput
+		Expect(json.Unmarshal(data, &output)).To(Succeed())
+
+		summary := Summarize(output)
+		Expect(summary.Probes).To(Equal(len(output.Items)))
+		Expect(summary.InternetExposed).NotTo(BeEmpty())
+	})
+})
@@ -0,0 +1,266 @@
+// Package audit keeps a tamper-evident record of the commands Kubesonde runs
+// in the pods. Every record is a JSON line signed with an HMAC-SHA256 key and
+// holding the signature of the previous record, so editing, removing or
+// reordering lines breaks the chain checked by Verify. Without the key the
+// records cannot be rewritten with a valid chain.
+package audit
+
+import (
+	"bufio"
+	"cmp"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	utilexec "k8s.io/client-go/util/exec"
+	kubesondemetrics "kubesonde.io/controllers/metrics"
+	"kubesonde.io/pkg/apitypes"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = logf.Log.WithName("audit")
+
+// Result of a command
+const (
+	// SUCCEEDED commands exited with status 0
+	SUCCEEDED = "succeeded"
+	// FAILED commands exited with a non-zero status
+	FAILED = "failed"
+	// ERROR commands could not be run or their status is unknown
+	ERROR = "error"
+	// BROKEN records start a new chain after a break of the chain found when
+	// opening the log, their error describes the break
+	BROKEN = "broken"
+)
+
+// MAX_RECORDS is the number of records kept in memory for GET /audit
+const MAX_RECORDS = 10000
+
+// UNKNOWN_EXIT_STATUS is the exit status of the commands that did not exit
+const UNKNOWN_EXIT_STATUS = -1
+
+type Record = apitypes.AuditRecord
+
+// Command records a command started at start that returned err
+func Command(namespace, pod, container string, argv []string, start time.Time, err error) Record {
+	record := Record{
+		Timestamp:  start.UTC().Format(time.RFC3339Nano),
+		Pod:        namespace + "/" + pod,
+		Container:  container,
+		Argv:       argv,
+		ExitStatus: 0,
+		DurationMs: time.Since(start).Milliseconds(),
+		Result:     SUCCEEDED,
+	}
+	if err == nil {
+		return record
+	}
+	record.Error = err.Error()
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) && exitErr.Exited() {
+		record.ExitStatus = exitErr.ExitStatus()
+		record.Result = FAILED
+	} else {
+		record.ExitStatus = UNKNOWN_EXIT_STATUS
+		record.Result = ERROR
+	}
+	return record
+}
+
+// Break records a break of the chain, it follows the last valid record
+func Break(err error) Record {
+	return Record{
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Result:    BROKEN,
+		Error:     err.Error(),
+	}
+}
+
+func hash(record Record, key []byte) (string, error) {
+	record.Hash = ""
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	if len(key) == 0 {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:]), nil
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Log chains the records, writes them as JSON lines to the sink and keeps the
+// last MAX_RECORDS in memory
+type Log struct {
+	mu       sync.Mutex
+	sink     io.Writer
+	key      []byte
+	sequence uint64
+	lastHash string
+	records  []Record
+	// owners maps the namespace/name of the instrumented pods to the
+	// namespace/name of their Kubesonde object
+	owners map[string]string
+}
+
+// NewLog writes the records to sink, a nil sink keeps them in memory only.
+// The records are only hashed, NewKeyedLog signs them.
+func NewLog(sink io.Writer) *Log {
+	return NewKeyedLog(sink, nil)
+}
+
+// NewKeyedLog writes the records signed with key to sink
+func NewKeyedLog(sink io.Writer, key []byte) *Log {
+	return &Log{sink: sink, key: key, records: []Record{}, owners: map[string]string{}}
+}
+
+// Resume continues the chain after the last record of an existing log
+func (l *Log) Resume(last Record) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sequence = last.Sequence
+	l.lastHash = last.Hash
+}
+
+// Attribute records that the commands run in the pod, a namespace/name, are
+// run for the kubesonde object, a namespace/name
+func (l *Log) Attribute(pod string, kubesonde string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.owners[pod] = kubesonde
+}
+
+// Forget removes the attribution of a deleted pod
+func (l *Log) Forget(pod string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.owners, pod)
+}
+
+// Append chains the record to the log and writes it to the sink. The record
+// is kept in memory even when the sink fails.
+func (l *Log) Append(record Record) (Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sequence++
+	record.Sequence = l.sequence
+	if record.Kubesonde == "" {
+		record.Kubesonde = l.owners[record.Pod]
+	}
+	record.PreviousHash = l.lastHash
+	var err error
+	if record.Hash, err = hash(record, l.key); err != nil {
+		l.sequence--
+		return Record{}, err
+	}
+	l.lastHash = record.Hash
+	l.records = append(l.records, record)
+	if len(l.records) > MAX_RECORDS {
+		l.records = l.records[len(l.records)-MAX_RECORDS:]
+	}
+	if l.sink == nil {
+		return record, nil
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return record, err
+	}
+	_, err = l.sink.Write(append(line, '\n'))
+	return record, err
+}
+
+// Records returns the records kept in memory, oldest first
+func (l *Log) Records() []Record {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Record{}, l.records...)
+}
+
+// Query selects records, the zero value selects all of them
+type Query = apitypes.AuditQuery
+
+// Verify checks the signatures and the chain of the JSON lines of r with key
+// and returns the last valid record. A log whose oldest files were rotated
+// away starts with a record whose previous hash is not empty, its signature is
+// still checked. The first break of the chain is returned as an error, unless
+// a BROKEN record following the last valid record acknowledges it; the
+// records after a break are still checked against each other. The truncation
+// of the newest records is only detected by comparing the last record to the
+// kubesonde_audit_sequence metric.
+func Verify(r io.Reader, key []byte) (Record, error) {
+	var previous *Record
+	var broken error
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			broken = cmp.Or(broken, fmt.Errorf("line %d: %w", line, err))
+			continue
+		}
+		expected, err := hash(record, key)
+		if err != nil {
+			broken = cmp.Or(broken, fmt.Errorf("line %d: %w", line, err))
+			continue
+		}
+		if record.Hash != expected {
+			broken = cmp.Or(broken, fmt.Errorf("line %d: record %d was modified", line, record.Sequence))
+			continue
+		}
+		follows := previous == nil || (record.PreviousHash == previous.Hash && record.Sequence == previous.Sequence+1)
+		if !follows {
+			broken = cmp.Or(broken, fmt.Errorf("line %d: record %d does not follow record %d", line, record.Sequence, previous.Sequence))
+		} else if record.Result == BROKEN {
+			broken = nil
+		}
+		previous = &record
+	}
+	if err := scanner.Err(); err != nil {
+		broken = cmp.Or(broken, err)
+	}
+	if previous == nil {
+		return Record{}, broken
+	}
+	return *previous, broken
+}
+
+var defaultLog atomic.Pointer[Log]
+
+func init() {
+	defaultLog.Store(NewLog(nil))
+}
+
+// GetDefaultLog returns the log of the commands run by the controller
+func GetDefaultLog() *Log {
+	return defaultLog.Load()
+}
+
+// SetDefaultLog replaces the log of the commands run by the controller
+func SetDefaultLog(l *Log) {
+	defaultLog.Store(l)
+}
+
+// Append adds the record to the default log and exports its sequence, the
+// head of the chain, as a metric. Failing to write it is logged, the command
+// already ran.
+func Append(record Record) {
+	appended, err := GetDefaultLog().Append(record)
+	if err != nil {
+		log.Error(err, "Failed to write the audit record", "pod", record.Pod, "argv", record.Argv)
+	}
+	if appended.Sequence > 0 {
+		kubesondemetrics.AuditSequenceSummary.Set(float64(appended.Sequence))
+	}
+}
@@ -0,0 +1,323 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+var _ = Describe("Command", func() {
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	It("Records a successful command", func() {
+		record := Command("shop", "web-1", "debugger", []string{"nc", "-z", "db", "5432"}, start, nil)
+		Expect(record.Pod).To(Equal("shop/web-1"))
+		Expect(record.Container).To(Equal("debugger"))
+		Expect(record.Timestamp).To(Equal("2025-03-01T10:00:00Z"))
+		Expect(record.ExitStatus).To(Equal(0))
+		Expect(record.Result).To(Equal(SUCCEEDED))
+		Expect(record.Error).To(BeEmpty())
+	})
+
+	It("Records the exit status of a failed command", func() {
+		err := utilexec.CodeExitError{Err: errors.New("command terminated with exit code 1"), Code: 1}
+		record := Command("shop", "web-1", "debugger", []string{"nc", "-z", "db", "5432"}, start, err)
+		Expect(record.ExitStatus).To(Equal(1))
+		Expect(record.Result).To(Equal(FAILED))
+		Expect(record.Error).To(Equal("command terminated with exit code 1"))
+	})
+
+	It("Records the commands that could not run", func() {
+		record := Command("shop", "web-1", "debugger", []string{"nc"}, start, errors.New("pods \"web-1\" not found"))
+		Expect(record.ExitStatus).To(Equal(UNKNOWN_EXIT_STATUS))
+		Expect(record.Result).To(Equal(ERROR))
+	})
+})
+
+var _ = Describe("Log", func() {
+	record := Record{Timestamp: "2025-03-01T10:00:00Z", Pod: "shop/web-1", Container: "debugger", Argv: []string{"nc", "-z", "db", "5432"}, Result: SUCCEEDED}
+	key := []byte("secret")
+
+	It("Chains the records", func() {
+		var sink bytes.Buffer
+		l := NewKeyedLog(&sink, key)
+		l.Attribute("shop/web-1", "default/shop")
+		first, err := l.Append(record)
+		Expect(err).NotTo(HaveOccurred())
+		second, err := l.Append(record)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(first.Sequence).To(Equal(uint64(1)))
+		Expect(first.Kubesonde).To(Equal("default/shop"))
+		Expect(first.PreviousHash).To(BeEmpty())
+		Expect(second.Sequence).To(Equal(uint64(2)))
+		Expect(second.PreviousHash).To(Equal(first.Hash))
+		Expect(l.Records()).To(Equal([]Record{first, second}))
+
+		last, err := Verify(bytes.NewReader(sink.Bytes()), key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(last).To(Equal(second))
+		_, err = Verify(bytes.NewReader(sink.Bytes()), []byte("guess"))
+		Expect(err).To(MatchError("line 1: record 1 was modified"))
+		_, err = Verify(bytes.NewReader(sink.Bytes()), nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Attributes the records to the object that instrumented the pod", func() {
+		l := NewLog(nil)
+		l.Attribute("shop/web-1", "default/shop")
+		l.Attribute("bank/api-1", "default/bank")
+		shop, err := l.Append(record)
+		Expect(err).NotTo(HaveOccurred())
+		bank, err := l.Append(Record{Pod: "bank/api-1"})
+		Expect(err).NotTo(HaveOccurred())
+		l.Forget("shop/web-1")
+		forgotten, err := l.Append(record)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(shop.Kubesonde).To(Equal("default/shop"))
+		Expect(bank.Kubesonde).To(Equal("default/bank"))
+		Expect(forgotten.Kubesonde).To(BeEmpty())
+	})
+
+	It("Cannot be rewritten without the key", func() {
+		var sink bytes.Buffer
+		l := NewKeyedLog(&sink, key)
+		_, err := l.Append(record)
+		Expect(err).NotTo(HaveOccurred())
+
+		// The records rewritten and hashed again without the key are rejected
+		var forged bytes.Buffer
+		forger := NewLog(&forged)
+		_, err = forger.Append(Record{Timestamp: record.Timestamp, Pod: "shop/web-2"})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = Verify(&forged, key)
+		Expect(err).To(MatchError("line 1: record 1 was modified"))
+	})
+
+	It("Detects modified, removed and reordered records", func() {
+		var sink bytes.Buffer
+		l := NewKeyedLog(&sink, key)
+		for range 3 {
+			_, err := l.Append(record)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		lines := strings.SplitAfter(sink.String(), "\n")
+
+		_, err := Verify(strings.NewReader(strings.Replace(sink.String(), `"5432"`, `"5433"`, 1)), key)
+		Expect(err).To(MatchError("line 1: record 1 was modified"))
+		_, err = Verify(strings.NewReader(lines[0]+lines[2]), key)
+		Expect(err).To(MatchError("line 2: record 3 does not follow record 1"))
+		_, err = Verify(strings.NewReader(lines[1]+lines[0]), key)
+		Expect(err).To(MatchError("line 2: record 1 does not follow record 2"))
+	})
+
+	It("Keeps the last records in memory", func() {
+		l := NewLog(nil)
+		for range MAX_RECORDS + 1 {
+			_, err := l.Append(record)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		records := l.Records()
+		Expect(records).To(HaveLen(MAX_RECORDS))
+		Expect(records[0].Sequence).To(Equal(uint64(2)))
+	})
+})
+
+var _ = Describe("Query", func() {
+	records := []Record{
+		{Sequence: 1, Kubesonde: "default/shop", Pod: "shop/web-1"},
+		{Sequence: 2, Kubesonde: "default/shop", Pod: "shop/db-1"},
+		{Sequence: 3, Kubesonde: "default/bank", Pod: "bank/api-1"},
+		{Sequence: 4, Kubesonde: "default/shop", Pod: "shop/web-1"},
+	}
+
+	It("Selects the records", func() {
+		Expect(Query{}.Apply(records)).To(Equal(records))
+		Expect(Query{Kubesonde: "default/shop", Pod: "shop/web-1"}.Apply(records)).To(Equal([]Record{records[0], records[3]}))
+		Expect(Query{After: 1, Limit: 2}.Apply(records)).To(Equal([]Record{records[1], records[2]}))
+	})
+})
+
+var _ = Describe("RotatingFile", func() {
+	record := Record{Timestamp: "2025-03-01T10:00:00Z", Pod: "shop/web-1", Container: "debugger", Argv: []string{"nc", "-z", "db", "5432"}, Result: SUCCEEDED}
+	key := []byte("secret")
+	var path string
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "audit.log")
+	})
+
+	It("Rotates the file and keeps the backups", func() {
+		l, file, err := Open(path, key, 600, 2)
+		Expect(err).NotTo(HaveOccurred())
+		for range 10 {
+			_, err := l.Append(record)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(file.Close()).To(Succeed())
+
+		info, err := os.Stat(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(FILE_MODE)))
+		Expect(info.Size()).To(BeNumerically("<=", 600))
+		Expect(backup(path, 1)).To(BeAnExistingFile())
+		Expect(backup(path, 2)).To(BeAnExistingFile())
+		Expect(backup(path, 3)).NotTo(BeAnExistingFile())
+
+		// The chain continues across the files
+		var all bytes.Buffer
+		for _, name := range []string{backup(path, 2), backup(path, 1), path} {
+			data, err := os.ReadFile(name)
+			Expect(err).NotTo(HaveOccurred())
+			all.Write(data)
+		}
+		last, err := Verify(&all, key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(last.Sequence).To(Equal(uint64(10)))
+	})
+
+	It("Keeps all the rotated files without a maximum", func() {
+		l, file, err := Open(path, key, 600, 0)
+		Expect(err).NotTo(HaveOccurred())
+		for range 10 {
+			_, err := l.Append(record)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(file.Close()).To(Succeed())
+
+		count := backups(path, 0)
+		Expect(count).To(BeNumerically(">", 2))
+		var all bytes.Buffer
+		for index := count; index >= 0; index-- {
+			name := path
+			if index > 0 {
+				name = backup(path, index)
+			}
+			data, err := os.ReadFile(name)
+			Expect(err).NotTo(HaveOccurred())
+			all.Write(data)
+		}
+		// The oldest record is kept
+		Expect(all.String()).To(HavePrefix(`{"sequence":1,`))
+		last, err := Verify(&all, key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(last.Sequence).To(Equal(uint64(10)))
+	})
+
+	// reopen opens the log again and returns the records appended by Open
+	reopen := func() []Record {
+		l, file, err := Open(path, key, 0, 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(file.Close()).To(Succeed())
+		return l.Records()
+	}
+
+	It("Starts a new chain after a removed rotated file", func() {
+		l, file, err := Open(path, key, 600, 2)
+		Expect(err).NotTo(HaveOccurred())
+		for range 10 {
+			_, err := l.Append(record)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(file.Close()).To(Succeed())
+		Expect(os.Remove(backup(path, 1))).To(Succeed())
+
+		appended := reopen()
+		Expect(appended).To(HaveLen(1))
+		Expect(appended[0].Result).To(Equal(BROKEN))
+		Expect(appended[0].Error).To(ContainSubstring("does not follow"))
+		Expect(appended[0].Sequence).To(Equal(uint64(11)))
+
+		// The break is acknowledged by the new chain
+		Expect(reopen()).To(BeEmpty())
+	})
+
+	It("Continues the chain of an existing log", func() {
+		l, file, err := Open(path, key, 0, 0)
+		Expect(err).NotTo(HaveOccurred())
+		first, err := l.Append(record)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(file.Close()).To(Succeed())
+
+		l, file, err = Open(path, key, 0, 0)
+		Expect(err).NotTo(HaveOccurred())
+		second, err := l.Append(record)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(file.Close()).To(Succeed())
+
+		Expect(second.Sequence).To(Equal(uint64(2)))
+		Expect(second.PreviousHash).To(Equal(first.Hash))
+	})
+
+	It("Starts a new chain after a modified record", func() {
+		l, file, err := Open(path, key, 0, 0)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = l.Append(record)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(file.Close()).To(Succeed())
+		data, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(path, bytes.Replace(data, []byte("web-1"), []byte("web-2"), 1), FILE_MODE)).To(Succeed())
+
+		appended := reopen()
+		Expect(appended).To(HaveLen(1))
+		Expect(appended[0].Error).To(ContainSubstring("record 1 was modified"))
+		Expect(appended[0].Sequence).To(Equal(uint64(1)))
+		Expect(appended[0].PreviousHash).To(BeEmpty())
+	})
+
+	It("Starts a new chain after a line torn by a crash", func() {
+		l, file, err := Open(path, key, 0, 0)
+		Expect(err).NotTo(HaveOccurred())
+		first, err := l.Append(record)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = l.Append(record)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(file.Close()).To(Succeed())
+		data, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(path, data[:len(data)-20], FILE_MODE)).To(Succeed())
+
+		appended := reopen()
+		Expect(appended).To(HaveLen(1))
+		Expect(appended[0].Result).To(Equal(BROKEN))
+		Expect(appended[0].Sequence).To(Equal(uint64(2)))
+		Expect(appended[0].PreviousHash).To(Equal(first.Hash))
+		Expect(reopen()).To(BeEmpty())
+
+		data, err = os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.Split(strings.TrimSpace(string(data)), "\n")).To(HaveLen(3))
+	})
+})
+
+var _ = Describe("LoadKey", func() {
+	It("Creates the secret once and reads its key", func() {
+		client := fake.NewSimpleClientset()
+		key, err := LoadKey(context.Background(), client, "kubesonde", "kubesonde-audit-key")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key).To(HaveLen(KEY_SIZE))
+
+		again, err := LoadKey(context.Background(), client, "kubesonde", "kubesonde-audit-key")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again).To(Equal(key))
+	})
+
+	It("Rejects a secret without key", func() {
+		client := fake.NewSimpleClientset(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "empty", Namespace: "kubesonde"}})
+		_, err := LoadKey(context.Background(), client, "kubesonde", "empty")
+		Expect(err).To(MatchError("audit key kubesonde/empty: the field key is empty"))
+	})
+})
@@ -0,0 +1,196 @@
+package audit
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// FILE_MODE restricts the audit files to the controller user
+const FILE_MODE = 0o600
+
+// RotatingFile appends to path and renames it to path.1 when it would grow
+// over maxSize bytes. path.1 becomes path.2 and so on, up to maxBackups files.
+// A maxBackups of 0 keeps all the files.
+type RotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// OpenFile opens path in append mode. A maxSize of 0 disables the rotation.
+func OpenFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	f := &RotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, FILE_MODE)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	terminated, err := terminated(f.path, f.size)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if !terminated {
+		// The last line was torn by a crash, the next record starts on its own line
+		n, err := file.Write([]byte{'\n'})
+		f.size += int64(n)
+		if err != nil {
+			file.Close()
+			return err
+		}
+	}
+	return nil
+}
+
+// terminated reports whether the file of the given size is empty or ends with
+// a new line
+func terminated(path string, size int64) (bool, error) {
+	if size == 0 {
+		return true, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, size-1); err != nil {
+		return false, err
+	}
+	return last[0] == '\n', nil
+}
+
+func backup(path string, index int) string {
+	return fmt.Sprintf("%s.%d", path, index)
+}
+
+// backups returns the number of rotated files of path, maxBackups unless all
+// of them are kept
+func backups(path string, maxBackups int) int {
+	if maxBackups > 0 {
+		return maxBackups
+	}
+	count := 0
+	for {
+		if _, err := os.Stat(backup(path, count+1)); err != nil {
+			return count
+		}
+		count++
+	}
+}
+
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	count := backups(f.path, f.maxBackups)
+	if f.maxBackups > 0 {
+		if err := os.Remove(backup(f.path, f.maxBackups)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		count--
+	}
+	for index := count; index > 0; index-- {
+		if err := os.Rename(backup(f.path, index), backup(f.path, index+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(f.path, backup(f.path, 1)); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+// Write appends p, rotating the file first when p does not fit. A line is
+// never split across files.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if err != nil {
+		return n, err
+	}
+	return n, f.file.Sync()
+}
+
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
+
+// lastRecord verifies the chain of the rotated files, oldest first, and of
+// path, and returns the last valid record and the break of the chain
+func lastRecord(path string, maxBackups int, key []byte) (last Record, broken error, err error) {
+	readers := []io.Reader{}
+	for index := backups(path, maxBackups); index >= 0; index-- {
+		name := path
+		if index > 0 {
+			name = backup(path, index)
+		}
+		existing, err := os.Open(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return Record{}, nil, err
+		}
+		defer existing.Close()
+		readers = append(readers, existing)
+	}
+	last, broken = Verify(io.MultiReader(readers...), key)
+	if broken != nil {
+		broken = fmt.Errorf("audit log %s: %w", path, broken)
+	}
+	return last, broken, nil
+}
+
+// Open verifies the chain of the log at path and of its rotated files with
+// key, opens it and continues the chain. A break of the chain, e.g. a line
+// torn by a crash or a modified record, does not stop the controller: it is
+// logged and a BROKEN record describing it starts a new chain after the last
+// valid record.
+func Open(path string, key []byte, maxSize int64, maxBackups int) (*Log, *RotatingFile, error) {
+	last, broken, err := lastRecord(path, maxBackups, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := OpenFile(path, maxSize, maxBackups)
+	if err != nil {
+		return nil, nil, err
+	}
+	l := NewKeyedLog(file, key)
+	l.Resume(last)
+	if broken != nil {
+		log.Error(broken, "The chain of the audit log is broken, starting a new chain")
+		if _, err := l.Append(Break(broken)); err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+	}
+	return l, file, nil
+}
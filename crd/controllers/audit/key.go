@@ -0,0 +1,47 @@
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// KEY_FIELD is the field of the secret holding the HMAC key
+const KEY_FIELD = "key"
+
+// KEY_SIZE is the size in bytes of the generated keys
+const KEY_SIZE = 32
+
+// LoadKey returns the HMAC key of the secret, the secret is created with a
+// random key when it does not exist
+func LoadKey(ctx context.Context, client kubernetes.Interface, namespace, name string) ([]byte, error) {
+	secrets := client.CoreV1().Secrets(namespace)
+	secret, err := secrets.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		key := make([]byte, KEY_SIZE)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		secret, err = secrets.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Data:       map[string][]byte{KEY_FIELD: key},
+		}, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			// Another replica created it first
+			secret, err = secrets.Get(ctx, name, metav1.GetOptions{})
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("audit key %s/%s: %w", namespace, name, err)
+	}
+	key := secret.Data[KEY_FIELD]
+	if len(key) == 0 {
+		return nil, fmt.Errorf("audit key %s/%s: the field %s is empty", namespace, name, KEY_FIELD)
+	}
+	return key, nil
+}
@@ -0,0 +1,252 @@
+// The baseline module compares the probes to an approved list of workload
+// edges, to report the connectivity that drifted from it
+package baseline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/samber/lo"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/pkg/apitypes"
+	"sigs.k8s.io/yaml"
+)
+
+// DEFAULT_KEY is the ConfigMap key read when the spec does not set one
+const DEFAULT_KEY = "baseline.yaml"
+
+// Edge allows a workload to reach a destination on the listed ports
+type Edge struct {
+	// From and To are namespace/workload, or the name of an external endpoint
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Ports are port/protocol pairs, e.g. 8080/TCP. The protocol defaults to TCP.
+	Ports []string `json:"ports"`
+}
+
+// Baseline is the approved connectivity between workloads
+type Baseline struct {
+	Edges []Edge `json:"edges"`
+}
+
+type DriftKind = apitypes.DriftKind
+
+const (
+	// UNEXPECTED edges are allowed but not in the baseline
+	UNEXPECTED = apitypes.UNEXPECTED
+	// MISSING edges are in the baseline but denied or not probed
+	MISSING = apitypes.MISSING
+)
+
+// DriftEdge is a port of an edge that differs from the baseline
+type DriftEdge = apitypes.DriftEdge
+
+// Drift lists the edges that differ from the baseline
+type Drift = apitypes.Drift
+
+type edgeKey struct {
+	from     string
+	to       string
+	port     string
+	protocol string
+}
+
+// EndpointName returns namespace/workload, the workload being the deployment,
+// the replica set or the endpoint itself. External endpoints have no namespace.
+func EndpointName(info v1.ProbeEndpointInfo) string {
+	name := lo.CoalesceOrEmpty(info.WorkloadName(), info.IPAddress)
+	if info.Namespace == "" {
+		return name
+	}
+	return info.Namespace + "/" + name
+}
+
+func parsePort(value string) (string, string, error) {
+	port, protocol, _ := strings.Cut(strings.TrimSpace(value), "/")
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return "", "", fmt.Errorf("invalid port %q, expected port/protocol, e.g. 8080/TCP", value)
+	}
+	return port, strings.ToUpper(lo.CoalesceOrEmpty(protocol, "TCP")), nil
+}
+
+// observedEdges returns the verdict of each probed workload edge, allowed when
+// the latest probe of one of the connections between the workloads is
+func observedEdges(output v1.ProbeOutput) map[edgeKey]v1.ActionType {
+	edges := map[edgeKey]v1.ActionType{}
+	for _, item := range v1.LatestProbes(output.Items) {
+		if item.Type != v1.PROBE {
+			continue
+		}
+		key := edgeKey{EndpointName(item.Source), EndpointName(item.Destination), item.Port, strings.ToUpper(lo.CoalesceOrEmpty(item.Protocol, "TCP"))}
+		if edges[key] != v1.ALLOW {
+			edges[key] = item.ResultingAction
+		}
+	}
+	return edges
+}
+
+func (b Baseline) edges() (map[edgeKey]bool, error) {
+	edges := map[edgeKey]bool{}
+	for i, edge := range b.Edges {
+		if edge.From == "" || edge.To == "" {
+			return nil, fmt.Errorf("edge %d: from and to are required", i)
+		}
+		for _, value := range edge.Ports {
+			port, protocol, err := parsePort(value)
+			if err != nil {
+				return nil, fmt.Errorf("edge %d: %w", i, err)
+			}
+			edges[edgeKey{edge.From, edge.To, port, protocol}] = true
+		}
+	}
+	return edges, nil
+}
+
+// Validate checks the endpoints and the ports of the edges
+func (b Baseline) Validate() error {
+	_, err := b.edges()
+	return err
+}
+
+// Parse reads a baseline in YAML or JSON. A probe output, e.g. saved from
+// /probes, is accepted too and converted with FromOutput.
+func Parse(data []byte) (Baseline, error) {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return Baseline{}, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(jsonData, &fields); err != nil {
+		return Baseline{}, errors.New("expected an object with a list of edges")
+	}
+	if _, isOutput := fields["items"]; isOutput {
+		var output v1.ProbeOutput
+		if err := json.Unmarshal(jsonData, &output); err != nil {
+			return Baseline{}, err
+		}
+		return FromOutput(output), nil
+	}
+	var baseline Baseline
+	if err := yaml.UnmarshalStrict(data, &baseline); err != nil {
+		return Baseline{}, err
+	}
+	return baseline, baseline.Validate()
+}
+
+// FromOutput returns the allowed edges of the output, to approve the current
+// connectivity
+func FromOutput(output v1.ProbeOutput) Baseline {
+	ports := map[[2]string][]edgeKey{}
+	for key, action := range observedEdges(output) {
+		if action == v1.ALLOW {
+			ports[[2]string{key.from, key.to}] = append(ports[[2]string{key.from, key.to}], key)
+		}
+	}
+	edges := lo.MapToSlice(ports, func(endpoints [2]string, keys []edgeKey) Edge {
+		sort.Slice(keys, func(i, j int) bool { return keyLess(keys[i], keys[j]) })
+		return Edge{
+			From:  endpoints[0],
+			To:    endpoints[1],
+			Ports: lo.Map(keys, func(key edgeKey, _ int) string { return key.port + "/" + key.protocol }),
+		}
+	})
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	return Baseline{Edges: edges}
+}
+
+// Compare returns the allowed edges missing from the baseline, and the edges
+// of the baseline that are denied or were not probed. The ports of an invalid
+// baseline are ignored.
+func Compare(baseline Baseline, output v1.ProbeOutput) Drift {
+	approved, _ := baseline.edges()
+	observed := observedEdges(output)
+	drift := Drift{Unexpected: []DriftEdge{}, Missing: []DriftEdge{}}
+	for key, action := range observed {
+		if action == v1.ALLOW && !approved[key] {
+			drift.Unexpected = append(drift.Unexpected, driftEdge(UNEXPECTED, key, action))
+		}
+	}
+	for key := range approved {
+		if action := observed[key]; action != v1.ALLOW {
+			drift.Missing = append(drift.Missing, driftEdge(MISSING, key, action))
+		}
+	}
+	sortDrift(drift.Unexpected)
+	sortDrift(drift.Missing)
+	return drift
+}
+
+func driftEdge(kind DriftKind, key edgeKey, action v1.ActionType) DriftEdge {
+	return DriftEdge{Kind: kind, From: key.from, To: key.to, Port: key.port, Protocol: key.protocol, Action: action}
+}
+
+func keyLess(a edgeKey, b edgeKey) bool {
+	if a.from != b.from {
+		return a.from < b.from
+	}
+	if a.to != b.to {
+		return a.to < b.to
+	}
+	if a.port != b.port {
+		portA, _ := strconv.Atoi(a.port)
+		portB, _ := strconv.Atoi(b.port)
+		return portA < portB
+	}
+	return a.protocol < b.protocol
+}
+
+func sortDrift(edges []DriftEdge) {
+	sort.Slice(edges, func(i, j int) bool {
+		return keyLess(
+			edgeKey{edges[i].From, edges[i].To, edges[i].Port, edges[i].Protocol},
+			edgeKey{edges[j].From, edges[j].To, edges[j].Port, edges[j].Protocol})
+	})
+}
+
+// Current is the baseline the controller compares the probes to
+type Current struct {
+	// Kubesonde is the namespace/name of the object referencing the baseline
+	Kubesonde string
+	// Source is the namespace/name/key of the ConfigMap holding the baseline
+	Source   string
+	Baseline Baseline
+}
+
+var (
+	currentMu sync.RWMutex
+	current   *Current
+)
+
+func SetCurrent(baseline Current) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = &baseline
+}
+
+// ClearCurrent forgets the baseline when it belongs to the Kubesonde object
+func ClearCurrent(kubesonde string) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	if current != nil && current.Kubesonde == kubesonde {
+		current = nil
+	}
+}
+
+func GetCurrent() (Current, bool) {
+	currentMu.RLock()
+	defer currentMu.RUnlock()
+	if current == nil {
+		return Current{}, false
+	}
+	return *current, true
+}
@@ -0,0 +1,96 @@
+package baseline
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "kubesonde.io/api/v1"
+)
+
+var _ = Describe("Baseline", func() {
+	pod := func(name string, deployment string) v1.ProbeEndpointInfo {
+		return v1.ProbeEndpointInfo{Type: v1.POD, Name: name, Namespace: "shop", DeploymentName: deployment}
+	}
+	internet := v1.ProbeEndpointInfo{Type: v1.INTERNET, Name: "Google", IPAddress: "google.com"}
+	probe := func(source v1.ProbeEndpointInfo, destination v1.ProbeEndpointInfo, port string, action v1.ActionType) v1.ProbeOutputItem {
+		return v1.ProbeOutputItem{Type: v1.PROBE, Source: source, Destination: destination, Port: port, Protocol: "TCP", ResultingAction: action}
+	}
+	output := v1.ProbeOutput{Items: []v1.ProbeOutputItem{
+		probe(pod("web-1", "web"), pod("api-1", "api"), "8080", v1.ALLOW),
+		probe(pod("web-2", "web"), pod("api-1", "api"), "8080", v1.DENY),
+		probe(pod("web-1", "web"), pod("api-1", "api"), "9090", v1.ALLOW),
+		probe(pod("web-1", "web"), pod("db-1", "db"), "5432", v1.DENY),
+		probe(pod("api-1", "api"), internet, "443", v1.ALLOW),
+	}}
+
+	It("Parses YAML", func() {
+		baseline, err := Parse([]byte(`
+edges:
+- from: shop/web
+  to: shop/api
+  ports: ["8080", 9090/tcp]
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(baseline.Edges).To(Equal([]Edge{{From: "shop/web", To: "shop/api", Ports: []string{"8080", "9090/tcp"}}}))
+	})
+
+	It("Rejects invalid baselines", func() {
+		_, err := Parse([]byte(`{"edges": [{"from": "shop/web", "to": "shop/api", "ports": ["http"]}]}`))
+		Expect(err).To(MatchError(ContainSubstring(`edge 0: invalid port "http"`)))
+		_, err = Parse([]byte(`{"edges": [{"from": "shop/web", "ports": ["80"]}]}`))
+		Expect(err).To(MatchError(ContainSubstring("from and to are required")))
+		_, err = Parse([]byte(`{"edge": []}`))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Generates the baseline from the allowed workload edges", func() {
+		Expect(FromOutput(output)).To(Equal(Baseline{Edges: []Edge{
+			{From: "shop/api", To: "Google", Ports: []string{"443/TCP"}},
+			{From: "shop/web", To: "shop/api", Ports: []string{"8080/TCP", "9090/TCP"}},
+		}}))
+
+		data, err := json.Marshal(output)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Parse(data)).To(Equal(FromOutput(output)))
+	})
+
+	It("Reports the unexpected and the missing edges", func() {
+		baseline := Baseline{Edges: []Edge{
+			{From: "shop/web", To: "shop/api", Ports: []string{"8080"}},
+			{From: "shop/web", To: "shop/db", Ports: []string{"5432/TCP"}},
+			{From: "shop/worker", To: "shop/db", Ports: []string{"5432/TCP"}},
+		}}
+
+		drift := Compare(baseline, output)
+		Expect(drift.Unexpected).To(Equal([]DriftEdge{
+			{Kind: UNEXPECTED, From: "shop/api", To: "Google", Port: "443", Protocol: "TCP", Action: v1.ALLOW},
+			{Kind: UNEXPECTED, From: "shop/web", To: "shop/api", Port: "9090", Protocol: "TCP", Action: v1.ALLOW},
+		}))
+		Expect(drift.Missing).To(Equal([]DriftEdge{
+			{Kind: MISSING, From: "shop/web", To: "shop/db", Port: "5432", Protocol: "TCP", Action: v1.DENY},
+			{Kind: MISSING, From: "shop/worker", To: "shop/db", Port: "5432", Protocol: "TCP"},
+		}))
+		Expect(drift.Edges()[0].String()).To(Equal("+ shop/api -> Google 443/TCP"))
+		Expect(drift.Missing[0].String()).To(Equal("- shop/web -> shop/db 5432/TCP"))
+
+		Expect(Compare(FromOutput(output), output)).To(Equal(Drift{Unexpected: []DriftEdge{}, Missing: []DriftEdge{}}))
+	})
+
+	It("Compares the latest probe of each connection", func() {
+		allowed := probe(pod("web-1", "web"), pod("db-1", "db"), "5432", v1.ALLOW)
+		allowed.Timestamp = 1
+		denied := probe(pod("web-1", "web"), pod("db-1", "db"), "5432", v1.DENY)
+		denied.Timestamp = 2
+		baseline := Baseline{Edges: []Edge{{From: "shop/web", To: "shop/db", Ports: []string{"5432"}}}}
+
+		drift := Compare(baseline, v1.ProbeOutput{Items: []v1.ProbeOutputItem{allowed, denied}})
+		Expect(drift.Unexpected).To(BeEmpty())
+		Expect(drift.Missing).To(Equal([]DriftEdge{
+			{Kind: MISSING, From: "shop/web", To: "shop/db", Port: "5432", Protocol: "TCP", Action: v1.DENY},
+		}))
+
+		drift = Compare(Baseline{}, v1.ProbeOutput{Items: []v1.ProbeOutputItem{denied, allowed}})
+		Expect(drift.Unexpected).To(BeEmpty())
+	})
+})
@@ -0,0 +1,214 @@
+// Package cloudevents sends the probe results and the findings as CloudEvents
+// 1.0 over HTTP, in binary or structured content mode, e.g. to a Knative broker.
+package cloudevents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/delivery"
+	"kubesonde.io/controllers/findings"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = logf.Log.WithName("CloudEvents")
+
+const SPEC_VERSION = "1.0"
+
+// Types of the events, the version is bumped on breaking changes of the data
+const (
+	// PROBE_RESULT_TYPE is a new probe result, or a probe whose outcome changed
+	PROBE_RESULT_TYPE = "io.kubesonde.probe.result.v1"
+	// FINDING_TYPE is a finding of the probes, see the findings package
+	FINDING_TYPE = "io.kubesonde.finding.v1"
+)
+
+// TYPES lists the types of events
+var TYPES = []string{PROBE_RESULT_TYPE, FINDING_TYPE}
+
+// Content modes of the HTTP binding
+const (
+	// BINARY_MODE sends the attributes in ce- headers and the data as the body
+	BINARY_MODE = "binary"
+	// STRUCTURED_MODE sends the whole event as the body
+	STRUCTURED_MODE = "structured"
+)
+
+const (
+	STRUCTURED_CONTENT_TYPE = "application/cloudevents+json; charset=UTF-8"
+	DATA_CONTENT_TYPE       = "application/json"
+)
+
+const DEFAULT_MAX_RETRIES = 5
+
+// Attributes are the context attributes of an event
+type Attributes struct {
+	SpecVersion string `json:"specversion"`
+	ID          string `json:"id"`
+	// Source is the Kubesonde object sending the event, e.g.
+	// /apis/security.kubesonde.io/v1/namespaces/default/kubesondes/shop
+	Source string `json:"source"`
+	Type   string `json:"type"`
+	// Subject is the namespace/name of the source pod of the probe
+	Subject         string `json:"subject,omitempty"`
+	Time            string `json:"time"`
+	DataContentType string `json:"datacontenttype"`
+}
+
+// ProbeResultEvent is an event of type PROBE_RESULT_TYPE in structured mode,
+// its data is the probe result
+type ProbeResultEvent struct {
+	Attributes
+	Data v1.ProbeOutputItem `json:"data"`
+}
+
+// FindingEvent is an event of type FINDING_TYPE in structured mode
+type FindingEvent struct {
+	Attributes
+	Data findings.Finding `json:"data"`
+}
+
+// Event is a ProbeResultEvent or a FindingEvent
+type Event interface {
+	Context() Attributes
+	Payload() any
+}
+
+func (e ProbeResultEvent) Context() Attributes { return e.Attributes }
+func (e ProbeResultEvent) Payload() any        { return e.Data }
+func (e FindingEvent) Context() Attributes     { return e.Attributes }
+func (e FindingEvent) Payload() any            { return e.Data }
+
+// SourceOf returns the source attribute of the events of a Kubesonde object
+func SourceOf(namespace string, name string) string {
+	return fmt.Sprintf("/apis/%s/%s/namespaces/%s/kubesondes/%s", v1.GroupVersion.Group, v1.GroupVersion.Version, namespace, name)
+}
+
+func newAttributes(source string, eventType string, subject string, timestamp int64) Attributes {
+	return Attributes{
+		SpecVersion:     SPEC_VERSION,
+		ID:              uuid.NewString(),
+		Source:          source,
+		Type:            eventType,
+		Subject:         subject,
+		Time:            time.Unix(timestamp, 0).UTC().Format(time.RFC3339),
+		DataContentType: DATA_CONTENT_TYPE,
+	}
+}
+
+// NewProbeResultEvent wraps a probe result, timestamp is in seconds since the epoch
+func NewProbeResultEvent(source string, timestamp int64, item v1.ProbeOutputItem) ProbeResultEvent {
+	subject := item.Source.Name
+	if item.Source.Namespace != "" {
+		subject = item.Source.Namespace + "/" + subject
+	}
+	return ProbeResultEvent{Attributes: newAttributes(source, PROBE_RESULT_TYPE, subject, timestamp), Data: item}
+}
+
+func NewFindingEvent(source string, finding findings.Finding) FindingEvent {
+	return FindingEvent{Attributes: newAttributes(source, FINDING_TYPE, finding.Source, finding.Timestamp), Data: finding}
+}
+
+// Sink is the CloudEvents sink of a Kubesonde object
+type Sink struct {
+	// Kubesonde is the namespace/name of the object declaring the sink
+	Kubesonde string
+	// Source is the source attribute of the events
+	Source     string
+	URL        string
+	Mode       string
+	Types      []string
+	MaxRetries int
+}
+
+// NewSink checks the spec of a sink and applies its defaults
+func NewSink(namespace string, name string, spec v1.CloudEventsSink) (Sink, error) {
+	sink := Sink{
+		Kubesonde:  namespace + "/" + name,
+		Source:     SourceOf(namespace, name),
+		URL:        spec.URL,
+		Mode:       lo.CoalesceOrEmpty(spec.Mode, BINARY_MODE),
+		MaxRetries: DEFAULT_MAX_RETRIES,
+	}
+	parsed, err := url.Parse(spec.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return Sink{}, errors.New("invalid CloudEvents sink URL, expected an http or https URL")
+	}
+	if sink.Mode != BINARY_MODE && sink.Mode != STRUCTURED_MODE {
+		return Sink{}, fmt.Errorf("invalid CloudEvents mode %s, expected %s or %s", sink.Mode, BINARY_MODE, STRUCTURED_MODE)
+	}
+	for _, eventType := range spec.Types {
+		if !lo.Contains(TYPES, eventType) {
+			return Sink{}, fmt.Errorf("invalid CloudEvents type %s, expected one of %v", eventType, TYPES)
+		}
+	}
+	sink.Types = spec.Types
+	if spec.MaxRetries != nil {
+		sink.MaxRetries = max(int(*spec.MaxRetries), 0)
+	}
+	return sink, nil
+}
+
+// Accepts tells whether the sink receives the type of events
+func (s Sink) Accepts(eventType string) bool {
+	return len(s.Types) == 0 || lo.Contains(s.Types, eventType)
+}
+
+// Request encodes an event in the content mode of the sink
+func (s Sink) Request(ctx context.Context, event Event) (*http.Request, error) {
+	attributes := event.Context()
+	var body []byte
+	var err error
+	if s.Mode == STRUCTURED_MODE {
+		body, err = json.Marshal(event)
+	} else {
+		body, err = json.Marshal(event.Payload())
+	}
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if s.Mode == STRUCTURED_MODE {
+		request.Header.Set("Content-Type", STRUCTURED_CONTENT_TYPE)
+		return request, nil
+	}
+	request.Header.Set("Content-Type", attributes.DataContentType)
+	request.Header.Set("ce-specversion", attributes.SpecVersion)
+	request.Header.Set("ce-id", attributes.ID)
+	request.Header.Set("ce-source", attributes.Source)
+	request.Header.Set("ce-type", attributes.Type)
+	request.Header.Set("ce-time", attributes.Time)
+	if attributes.Subject != "" {
+		request.Header.Set("ce-subject", attributes.Subject)
+	}
+	return request, nil
+}
+
+func (s Sink) post(ctx context.Context, client *http.Client, event Event) error {
+	request, err := s.Request(ctx, event)
+	if err != nil {
+		return err
+	}
+	return delivery.Post(client, request)
+}
+
+// Send posts an event. Failed deliveries are retried with an exponential
+// backoff, up to MaxRetries times, with the same event ID so that the sink
+// can drop duplicates.
+func (s Sink) Send(ctx context.Context, client *http.Client, event Event) error {
+	return delivery.Retry(ctx, s.MaxRetries, func() error { return s.post(ctx, client, event) }, func(attempt int, err error) {
+		log.Info("Retrying the CloudEvents sink", "kubesonde", s.Kubesonde, "id", event.Context().ID, "attempt", attempt, "error", err.Error())
+	})
+}
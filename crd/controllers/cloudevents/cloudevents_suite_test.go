@@ -0,0 +1,13 @@
+package cloudevents
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestCloudEvents(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CloudEvents")
+}
@@ -0,0 +1,180 @@
+package cloudevents
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/delivery"
+	"kubesonde.io/controllers/findings"
+	"kubesonde.io/controllers/state"
+)
+
+// receiver records the requests posted to it and answers with the statuses,
+// then with 202 like a Knative broker
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	headers  []http.Header
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+	body, _ := io.ReadAll(request.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.headers = append(r.headers, request.Header.Clone())
+	r.bodies = append(r.bodies, body)
+	status := http.StatusAccepted
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (r *receiver) requests() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]byte{}, r.bodies...)
+}
+
+func (r *receiver) header(i int) http.Header {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.headers[i]
+}
+
+var _ = Describe("CloudEvents", func() {
+	var server *httptest.Server
+	var received *receiver
+	web := v1.ProbeEndpointInfo{Type: v1.POD, Name: "web-1", Namespace: "shop"}
+	google := v1.ProbeEndpointInfo{Type: v1.INTERNET, Name: "Google", IPAddress: "google.com"}
+	db := v1.ProbeEndpointInfo{Type: v1.POD, Name: "db-1", Namespace: "shop"}
+	item := v1.ProbeOutputItem{Type: v1.PROBE, Source: web, Destination: db, Port: "5432", Protocol: "TCP", ResultingAction: v1.ALLOW}
+
+	BeforeEach(func() {
+		received = &receiver{}
+		server = httptest.NewServer(received)
+		delivery.INITIAL_BACKOFF = time.Millisecond
+	})
+
+	AfterEach(func() {
+		server.Close()
+		delivery.INITIAL_BACKOFF = time.Second
+	})
+
+	It("Checks the spec of the sink", func() {
+		sink, err := NewSink("default", "kubesonde", v1.CloudEventsSink{URL: server.URL})
+		Expect(err).To(BeNil())
+		Expect(sink.Kubesonde).To(Equal("default/kubesonde"))
+		Expect(sink.Source).To(Equal("/apis/security.kubesonde.io/v1/namespaces/default/kubesondes/kubesonde"))
+		Expect(sink.Mode).To(Equal(BINARY_MODE))
+		Expect(sink.MaxRetries).To(Equal(DEFAULT_MAX_RETRIES))
+		Expect(sink.Accepts(FINDING_TYPE)).To(BeTrue())
+
+		sink, err = NewSink("default", "kubesonde", v1.CloudEventsSink{URL: server.URL, Types: []string{PROBE_RESULT_TYPE}, MaxRetries: lo.ToPtr(int32(0))})
+		Expect(err).To(BeNil())
+		Expect(sink.Accepts(FINDING_TYPE)).To(BeFalse())
+		Expect(sink.MaxRetries).To(Equal(0))
+
+		_, err = NewSink("default", "kubesonde", v1.CloudEventsSink{URL: "broker.default"})
+		Expect(err).To(MatchError(ContainSubstring("invalid CloudEvents sink URL")))
+		_, err = NewSink("default", "kubesonde", v1.CloudEventsSink{URL: server.URL, Mode: "batched"})
+		Expect(err).To(MatchError(ContainSubstring("invalid CloudEvents mode batched")))
+		_, err = NewSink("default", "kubesonde", v1.CloudEventsSink{URL: server.URL, Types: []string{"io.kubesonde.probe.result.v2"}})
+		Expect(err).To(MatchError(ContainSubstring("invalid CloudEvents type io.kubesonde.probe.result.v2")))
+	})
+
+	It("Sends the attributes as headers in binary mode", func() {
+		sink, _ := NewSink("default", "kubesonde", v1.CloudEventsSink{URL: server.URL})
+		event := NewProbeResultEvent(sink.Source, 1700000000, item)
+		Expect(sink.Send(context.Background(), http.DefaultClient, event)).To(Succeed())
+
+		Expect(received.requests()).To(HaveLen(1))
+		header := received.header(0)
+		Expect(header.Get("Content-Type")).To(Equal(DATA_CONTENT_TYPE))
+		Expect(header.Get("ce-specversion")).To(Equal("1.0"))
+		Expect(header.Get("ce-id")).To(Equal(event.ID))
+		Expect(header.Get("ce-source")).To(Equal(sink.Source))
+		Expect(header.Get("ce-type")).To(Equal(PROBE_RESULT_TYPE))
+		Expect(header.Get("ce-subject")).To(Equal("shop/web-1"))
+		Expect(header.Get("ce-time")).To(Equal("2023-11-14T22:13:20Z"))
+		var data v1.ProbeOutputItem
+		Expect(json.Unmarshal(received.requests()[0], &data)).To(Succeed())
+		Expect(data).To(Equal(item))
+	})
+
+	It("Sends the whole event as the body in structured mode", func() {
+		sink, _ := NewSink("default", "kubesonde", v1.CloudEventsSink{URL: server.URL, Mode: STRUCTURED_MODE})
+		finding := findings.Finding{Kind: findings.VERDICT_FLIP, Timestamp: 1700000000, Source: "shop/web-1", Action: v1.ALLOW, PreviousAction: v1.DENY, Probe: item}
+		event := NewFindingEvent(sink.Source, finding)
+		Expect(sink.Send(context.Background(), http.DefaultClient, event)).To(Succeed())
+
+		Expect(received.header(0).Get("Content-Type")).To(Equal(STRUCTURED_CONTENT_TYPE))
+		Expect(received.header(0).Get("ce-id")).To(BeEmpty())
+		var sent FindingEvent
+		Expect(json.Unmarshal(received.requests()[0], &sent)).To(Succeed())
+		Expect(sent).To(Equal(event))
+		Expect(sent.SpecVersion).To(Equal("1.0"))
+		Expect(sent.Type).To(Equal(FINDING_TYPE))
+		Expect(sent.DataContentType).To(Equal(DATA_CONTENT_TYPE))
+	})
+
+	It("Retries the failed deliveries with the same ID", func() {
+		sink, _ := NewSink("default", "kubesonde", v1.CloudEventsSink{URL: server.URL, MaxRetries: lo.ToPtr(int32(2))})
+		event := NewProbeResultEvent(sink.Source, 1700000000, item)
+		received.statuses = []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}
+		Expect(sink.Send(context.Background(), http.DefaultClient, event)).To(Succeed())
+		Expect(received.requests()).To(HaveLen(3))
+		Expect(received.header(2).Get("ce-id")).To(Equal(received.header(0).Get("ce-id")))
+
+		received.statuses = []int{http.StatusBadRequest}
+		Expect(sink.Send(context.Background(), http.DefaultClient, event)).To(MatchError("unexpected status 400"))
+		Expect(received.requests()).To(HaveLen(4))
+	})
+
+	It("Publishes the probe results of the state and their findings", func() {
+		sm := state.NewStateManager()
+		publisher := NewPublisher()
+		feed := delivery.NewFeed(sm, publisher)
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			Expect(feed.Start(ctx)).To(Succeed())
+		}()
+
+		sink, _ := NewSink("default", "kubesonde", v1.CloudEventsSink{URL: server.URL})
+		publisher.Configure("default/kubesonde", &sink)
+		Expect(publisher.Sinks()).To(Equal([]Sink{sink}))
+
+		Expect(sm.AppendProbes(&[]v1.ProbeOutputItem{
+			{Type: v1.PROBE, Source: web, Destination: google, Port: "443", Protocol: "TCP", ResultingAction: v1.ALLOW},
+			item,
+		})).To(Succeed())
+		Eventually(received.requests).Should(HaveLen(3))
+		types := lo.Times(3, func(i int) string { return received.header(i).Get("ce-type") })
+		Expect(types).To(Equal([]string{PROBE_RESULT_TYPE, FINDING_TYPE, PROBE_RESULT_TYPE}))
+		var finding findings.Finding
+		Expect(json.Unmarshal(received.requests()[1], &finding)).To(Succeed())
+		Expect(finding.Kind).To(Equal(findings.INTERNET_REACHABLE))
+
+		publisher.Configure("default/kubesonde", nil)
+		Expect(publisher.Sinks()).To(BeEmpty())
+		Expect(sm.AppendProbes(&[]v1.ProbeOutputItem{
+			{Type: v1.PROBE, Source: web, Destination: db, Port: "5432", Protocol: "TCP", ResultingAction: v1.DENY},
+		})).To(Succeed())
+		Consistently(received.requests, "100ms").Should(HaveLen(3))
+
+		cancel()
+		Eventually(stopped).Should(BeClosed())
+	})
+})
@@ -0,0 +1,129 @@
+package cloudevents
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	"kubesonde.io/controllers/delivery"
+	"kubesonde.io/controllers/findings"
+	kubesondemetrics "kubesonde.io/controllers/metrics"
+	"kubesonde.io/controllers/state"
+)
+
+// QUEUE_SIZE bounds the events waiting for a sink, newer ones are dropped
+const QUEUE_SIZE = 1024
+
+// sender posts the events of a sink in order
+type sender struct {
+	sink   Sink
+	events chan Event
+	done   chan struct{}
+}
+
+func (s *sender) run(ctx context.Context, client *http.Client) {
+	defer close(s.done)
+	namespace, name, _ := strings.Cut(s.sink.Kubesonde, "/")
+	for event := range s.events {
+		result := "delivered"
+		if err := s.sink.Send(ctx, client, event); err != nil {
+			log.Error(err, "Failed to send the event", "kubesonde", s.sink.Kubesonde, "id", event.Context().ID, "type", event.Context().Type)
+			result = "failed"
+		}
+		kubesondemetrics.CloudEventsSummary.WithLabelValues(namespace, name, event.Context().Type, result).Inc()
+	}
+}
+
+// Publisher sends the probe results of the state and their findings to the
+// CloudEvents sinks of the Kubesonde objects. It is a handler of the delivery
+// feed.
+type Publisher struct {
+	client *http.Client
+	ctx    context.Context
+	cancel context.CancelFunc
+	mu     sync.Mutex
+	// senders are the sinks by namespace/name of the Kubesonde object
+	senders map[string]*sender
+}
+
+func NewPublisher() *Publisher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Publisher{
+		client:  &http.Client{Timeout: delivery.DELIVERY_TIMEOUT},
+		ctx:     ctx,
+		cancel:  cancel,
+		senders: map[string]*sender{},
+	}
+}
+
+// Configure replaces the sink of a Kubesonde object, nil removes it. The
+// pending events of the previous sink are sent first.
+func (p *Publisher) Configure(kubesonde string, sink *Sink) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if s, found := p.senders[kubesonde]; found {
+		close(s.events)
+		delete(p.senders, kubesonde)
+	}
+	if sink == nil {
+		return
+	}
+	s := &sender{sink: *sink, events: make(chan Event, QUEUE_SIZE), done: make(chan struct{})}
+	go s.run(p.ctx, p.client)
+	p.senders[kubesonde] = s
+}
+
+// Sinks returns the configured sinks
+func (p *Publisher) Sinks() []Sink {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return lo.Map(lo.Values(p.senders), func(s *sender, _ int) Sink { return s.sink })
+}
+
+// Handle queues the events of a change for the sinks accepting them, each
+// sink gets its own events with its source
+func (p *Publisher) Handle(change state.Change, detected []findings.Finding) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, s := range p.senders {
+		events := []Event{}
+		if change.Kind == state.PROBE_CHANGE && s.sink.Accepts(PROBE_RESULT_TYPE) {
+			events = append(events, NewProbeResultEvent(s.sink.Source, change.Timestamp, *change.Probe))
+		}
+		if s.sink.Accepts(FINDING_TYPE) {
+			for _, finding := range detected {
+				events = append(events, NewFindingEvent(s.sink.Source, finding))
+			}
+		}
+		for _, event := range events {
+			select {
+			case s.events <- event:
+			default:
+				log.Info("Dropping an event, the sink does not keep up", "kubesonde", s.sink.Kubesonde, "type", event.Context().Type)
+			}
+		}
+	}
+}
+
+// Stop sends the pending events and waits for the sinks
+func (p *Publisher) Stop() {
+	p.mu.Lock()
+	senders := lo.Values(p.senders)
+	p.senders = map[string]*sender{}
+	p.mu.Unlock()
+	for _, s := range senders {
+		close(s.events)
+	}
+	timeout := time.After(delivery.DELIVERY_TIMEOUT)
+	for _, s := range senders {
+		select {
+		case <-s.done:
+		case <-timeout:
+			p.cancel()
+		}
+	}
+	p.cancel()
+}
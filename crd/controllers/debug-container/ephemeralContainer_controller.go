@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/samber/lo"
@@ -14,19 +15,29 @@ import (
 	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"k8s.io/client-go/kubernetes"
 	scheme "k8s.io/client-go/kubernetes/scheme"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/remotecommand"
 	kubesondev1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/audit"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 var log = logf.Log.WithName("controllers.state")
 
-func InstallEphameralContainers(client kubernetes.Interface, kubesonde kubesondev1.Kubesonde, pods *v1.PodList) {
+// InstallEphameralContainers installs the debug containers in the pods and
+// updates them with their patched spec. The profile of the spec falls back to
+// restricted in the namespaces whose Pod Security level rejects it.
+func InstallEphameralContainers(client kubernetes.Interface, namespaces corelisters.NamespaceLister, kubesonde kubesondev1.Kubesonde, pods *v1.PodList) {
 	podList := pods.Items
 	for i := range podList {
 		if !EphemeralContainerExists(&podList[i]) {
-			installContainers(client, kubesonde, &podList[i])
+			namespace, err := namespaces.Get(podList[i].Namespace)
+			if err != nil {
+				log.Error(err, "Failed to read the namespace of the pod, ignoring its Pod Security level", "pod", podList[i].Name, "namespace", podList[i].Namespace)
+				namespace = nil
+			}
+			installContainers(client, effectiveSpec(kubesonde, namespace), &podList[i])
 			log.V(1).Info(fmt.Sprintf("Installing debug containers in %s pod", podList[i].Name))
 		}
 
@@ -62,6 +73,12 @@ func EphemeralContainersRunning(pod *v1.Pod) bool {
 }
 
 func installContainers(client kubernetes.Interface, kubesonde kubesondev1.Kubesonde, pod *v1.Pod) {
+	if missing := missingImagePullSecrets(kubesonde, pod); len(missing) > 0 {
+		log.Info(fmt.Sprintf("Pod %s does not reference the image pull secrets %v, skipping it", pod.Name, missing))
+		recordFailure(pod, IMAGE_PULL_SECRET_MISSING, fmt.Sprintf("the pod does not reference the image pull secrets %s, add them to the service account %s and restart the pod",
+			strings.Join(missing, ", "), lo.CoalesceOrEmpty(pod.Spec.ServiceAccountName, "default")))
+		return
+	}
 	podJS, err := json.Marshal(pod)
 	if err != nil {
 		log.Error(err, "error creating JSON for pod: %s", pod.Name)
@@ -80,16 +97,19 @@ func installContainers(client kubernetes.Interface, kubesonde kubesondev1.Kubeso
 	}
 
 	pods := client.CoreV1().Pods(pod.Namespace)
-	_, err = pods.Patch(context.TODO(), pod.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{}, "ephemeralcontainers")
+	patched, err := pods.Patch(context.TODO(), pod.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{}, "ephemeralcontainers")
 	if err != nil {
 		log.Error(err, fmt.Sprintf("Error while setting up the ephemeral container in pod %s", pod.Name))
+		recordFailure(pod, PATCH_FAILED, err.Error())
 		return
 	}
+	ForgetPod(pod)
+	*pod = *patched
 
 }
 
 func generateDebugContainers(kubesonde kubesondev1.Kubesonde, pod *v1.Pod) (*v1.Pod, error) {
-	privileged := true
+	debuggerSecurity, monitorSecurity := securityContexts(kubesonde)
 
 	// Use configurable images from the spec
 	debuggerImage := kubesonde.Spec.DebuggerImage
@@ -112,9 +132,7 @@ func generateDebugContainers(kubesonde kubesondev1.Kubesonde, pod *v1.Pod) (*v1.
 			TerminationMessagePolicy: v1.TerminationMessageReadFile,
 			TTY:                      true,
 			Command:                  []string{"sh"},
-			SecurityContext: &v1.SecurityContext{
-				Privileged: &privileged,
-			},
+			SecurityContext:          debuggerSecurity,
 		},
 	}
 	ec2 := &v1.EphemeralContainer{
@@ -126,9 +144,8 @@ func generateDebugContainers(kubesonde kubesondev1.Kubesonde, pod *v1.Pod) (*v1.
 			TerminationMessagePolicy: v1.TerminationMessageReadFile,
 			TTY:                      true,
 			Command:                  []string{"sh"},
-			SecurityContext: &v1.SecurityContext{
-				Privileged: &privileged,
-			},
+			Env:                      monitorEnv(kubesonde),
+			SecurityContext:          monitorSecurity,
 		}}
 
 	copied := pod.DeepCopy()
@@ -173,13 +190,14 @@ func RunMonitorContainerProcess(client kubernetes.Interface, namespace string, s
 
 	go func() { // On the background try to enstablish again connection
 		for {
-
+			start := time.Now()
 			err = exec.Stream(remotecommand.StreamOptions{
 				Stdin:  nil,
 				Stdout: &stdout,
 				Stderr: &stderr,
 				Tty:    false,
 			})
+			audit.Append(audit.Command(namespace, sourcePodName, "monitor", []string{"./main"}, start, err))
 			if err != nil {
 				log.Info(fmt.Sprintf("Monitor container not found in Pod %s - %s", sourcePodName, err.Error()))
 				time.Sleep(3 * time.Second)
@@ -2,14 +2,21 @@ package debug_container
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	testclient "k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 	kubesondev1 "kubesonde.io/api/v1"
 )
 
@@ -168,3 +175,139 @@ func TestEphemeralContainerNamesAndImages(t *testing.T) {
 	// Verify no other container names exist
 	assert.Len(t, containerMap, 2, "Should have exactly 2 containers")
 }
+
+func TestSecurityProfiles(t *testing.T) {
+	containers := func(spec *kubesondev1.DebugContainers) (v1.EphemeralContainer, v1.EphemeralContainer) {
+		kubesonde := kubesondev1.Kubesonde{Spec: kubesondev1.KubesondeSpec{DebugContainers: spec}}
+		pod, err := generateDebugContainers(kubesonde, &v1.Pod{})
+		assert.NoError(t, err)
+		return pod.Spec.EphemeralContainers[0], pod.Spec.EphemeralContainers[1]
+	}
+
+	t.Run("Adds only the raw socket capabilities to the debugger by default", func(t *testing.T) {
+		debugger, monitor := containers(nil)
+		assert.Nil(t, debugger.SecurityContext.Privileged)
+		assert.Equal(t, &v1.Capabilities{Drop: []v1.Capability{"ALL"}, Add: []v1.Capability{"NET_RAW"}}, debugger.SecurityContext.Capabilities)
+		assert.Equal(t, v1.SeccompProfileTypeRuntimeDefault, debugger.SecurityContext.SeccompProfile.Type)
+		assert.False(t, *debugger.SecurityContext.AllowPrivilegeEscalation)
+		// The user of the pod does not apply to the debugger
+		assert.False(t, *debugger.SecurityContext.RunAsNonRoot)
+		assert.Equal(t, ROOT_USER, *debugger.SecurityContext.RunAsUser)
+		assert.True(t, *monitor.SecurityContext.RunAsNonRoot)
+		assert.Equal(t, DEFAULT_RUN_AS_USER, *monitor.SecurityContext.RunAsUser)
+		assert.Equal(t, []v1.Capability{"ALL"}, monitor.SecurityContext.Capabilities.Drop)
+		assert.Nil(t, monitor.Env)
+	})
+
+	t.Run("Runs both containers as non-root without capabilities", func(t *testing.T) {
+		debugger, monitor := containers(&kubesondev1.DebugContainers{Profile: kubesondev1.RESTRICTED_PROFILE, RunAsUser: lo.ToPtr(int64(1000))})
+		for _, container := range []v1.EphemeralContainer{debugger, monitor} {
+			assert.True(t, *container.SecurityContext.RunAsNonRoot, container.Name)
+			assert.Equal(t, int64(1000), *container.SecurityContext.RunAsUser, container.Name)
+			assert.Equal(t, &v1.Capabilities{Drop: []v1.Capability{"ALL"}}, container.SecurityContext.Capabilities, container.Name)
+			assert.Equal(t, v1.SeccompProfileTypeRuntimeDefault, container.SecurityContext.SeccompProfile.Type, container.Name)
+		}
+	})
+
+	t.Run("Keeps the privileged containers", func(t *testing.T) {
+		debugger, monitor := containers(&kubesondev1.DebugContainers{Profile: kubesondev1.PRIVILEGED_PROFILE})
+		assert.True(t, *debugger.SecurityContext.Privileged)
+		assert.True(t, *monitor.SecurityContext.Privileged)
+	})
+
+	t.Run("Passes the resource hints to the monitor", func(t *testing.T) {
+		_, monitor := containers(&kubesondev1.DebugContainers{ResourceHints: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("1500m"),
+			v1.ResourceMemory: resource.MustParse("64Mi"),
+		}})
+		assert.Equal(t, []v1.EnvVar{{Name: "GOMAXPROCS", Value: "2"}, {Name: "GOMEMLIMIT", Value: "67108864"}}, monitor.Env)
+	})
+}
+
+func TestPodSecurityFallback(t *testing.T) {
+	namespace := func(level string) *v1.Namespace {
+		return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop", Labels: map[string]string{PSA_ENFORCE_LABEL: level}}}
+	}
+	kubesonde := kubesondev1.Kubesonde{}
+
+	assert.Equal(t, kubesondev1.RESTRICTED_PROFILE, profileOf(effectiveSpec(kubesonde, namespace("baseline"))))
+	assert.Equal(t, kubesondev1.RESTRICTED_PROFILE, profileOf(effectiveSpec(kubesonde, namespace("restricted"))))
+	assert.Equal(t, kubesondev1.NET_RAW_PROFILE, profileOf(effectiveSpec(kubesonde, namespace("privileged"))))
+	assert.Equal(t, kubesondev1.NET_RAW_PROFILE, profileOf(effectiveSpec(kubesonde, nil)))
+	assert.Nil(t, kubesonde.Spec.DebugContainers, "the spec of the Kubesonde object is not modified")
+
+	t.Run("Installs the restricted containers in the namespaces enforcing baseline", func(t *testing.T) {
+		pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"}}
+		client := testclient.NewSimpleClientset(&pod)
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		assert.NoError(t, indexer.Add(namespace("baseline")))
+		pods := v1.PodList{Items: []v1.Pod{pod}}
+		InstallEphameralContainers(client, corelisters.NewNamespaceLister(indexer), kubesonde, &pods)
+
+		assert.Len(t, pods.Items[0].Spec.EphemeralContainers, 2, "the pod is updated with its patched spec")
+		debugger := pods.Items[0].Spec.EphemeralContainers[0]
+		assert.True(t, *debugger.SecurityContext.RunAsNonRoot)
+		assert.Equal(t, []v1.Capability{"ALL"}, debugger.SecurityContext.Capabilities.Drop)
+		assert.Empty(t, debugger.SecurityContext.Capabilities.Add)
+	})
+}
+
+func TestInstallationFailures(t *testing.T) {
+	newPod := func(name string) v1.Pod {
+		return v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}, Spec: v1.PodSpec{ServiceAccountName: "web"}}
+	}
+
+	t.Run("Skips the pods without the image pull secrets", func(t *testing.T) {
+		pod := newPod("no-secret")
+		client := testclient.NewSimpleClientset(&pod)
+		kubesonde := kubesondev1.Kubesonde{Spec: kubesondev1.KubesondeSpec{DebugContainers: &kubesondev1.DebugContainers{
+			ImagePullSecrets: []v1.LocalObjectReference{{Name: "registry"}},
+		}}}
+		installContainers(client, kubesonde, &pod)
+		defer ForgetPod(&pod)
+
+		updatedPod, err := client.CoreV1().Pods("default").Get(context.TODO(), "no-secret", metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Empty(t, updatedPod.Spec.EphemeralContainers)
+		failure, found := lo.Find(InstallationFailures(), func(failure kubesondev1.InstallationFailure) bool { return failure.Pod == "default/no-secret" })
+		assert.True(t, found)
+		assert.Equal(t, IMAGE_PULL_SECRET_MISSING, failure.Reason)
+		assert.Contains(t, failure.Message, "registry")
+		assert.Contains(t, failure.Message, "service account web")
+	})
+
+	t.Run("Records the rejected installations", func(t *testing.T) {
+		pod := newPod("rejected")
+		client := testclient.NewSimpleClientset(&pod)
+		client.PrependReactor("patch", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New(`pods "rejected" is forbidden: violates PodSecurity "restricted:latest"`)
+		})
+		installContainers(client, kubesondev1.Kubesonde{}, &pod)
+
+		failure, found := lo.Find(InstallationFailures(), func(failure kubesondev1.InstallationFailure) bool { return failure.Pod == "default/rejected" })
+		assert.True(t, found)
+		assert.Equal(t, PATCH_FAILED, failure.Reason)
+
+		client.ReactionChain = client.ReactionChain[1:]
+		installContainers(client, kubesondev1.Kubesonde{}, &pod)
+		_, found = lo.Find(InstallationFailures(), func(failure kubesondev1.InstallationFailure) bool { return failure.Pod == "default/rejected" })
+		assert.False(t, found)
+	})
+
+	t.Run("Reports the containers that do not run", func(t *testing.T) {
+		pod := newPod("web-1")
+		pod.Status.EphemeralContainerStatuses = []v1.ContainerStatus{
+			{Name: "debugger", State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+			{Name: "monitor", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image"}}},
+		}
+		failure, found := ContainerFailure(&pod)
+		assert.True(t, found)
+		assert.Equal(t, "default/web-1", failure.Pod)
+		assert.Equal(t, "ImagePullBackOff", failure.Reason)
+		assert.Equal(t, "container monitor: Back-off pulling image, check the image pull secrets of the pod", failure.Message)
+
+		pod.Status.EphemeralContainerStatuses[1].State = v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ContainerCreating"}}
+		_, found = ContainerFailure(&pod)
+		assert.False(t, found)
+	})
+}
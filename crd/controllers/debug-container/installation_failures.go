@@ -0,0 +1,87 @@
+package debug_container
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubesondev1 "kubesonde.io/api/v1"
+)
+
+// Reasons of the installation failures, besides the waiting reasons of the
+// containers such as ImagePullBackOff
+const (
+	// IMAGE_PULL_SECRET_MISSING pods do not reference the image pull secrets of the spec
+	IMAGE_PULL_SECRET_MISSING = "ImagePullSecretMissing"
+	// PATCH_FAILED is the API server rejecting the containers, e.g. Pod Security admission
+	PATCH_FAILED = "PatchFailed"
+	// TERMINATED containers stopped, the probes of the pod fail
+	TERMINATED = "Terminated"
+)
+
+var (
+	failuresMu sync.Mutex
+	failures   = map[string]kubesondev1.InstallationFailure{}
+)
+
+func podKey(pod *v1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
+
+func recordFailure(pod *v1.Pod, reason string, message string) {
+	failuresMu.Lock()
+	defer failuresMu.Unlock()
+	key := podKey(pod)
+	if previous, found := failures[key]; found && previous.Reason == reason {
+		return
+	}
+	failures[key] = kubesondev1.InstallationFailure{Pod: key, Reason: reason, Message: message, Time: metav1.Now()}
+}
+
+// ForgetPod drops the installation failure of the pod
+func ForgetPod(pod *v1.Pod) {
+	failuresMu.Lock()
+	defer failuresMu.Unlock()
+	delete(failures, podKey(pod))
+}
+
+// InstallationFailures are the pods the containers could not be installed in,
+// sorted by pod
+func InstallationFailures() []kubesondev1.InstallationFailure {
+	failuresMu.Lock()
+	defer failuresMu.Unlock()
+	result := lo.Values(failures)
+	sort.Slice(result, func(i, j int) bool { return result[i].Pod < result[j].Pod })
+	return result
+}
+
+// ContainerFailure reports the debug containers of the pod that are waiting
+// for another reason than their creation, e.g. ErrImagePull, or that stopped
+func ContainerFailure(pod *v1.Pod) (kubesondev1.InstallationFailure, bool) {
+	for _, status := range pod.Status.EphemeralContainerStatuses {
+		if status.Name != "debugger" && status.Name != "monitor" {
+			continue
+		}
+		failure := kubesondev1.InstallationFailure{Pod: podKey(pod), Time: metav1.Now()}
+		switch {
+		case status.State.Waiting != nil && status.State.Waiting.Reason != "" && status.State.Waiting.Reason != "ContainerCreating":
+			failure.Reason = status.State.Waiting.Reason
+			failure.Message = fmt.Sprintf("container %s: %s", status.Name, status.State.Waiting.Message)
+		case status.State.Terminated != nil:
+			failure.Reason = TERMINATED
+			failure.Message = fmt.Sprintf("container %s exited with %d: %s", status.Name, status.State.Terminated.ExitCode,
+				lo.CoalesceOrEmpty(status.State.Terminated.Message, status.State.Terminated.Reason))
+		default:
+			continue
+		}
+		if strings.Contains(failure.Reason, "ImagePull") {
+			failure.Message += ", check the image pull secrets of the pod"
+		}
+		return failure, true
+	}
+	return kubesondev1.InstallationFailure{}, false
+}
@@ -0,0 +1,117 @@
+package debug_container
+
+import (
+	"fmt"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	kubesondev1 "kubesonde.io/api/v1"
+)
+
+// DEFAULT_RUN_AS_USER is the UID of the non-root containers, nobody
+const DEFAULT_RUN_AS_USER int64 = 65534
+
+// ROOT_USER is the UID of the debugger of the netRaw profile
+const ROOT_USER int64 = 0
+
+// RAW_SOCKET_CAPABILITIES are the capabilities nmap needs for the UDP, SCTP
+// and SYN scans
+var RAW_SOCKET_CAPABILITIES = []v1.Capability{"NET_RAW"}
+
+// PSA_ENFORCE_LABEL is the label of the Pod Security level enforced in a namespace
+const PSA_ENFORCE_LABEL = "pod-security.kubernetes.io/enforce"
+
+func profileOf(kubesonde kubesondev1.Kubesonde) string {
+	if kubesonde.Spec.DebugContainers == nil || kubesonde.Spec.DebugContainers.Profile == "" {
+		return kubesondev1.NET_RAW_PROFILE
+	}
+	return kubesonde.Spec.DebugContainers.Profile
+}
+
+// effectiveSpec returns the spec with the restricted profile when the Pod
+// Security level enforced in the namespace rejects the containers of its
+// profile: neither baseline nor restricted allow NET_RAW. The namespace may be
+// nil when it could not be read.
+func effectiveSpec(kubesonde kubesondev1.Kubesonde, namespace *v1.Namespace) kubesondev1.Kubesonde {
+	if namespace == nil || profileOf(kubesonde) == kubesondev1.RESTRICTED_PROFILE {
+		return kubesonde
+	}
+	level := namespace.Labels[PSA_ENFORCE_LABEL]
+	if level != "baseline" && level != "restricted" {
+		return kubesonde
+	}
+	log.Info(fmt.Sprintf("Namespace %s enforces the %s Pod Security level, using the %s profile", namespace.Name, level, kubesondev1.RESTRICTED_PROFILE))
+	fallback := *kubesonde.DeepCopy()
+	if fallback.Spec.DebugContainers == nil {
+		fallback.Spec.DebugContainers = &kubesondev1.DebugContainers{}
+	}
+	fallback.Spec.DebugContainers.Profile = kubesondev1.RESTRICTED_PROFILE
+	return fallback
+}
+
+func nonRoot(kubesonde kubesondev1.Kubesonde) *v1.SecurityContext {
+	runAsUser := DEFAULT_RUN_AS_USER
+	if kubesonde.Spec.DebugContainers != nil && kubesonde.Spec.DebugContainers.RunAsUser != nil {
+		runAsUser = *kubesonde.Spec.DebugContainers.RunAsUser
+	}
+	return &v1.SecurityContext{
+		RunAsNonRoot:             lo.ToPtr(true),
+		RunAsUser:                &runAsUser,
+		AllowPrivilegeEscalation: lo.ToPtr(false),
+		Capabilities:             &v1.Capabilities{Drop: []v1.Capability{"ALL"}},
+		SeccompProfile:           &v1.SeccompProfile{Type: v1.SeccompProfileTypeRuntimeDefault},
+	}
+}
+
+// securityContexts returns the security contexts of the debugger and the
+// monitor containers of the profile of the spec
+func securityContexts(kubesonde kubesondev1.Kubesonde) (*v1.SecurityContext, *v1.SecurityContext) {
+	switch profileOf(kubesonde) {
+	case kubesondev1.PRIVILEGED_PROFILE:
+		return &v1.SecurityContext{Privileged: lo.ToPtr(true)}, &v1.SecurityContext{Privileged: lo.ToPtr(true)}
+	case kubesondev1.RESTRICTED_PROFILE:
+		return nonRoot(kubesonde), nonRoot(kubesonde)
+	default:
+		// The debugger stays root: a non-root process only gets NET_RAW from
+		// the file capabilities of nmap, which the default image does not set
+		// and which no_new_privs, set without privilege escalation, ignores.
+		// Kubernetes cannot set ambient capabilities. The user is set so that
+		// the runAsUser and runAsNonRoot of the pod do not apply: the root
+		// user holds no capability but NET_RAW.
+		return &v1.SecurityContext{
+			RunAsNonRoot:             lo.ToPtr(false),
+			RunAsUser:                lo.ToPtr(ROOT_USER),
+			AllowPrivilegeEscalation: lo.ToPtr(false),
+			Capabilities:             &v1.Capabilities{Drop: []v1.Capability{"ALL"}, Add: RAW_SOCKET_CAPABILITIES},
+			SeccompProfile:           &v1.SeccompProfile{Type: v1.SeccompProfileTypeRuntimeDefault},
+		}, nonRoot(kubesonde)
+	}
+}
+
+// monitorEnv passes the resource hints to the Go runtime of the monitor
+func monitorEnv(kubesonde kubesondev1.Kubesonde) []v1.EnvVar {
+	if kubesonde.Spec.DebugContainers == nil {
+		return nil
+	}
+	hints := kubesonde.Spec.DebugContainers.ResourceHints
+	env := []v1.EnvVar{}
+	if cpu, found := hints[v1.ResourceCPU]; found {
+		procs := max(1, (cpu.MilliValue()+999)/1000)
+		env = append(env, v1.EnvVar{Name: "GOMAXPROCS", Value: fmt.Sprint(procs)})
+	}
+	if memory, found := hints[v1.ResourceMemory]; found {
+		env = append(env, v1.EnvVar{Name: "GOMEMLIMIT", Value: fmt.Sprint(memory.Value())})
+	}
+	return lo.Ternary(len(env) == 0, nil, env)
+}
+
+// missingImagePullSecrets are the secrets of the spec the pod does not reference
+func missingImagePullSecrets(kubesonde kubesondev1.Kubesonde, pod *v1.Pod) []string {
+	if kubesonde.Spec.DebugContainers == nil {
+		return nil
+	}
+	referenced := lo.Map(pod.Spec.ImagePullSecrets, func(secret v1.LocalObjectReference, _ int) string { return secret.Name })
+	required := lo.Map(kubesonde.Spec.DebugContainers.ImagePullSecrets, func(secret v1.LocalObjectReference, _ int) string { return secret.Name })
+	missing, _ := lo.Difference(required, referenced)
+	return missing
+}
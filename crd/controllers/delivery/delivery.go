@@ -0,0 +1,76 @@
+// Package delivery posts to the HTTP endpoints of the Kubesonde objects with
+// retries, and feeds the changes of the probe state with their findings to the
+// webhooks and the CloudEvents sinks.
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DELIVERY_TIMEOUT bounds a single POST
+const DELIVERY_TIMEOUT = 10 * time.Second
+
+// INITIAL_BACKOFF is the delay before the first retry, doubled after each
+// failed retry up to MAX_BACKOFF
+var (
+	INITIAL_BACKOFF = time.Second
+	MAX_BACKOFF     = time.Minute
+)
+
+// statusError is a response of the endpoint other than 2xx
+type statusError struct {
+	code int
+}
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.code)
+}
+
+// retryable tells whether a failed delivery may succeed later: network errors,
+// throttling and server errors
+func retryable(err error) bool {
+	var status statusError
+	if errors.As(err, &status) {
+		return status.code == http.StatusTooManyRequests || status.code >= http.StatusInternalServerError
+	}
+	return true
+}
+
+// Post sends the request, a response other than 2xx is an error
+func Post(client *http.Client, request *http.Request) error {
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return statusError{response.StatusCode}
+	}
+	return nil
+}
+
+// Retry calls post until it succeeds, up to maxRetries more times with an
+// exponential backoff. Rejected requests are not retried. retrying is called
+// before each retry with the number of the attempt that failed.
+func Retry(ctx context.Context, maxRetries int, post func() error, retrying func(attempt int, err error)) error {
+	backoff := INITIAL_BACKOFF
+	for attempt := 0; ; attempt++ {
+		err := post()
+		if err == nil || !retryable(err) || attempt >= maxRetries {
+			return err
+		}
+		retrying(attempt+1, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, MAX_BACKOFF)
+	}
+}
@@ -0,0 +1,112 @@
+package delivery
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/findings"
+	"kubesonde.io/controllers/state"
+)
+
+// recorder is a handler recording the findings it receives
+type recorder struct {
+	mu       sync.Mutex
+	findings [][]findings.Finding
+	stopped  bool
+}
+
+func (r *recorder) Handle(_ state.Change, detected []findings.Finding) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.findings = append(r.findings, detected)
+}
+
+func (r *recorder) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopped = true
+}
+
+func (r *recorder) received() [][]findings.Finding {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]findings.Finding{}, r.findings...)
+}
+
+func (r *recorder) isStopped() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stopped
+}
+
+var _ = Describe("Delivery", func() {
+	BeforeEach(func() {
+		INITIAL_BACKOFF = time.Millisecond
+	})
+
+	AfterEach(func() {
+		INITIAL_BACKOFF = time.Second
+	})
+
+	It("Retries the throttled and failed requests but not the rejected ones", func() {
+		statuses := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(statuses[min(requests, len(statuses)-1)])
+			requests++
+		}))
+		defer server.Close()
+		post := func() error {
+			request, _ := http.NewRequest(http.MethodPost, server.URL, nil)
+			return Post(http.DefaultClient, request)
+		}
+		retries := []int{}
+		retrying := func(attempt int, _ error) { retries = append(retries, attempt) }
+
+		Expect(Retry(context.Background(), 5, post, retrying)).To(Succeed())
+		Expect(retries).To(Equal([]int{1, 2}))
+
+		requests = 0
+		Expect(Retry(context.Background(), 1, post, retrying)).To(MatchError("unexpected status 429"))
+		Expect(requests).To(Equal(2))
+
+		statuses = []int{http.StatusBadRequest}
+		requests = 0
+		Expect(Retry(context.Background(), 5, post, retrying)).To(MatchError("unexpected status 400"))
+		Expect(requests).To(Equal(1))
+	})
+
+	It("Passes the same findings to all the handlers and stops them", func() {
+		sm := state.NewStateManager()
+		webhooks, sinks := &recorder{}, &recorder{}
+		feed := NewFeed(sm, webhooks, sinks)
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			Expect(feed.Start(ctx)).To(Succeed())
+		}()
+
+		web := v1.ProbeEndpointInfo{Type: v1.POD, Name: "web-1", Namespace: "shop"}
+		google := v1.ProbeEndpointInfo{Type: v1.INTERNET, Name: "Google", IPAddress: "google.com"}
+		Expect(sm.AppendProbes(&[]v1.ProbeOutputItem{
+			{Type: v1.PROBE, Source: web, Destination: google, Port: "443", Protocol: "TCP", ResultingAction: v1.ALLOW},
+		})).To(Succeed())
+
+		Eventually(sinks.received).Should(HaveLen(1))
+		Expect(webhooks.received()).To(Equal(sinks.received()))
+		Expect(lo.Map(webhooks.received()[0], func(f findings.Finding, _ int) findings.Kind { return f.Kind })).To(Equal([]findings.Kind{findings.INTERNET_REACHABLE}))
+
+		cancel()
+		Eventually(stopped).Should(BeClosed())
+		Expect(webhooks.isStopped()).To(BeTrue())
+		Expect(sinks.isStopped()).To(BeTrue())
+	})
+})
@@ -0,0 +1,89 @@
+package delivery
+
+import (
+	"context"
+	"sync"
+
+	"kubesonde.io/controllers/baseline"
+	"kubesonde.io/controllers/findings"
+	"kubesonde.io/controllers/state"
+)
+
+// Handler receives the changes of the probe state with their findings
+type Handler interface {
+	Handle(change state.Change, detected []findings.Finding)
+	// Stop delivers the pending messages, the feed calls it when it stops
+	Stop()
+}
+
+// Feed follows the changes of the probe state, detects their findings once
+// and passes both to its handlers. It runs with the manager.
+type Feed struct {
+	sm       *state.StateManager
+	detector *findings.Detector
+	handlers []Handler
+	// last is the sequence number of the latest change of the state handled
+	last uint64
+}
+
+// NewFeed follows the changes of the state made after its creation
+func NewFeed(sm *state.StateManager, handlers ...Handler) *Feed {
+	return &Feed{
+		sm:       sm,
+		detector: findings.NewDetector(baseline.GetCurrent),
+		handlers: handlers,
+		last:     sm.LastSequence(),
+	}
+}
+
+// Start follows the changes of the probe state until the context is done
+func (f *Feed) Start(ctx context.Context) error {
+	defer f.stop()
+	for {
+		backlog, subscription := f.sm.Subscribe(f.last)
+		for _, change := range backlog {
+			f.handle(change)
+		}
+		if !f.follow(ctx, subscription) {
+			return nil
+		}
+	}
+}
+
+func (f *Feed) handle(change state.Change) {
+	f.last = change.Sequence
+	detected := f.detector.Detect(change)
+	for _, handler := range f.handlers {
+		handler.Handle(change, detected)
+	}
+}
+
+// follow returns false when the context is done, true when the subscriber was
+// dropped for not keeping up
+func (f *Feed) follow(ctx context.Context, subscription *state.Subscription) bool {
+	defer subscription.Cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case change, open := <-subscription.Changes:
+			if !open {
+				return true
+			}
+			f.handle(change)
+		}
+	}
+}
+
+// stop waits for the handlers to deliver their pending messages
+func (f *Feed) stop() {
+	var wg sync.WaitGroup
+	for _, handler := range f.handlers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.Stop()
+		}()
+	}
+	wg.Wait()
+}
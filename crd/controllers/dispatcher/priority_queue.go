@@ -1,6 +1,9 @@
 package dispatcher
 
 import (
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
 	"kubesonde.io/controllers/probe_command"
 )
 
@@ -10,6 +13,9 @@ import (
 type Item struct {
 	value    probe_command.KubesondeCommand // The value of the item; arbitrary.
 	priority int                            // The priority of the item in the queue.
+	queuedAt time.Time                      // When the item entered the queue.
+	// The span that queued the item, the parent of the spans of its run.
+	spanContext trace.SpanContext
 	// The index is needed by update and is maintained by the heap.Interface methods.
 	index int // The index of the item in the heap.
 }
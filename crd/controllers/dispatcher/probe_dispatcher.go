@@ -7,10 +7,15 @@ import (
 	"context"
 	"time"
 
+	"github.com/samber/lo"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/semaphore"
 	"k8s.io/client-go/kubernetes"
+	v1 "kubesonde.io/api/v1"
+	eventstorage "kubesonde.io/controllers/event-storage"
 	"kubesonde.io/controllers/inner"
 	"kubesonde.io/controllers/probe_command"
+	"kubesonde.io/controllers/tracing"
 )
 
 type Priority int
@@ -25,25 +30,71 @@ var (
 	pq                  = make(PriorityQueue, 0, 1000)
 )
 
-// Add probes to queue
+// Add probes to queue. Probes already queued with a lower priority are moved
+// up to the given priority.
 func SendToQueue(commands []probe_command.KubesondeCommand, priority Priority) {
+	SendToQueueWithContext(context.Background(), commands, priority)
+}
+
+// SendToQueueWithContext queues the probes, the spans of their runs are
+// children of the span of ctx
+func SendToQueueWithContext(ctx context.Context, commands []probe_command.KubesondeCommand, priority Priority) {
+	ctx, span := tracing.Tracer().Start(ctx, tracing.ENQUEUE_SPAN, trace.WithAttributes(
+		tracing.PROBES_KEY.Int(len(commands)),
+		tracing.PRIORITY_KEY.Int(int(priority)),
+	))
+	defer span.End()
 	dispatcherSemaphore.Acquire(context.Background(), 1)
 	defer dispatcherSemaphore.Release(1)
+	sendToQueue(ctx, commands, priority)
+}
 
-	inQueue := make(map[probe_command.ComparableKubesondeCommand]bool, len(pq))
+// sendToQueue must be called while holding the dispatcher semaphore
+func sendToQueue(ctx context.Context, commands []probe_command.KubesondeCommand, priority Priority) {
+	inQueue := make(map[probe_command.ComparableKubesondeCommand]*Item, len(pq))
 	for _, item := range pq {
-		inQueue[item.value.ToComparableCommand()] = true
+		inQueue[item.value.ToComparableCommand()] = item
 	}
 
 	for _, command := range commands {
-		if !inQueue[command.ToComparableCommand()] {
+		queued, found := inQueue[command.ToComparableCommand()]
+		if !found {
 			heap.Push(&pq, &Item{
-				value:    command,
-				priority: int(priority),
+				value:       command,
+				priority:    int(priority),
+				queuedAt:    time.Now(),
+				spanContext: trace.SpanContextFromContext(ctx),
 			})
+		} else if queued.priority < int(priority) {
+			queued.priority = int(priority)
+			heap.Fix(&pq, queued.index)
 		}
 	}
 }
+
+// involvesPod reports whether the probe runs from or targets the pod
+func involvesPod(command probe_command.ComparableKubesondeCommand, namespace string, name string) bool {
+	if command.Namespace == namespace && command.SourcePodName == name {
+		return true
+	}
+	return command.DestinationType == v1.POD && command.DestinationNamespace == namespace && command.Destination == name
+}
+
+// RemovePod drops the queued probes from or to a deleted pod and counts them
+// as skipped in the open rounds, which would otherwise never complete
+func RemovePod(namespace string, name string) {
+	dispatcherSemaphore.Acquire(context.Background(), 1)
+	defer dispatcherSemaphore.Release(1)
+	pq = lo.Reject(pq, func(item *Item, _ int) bool {
+		return involvesPod(item.value.ToComparableCommand(), namespace, name)
+	})
+	for index, item := range pq {
+		item.index = index
+	}
+	heap.Init(&pq)
+	skipInRounds(namespace, name)
+}
+
 func QueueSize() int {
 	for result := dispatcherSemaphore.TryAcquire(1); !result; result = dispatcherSemaphore.TryAcquire(1) {
 		// Keep trying to acquire
@@ -65,10 +116,20 @@ func Run(apiClient kubernetes.Interface) {
 			continue
 		}
 		item := heap.Pop(&pq).(*Item)
+		start := time.Now()
+		startProbe(item.value, start)
 		dispatcherSemaphore.Release(1)
 
-		start := time.Now()
-		inner.InspectAndStoreResult(apiClient, []probe_command.KubesondeCommand{item.value})
+		ctx := trace.ContextWithSpanContext(context.Background(), item.spanContext)
+		_, dequeue := tracing.Tracer().Start(ctx, tracing.DEQUEUE_SPAN, trace.WithTimestamp(item.queuedAt), trace.WithAttributes(
+			append(tracing.CommandAttributes(item.value), tracing.QUEUE_WAIT_KEY.Int64(start.Sub(item.queuedAt).Milliseconds()))...,
+		))
+		dequeue.End(trace.WithTimestamp(start))
+		inner.InspectAndStoreResult(ctx, apiClient, []probe_command.KubesondeCommand{item.value})
+		eventstorage.RecordExecution(item.value, start)
+		dispatcherSemaphore.Acquire(context.Background(), 1)
+		finishProbe(item.value, start, time.Now())
+		dispatcherSemaphore.Release(1)
 		duration := time.Since(start)
 		if duration < probeInterval {
 			time.Sleep(probeInterval - duration)
@@ -2,12 +2,19 @@ package dispatcher
 
 import (
 	"container/heap"
+	"context"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 	v1 "kubesonde.io/api/v1"
 	"kubesonde.io/controllers/probe_command"
+	"kubesonde.io/controllers/tracing"
 )
 
 func TestContinuousMode(t *testing.T) {
@@ -17,7 +24,7 @@ func TestContinuousMode(t *testing.T) {
 
 var _ = Describe("SendToQueue", func() {
 	BeforeEach(func() {
-
+		pq = pq[:0]
 	})
 	It("Updates queue", func() {
 		command := probe_command.KubesondeCommand{
@@ -41,6 +48,138 @@ var _ = Describe("SendToQueue", func() {
 		Expect(result).To(Equal(command))
 		Expect(pq.Len()).To(Equal(0))
 	})
+
+	It("Raises the priority of queued probes", func() {
+		low := probe_command.KubesondeCommand{SourcePodName: "a", Command: "low"}
+		raised := probe_command.KubesondeCommand{SourcePodName: "b", Command: "raised"}
+		SendToQueue([]probe_command.KubesondeCommand{low, raised}, LOW)
+		SendToQueue([]probe_command.KubesondeCommand{raised}, HIGH)
+
+		Expect(pq.Len()).To(Equal(2))
+		Expect(heap.Pop(&pq).(*Item).value.Command).To(Equal("raised"))
+		Expect(heap.Pop(&pq).(*Item).value.Command).To(Equal("low"))
+	})
+
+	It("Keeps the span that queued the probes", func() {
+		exporter := tracetest.NewInMemoryExporter()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+		otel.SetTracerProvider(provider)
+		defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+		ctx, event := provider.Tracer("test").Start(context.Background(), "pod event")
+		SendToQueueWithContext(ctx, []probe_command.KubesondeCommand{{SourcePodName: "a", Command: "1"}}, HIGH)
+		event.End()
+
+		spans := exporter.GetSpans()
+		Expect(spans).To(HaveLen(2))
+		enqueue := spans[0]
+		Expect(enqueue.Name).To(Equal(tracing.ENQUEUE_SPAN))
+		Expect(enqueue.Parent.SpanID()).To(Equal(event.SpanContext().SpanID()))
+		Expect(enqueue.Attributes).To(ContainElements(tracing.PROBES_KEY.Int(1), tracing.PRIORITY_KEY.Int(int(HIGH))))
+		Expect(heap.Pop(&pq).(*Item).spanContext).To(Equal(enqueue.SpanContext))
+	})
+})
+
+var _ = Describe("Stats", func() {
+	BeforeEach(func() {
+		pq = pq[:0]
+		inFlight = map[probe_command.ComparableKubesondeCommand]InFlightProbe{}
+		completions = nil
+		rounds = map[int]*Round{}
+	})
+
+	It("Counts the pending probes", func() {
+		now := time.Now()
+		SendToQueue([]probe_command.KubesondeCommand{
+			{SourcePodName: "a", Namespace: "ns1", Command: "1"},
+			{SourcePodName: "a", Namespace: "ns1", Command: "2"},
+		}, LOW)
+		SendToQueue([]probe_command.KubesondeCommand{{SourcePodName: "b", Namespace: "ns2", Command: "3"}}, HIGH)
+		pq[0].queuedAt = now.Add(-30 * time.Second)
+
+		stats := statsAt(now)
+		Expect(stats.Pending).To(Equal(3))
+		Expect(stats.ByPriority).To(Equal(map[string]int{"low": 2, "high": 1}))
+		Expect(stats.BySourcePod).To(Equal(map[string]int{"ns1/a": 2, "ns2/b": 1}))
+		Expect(stats.ByNamespace).To(Equal(map[string]int{"ns1": 2, "ns2": 1}))
+		Expect(stats.OldestItemAgeSeconds).To(BeNumerically("~", 30, 1))
+		Expect(stats.InFlight).To(BeEmpty())
+		Expect(stats.ThroughputPerSecond).To(BeZero())
+		Expect(stats.EstimatedDrainSeconds).To(BeNil())
+	})
+
+	It("Reports the running probes and the throughput", func() {
+		now := time.Now()
+		SendToQueue([]probe_command.KubesondeCommand{{SourcePodName: "a", Namespace: "ns1", Command: "1"}}, LOW)
+		finished := probe_command.KubesondeCommand{SourcePodName: "a", Namespace: "ns1", Command: "2"}
+		startProbe(finished, now.Add(-2*THROUGHPUT_WINDOW))
+		finishProbe(finished, now.Add(-2*THROUGHPUT_WINDOW), now.Add(-2*THROUGHPUT_WINDOW))
+		for range 30 {
+			startProbe(finished, now.Add(-time.Second))
+			finishProbe(finished, now.Add(-time.Second), now.Add(-time.Second))
+		}
+		running := probe_command.KubesondeCommand{SourcePodName: "a", Namespace: "ns1", Destination: "b", DestinationPort: "80", Command: "3"}
+		startProbe(running, now)
+
+		stats := statsAt(now)
+		Expect(stats.InFlight).To(Equal([]InFlightProbe{{Source: "ns1/a", Destination: "b", DestinationPort: "80", StartedAt: now.Unix()}}))
+		Expect(stats.ThroughputPerSecond).To(BeNumerically("~", 0.5))
+		Expect(*stats.EstimatedDrainSeconds).To(BeNumerically("~", 2))
+	})
+})
+
+var _ = Describe("Rounds", func() {
+	BeforeEach(func() {
+		pq = pq[:0]
+		inFlight = map[probe_command.ComparableKubesondeCommand]InFlightProbe{}
+		rounds = map[int]*Round{}
+	})
+
+	It("Completes when all the probes ran after the round started", func() {
+		first := probe_command.KubesondeCommand{SourcePodName: "a", Namespace: "ns1", Command: "1"}
+		second := probe_command.KubesondeCommand{SourcePodName: "b", Namespace: "ns1", Command: "2"}
+		round := StartRound([]probe_command.KubesondeCommand{first, second}, HIGH)
+		Expect(pq.Len()).To(Equal(2))
+		Expect(round.Total).To(Equal(2))
+
+		// The probe started before the round does not count
+		finishProbe(first, round.StartedAt.Add(-time.Second), time.Now())
+		Expect(round.Remaining()).To(Equal(2))
+		Expect(statsAt(time.Now()).Rounds).To(Equal([]RoundProgress{{ID: round.ID, Total: 2, Remaining: 2, StartedAt: round.StartedAt.Unix()}}))
+
+		finishProbe(first, round.StartedAt, time.Now())
+		Expect(round.Remaining()).To(Equal(1))
+		Expect(round.Done()).NotTo(BeClosed())
+
+		finishProbe(second, round.StartedAt.Add(time.Second), time.Now())
+		Expect(round.Done()).To(BeClosed())
+		Expect(statsAt(time.Now()).Rounds).To(BeEmpty())
+	})
+
+	It("Skips the probes of the deleted pods", func() {
+		from := probe_command.KubesondeCommand{SourcePodName: "a", Namespace: "ns1", Command: "1"}
+		to := probe_command.KubesondeCommand{SourcePodName: "b", Namespace: "ns1", Destination: "a", DestinationNamespace: "ns1", DestinationType: v1.POD, Command: "2"}
+		other := probe_command.KubesondeCommand{SourcePodName: "b", Namespace: "ns1", Destination: "c", DestinationNamespace: "ns1", DestinationType: v1.POD, Command: "3"}
+		round := StartRound([]probe_command.KubesondeCommand{from, to, other}, HIGH)
+
+		RemovePod("ns1", "a")
+		Expect(pq.Len()).To(Equal(1))
+		Expect(pq[0].value).To(Equal(other))
+		Expect(round.Remaining()).To(Equal(1))
+		Expect(round.Skipped()).To(Equal(2))
+		Expect(statsAt(time.Now()).Rounds).To(Equal([]RoundProgress{{ID: round.ID, Total: 3, Remaining: 1, Skipped: 2, StartedAt: round.StartedAt.Unix()}}))
+
+		RemovePod("ns1", "b")
+		Expect(pq.Len()).To(BeZero())
+		Expect(round.Done()).To(BeClosed())
+		Expect(round.Skipped()).To(Equal(3))
+	})
+
+	It("Completes the empty rounds immediately", func() {
+		round := StartRound(nil, HIGH)
+		Expect(round.Done()).To(BeClosed())
+		Expect(pq.Len()).To(BeZero())
+	})
 })
 
 /*
@@ -0,0 +1,127 @@
+package dispatcher
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/samber/lo"
+	"kubesonde.io/controllers/probe_command"
+	"kubesonde.io/pkg/apitypes"
+)
+
+// A Round is a set of probes queued together. It is complete when each of its
+// probes started and finished after the round started, so that the results
+// of the probes running when the round started are not counted.
+type Round struct {
+	ID        int
+	Total     int
+	StartedAt time.Time
+	pending   map[probe_command.ComparableKubesondeCommand]struct{}
+	skipped   int
+	done      chan struct{}
+}
+
+// RoundProgress describes a round that did not complete yet
+type RoundProgress = apitypes.RoundProgress
+
+var (
+	rounds      = make(map[int]*Round)
+	lastRoundID int
+)
+
+// StartRound queues the probes and returns a round that completes when all of
+// them ran
+func StartRound(commands []probe_command.KubesondeCommand, priority Priority) *Round {
+	round := registerRound(commands)
+	SendToQueueWithContext(context.Background(), commands, priority)
+	return round
+}
+
+// registerRound returns a round of the commands, the runs of the commands
+// started from now on complete it
+func registerRound(commands []probe_command.KubesondeCommand) *Round {
+	dispatcherSemaphore.Acquire(context.Background(), 1)
+	defer dispatcherSemaphore.Release(1)
+
+	lastRoundID++
+	round := &Round{
+		ID:        lastRoundID,
+		StartedAt: time.Now(),
+		pending:   make(map[probe_command.ComparableKubesondeCommand]struct{}, len(commands)),
+		done:      make(chan struct{}),
+	}
+	for _, command := range commands {
+		round.pending[command.ToComparableCommand()] = struct{}{}
+	}
+	round.Total = len(round.pending)
+	if round.Total == 0 {
+		close(round.done)
+		return round
+	}
+	rounds[round.ID] = round
+	return round
+}
+
+// Done is closed when the round is complete
+func (r *Round) Done() <-chan struct{} {
+	return r.done
+}
+
+// Remaining returns the number of probes of the round that did not run yet
+func (r *Round) Remaining() int {
+	dispatcherSemaphore.Acquire(context.Background(), 1)
+	defer dispatcherSemaphore.Release(1)
+	return len(r.pending)
+}
+
+// Skipped returns the number of probes of the round dropped because their pod
+// was deleted
+func (r *Round) Skipped() int {
+	dispatcherSemaphore.Acquire(context.Background(), 1)
+	defer dispatcherSemaphore.Release(1)
+	return r.skipped
+}
+
+// closeRound must be called while holding the dispatcher semaphore
+func closeRound(id int, round *Round) {
+	if len(round.pending) == 0 {
+		close(round.done)
+		delete(rounds, id)
+	}
+}
+
+// completeInRounds must be called while holding the dispatcher semaphore
+func completeInRounds(command probe_command.KubesondeCommand, start time.Time) {
+	key := command.ToComparableCommand()
+	for id, round := range rounds {
+		if start.Before(round.StartedAt) {
+			continue
+		}
+		delete(round.pending, key)
+		closeRound(id, round)
+	}
+}
+
+// skipInRounds counts the pending probes from or to the pod as skipped. It
+// must be called while holding the dispatcher semaphore.
+func skipInRounds(namespace string, name string) {
+	for id, round := range rounds {
+		for key := range round.pending {
+			if involvesPod(key, namespace, name) {
+				delete(round.pending, key)
+				round.skipped++
+			}
+		}
+		closeRound(id, round)
+	}
+}
+
+// roundsProgress must be called while holding the dispatcher semaphore
+func roundsProgress() []RoundProgress {
+	progress := lo.MapToSlice(rounds, func(id int, round *Round) RoundProgress {
+		return RoundProgress{ID: id, Total: round.Total, Remaining: len(round.pending), Skipped: round.skipped, StartedAt: round.StartedAt.Unix()}
+	})
+	sort.Slice(progress, func(i, j int) bool { return progress[i].ID < progress[j].ID })
+	return progress
+}
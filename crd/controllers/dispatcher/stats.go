@@ -0,0 +1,99 @@
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	"kubesonde.io/controllers/probe_command"
+	"kubesonde.io/pkg/apitypes"
+)
+
+// THROUGHPUT_WINDOW is the period the throughput is measured on
+const THROUGHPUT_WINDOW = time.Minute
+
+// InFlightProbe is a probe the dispatcher is running
+type InFlightProbe = apitypes.InFlightProbe
+
+// QueueStats describes the state of the dispatcher
+type QueueStats = apitypes.QueueStats
+
+var (
+	inFlight    = make(map[probe_command.ComparableKubesondeCommand]InFlightProbe)
+	completions []time.Time
+)
+
+func (p Priority) String() string {
+	switch p {
+	case HIGH:
+		return "high"
+	case LOW:
+		return "low"
+	default:
+		return fmt.Sprintf("%d", int(p))
+	}
+}
+
+func sourceName(command probe_command.KubesondeCommand) string {
+	return fmt.Sprintf("%s/%s", command.Namespace, command.SourcePodName)
+}
+
+// startProbe and finishProbe must be called while holding the dispatcher semaphore
+func startProbe(command probe_command.KubesondeCommand, at time.Time) {
+	inFlight[command.ToComparableCommand()] = InFlightProbe{
+		Source:          sourceName(command),
+		Destination:     command.Destination,
+		DestinationPort: command.DestinationPort,
+		Protocol:        command.Protocol,
+		StartedAt:       at.Unix(),
+	}
+}
+
+func finishProbe(command probe_command.KubesondeCommand, start time.Time, end time.Time) {
+	delete(inFlight, command.ToComparableCommand())
+	completions = append(pruneCompletions(end), end)
+	completeInRounds(command, start)
+}
+
+func pruneCompletions(now time.Time) []time.Time {
+	start := now.Add(-THROUGHPUT_WINDOW)
+	_, index, found := lo.FindIndexOf(completions, func(at time.Time) bool { return at.After(start) })
+	if !found {
+		return completions[:0]
+	}
+	return completions[index:]
+}
+
+// Stats returns the pending probes, the running ones and the recent throughput
+func Stats() QueueStats {
+	dispatcherSemaphore.Acquire(context.Background(), 1)
+	defer dispatcherSemaphore.Release(1)
+	return statsAt(time.Now())
+}
+
+func statsAt(now time.Time) QueueStats {
+	stats := QueueStats{
+		Pending:     pq.Len(),
+		ByPriority:  map[string]int{},
+		BySourcePod: map[string]int{},
+		ByNamespace: map[string]int{},
+		InFlight:    lo.Values(inFlight),
+		Rounds:      roundsProgress(),
+	}
+	for _, item := range pq {
+		stats.ByPriority[Priority(item.priority).String()]++
+		stats.BySourcePod[sourceName(item.value)]++
+		stats.ByNamespace[item.value.Namespace]++
+		if age := now.Sub(item.queuedAt).Seconds(); age > stats.OldestItemAgeSeconds {
+			stats.OldestItemAgeSeconds = age
+		}
+	}
+
+	completions = pruneCompletions(now)
+	stats.ThroughputPerSecond = float64(len(completions)) / THROUGHPUT_WINDOW.Seconds()
+	if stats.ThroughputPerSecond > 0 {
+		stats.EstimatedDrainSeconds = lo.ToPtr(float64(stats.Pending) / stats.ThroughputPerSecond)
+	}
+	return stats
+}
@@ -3,6 +3,7 @@ package eventstorage
 import (
 	"sync"
 	"testing"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -86,6 +87,40 @@ func TestRaceCommandsMap(t *testing.T) {
 	wg.Wait()
 }
 
+// TestRaceExecutions confirms that the executions map is properly protected
+// by a mutex and keeps the last execution time.
+func TestRaceExecutions(t *testing.T) {
+	cmd := probe_command.KubesondeCommand{
+		SourcePodName:        "pod-4",
+		Command:              "ping",
+		DestinationIPAddress: "10.0.0.4",
+		DestinationPort:      "80",
+		Protocol:             "TCP",
+	}
+	if _, ok := GetLastExecution(cmd); ok {
+		t.Fatal("command should not have run yet")
+	}
+
+	var wg sync.WaitGroup
+	for i := range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 50 {
+				RecordExecution(cmd, time.Unix(int64(i*50+j), 0))
+				GetLastExecution(cmd)
+			}
+		}()
+	}
+	wg.Wait()
+
+	last := time.Unix(1000, 0)
+	RecordExecution(cmd, last)
+	if at, ok := GetLastExecution(cmd); !ok || !at.Equal(last) {
+		t.Fatalf("expected last execution at %v, got %v", last, at)
+	}
+}
+
 // TestRaceActivePods confirms that _activePods map is properly protected
 // by a mutex. Run with: go test -race -run TestRaceActivePods
 func TestRaceActivePods(t *testing.T) {
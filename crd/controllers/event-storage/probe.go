@@ -3,6 +3,7 @@ package eventstorage
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/samber/lo"
 	"kubesonde.io/controllers/probe_command"
@@ -10,11 +11,16 @@ import (
 
 var (
 	commands   = make(map[string]probe_command.KubesondeCommand)
+	executions = make(map[string]time.Time)
 	commandsMu sync.RWMutex
 )
 
+func probeKey(command probe_command.KubesondeCommand) string {
+	return fmt.Sprintf("%s-%s-%s-%s-%s", command.SourcePodName, command.Command, command.DestinationIPAddress, command.DestinationPort, command.Protocol)
+}
+
 func AddProbe(command probe_command.KubesondeCommand) {
-	key := fmt.Sprintf("%s-%s-%s-%s-%s", command.SourcePodName, command.Command, command.DestinationIPAddress, command.DestinationPort, command.Protocol)
+	key := probeKey(command)
 	commandsMu.RLock()
 	_, ok := commands[key]
 	commandsMu.RUnlock()
@@ -39,9 +45,24 @@ func GetProbes() []probe_command.KubesondeCommand {
 }
 
 func ProbeAvailable(command probe_command.KubesondeCommand) bool {
-	key := fmt.Sprintf("%s-%s-%s-%s-%s", command.SourcePodName, command.Command, command.DestinationIPAddress, command.DestinationPort, command.Protocol)
+	key := probeKey(command)
 	commandsMu.RLock()
 	defer commandsMu.RUnlock()
 	_, ok := commands[key]
 	return ok
 }
+
+// RecordExecution stores the time the command last ran
+func RecordExecution(command probe_command.KubesondeCommand, at time.Time) {
+	commandsMu.Lock()
+	defer commandsMu.Unlock()
+	executions[probeKey(command)] = at
+}
+
+// GetLastExecution returns the time the command last ran, false when it never ran
+func GetLastExecution(command probe_command.KubesondeCommand) (time.Time, bool) {
+	commandsMu.RLock()
+	defer commandsMu.RUnlock()
+	at, ok := executions[probeKey(command)]
+	return at, ok
+}
@@ -11,6 +11,8 @@ type CreatedPodRecord struct {
 	DeploymentName    string
 	ReplicaSetName    string
 	CreationTimestamp int64
+	// Skipped pods are not instrumented, they are only probe destinations
+	Skipped bool
 }
 
 type DeletedPodRecord struct {
@@ -73,6 +75,28 @@ func GetActivePods() []v1.Pod {
 	return v
 }
 
+// GetSourcePods returns the active pods that are instrumented and are probe sources
+func GetSourcePods() []v1.Pod {
+	return activePodsWhere(false)
+}
+
+// GetSkippedPods returns the active pods that are only probe destinations
+func GetSkippedPods() []v1.Pod {
+	return activePodsWhere(true)
+}
+
+func activePodsWhere(skipped bool) []v1.Pod {
+	storageMu.RLock()
+	defer storageMu.RUnlock()
+	v := []v1.Pod{}
+	for _, value := range _activePods {
+		if value.Skipped == skipped {
+			v = append(v, value.Pod)
+		}
+	}
+	return v
+}
+
 func GetActivePodNames() []string {
 	storageMu.RLock()
 	defer storageMu.RUnlock()
@@ -1,28 +1,38 @@
 package events
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	kubesondev1 "kubesonde.io/api/v1"
 	eventstorage "kubesonde.io/controllers/event-storage"
 
+	"go.opentelemetry.io/otel/trace"
 	v1 "k8s.io/api/core/v1"
 	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"kubesonde.io/controllers/state"
+	"kubesonde.io/controllers/tracing"
 	"kubesonde.io/controllers/utils"
 )
 
-func podEventHandler(client kubernetes.Interface, Kubesonde kubesondev1.Kubesonde) cache.ResourceEventHandler {
+func podEventHandler(client kubernetes.Interface, namespaces corelisters.NamespaceLister, Kubesonde kubesondev1.Kubesonde) cache.ResourceEventHandler {
 	return cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			pod := obj.(*v1.Pod)
 
 			if utils.SourcePodMatchesKubesondeSpec(Kubesonde, *pod) {
 				log.Info(fmt.Sprintf("EventHandler::AddPodEvent %s", pod.Name))
-				AddPodEvent(client, Kubesonde, *pod)
+				ctx, span := tracing.Tracer().Start(context.Background(), tracing.POD_EVENT_SPAN, trace.WithAttributes(
+					tracing.KUBESONDE_KEY.String(Kubesonde.Namespace+"/"+Kubesonde.Name),
+					tracing.POD_KEY.String(pod.Name),
+					tracing.NAMESPACE_KEY.String(pod.Namespace),
+				))
+				AddPodEvent(ctx, client, namespaces, Kubesonde, *pod)
+				span.End()
 			}
 		},
 		DeleteFunc: func(obj interface{}) {
@@ -57,7 +67,7 @@ func CanBeProbed(srv v1.Service) bool {
 }
 
 func AddServiceToProbes(srv v1.Service) {
-	currPods := eventstorage.GetActivePods()
+	currPods := eventstorage.GetSourcePods()
 	if len(currPods) == 0 {
 		return
 	}
@@ -93,14 +103,23 @@ func svcEventHandler(Kubesonde kubesondev1.Kubesonde) cache.ResourceEventHandler
 func InitEventListener(client kubernetes.Interface, Kubesonde kubesondev1.Kubesonde) {
 	log.Info("Setting up the event listener...")
 	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(client, time.Second*5)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	// The skip policy reads the namespaces of the pods from the cache
+	namespaces := kubeInformerFactory.Core().V1().Namespaces()
+	namespaceInformer := namespaces.Informer()
+	kubeInformerFactory.Start(stop)
+	if !cache.WaitForCacheSync(stop, namespaceInformer.HasSynced) {
+		log.Info("Failed to sync the namespaces, the skip policy ignores their annotations and labels")
+	}
+
 	podInformer := kubeInformerFactory.Core().V1().Pods().Informer()
 	svcInformer := kubeInformerFactory.Core().V1().Services().Informer()
 
-	podInformer.AddEventHandler(podEventHandler(client, Kubesonde))
+	podInformer.AddEventHandler(podEventHandler(client, namespaces.Lister(), Kubesonde))
 	svcInformer.AddEventHandler(svcEventHandler(Kubesonde))
 
-	stop := make(chan struct{})
-	defer close(stop)
 	kubeInformerFactory.Start(stop)
 	for {
 		time.Sleep(time.Second * 30)
@@ -11,14 +11,17 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	kubesondev1 "kubesonde.io/api/v1"
 	v12 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/audit"
 	debugcontainer "kubesonde.io/controllers/debug-container"
 	kubesondeDispatcher "kubesonde.io/controllers/dispatcher"
 	. "kubesonde.io/controllers/event-storage"
 	eventstorage "kubesonde.io/controllers/event-storage"
 	"kubesonde.io/controllers/probe_command"
 	"kubesonde.io/controllers/state"
+	"kubesonde.io/controllers/tracing"
 	"kubesonde.io/controllers/utils"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -31,7 +34,6 @@ Boundaries of this function are:
 */
 var probe_processing_semaphore = semaphore.NewWeighted(1)
 var log = logf.Log.WithName("kubesonde.podEvents")
-var notDebuggablePods = []string{"kube-apiserver-minikube", "etcd-minikube", "kube-scheduler-minikube", "kube-controller-manager-minikube"}
 
 func IsProcessingEvent() bool {
 	var result = probe_processing_semaphore.TryAcquire(1)
@@ -42,44 +44,71 @@ func IsProcessingEvent() bool {
 }
 
 // TODO: Add resilience mechanism to to unlock the resource when pending for too long.
-func AddPodEvent(client kubernetes.Interface, kubesonde kubesondev1.Kubesonde, pod v1.Pod) {
-	pods := v1.PodList{
-		Items: []v1.Pod{pod},
-	}
-	_, isnotDebuggable := lo.Find(notDebuggablePods, func(s string) bool {
-		return s == pod.Name
-	})
-	if !isnotDebuggable {
-		debugcontainer.InstallEphameralContainers(client, kubesonde, &pods)
+// AddPodEvent instruments a pod and queues its probes, the spans of their
+// runs are children of the span of ctx. The pods the skip policy of the spec
+// excludes are recorded as skipped: they are not instrumented and are only
+// probed as destinations.
+func AddPodEvent(ctx context.Context, client kubernetes.Interface, namespaces corelisters.NamespaceLister, kubesonde kubesondev1.Kubesonde, pod v1.Pod) {
+	var timestamp = time.Now().Unix()
+	skipped := SkippedPod(namespaces, kubesonde, pod, timestamp)
+	if skipped != nil {
+		log.Info(fmt.Sprintf("Skipping pod %s: %s", pod.Name, skipped.Message), "reason", skipped.Reason)
+		state.AppendSkipped(&[]v12.SkippedPod{*skipped})
 	} else {
-		log.Info(fmt.Sprintf("Non debuggable pod: %s", pod.Name))
-		log.Info(fmt.Sprintf("IP: %s", pod.Status.PodIP))
-		log.Info(fmt.Sprintf("Containers: %v", pod.Spec.Containers))
-
+		pods := v1.PodList{
+			Items: []v1.Pod{pod},
+		}
+		// The commands run in the pod are audited for this object
+		audit.GetDefaultLog().Attribute(pod.Namespace+"/"+pod.Name, kubesonde.Namespace+"/"+kubesonde.Name)
+		debugcontainer.InstallEphameralContainers(client, namespaces, kubesonde, &pods)
+		pod = pods.Items[0]
+		if !probe_command.RawSockets(pod) {
+			log.Info(fmt.Sprintf("The debugger of pod %s cannot open raw sockets, skipping its UDP and SCTP probes", pod.Name))
+			errors := []v12.ProbeOutputError{{
+				Value: v12.ProbeOutputItem{
+					Timestamp: timestamp,
+					Source: v12.ProbeEndpointInfo{
+						Name:      pod.Name,
+						Namespace: pod.Namespace,
+						IPAddress: pod.Status.PodIP,
+					},
+				},
+				Reason: probe_command.NO_RAW_SOCKETS,
+			}}
+			state.AppendErrors(&errors)
+		}
 	}
-	//log.Info(fmt.Sprintf("Pod: %s (%s)", pod.Name, pod.Status.PodIP))
-	var timestamp = time.Now().Unix()
 
 	/**
 	If the active pods list is not empty then build probes
 	*/
-	var activePods = GetActivePods()
-	if len(activePods) > 0 {
+	if len(GetActivePods()) > 0 {
+		sources, destinations := GetSourcePods(), GetSkippedPods()
 		// Build probes
-		probes := probe_command.BuildTargetedCommands(pod, activePods)
-		probes_from_pods := probe_command.BuildCommandsFromPodSelectors(activePods, "nothing")
+		_, build := tracing.Tracer().Start(ctx, tracing.BUILD_SPAN)
+		var probes []probe_command.KubesondeCommand
+		if skipped != nil {
+			probes = probe_command.BuildCommandsBetween(sources, []v1.Pod{pod})
+		} else {
+			probes = append(probe_command.BuildTargetedCommands(pod, sources), probe_command.BuildCommandsBetween([]v1.Pod{pod}, destinations)...)
+		}
+		probes_from_pods := append(probe_command.BuildCommandsFromPodSelectors(sources, "nothing"), probe_command.BuildCommandsBetween(sources, destinations)...)
+		build.SetAttributes(tracing.PROBES_KEY.Int(len(probes) + len(probes_from_pods)))
+		build.End()
 		// Current pod probes all services
 
 		AddProbes(probes)
 		AddProbes(probes_from_pods)
-		kubesondeDispatcher.SendToQueue(probes, kubesondeDispatcher.HIGH)
+		kubesondeDispatcher.SendToQueueWithContext(ctx, probes, kubesondeDispatcher.HIGH)
+	}
+	if skipped == nil {
+		// TODO: Maybe there should be an event listener on the services to do the same thing.
+		curr_services := eventstorage.GetServices()
+		services_probes := probe_command.BuildCommandsToServices(pod, curr_services)
+		AddProbes(services_probes)
+		other_probes := probe_command.BuildCommandsToOutsideWorld(pod)
+		AddProbes(other_probes)
 	}
-	// TODO: Maybe there should be an event listener on the services to do the same thing.
-	curr_services := eventstorage.GetServices()
-	services_probes := probe_command.BuildCommandsToServices(pod, curr_services)
-	AddProbes(services_probes)
-	other_probes := probe_command.BuildCommandsToOutsideWorld(pod)
-	AddProbes(other_probes)
 	replicaSet, deployment := utils.GetReplicaAndDeployment(client, pod)
 
 	AddActivePod(pod.Name, CreatedPodRecord{
@@ -87,6 +116,7 @@ func AddPodEvent(client kubernetes.Interface, kubesonde kubesondev1.Kubesonde, p
 		CreationTimestamp: timestamp,
 		DeploymentName:    deployment,
 		ReplicaSetName:    replicaSet,
+		Skipped:           skipped != nil,
 	})
 
 	addPodPortsToState(pod)
@@ -182,6 +212,7 @@ polleph:
 }
 
 func deletePodEvent(pod v1.Pod) {
+	state.DeleteSkipped(pod.Namespace, pod.Name)
 	var deleteTimestamp = time.Now().Unix()
 	var activePod = GetActivePodByName(pod.Name)
 	AddDeletedPod(pod.Name, DeletedPodRecord{
@@ -191,7 +222,10 @@ func deletePodEvent(pod v1.Pod) {
 		DeletionTimestamp: deleteTimestamp,
 	})
 	DeleteActivePod(pod.Name)
+	kubesondeDispatcher.RemovePod(pod.Namespace, pod.Name)
 	state.DeleteNetstatPod(pod.Name)
+	debugcontainer.ForgetPod(&pod)
+	audit.GetDefaultLog().Forget(pod.Namespace + "/" + pod.Name)
 	errors := []v12.ProbeOutputError{{
 		Value: v12.ProbeOutputItem{
 			Timestamp: time.Now().Unix(),
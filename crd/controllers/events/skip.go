@@ -0,0 +1,114 @@
+package events
+
+import (
+	"fmt"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	kubesondev1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/utils"
+)
+
+// Annotations and labels opting a pod or the pods of a namespace out of
+// Kubesonde, with any value but "false"
+const (
+	SKIP_KEY          = "kubesonde.io/skip"
+	NO_INSTRUMENT_KEY = "kubesonde.io/no-instrument"
+)
+
+// MIRROR_POD_ANNOTATION is set by the kubelet on the mirror pods of the static pods
+const MIRROR_POD_ANNOTATION = "kubernetes.io/config.mirror"
+
+const KUBE_SYSTEM_NAMESPACE = "kube-system"
+
+// Reasons a pod is skipped
+const (
+	OPTED_OUT           = "OptedOut"
+	NAMESPACE_OPTED_OUT = "NamespaceOptedOut"
+	STATIC_POD          = "StaticPod"
+	HOST_NETWORK        = "HostNetwork"
+	KUBE_SYSTEM         = "KubeSystem"
+	OWN_NAMESPACE       = "OwnNamespace"
+	SKIPPED_NAMESPACE   = "SkippedNamespace"
+)
+
+func enabled(flag *bool) bool {
+	return flag == nil || *flag
+}
+
+// optedOut returns the annotation or label opting the object out
+func optedOut(object metav1.ObjectMeta) (string, bool) {
+	for _, key := range []string{SKIP_KEY, NO_INSTRUMENT_KEY} {
+		if value, found := object.Annotations[key]; found && value != "false" {
+			return "annotation " + key, true
+		}
+		if value, found := object.Labels[key]; found && value != "false" {
+			return "label " + key, true
+		}
+	}
+	return "", false
+}
+
+func isStaticPod(pod v1.Pod) bool {
+	if _, found := pod.Annotations[MIRROR_POD_ANNOTATION]; found {
+		return true
+	}
+	return lo.ContainsBy(pod.OwnerReferences, func(owner metav1.OwnerReference) bool {
+		return owner.Kind == "Node"
+	})
+}
+
+// skipReason returns the reason and the message of the policy skipping the pod,
+// an empty reason when it is instrumented. The namespace may be nil when it
+// could not be read.
+func skipReason(policy kubesondev1.SkipPolicy, pod v1.Pod, namespace *v1.Namespace, own string) (string, string) {
+	if marker, found := optedOut(pod.ObjectMeta); found {
+		return OPTED_OUT, fmt.Sprintf("The pod has the %s", marker)
+	}
+	if namespace != nil {
+		if marker, found := optedOut(namespace.ObjectMeta); found {
+			return NAMESPACE_OPTED_OUT, fmt.Sprintf("The namespace %s has the %s", namespace.Name, marker)
+		}
+	}
+	if enabled(policy.StaticPods) && isStaticPod(pod) {
+		return STATIC_POD, "The pod is a static pod managed by the kubelet"
+	}
+	if enabled(policy.HostNetwork) && pod.Spec.HostNetwork {
+		return HOST_NETWORK, "The pod uses the network of its node"
+	}
+	if enabled(policy.KubeSystem) && pod.Namespace == KUBE_SYSTEM_NAMESPACE {
+		return KUBE_SYSTEM, "The pod runs in kube-system"
+	}
+	if enabled(policy.OwnNamespace) && own != "" && pod.Namespace == own {
+		return OWN_NAMESPACE, "The pod runs in the namespace of Kubesonde"
+	}
+	if lo.Contains(policy.Namespaces, pod.Namespace) {
+		return SKIPPED_NAMESPACE, fmt.Sprintf("The namespace %s is skipped by the spec", pod.Namespace)
+	}
+	return "", ""
+}
+
+// SkippedPod returns the skip record of the pod, nil when the skip policy of
+// the spec instruments it. The namespace of the pod is read from the cache of
+// the lister.
+func SkippedPod(namespaces corelisters.NamespaceLister, kubesonde kubesondev1.Kubesonde, pod v1.Pod, timestamp int64) *kubesondev1.SkippedPod {
+	policy := lo.FromPtr(kubesonde.Spec.Skip)
+	namespace, err := namespaces.Get(pod.Namespace)
+	if err != nil {
+		log.Error(err, "Failed to read the namespace of the pod, ignoring its annotations", "pod", pod.Name, "namespace", pod.Namespace)
+		namespace = nil
+	}
+	reason, message := skipReason(policy, pod, namespace, utils.OwnNamespace())
+	if reason == "" {
+		return nil
+	}
+	return &kubesondev1.SkippedPod{
+		Name:      pod.Name,
+		Namespace: pod.Namespace,
+		Reason:    reason,
+		Message:   message,
+		Timestamp: timestamp,
+	}
+}
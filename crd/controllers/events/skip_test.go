@@ -0,0 +1,86 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	kubesondev1 "kubesonde.io/api/v1"
+)
+
+func skipTestPod(namespace string) v1.Pod {
+	return v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: namespace}}
+}
+
+func TestSkipReason(t *testing.T) {
+	optedOutPod := skipTestPod("default")
+	optedOutPod.Annotations = map[string]string{SKIP_KEY: "true"}
+	labelledPod := skipTestPod("default")
+	labelledPod.Labels = map[string]string{NO_INSTRUMENT_KEY: ""}
+	optedInPod := skipTestPod("default")
+	optedInPod.Annotations = map[string]string{SKIP_KEY: "false"}
+	mirrorPod := skipTestPod("default")
+	mirrorPod.Annotations = map[string]string{MIRROR_POD_ANNOTATION: "hash"}
+	staticPod := skipTestPod("default")
+	staticPod.OwnerReferences = []metav1.OwnerReference{{Kind: "Node", Name: "minikube"}}
+	hostNetworkPod := skipTestPod("default")
+	hostNetworkPod.Spec.HostNetwork = true
+	optedOutNamespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Labels: map[string]string{SKIP_KEY: "yes"}}}
+
+	tests := map[string]struct {
+		policy    kubesondev1.SkipPolicy
+		pod       v1.Pod
+		namespace *v1.Namespace
+		reason    string
+	}{
+		"instrumented":           {pod: skipTestPod("default")},
+		"pod annotation":         {pod: optedOutPod, reason: OPTED_OUT},
+		"pod label":              {pod: labelledPod, reason: OPTED_OUT},
+		"false annotation":       {pod: optedInPod},
+		"namespace label":        {pod: skipTestPod("default"), namespace: optedOutNamespace, reason: NAMESPACE_OPTED_OUT},
+		"mirror pod":             {pod: mirrorPod, reason: STATIC_POD},
+		"static pod":             {pod: staticPod, reason: STATIC_POD},
+		"static pods allowed":    {policy: kubesondev1.SkipPolicy{StaticPods: lo.ToPtr(false)}, pod: staticPod},
+		"host network":           {pod: hostNetworkPod, reason: HOST_NETWORK},
+		"host network allowed":   {policy: kubesondev1.SkipPolicy{HostNetwork: lo.ToPtr(false)}, pod: hostNetworkPod},
+		"kube-system":            {pod: skipTestPod(KUBE_SYSTEM_NAMESPACE), reason: KUBE_SYSTEM},
+		"kube-system allowed":    {policy: kubesondev1.SkipPolicy{KubeSystem: lo.ToPtr(false)}, pod: skipTestPod(KUBE_SYSTEM_NAMESPACE)},
+		"own namespace":          {pod: skipTestPod("kubesonde"), reason: OWN_NAMESPACE},
+		"own namespace allowed":  {policy: kubesondev1.SkipPolicy{OwnNamespace: lo.ToPtr(false)}, pod: skipTestPod("kubesonde")},
+		"skipped namespace":      {policy: kubesondev1.SkipPolicy{Namespaces: []string{"monitoring"}}, pod: skipTestPod("monitoring"), reason: SKIPPED_NAMESPACE},
+		"opt-out beats the spec": {policy: kubesondev1.SkipPolicy{StaticPods: lo.ToPtr(false)}, pod: optedOutPod, reason: OPTED_OUT},
+		"unreadable namespace":   {pod: skipTestPod("default"), namespace: nil},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			reason, message := skipReason(test.policy, test.pod, test.namespace, "kubesonde")
+			assert.Equal(t, test.reason, reason)
+			assert.Equal(t, reason == "", message == "")
+		})
+	}
+}
+
+func TestSkippedPod(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	assert.NoError(t, indexer.Add(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "shop",
+		Annotations: map[string]string{NO_INSTRUMENT_KEY: "true"},
+	}}))
+	namespaces := corelisters.NewNamespaceLister(indexer)
+	kubesonde := kubesondev1.Kubesonde{}
+
+	skipped := SkippedPod(namespaces, kubesonde, skipTestPod("shop"), 42)
+	assert.Equal(t, &kubesondev1.SkippedPod{
+		Name:      "pod",
+		Namespace: "shop",
+		Reason:    NAMESPACE_OPTED_OUT,
+		Message:   "The namespace shop has the annotation kubesonde.io/no-instrument",
+		Timestamp: 42,
+	}, skipped)
+
+	assert.Nil(t, SkippedPod(namespaces, kubesonde, skipTestPod("default"), 42))
+}
@@ -0,0 +1,29 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+
+	"github.com/samber/lo"
+)
+
+// CSV_HEADER uses the column names Gephi expects for edge lists
+var CSV_HEADER = []string{"Source", "Target", "Port", "Protocol", "Action", "SourceNamespace", "TargetNamespace", "TargetType"}
+
+func renderCSV(g diagram) ([]byte, error) {
+	nodes := lo.KeyBy(g.nodes, func(n node) string { return n.ID })
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	if err := writer.Write(CSV_HEADER); err != nil {
+		return nil, err
+	}
+	for _, e := range g.edges {
+		source, target := nodes[e.source], nodes[e.target]
+		record := []string{e.source, e.target, e.port, e.protocol, string(e.action), source.Namespace, target.Namespace, string(target.Type)}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buffer.Bytes(), writer.Error()
+}
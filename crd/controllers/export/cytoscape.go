@@ -0,0 +1,57 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// CytoscapeElements is the elements object accepted by cytoscape.js
+type CytoscapeElements struct {
+	Nodes []CytoscapeElement `json:"nodes"`
+	Edges []CytoscapeElement `json:"edges"`
+}
+
+type CytoscapeElement struct {
+	Data    map[string]string `json:"data"`
+	Classes string            `json:"classes,omitempty"`
+}
+
+// renderCytoscape renders clusters as compound parent nodes
+func renderCytoscape(g diagram) ([]byte, error) {
+	elements := CytoscapeElements{Nodes: []CytoscapeElement{}, Edges: []CytoscapeElement{}}
+	clustered, _ := clusters(g.nodes)
+	for _, name := range sortedClusters(clustered) {
+		elements.Nodes = append(elements.Nodes, CytoscapeElement{
+			Data:    map[string]string{"id": "cluster/" + name, "label": clustered[name][0].cluster},
+			Classes: "cluster",
+		})
+	}
+	for _, n := range g.nodes {
+		data := map[string]string{"id": n.ID, "label": n.Label, "type": string(n.Type)}
+		if n.Namespace != "" {
+			data["namespace"] = n.Namespace
+		}
+		if n.cluster != "" {
+			data["parent"] = "cluster/" + n.Namespace + "/" + n.cluster
+		}
+		elements.Nodes = append(elements.Nodes, CytoscapeElement{Data: data})
+	}
+	elements.Edges = lo.Map(g.edges, func(e edge, i int) CytoscapeElement {
+		return CytoscapeElement{
+			Data: map[string]string{
+				"id":       fmt.Sprintf("e%d", i),
+				"source":   e.source,
+				"target":   e.target,
+				"label":    e.label(),
+				"port":     e.port,
+				"protocol": e.protocol,
+				"action":   string(e.action),
+			},
+			Classes: strings.ToLower(string(e.action)),
+		}
+	})
+	return json.MarshalIndent(map[string]CytoscapeElements{"elements": elements}, "", "  ")
+}
@@ -0,0 +1,93 @@
+package export
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+	v1 "kubesonde.io/api/v1"
+)
+
+// clusters returns the clustered nodes by cluster name and the other nodes
+func clusters(nodes []node) (map[string][]node, []node) {
+	clustered := lo.GroupBy(lo.Filter(nodes, func(n node, _ int) bool { return n.cluster != "" }), func(n node) string {
+		return n.Namespace + "/" + n.cluster
+	})
+	return clustered, lo.Filter(nodes, func(n node, _ int) bool { return n.cluster == "" })
+}
+
+func sortedClusters(clustered map[string][]node) []string {
+	names := lo.Keys(clustered)
+	sort.Strings(names)
+	return names
+}
+
+func renderDOT(g diagram) []byte {
+	var sb strings.Builder
+	sb.WriteString("digraph kubesonde {\n  rankdir=LR;\n  node [shape=box];\n")
+	writeNode := func(indent string, n node) {
+		shape := lo.Ternary(n.Type == v1.INTERNET, "ellipse", lo.Ternary(n.Type == v1.SERVICE, "hexagon", "box"))
+		fmt.Fprintf(&sb, "%s%s [label=%s, shape=%s];\n", indent, strconv.Quote(n.ID), strconv.Quote(n.Label), shape)
+	}
+	clustered, others := clusters(g.nodes)
+	for _, name := range sortedClusters(clustered) {
+		fmt.Fprintf(&sb, "  subgraph %s {\n    label=%s;\n", strconv.Quote("cluster_"+name), strconv.Quote(clustered[name][0].cluster))
+		for _, n := range clustered[name] {
+			writeNode("    ", n)
+		}
+		sb.WriteString("  }\n")
+	}
+	for _, n := range others {
+		writeNode("  ", n)
+	}
+	for _, e := range g.edges {
+		style := lo.Ternary(e.action == v1.DENY, ", color=red, style=dashed", "")
+		fmt.Fprintf(&sb, "  %s -> %s [label=%s%s];\n", strconv.Quote(e.source), strconv.Quote(e.target), strconv.Quote(e.label()), style)
+	}
+	sb.WriteString("}\n")
+	return []byte(sb.String())
+}
+
+// mermaidText escapes the characters Mermaid does not accept in labels
+func mermaidText(text string) string {
+	return strings.ReplaceAll(text, `"`, "#quot;")
+}
+
+func renderMermaid(g diagram) []byte {
+	// Mermaid identifiers cannot contain slashes or dots
+	ids := map[string]string{}
+	for i, n := range g.nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+	}
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+	writeNode := func(indent string, n node) {
+		openShape, closeShape := "[\"", "\"]"
+		switch n.Type {
+		case v1.INTERNET:
+			openShape, closeShape = "((\"", "\"))"
+		case v1.SERVICE:
+			openShape, closeShape = "{{\"", "\"}}"
+		}
+		fmt.Fprintf(&sb, "%s%s%s%s%s\n", indent, ids[n.ID], openShape, mermaidText(n.Label), closeShape)
+	}
+	clustered, others := clusters(g.nodes)
+	for i, name := range sortedClusters(clustered) {
+		fmt.Fprintf(&sb, "  subgraph c%d [\"%s\"]\n", i, mermaidText(clustered[name][0].cluster))
+		for _, n := range clustered[name] {
+			writeNode("    ", n)
+		}
+		sb.WriteString("  end\n")
+	}
+	for _, n := range others {
+		writeNode("  ", n)
+	}
+	for _, e := range g.edges {
+		arrow := lo.Ternary(e.action == v1.DENY, "-.->", "-->")
+		label := lo.Ternary(e.action == v1.DENY, e.label()+" denied", e.label())
+		fmt.Fprintf(&sb, "  %s %s|\"%s\"| %s\n", ids[e.source], arrow, mermaidText(label), ids[e.target])
+	}
+	return []byte(sb.String())
+}
@@ -0,0 +1,166 @@
+package export
+
+import (
+	"fmt"
+	"mime"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/graph"
+)
+
+type Format string
+
+const (
+	JSON      Format = "json"
+	CSV       Format = "csv"
+	DOT       Format = "dot"
+	MERMAID   Format = "mermaid"
+	GRAPHML   Format = "graphml"
+	CYTOSCAPE Format = "cytoscape"
+)
+
+var contentTypes = map[Format]string{
+	JSON:      "application/json",
+	CSV:       "text/csv",
+	DOT:       "text/vnd.graphviz",
+	MERMAID:   "text/vnd.mermaid",
+	GRAPHML:   "application/graphml+xml",
+	CYTOSCAPE: "application/vnd.cytoscape+json",
+}
+
+// ContentType returns the media type of the format
+func (f Format) ContentType() string {
+	return contentTypes[f]
+}
+
+// ParseFormat validates a format name
+func ParseFormat(name string) (Format, error) {
+	format := Format(strings.ToLower(name))
+	if _, ok := contentTypes[format]; !ok {
+		return "", fmt.Errorf("unsupported format %s", name)
+	}
+	return format, nil
+}
+
+// NegotiateFormat returns the first format of an Accept header that can be
+// rendered, JSON when none can
+func NegotiateFormat(accept string) Format {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		for format, contentType := range contentTypes {
+			if mediaType == contentType {
+				return format
+			}
+		}
+	}
+	return JSON
+}
+
+type Grouping string
+
+const (
+	// POD_GROUPING draws one node per pod, the pods are clustered by deployment
+	POD_GROUPING Grouping = "pod"
+	// DEPLOYMENT_GROUPING collapses the replicas of a deployment in one node
+	DEPLOYMENT_GROUPING Grouping = "deployment"
+)
+
+// ParseGrouping validates a grouping name, pods are the default
+func ParseGrouping(name string) (Grouping, error) {
+	switch Grouping(name) {
+	case "", POD_GROUPING:
+		return POD_GROUPING, nil
+	case DEPLOYMENT_GROUPING:
+		return DEPLOYMENT_GROUPING, nil
+	}
+	return "", fmt.Errorf("unsupported grouping %s", name)
+}
+
+type node struct {
+	graph.Node
+	// cluster is the deployment of a pod, or the namespace of a deployment
+	cluster string
+}
+
+type edge struct {
+	source   string
+	target   string
+	port     string
+	protocol string
+	action   v1.ActionType
+}
+
+func (e edge) label() string {
+	return e.port + "/" + e.protocol
+}
+
+type diagram struct {
+	nodes []node
+	edges []edge
+}
+
+func nodeOf(endpoint v1.ProbeEndpointInfo, grouping Grouping) node {
+	if grouping == DEPLOYMENT_GROUPING {
+		n := graph.NodeOf(endpoint, graph.WORKLOAD_LEVEL)
+		return node{Node: n, cluster: lo.Ternary(endpoint.Type == v1.INTERNET, "", n.Namespace)}
+	}
+	n := graph.NodeOf(endpoint, graph.POD_LEVEL)
+	return node{Node: n, cluster: n.Workload}
+}
+
+// buildGraph returns the probed endpoints and one edge per distinct port,
+// protocol and outcome between two nodes
+func buildGraph(output v1.ProbeOutput, grouping Grouping) diagram {
+	nodes := map[string]node{}
+	edges := []edge{}
+	for _, item := range output.Items {
+		if item.Type != v1.PROBE {
+			continue
+		}
+		source, target := nodeOf(item.Source, grouping), nodeOf(item.Destination, grouping)
+		nodes[source.ID] = source
+		nodes[target.ID] = target
+		edges = append(edges, edge{
+			source:   source.ID,
+			target:   target.ID,
+			port:     item.Port,
+			protocol: item.Protocol,
+			action:   item.ResultingAction,
+		})
+	}
+	edges = lo.Uniq(edges)
+	sort.SliceStable(edges, func(i, j int) bool {
+		if edges[i].source != edges[j].source {
+			return edges[i].source < edges[j].source
+		}
+		return edges[i].target < edges[j].target
+	})
+	sortedNodes := lo.Values(nodes)
+	sort.Slice(sortedNodes, func(i, j int) bool { return sortedNodes[i].ID < sortedNodes[j].ID })
+	return diagram{nodes: sortedNodes, edges: edges}
+}
+
+// Render converts the probe output to the given format. JSON is the probe
+// output itself and is left to the caller.
+func Render(output v1.ProbeOutput, format Format, grouping Grouping) ([]byte, error) {
+	g := buildGraph(output, grouping)
+	switch format {
+	case CSV:
+		return renderCSV(g)
+	case DOT:
+		return renderDOT(g), nil
+	case MERMAID:
+		return renderMermaid(g), nil
+	case GRAPHML:
+		return renderGraphML(g)
+	case CYTOSCAPE:
+		return renderCytoscape(g)
+	}
+	return nil, fmt.Errorf("unsupported format %s", format)
+}
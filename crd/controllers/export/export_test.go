@@ -0,0 +1,115 @@
+package export
+
+import (
+	"encoding/json"
+	"encoding/xml"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "kubesonde.io/api/v1"
+)
+
+var (
+	frontendA = v1.ProbeEndpointInfo{Type: v1.POD, Name: "frontend-1", Namespace: "shop", DeploymentName: "frontend"}
+	frontendB = v1.ProbeEndpointInfo{Type: v1.POD, Name: "frontend-2", Namespace: "shop", DeploymentName: "frontend"}
+	database  = v1.ProbeEndpointInfo{Type: v1.POD, Name: "db-0", Namespace: "data"}
+	google    = v1.ProbeEndpointInfo{Type: v1.INTERNET, Name: "Google DNS", IPAddress: "8.8.8.8"}
+)
+
+func probe(src v1.ProbeEndpointInfo, dst v1.ProbeEndpointInfo, port string, result v1.ActionType) v1.ProbeOutputItem {
+	return v1.ProbeOutputItem{
+		Type:            v1.PROBE,
+		ResultingAction: result,
+		Source:          src,
+		Destination:     dst,
+		Port:            port,
+		Protocol:        "TCP",
+	}
+}
+
+var output = v1.ProbeOutput{
+	Items: []v1.ProbeOutputItem{
+		probe(frontendA, database, "5432", v1.ALLOW),
+		probe(frontendB, database, "5432", v1.ALLOW),
+		probe(database, frontendA, "8080", v1.DENY),
+		probe(frontendA, google, "53", v1.ALLOW),
+	},
+}
+
+var _ = Describe("Format", func() {
+	It("Parses the formats", func() {
+		format, err := ParseFormat("GraphML")
+		Expect(err).To(BeNil())
+		Expect(format).To(Equal(GRAPHML))
+		_, err = ParseFormat("pdf")
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("Negotiates the format", func() {
+		Expect(NegotiateFormat("text/html, text/csv;q=0.9")).To(Equal(CSV))
+		Expect(NegotiateFormat("*/*")).To(Equal(JSON))
+		Expect(NegotiateFormat("")).To(Equal(JSON))
+	})
+})
+
+var _ = Describe("Render", func() {
+	It("Renders CSV edge lists", func() {
+		data, err := Render(output, CSV, POD_GROUPING)
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal(`Source,Target,Port,Protocol,Action,SourceNamespace,TargetNamespace,TargetType
+data/db-0,shop/frontend-1,8080,TCP,Deny,data,shop,Pod
+shop/frontend-1,Google DNS,53,TCP,Allow,shop,,Internet
+shop/frontend-1,data/db-0,5432,TCP,Allow,shop,data,Pod
+shop/frontend-2,data/db-0,5432,TCP,Allow,shop,data,Pod
+`))
+	})
+
+	It("Collapses the replicas of a deployment", func() {
+		data, err := Render(output, CSV, DEPLOYMENT_GROUPING)
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(Equal(`Source,Target,Port,Protocol,Action,SourceNamespace,TargetNamespace,TargetType
+data/db-0,shop/frontend,8080,TCP,Deny,data,shop,Pod
+shop/frontend,Google DNS,53,TCP,Allow,shop,,Internet
+shop/frontend,data/db-0,5432,TCP,Allow,shop,data,Pod
+`))
+	})
+
+	It("Renders DOT with a cluster per deployment", func() {
+		data, err := Render(output, DOT, POD_GROUPING)
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(ContainSubstring(`subgraph "cluster_shop/frontend" {`))
+		Expect(string(data)).To(ContainSubstring(`"shop/frontend-1" [label="frontend-1", shape=box];`))
+		Expect(string(data)).To(ContainSubstring(`"Google DNS" [label="Google DNS", shape=ellipse];`))
+		Expect(string(data)).To(ContainSubstring(`"data/db-0" -> "shop/frontend-1" [label="8080/TCP", color=red, style=dashed];`))
+	})
+
+	It("Renders Mermaid", func() {
+		data, err := Render(output, MERMAID, DEPLOYMENT_GROUPING)
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(HavePrefix("flowchart LR\n"))
+		Expect(string(data)).To(ContainSubstring(`subgraph c1 ["shop"]`))
+		Expect(string(data)).To(ContainSubstring(`n0(("Google DNS"))`))
+		Expect(string(data)).To(ContainSubstring(`n1 -.->|"8080/TCP denied"| n2`))
+	})
+
+	It("Renders GraphML", func() {
+		data, err := Render(output, GRAPHML, POD_GROUPING)
+		Expect(err).To(BeNil())
+		var document graphMLDocument
+		Expect(xml.Unmarshal(data, &document)).To(Succeed())
+		Expect(document.Graph.Nodes).To(HaveLen(4))
+		Expect(document.Graph.Edges).To(HaveLen(4))
+	})
+
+	It("Renders Cytoscape JSON", func() {
+		data, err := Render(output, CYTOSCAPE, POD_GROUPING)
+		Expect(err).To(BeNil())
+		var document map[string]CytoscapeElements
+		Expect(json.Unmarshal(data, &document)).To(Succeed())
+		elements := document["elements"]
+		// The frontend deployment is a compound node
+		Expect(elements.Nodes).To(HaveLen(5))
+		Expect(elements.Nodes[0].Data).To(Equal(map[string]string{"id": "cluster/shop/frontend", "label": "frontend"}))
+		Expect(elements.Edges).To(HaveLen(4))
+	})
+})
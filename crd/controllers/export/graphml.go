@@ -0,0 +1,86 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/samber/lo"
+)
+
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+func renderGraphML(g diagram) ([]byte, error) {
+	keys := []graphMLKey{
+		{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+		{ID: "namespace", For: "node", AttrName: "namespace", AttrType: "string"},
+		{ID: "type", For: "node", AttrName: "type", AttrType: "string"},
+		{ID: "group", For: "node", AttrName: "group", AttrType: "string"},
+		{ID: "port", For: "edge", AttrName: "port", AttrType: "string"},
+		{ID: "protocol", For: "edge", AttrName: "protocol", AttrType: "string"},
+		{ID: "action", For: "edge", AttrName: "action", AttrType: "string"},
+	}
+	document := graphMLDocument{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys:  keys,
+		Graph: graphMLGraph{
+			ID:          "kubesonde",
+			EdgeDefault: "directed",
+			Nodes: lo.Map(g.nodes, func(n node, _ int) graphMLNode {
+				return graphMLNode{ID: n.ID, Data: []graphMLData{
+					{Key: "label", Value: n.Label},
+					{Key: "namespace", Value: n.Namespace},
+					{Key: "type", Value: string(n.Type)},
+					{Key: "group", Value: n.cluster},
+				}}
+			}),
+			Edges: lo.Map(g.edges, func(e edge, i int) graphMLEdge {
+				return graphMLEdge{ID: fmt.Sprintf("e%d", i), Source: e.source, Target: e.target, Data: []graphMLData{
+					{Key: "port", Value: e.port},
+					{Key: "protocol", Value: e.protocol},
+					{Key: "action", Value: string(e.action)},
+				}}
+			}),
+		},
+	}
+	data, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
@@ -0,0 +1,176 @@
+// Package findings turns the changes of the probe state into notable events,
+// e.g. a pod that started to reach the Internet, for the notifications.
+package findings
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/baseline"
+	"kubesonde.io/controllers/report"
+	"kubesonde.io/controllers/state"
+)
+
+type Kind string
+
+const (
+	// INTERNET_REACHABLE is a pod reaching the Internet for the first time
+	INTERNET_REACHABLE Kind = "internet-reachable"
+	// EXPECTED_ACTION_VIOLATION is a probe whose verdict differs from its expected action
+	EXPECTED_ACTION_VIOLATION Kind = "expected-action-violation"
+	// BASELINE_DRIFT is an allowed edge missing from the baseline, or a denied edge of the baseline
+	BASELINE_DRIFT Kind = "baseline-drift"
+	// VERDICT_FLIP is a connection whose verdict changed since its previous probe
+	VERDICT_FLIP Kind = "verdict-flip"
+)
+
+// KINDS lists the kinds of findings
+var KINDS = []Kind{INTERNET_REACHABLE, EXPECTED_ACTION_VIOLATION, BASELINE_DRIFT, VERDICT_FLIP}
+
+// ParseKind checks a kind of findings, e.g. from the spec of a webhook
+func ParseKind(value string) (Kind, error) {
+	kind := Kind(value)
+	if !lo.Contains(KINDS, kind) {
+		return "", fmt.Errorf("invalid finding %s, expected one of %v", value, KINDS)
+	}
+	return kind, nil
+}
+
+// Finding is a notable probe result
+type Finding struct {
+	Kind Kind `json:"kind"`
+	// Timestamp is in seconds since the epoch
+	Timestamp int64 `json:"timestamp"`
+	// Message describes the finding, e.g. "shop/web-1 reaches Google (google.com) on 443/TCP"
+	Message string `json:"message"`
+	// Source is the namespace/name of the source pod
+	Source string `json:"source"`
+	// Destination is the namespace/name of the destination, or the name of an external one
+	Destination string        `json:"destination"`
+	Port        string        `json:"port"`
+	Protocol    string        `json:"protocol"`
+	Action      v1.ActionType `json:"action"`
+	// ExpectedAction is set on expected action violations
+	ExpectedAction v1.ActionType `json:"expectedAction,omitempty"`
+	// PreviousAction is set on verdict flips
+	PreviousAction v1.ActionType `json:"previousAction,omitempty"`
+	// Baseline is the namespace/name/key of the ConfigMap holding the baseline, on baseline drifts
+	Baseline string `json:"baseline,omitempty"`
+	// Probe is the probe result the finding comes from
+	Probe v1.ProbeOutputItem `json:"probe"`
+}
+
+func endpointName(info v1.ProbeEndpointInfo) string {
+	name := lo.CoalesceOrEmpty(info.Name, info.IPAddress)
+	if info.Namespace == "" {
+		return name
+	}
+	return info.Namespace + "/" + name
+}
+
+type edgeKey struct {
+	source      string
+	destination string
+	port        string
+	protocol    string
+}
+
+// Detector remembers the probes seen so far, so that each finding is
+// reported once. It is not safe for concurrent use.
+type Detector struct {
+	// current returns the baseline the probes are compared to
+	current  func() (baseline.Current, bool)
+	verdicts map[edgeKey]v1.ActionType
+	internet map[string]bool
+	drifted  map[string]bool
+}
+
+// NewDetector compares the probes to the baseline returned by current, e.g.
+// baseline.GetCurrent
+func NewDetector(current func() (baseline.Current, bool)) *Detector {
+	d := &Detector{current: current}
+	d.Reset()
+	return d
+}
+
+// Reset forgets the probes seen so far
+func (d *Detector) Reset() {
+	d.verdicts = map[edgeKey]v1.ActionType{}
+	d.internet = map[string]bool{}
+	d.drifted = map[string]bool{}
+}
+
+// Detect returns the findings of a change of the probe state. A reset of the
+// state resets the detector.
+func (d *Detector) Detect(change state.Change) []Finding {
+	switch change.Kind {
+	case state.RESET_CHANGE:
+		d.Reset()
+		return nil
+	case state.PROBE_CHANGE:
+		return d.detectProbe(*change.Probe, change.Timestamp)
+	}
+	return nil
+}
+
+func (d *Detector) detectProbe(item v1.ProbeOutputItem, timestamp int64) []Finding {
+	if item.Type != v1.PROBE || item.Source.Type != v1.POD {
+		return nil
+	}
+	protocol := strings.ToUpper(lo.CoalesceOrEmpty(item.Protocol, "TCP"))
+	key := edgeKey{endpointName(item.Source), endpointName(item.Destination), item.Port, protocol}
+	finding := func(kind Kind, message string) Finding {
+		return Finding{
+			Kind:        kind,
+			Timestamp:   timestamp,
+			Message:     message,
+			Source:      key.source,
+			Destination: key.destination,
+			Port:        key.port,
+			Protocol:    key.protocol,
+			Action:      item.ResultingAction,
+			Probe:       item,
+		}
+	}
+	edge := fmt.Sprintf("%s -> %s %s/%s", key.source, key.destination, key.port, key.protocol)
+	findings := []Finding{}
+
+	if item.ResultingAction == v1.ALLOW && report.IsInternet(item.Destination) && !d.internet[key.source] {
+		d.internet[key.source] = true
+		findings = append(findings, finding(INTERNET_REACHABLE, fmt.Sprintf("%s reaches the Internet: %s", key.source, edge)))
+	}
+	if item.ExpectedAction != "" && item.ExpectedAction != item.ResultingAction {
+		violation := finding(EXPECTED_ACTION_VIOLATION, fmt.Sprintf("%s is %s, expected %s", edge, item.ResultingAction, item.ExpectedAction))
+		violation.ExpectedAction = item.ExpectedAction
+		findings = append(findings, violation)
+	}
+	if previous, found := d.verdicts[key]; found && previous != item.ResultingAction {
+		flip := finding(VERDICT_FLIP, fmt.Sprintf("%s changed from %s to %s", edge, previous, item.ResultingAction))
+		flip.PreviousAction = previous
+		findings = append(findings, flip)
+	}
+	d.verdicts[key] = item.ResultingAction
+
+	if approved, found := d.current(); found {
+		drift := baseline.Compare(approved.Baseline, v1.ProbeOutput{Items: []v1.ProbeOutputItem{item}})
+		workload := baseline.DriftEdge{From: baseline.EndpointName(item.Source), To: baseline.EndpointName(item.Destination), Port: item.Port, Protocol: protocol}
+		edges := lo.Filter(drift.Edges(), func(e baseline.DriftEdge, _ int) bool {
+			return e.From == workload.From && e.To == workload.To && e.Port == workload.Port && e.Protocol == workload.Protocol
+		})
+		for _, e := range edges {
+			if d.drifted[e.String()] {
+				continue
+			}
+			d.drifted[e.String()] = true
+			message := lo.Ternary(e.Kind == baseline.UNEXPECTED,
+				fmt.Sprintf("%s is allowed, not in the baseline", edge),
+				fmt.Sprintf("%s is denied, allowed in the baseline", edge))
+			drifted := finding(BASELINE_DRIFT, message)
+			drifted.Baseline = approved.Source
+			findings = append(findings, drifted)
+		}
+	}
+	return findings
+}
@@ -0,0 +1,106 @@
+package findings
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/baseline"
+	"kubesonde.io/controllers/state"
+)
+
+var _ = Describe("Detector", func() {
+	web := v1.ProbeEndpointInfo{Type: v1.POD, Name: "web-1", Namespace: "shop", DeploymentName: "web"}
+	db := v1.ProbeEndpointInfo{Type: v1.POD, Name: "db-1", Namespace: "shop", DeploymentName: "db"}
+	google := v1.ProbeEndpointInfo{Type: v1.INTERNET, Name: "Google", IPAddress: "google.com"}
+	dns := v1.ProbeEndpointInfo{Type: v1.INTERNET, Name: "KUBE DNS", IPAddress: "kube-dns.kube-system.svc.cluster.local"}
+	probe := func(source v1.ProbeEndpointInfo, destination v1.ProbeEndpointInfo, port string, action v1.ActionType) state.Change {
+		return state.Change{Kind: state.PROBE_CHANGE, Timestamp: 1700000000, Probe: &v1.ProbeOutputItem{
+			Type: v1.PROBE, Source: source, Destination: destination, Port: port, Protocol: "TCP", ResultingAction: action,
+		}}
+	}
+	messages := func(findings []Finding) []string {
+		return lo.Map(findings, func(f Finding, _ int) string { return string(f.Kind) + ": " + f.Message })
+	}
+	none := func() (baseline.Current, bool) { return baseline.Current{}, false }
+
+	It("Reports the pods reaching the Internet once", func() {
+		d := NewDetector(none)
+		Expect(d.Detect(probe(web, dns, "53", v1.ALLOW))).To(BeEmpty())
+		findings := d.Detect(probe(web, google, "443", v1.ALLOW))
+		Expect(messages(findings)).To(Equal([]string{"internet-reachable: shop/web-1 reaches the Internet: shop/web-1 -> Google 443/TCP"}))
+		Expect(findings[0].Timestamp).To(Equal(int64(1700000000)))
+		Expect(findings[0].Source).To(Equal("shop/web-1"))
+		Expect(findings[0].Destination).To(Equal("Google"))
+		Expect(findings[0].Port).To(Equal("443"))
+		Expect(findings[0].Action).To(Equal(v1.ALLOW))
+		Expect(d.Detect(probe(web, google, "80", v1.ALLOW))).To(BeEmpty())
+		Expect(d.Detect(probe(db, google, "443", v1.DENY))).To(BeEmpty())
+	})
+
+	It("Reports the expected action violations", func() {
+		change := probe(web, db, "5432", v1.ALLOW)
+		change.Probe.ExpectedAction = v1.DENY
+		findings := NewDetector(none).Detect(change)
+		Expect(messages(findings)).To(Equal([]string{"expected-action-violation: shop/web-1 -> shop/db-1 5432/TCP is Allow, expected Deny"}))
+		Expect(findings[0].ExpectedAction).To(Equal(v1.DENY))
+	})
+
+	It("Reports the verdict flips", func() {
+		d := NewDetector(none)
+		Expect(d.Detect(probe(web, db, "5432", v1.DENY))).To(BeEmpty())
+		findings := d.Detect(probe(web, db, "5432", v1.ALLOW))
+		Expect(messages(findings)).To(Equal([]string{"verdict-flip: shop/web-1 -> shop/db-1 5432/TCP changed from Deny to Allow"}))
+		Expect(findings[0].PreviousAction).To(Equal(v1.DENY))
+
+		d.Detect(state.Change{Kind: state.RESET_CHANGE})
+		Expect(d.Detect(probe(web, db, "5432", v1.DENY))).To(BeEmpty())
+	})
+
+	It("Reports the verdicts flipping back through the state", func() {
+		sm := state.NewStateManager()
+		_, subscription := sm.Subscribe(sm.LastSequence())
+		defer subscription.Cancel()
+		d := NewDetector(none)
+		for i, action := range []v1.ActionType{v1.ALLOW, v1.DENY, v1.ALLOW} {
+			item := *probe(web, db, "5432", action).Probe
+			item.Timestamp = int64(1700000000 + i)
+			Expect(sm.AppendProbes(&[]v1.ProbeOutputItem{item})).To(Succeed())
+		}
+		findings := []Finding{}
+		for range 3 {
+			findings = append(findings, d.Detect(<-subscription.Changes)...)
+		}
+		Expect(messages(findings)).To(Equal([]string{
+			"verdict-flip: shop/web-1 -> shop/db-1 5432/TCP changed from Allow to Deny",
+			"verdict-flip: shop/web-1 -> shop/db-1 5432/TCP changed from Deny to Allow",
+		}))
+		Expect(findings[1].Probe.Timestamp).To(Equal(int64(1700000002)))
+	})
+
+	It("Reports the drift from the baseline", func() {
+		current := func() (baseline.Current, bool) {
+			return baseline.Current{
+				Source:   "default/approved/baseline.yaml",
+				Baseline: baseline.Baseline{Edges: []baseline.Edge{{From: "shop/web", To: "shop/db", Ports: []string{"5432"}}}},
+			}, true
+		}
+		d := NewDetector(current)
+		Expect(d.Detect(probe(web, db, "5432", v1.ALLOW))).To(BeEmpty())
+		findings := d.Detect(probe(web, db, "6379", v1.ALLOW))
+		Expect(messages(findings)).To(Equal([]string{"baseline-drift: shop/web-1 -> shop/db-1 6379/TCP is allowed, not in the baseline"}))
+		Expect(findings[0].Baseline).To(Equal("default/approved/baseline.yaml"))
+		Expect(d.Detect(probe(web, db, "6379", v1.ALLOW))).To(BeEmpty())
+
+		second := v1.ProbeEndpointInfo{Type: v1.POD, Name: "web-2", Namespace: "shop", DeploymentName: "web"}
+		Expect(messages(d.Detect(probe(second, db, "5432", v1.DENY)))).To(Equal([]string{
+			"baseline-drift: shop/web-2 -> shop/db-1 5432/TCP is denied, allowed in the baseline",
+		}))
+	})
+
+	It("Parses the kinds", func() {
+		Expect(ParseKind("verdict-flip")).To(Equal(VERDICT_FLIP))
+		_, err := ParseKind("flip")
+		Expect(err).NotTo(BeNil())
+	})
+})
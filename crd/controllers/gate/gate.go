@@ -0,0 +1,230 @@
+// The gate module runs a single probe round and evaluates it, for pipelines
+// that scan an ephemeral cluster and fail on policy violations
+package gate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/analysis"
+	"kubesonde.io/controllers/baseline"
+	"kubesonde.io/controllers/dispatcher"
+	eventstorage "kubesonde.io/controllers/event-storage"
+	"kubesonde.io/controllers/events"
+	"kubesonde.io/controllers/probe_command"
+	"kubesonde.io/controllers/report"
+	"kubesonde.io/controllers/state"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = logf.Log.WithName("Gate")
+
+// Exit codes of the one-shot mode
+const (
+	EXIT_PASSED     = 0
+	EXIT_VIOLATIONS = 1
+	EXIT_ERROR      = 2
+)
+
+// Names of the files written to the report directory
+const (
+	JUNIT_FILE   = "kubesonde-junit.xml"
+	SARIF_FILE   = "kubesonde.sarif"
+	REPORT_FILE  = "kubesonde-report.json"
+	RESULTS_FILE = "kubesonde-results.json"
+)
+
+// POLL_INTERVAL is the period the instrumentation of the pods is checked on
+var POLL_INTERVAL = time.Second
+
+type Options struct {
+	// Settle is how long the probes must not change before the round starts
+	Settle time.Duration
+	// Timeout bounds the instrumentation of the pods and the round
+	Timeout time.Duration
+	// BaselinePath is an optional baseline file, or a probe output, the
+	// results must match
+	BaselinePath string
+	ReportDir    string
+}
+
+// Environment is the state of the controller the gate relies on
+type Environment struct {
+	// Probes returns the probes built from the instrumented pods
+	Probes func() []probe_command.KubesondeCommand
+	// Busy reports whether a pod event is being processed
+	Busy       func() bool
+	StartRound func(commands []probe_command.KubesondeCommand) *dispatcher.Round
+	Results    func() v1.ProbeOutput
+}
+
+func DefaultEnvironment() Environment {
+	return Environment{
+		Probes: eventstorage.GetProbes,
+		Busy:   events.IsProcessingEvent,
+		StartRound: func(commands []probe_command.KubesondeCommand) *dispatcher.Round {
+			return dispatcher.StartRound(commands, dispatcher.HIGH)
+		},
+		Results: state.GetProbeState,
+	}
+}
+
+// Round describes the probe round the gate ran
+type Round struct {
+	ID     int `json:"id"`
+	Probes int `json:"probes"`
+	// Skipped is the number of probes dropped because their pod was deleted
+	Skipped         int     `json:"skipped"`
+	DurationSeconds float64 `json:"durationSeconds"`
+}
+
+// Outcome is the report of the gate
+type Outcome struct {
+	Passed bool `json:"passed"`
+	// StartedAt and FinishedAt are in seconds since the epoch
+	StartedAt  int64            `json:"startedAt"`
+	FinishedAt int64            `json:"finishedAt"`
+	Round      Round            `json:"round"`
+	Summary    analysis.Summary `json:"summary"`
+	// Checks is the number of asserted edges
+	Checks     int               `json:"checks"`
+	Violations []report.TestCase `json:"violations"`
+}
+
+// Run waits for the pods to be instrumented, runs one round of all the probes
+// and writes the reports. It returns the exit code of the process.
+func Run(ctx context.Context, options Options) int {
+	return RunWithEnvironment(ctx, options, DefaultEnvironment())
+}
+
+func RunWithEnvironment(ctx context.Context, options Options, env Environment) int {
+	startedAt := time.Now()
+	var approved *baseline.Baseline
+	if options.BaselinePath != "" {
+		read, err := readBaseline(options.BaselinePath)
+		if err != nil {
+			log.Error(err, "Invalid baseline")
+			return EXIT_ERROR
+		}
+		approved = &read
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, options.Timeout)
+	defer cancel()
+	probes, err := waitForProbes(ctx, options.Settle, env)
+	if err != nil {
+		log.Error(err, "Pods not instrumented")
+		return EXIT_ERROR
+	}
+
+	log.Info(fmt.Sprintf("Running %d probes", len(probes)))
+	round := env.StartRound(probes)
+	select {
+	case <-round.Done():
+	case <-ctx.Done():
+		log.Error(ctx.Err(), fmt.Sprintf("Round not complete, %d probes of %d did not run", round.Remaining(), round.Total))
+		return EXIT_ERROR
+	}
+	skipped := round.Skipped()
+	if skipped > 0 {
+		log.Info(fmt.Sprintf("Skipped %d probes of deleted pods", skipped))
+	}
+
+	output := env.Results()
+	cases := report.Cases(output, approved)
+	result := Outcome{
+		StartedAt:  startedAt.Unix(),
+		FinishedAt: time.Now().Unix(),
+		Round:      Round{ID: round.ID, Probes: round.Total, Skipped: skipped, DurationSeconds: time.Since(round.StartedAt).Seconds()},
+		Summary:    analysis.Summarize(output),
+		Checks:     len(cases),
+		Violations: report.Failures(cases),
+	}
+	result.Passed = len(result.Violations) == 0
+	if err := writeReports(options.ReportDir, result, cases, output); err != nil {
+		log.Error(err, "Failed to write the reports")
+		return EXIT_ERROR
+	}
+
+	if !result.Passed {
+		for _, violation := range result.Violations {
+			log.Info(fmt.Sprintf("Violation: %s -> %s: %s", violation.Classname, violation.Name, violation.Failure))
+		}
+		return EXIT_VIOLATIONS
+	}
+	log.Info(fmt.Sprintf("Passed %d checks", result.Checks))
+	return EXIT_PASSED
+}
+
+func readBaseline(path string) (baseline.Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return baseline.Baseline{}, err
+	}
+	approved, err := baseline.Parse(data)
+	if err != nil {
+		return baseline.Baseline{}, fmt.Errorf("failed to read the baseline %s: %w", path, err)
+	}
+	return approved, nil
+}
+
+// waitForProbes returns the probes once no pod event is processed and their
+// number did not change during the settle period
+func waitForProbes(ctx context.Context, settle time.Duration, env Environment) ([]probe_command.KubesondeCommand, error) {
+	ticker := time.NewTicker(POLL_INTERVAL)
+	defer ticker.Stop()
+	count := -1
+	stableSince := time.Now()
+	for {
+		probes := env.Probes()
+		if len(probes) != count || env.Busy() {
+			count = len(probes)
+			stableSince = time.Now()
+		} else if count > 0 && time.Since(stableSince) >= settle {
+			return probes, nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if count == 0 {
+				return nil, errors.New("no probes found, is there a Kubesonde object?")
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func writeJSON(path string, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func writeReports(dir string, result Outcome, cases []report.TestCase, output v1.ProbeOutput) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(filepath.Join(dir, JUNIT_FILE))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := report.WriteJUnit(file, "kubesonde", cases); err != nil {
+		return err
+	}
+	if err := writeJSON(filepath.Join(dir, SARIF_FILE), report.SARIF(output)); err != nil {
+		return err
+	}
+	if err := writeJSON(filepath.Join(dir, REPORT_FILE), result); err != nil {
+		return err
+	}
+	return writeJSON(filepath.Join(dir, RESULTS_FILE), output)
+}
@@ -0,0 +1,120 @@
+package gate
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/dispatcher"
+	"kubesonde.io/controllers/probe_command"
+)
+
+var _ = Describe("RunWithEnvironment", func() {
+	var options Options
+	var env Environment
+	probes := []probe_command.KubesondeCommand{
+		{SourcePodName: "a", Namespace: "default", Destination: "b", DestinationPort: "80", Command: "1"},
+		{SourcePodName: "b", Namespace: "default", Destination: "a", DestinationPort: "80", Command: "2"},
+	}
+	pod := func(name string) v1.ProbeEndpointInfo {
+		return v1.ProbeEndpointInfo{Type: v1.POD, Name: name, Namespace: "default"}
+	}
+	results := v1.ProbeOutput{Items: []v1.ProbeOutputItem{
+		{Type: v1.PROBE, Source: pod("a"), Destination: pod("b"), Port: "80", Protocol: "TCP", ExpectedAction: v1.ALLOW, ResultingAction: v1.ALLOW},
+		{Type: v1.PROBE, Source: pod("b"), Destination: pod("a"), Port: "80", Protocol: "TCP", ResultingAction: v1.ALLOW},
+	}}
+
+	BeforeEach(func() {
+		POLL_INTERVAL = 10 * time.Millisecond
+		options = Options{Settle: 50 * time.Millisecond, Timeout: time.Second, ReportDir: GinkgoT().TempDir()}
+		env = Environment{
+			Probes: func() []probe_command.KubesondeCommand { return probes },
+			Busy:   func() bool { return false },
+			StartRound: func(commands []probe_command.KubesondeCommand) *dispatcher.Round {
+				Expect(commands).To(Equal(probes))
+				return dispatcher.StartRound(nil, dispatcher.HIGH)
+			},
+			Results: func() v1.ProbeOutput { return results },
+		}
+	})
+
+	readReport := func() Outcome {
+		data, err := os.ReadFile(filepath.Join(options.ReportDir, REPORT_FILE))
+		Expect(err).NotTo(HaveOccurred())
+		var result Outcome
+		Expect(json.Unmarshal(data, &result)).To(Succeed())
+		return result
+	}
+
+	It("Passes when the edges match the expected actions", func() {
+		Expect(RunWithEnvironment(context.Background(), options, env)).To(Equal(EXIT_PASSED))
+
+		result := readReport()
+		Expect(result.Passed).To(BeTrue())
+		Expect(result.Checks).To(Equal(1))
+		Expect(result.Violations).To(BeEmpty())
+		Expect(result.Summary.Probes).To(Equal(2))
+		Expect(filepath.Join(options.ReportDir, JUNIT_FILE)).To(BeAnExistingFile())
+		Expect(filepath.Join(options.ReportDir, SARIF_FILE)).To(BeAnExistingFile())
+		Expect(filepath.Join(options.ReportDir, RESULTS_FILE)).To(BeAnExistingFile())
+	})
+
+	It("Fails when an edge drifted from the baseline", func() {
+		baseline := v1.ProbeOutput{Items: results.Items[:1]}
+		data, err := json.Marshal(baseline)
+		Expect(err).NotTo(HaveOccurred())
+		options.BaselinePath = filepath.Join(options.ReportDir, "baseline.json")
+		Expect(os.WriteFile(options.BaselinePath, data, 0o644)).To(Succeed())
+
+		Expect(RunWithEnvironment(context.Background(), options, env)).To(Equal(EXIT_VIOLATIONS))
+
+		result := readReport()
+		Expect(result.Passed).To(BeFalse())
+		Expect(result.Checks).To(Equal(2))
+		Expect(result.Violations).To(HaveLen(1))
+		Expect(result.Violations[0].Classname).To(Equal("default/b"))
+		junit, err := os.ReadFile(filepath.Join(options.ReportDir, JUNIT_FILE))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(junit)).To(ContainSubstring(`<failure message="allowed, not in the baseline">`))
+	})
+
+	It("Fails to start without probes", func() {
+		env.Probes = func() []probe_command.KubesondeCommand { return nil }
+		options.Timeout = 100 * time.Millisecond
+
+		Expect(RunWithEnvironment(context.Background(), options, env)).To(Equal(EXIT_ERROR))
+		Expect(filepath.Join(options.ReportDir, REPORT_FILE)).NotTo(BeAnExistingFile())
+	})
+
+	It("Fails when the round does not complete in time", func() {
+		env.StartRound = func(commands []probe_command.KubesondeCommand) *dispatcher.Round {
+			return dispatcher.StartRound(commands, dispatcher.HIGH)
+		}
+		options.Timeout = 200 * time.Millisecond
+
+		Expect(RunWithEnvironment(context.Background(), options, env)).To(Equal(EXIT_ERROR))
+	})
+
+	It("Reports the probes of the deleted pods as skipped", func() {
+		env.StartRound = func(commands []probe_command.KubesondeCommand) *dispatcher.Round {
+			round := dispatcher.StartRound(commands, dispatcher.HIGH)
+			dispatcher.RemovePod("default", "a")
+			dispatcher.RemovePod("default", "b")
+			return round
+		}
+
+		Expect(RunWithEnvironment(context.Background(), options, env)).To(Equal(EXIT_PASSED))
+		Expect(readReport().Round.Skipped).To(Equal(2))
+	})
+
+	It("Rejects an invalid baseline", func() {
+		options.BaselinePath = filepath.Join(options.ReportDir, "missing.json")
+
+		Expect(RunWithEnvironment(context.Background(), options, env)).To(Equal(EXIT_ERROR))
+	})
+})
@@ -0,0 +1,186 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/samber/lo"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/pkg/apitypes"
+)
+
+type Level = apitypes.GraphLevel
+
+const (
+	// POD_LEVEL draws one node per pod
+	POD_LEVEL = apitypes.POD_LEVEL
+	// WORKLOAD_LEVEL collapses the replicas of a deployment or a replica set
+	WORKLOAD_LEVEL = apitypes.WORKLOAD_LEVEL
+	// NAMESPACE_LEVEL collapses all the pods and services of a namespace
+	NAMESPACE_LEVEL = apitypes.NAMESPACE_LEVEL
+)
+
+// ParseLevel validates a level name, pods are the default
+func ParseLevel(name string) (Level, error) {
+	switch Level(name) {
+	case "", POD_LEVEL:
+		return POD_LEVEL, nil
+	case WORKLOAD_LEVEL, NAMESPACE_LEVEL:
+		return Level(name), nil
+	}
+	return "", fmt.Errorf("unsupported level %s", name)
+}
+
+type Verdict = apitypes.Verdict
+
+const (
+	ALLOWED = apitypes.ALLOWED
+	DENIED  = apitypes.DENIED
+	// MIXED means that some of the merged probes were allowed and some denied
+	MIXED = apitypes.MIXED
+)
+
+// Node is a pod, a workload or a namespace depending on the level. Services and
+// Internet endpoints are never collapsed.
+type Node = apitypes.GraphNode
+
+// VerdictSummary counts the probes merged in an edge or a port
+type VerdictSummary = apitypes.VerdictSummary
+
+func add(s *VerdictSummary, action v1.ActionType) {
+	if action == v1.ALLOW {
+		s.Allowed++
+	} else {
+		s.Denied++
+	}
+	switch {
+	case s.Denied == 0:
+		s.Verdict = ALLOWED
+	case s.Allowed == 0:
+		s.Verdict = DENIED
+	default:
+		s.Verdict = MIXED
+	}
+}
+
+type Port = apitypes.GraphPort
+
+// Edge merges all the probes between two nodes
+type Edge = apitypes.GraphEdge
+
+type Graph = apitypes.Graph
+
+// NodeOf returns the node an endpoint belongs to at the given level, without members
+func NodeOf(endpoint v1.ProbeEndpointInfo, level Level) Node {
+	switch {
+	case endpoint.Type == v1.INTERNET:
+		name := lo.Ternary(endpoint.Name != "", endpoint.Name, endpoint.IPAddress)
+		return Node{ID: name, Label: name, Type: endpoint.Type}
+	case level == NAMESPACE_LEVEL:
+		return Node{ID: endpoint.Namespace, Label: endpoint.Namespace, Type: endpoint.Type, Namespace: endpoint.Namespace}
+	case endpoint.Type == v1.SERVICE:
+		return Node{
+			ID:        "svc/" + endpoint.Namespace + "/" + endpoint.Name,
+			Label:     endpoint.Name,
+			Type:      endpoint.Type,
+			Namespace: endpoint.Namespace,
+		}
+	case level == WORKLOAD_LEVEL:
+		return Node{
+			ID:        endpoint.Namespace + "/" + endpoint.WorkloadName(),
+			Label:     endpoint.WorkloadName(),
+			Type:      endpoint.Type,
+			Namespace: endpoint.Namespace,
+			Workload:  endpoint.WorkloadName(),
+		}
+	}
+	return Node{
+		ID:        endpoint.Namespace + "/" + endpoint.Name,
+		Label:     endpoint.Name,
+		Type:      endpoint.Type,
+		Namespace: endpoint.Namespace,
+		Workload:  lo.Ternary(endpoint.WorkloadName() != endpoint.Name, endpoint.WorkloadName(), ""),
+	}
+}
+
+type edgeKey struct {
+	source string
+	target string
+}
+
+type portKey struct {
+	port     string
+	protocol string
+}
+
+// Build aggregates the probes at the given level. The edges between two nodes
+// are merged, with the ports probed and a summary of the verdicts.
+func Build(output v1.ProbeOutput, level Level) Graph {
+	nodes := map[string]*Node{}
+	addNode := func(endpoint v1.ProbeEndpointInfo) string {
+		n := NodeOf(endpoint, level)
+		if _, ok := nodes[n.ID]; !ok {
+			nodes[n.ID] = &n
+		}
+		if endpoint.Type == v1.POD && level != POD_LEVEL {
+			nodes[n.ID].Members = lo.Uniq(append(nodes[n.ID].Members, endpoint.Namespace+"/"+endpoint.Name))
+		}
+		return n.ID
+	}
+
+	edges := map[edgeKey]*Edge{}
+	ports := map[edgeKey]map[portKey]*Port{}
+	for _, item := range output.Items {
+		if item.Type != v1.PROBE {
+			continue
+		}
+		key := edgeKey{source: addNode(item.Source), target: addNode(item.Destination)}
+		if _, ok := edges[key]; !ok {
+			edges[key] = &Edge{Source: key.source, Target: key.target}
+			ports[key] = map[portKey]*Port{}
+		}
+		add(&edges[key].VerdictSummary, item.ResultingAction)
+		pk := portKey{port: item.Port, protocol: item.Protocol}
+		if _, ok := ports[key][pk]; !ok {
+			ports[key][pk] = &Port{Port: item.Port, Protocol: item.Protocol}
+		}
+		add(&ports[key][pk].VerdictSummary, item.ResultingAction)
+	}
+
+	graph := Graph{Level: level, Nodes: []Node{}, Edges: []Edge{}}
+	for _, n := range nodes {
+		sort.Strings(n.Members)
+		graph.Nodes = append(graph.Nodes, *n)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].ID < graph.Nodes[j].ID })
+	for key, e := range edges {
+		e.Ports = lo.Map(lo.Values(ports[key]), func(p *Port, _ int) Port { return *p })
+		sortPorts(e.Ports)
+		graph.Edges = append(graph.Edges, *e)
+	}
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].Source != graph.Edges[j].Source {
+			return graph.Edges[i].Source < graph.Edges[j].Source
+		}
+		return graph.Edges[i].Target < graph.Edges[j].Target
+	})
+	for i := range graph.Edges {
+		graph.Edges[i].ID = strconv.Itoa(i)
+	}
+	return graph
+}
+
+func sortPorts(ports []Port) {
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].Port != ports[j].Port {
+			a, errA := strconv.Atoi(ports[i].Port)
+			b, errB := strconv.Atoi(ports[j].Port)
+			if errA == nil && errB == nil {
+				return a < b
+			}
+			return ports[i].Port < ports[j].Port
+		}
+		return ports[i].Protocol < ports[j].Protocol
+	})
+}
@@ -0,0 +1,93 @@
+package graph
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "kubesonde.io/api/v1"
+)
+
+var (
+	frontendA = v1.ProbeEndpointInfo{Type: v1.POD, Name: "frontend-abc-1", Namespace: "shop", DeploymentName: "frontend", ReplicaSetName: "frontend-abc"}
+	frontendB = v1.ProbeEndpointInfo{Type: v1.POD, Name: "frontend-abc-2", Namespace: "shop", DeploymentName: "frontend", ReplicaSetName: "frontend-abc"}
+	worker    = v1.ProbeEndpointInfo{Type: v1.POD, Name: "worker-xyz-1", Namespace: "shop", ReplicaSetName: "worker-xyz"}
+	database  = v1.ProbeEndpointInfo{Type: v1.POD, Name: "db-0", Namespace: "data"}
+	dbService = v1.ProbeEndpointInfo{Type: v1.SERVICE, Name: "db", Namespace: "data"}
+	google    = v1.ProbeEndpointInfo{Type: v1.INTERNET, IPAddress: "8.8.8.8"}
+)
+
+func probe(src v1.ProbeEndpointInfo, dst v1.ProbeEndpointInfo, port string, result v1.ActionType) v1.ProbeOutputItem {
+	return v1.ProbeOutputItem{
+		Type:            v1.PROBE,
+		ResultingAction: result,
+		Source:          src,
+		Destination:     dst,
+		Port:            port,
+		Protocol:        "TCP",
+	}
+}
+
+var output = v1.ProbeOutput{
+	Items: []v1.ProbeOutputItem{
+		probe(frontendA, database, "5432", v1.ALLOW),
+		probe(frontendB, database, "5432", v1.DENY),
+		probe(frontendA, database, "9187", v1.ALLOW),
+		probe(frontendA, dbService, "5432", v1.ALLOW),
+		probe(worker, database, "5432", v1.DENY),
+		probe(worker, google, "443", v1.ALLOW),
+		{Type: v1.INFO, Source: frontendA, Destination: worker},
+	},
+}
+
+var _ = Describe("Build", func() {
+	It("Keeps the pods at pod level", func() {
+		g := Build(output, POD_LEVEL)
+		Expect(g.Level).To(Equal(POD_LEVEL))
+		Expect(g.Nodes).To(HaveLen(6))
+		Expect(g.Nodes[2]).To(Equal(Node{ID: "shop/frontend-abc-1", Label: "frontend-abc-1", Type: v1.POD, Namespace: "shop", Workload: "frontend"}))
+		Expect(g.Edges).To(HaveLen(5))
+	})
+
+	It("Collapses the replicas at workload level", func() {
+		g := Build(output, WORKLOAD_LEVEL)
+		Expect(g.Nodes).To(Equal([]Node{
+			{ID: "8.8.8.8", Label: "8.8.8.8", Type: v1.INTERNET},
+			{ID: "data/db-0", Label: "db-0", Type: v1.POD, Namespace: "data", Workload: "db-0", Members: []string{"data/db-0"}},
+			{ID: "shop/frontend", Label: "frontend", Type: v1.POD, Namespace: "shop", Workload: "frontend", Members: []string{"shop/frontend-abc-1", "shop/frontend-abc-2"}},
+			{ID: "shop/worker-xyz", Label: "worker-xyz", Type: v1.POD, Namespace: "shop", Workload: "worker-xyz", Members: []string{"shop/worker-xyz-1"}},
+			{ID: "svc/data/db", Label: "db", Type: v1.SERVICE, Namespace: "data"},
+		}))
+		Expect(g.Edges[0]).To(Equal(Edge{
+			ID:     "0",
+			Source: "shop/frontend",
+			Target: "data/db-0",
+			Ports: []Port{
+				{Port: "5432", Protocol: "TCP", VerdictSummary: VerdictSummary{Verdict: MIXED, Allowed: 1, Denied: 1}},
+				{Port: "9187", Protocol: "TCP", VerdictSummary: VerdictSummary{Verdict: ALLOWED, Allowed: 1}},
+			},
+			VerdictSummary: VerdictSummary{Verdict: MIXED, Allowed: 2, Denied: 1},
+		}))
+		Expect(g.Edges).To(HaveLen(4))
+	})
+
+	It("Collapses the namespaces", func() {
+		g := Build(output, NAMESPACE_LEVEL)
+		Expect(nodeIDs(g.Nodes)).To(Equal([]string{"8.8.8.8", "data", "shop"}))
+		Expect(g.Edges).To(HaveLen(2))
+		Expect(g.Edges[1].Source).To(Equal("shop"))
+		Expect(g.Edges[1].Target).To(Equal("data"))
+		Expect(g.Edges[1].VerdictSummary).To(Equal(VerdictSummary{Verdict: MIXED, Allowed: 3, Denied: 2}))
+	})
+
+	It("Rejects unknown levels", func() {
+		_, err := ParseLevel("cluster")
+		Expect(err).NotTo(BeNil())
+	})
+})
+
+func nodeIDs(nodes []Node) []string {
+	ids := []string{}
+	for _, n := range nodes {
+		ids = append(ids, n.ID)
+	}
+	return ids
+}
@@ -0,0 +1,85 @@
+package inner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/samber/lo"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"kubesonde.io/controllers/probe_command"
+	"kubesonde.io/pkg/apitypes"
+)
+
+var (
+	ErrInvalidProbe     = errors.New("invalid probe")
+	ErrEndpointNotFound = errors.New("endpoint not found")
+)
+
+// AdhocProbe is a single probe requested on demand
+type AdhocProbe = apitypes.AdhocProbe
+
+func splitName(name string, defaultNamespace string) (string, string) {
+	if namespace, podName, found := strings.Cut(name, "/"); found {
+		return namespace, podName
+	}
+	return defaultNamespace, name
+}
+
+// BuildAdhocCommand resolves the endpoints of the probe in the cluster
+func BuildAdhocCommand(ctx context.Context, client kubernetes.Interface, probe AdhocProbe) (probe_command.KubesondeCommand, error) {
+	protocol := strings.ToUpper(lo.Ternary(probe.Protocol == "", "TCP", probe.Protocol))
+	if !lo.Contains([]string{"TCP", "UDP", "SCTP"}, protocol) {
+		return probe_command.KubesondeCommand{}, fmt.Errorf("%w: unsupported protocol %s", ErrInvalidProbe, probe.Protocol)
+	}
+	if probe.Port < 1 || probe.Port > 65535 {
+		return probe_command.KubesondeCommand{}, fmt.Errorf("%w: invalid port %d", ErrInvalidProbe, probe.Port)
+	}
+	if probe.Source == "" || probe.Destination == "" {
+		return probe_command.KubesondeCommand{}, fmt.Errorf("%w: source and destination are required", ErrInvalidProbe)
+	}
+
+	namespace, name := splitName(probe.Source, metav1.NamespaceDefault)
+	source, err := client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return probe_command.KubesondeCommand{}, fmt.Errorf("%w: pod %s/%s", ErrEndpointNotFound, namespace, name)
+	} else if err != nil {
+		return probe_command.KubesondeCommand{}, err
+	}
+	if !probe_command.Scannable(*source, protocol) {
+		return probe_command.KubesondeCommand{}, fmt.Errorf("%w: the debugger of pod %s/%s cannot open the raw sockets of the %s probes", ErrInvalidProbe, namespace, name, protocol)
+	}
+
+	if net.ParseIP(probe.Destination) != nil {
+		return probe_command.BuildCommandToAddress(*source, probe.Destination, probe.Port, protocol), nil
+	}
+	isPath := strings.Contains(probe.Destination, "/")
+	namespace, name = splitName(probe.Destination, source.Namespace)
+	// a destination outside the cluster becomes an argument of nmap
+	if errs := append(validation.IsDNS1123Label(namespace), validation.IsDNS1123Subdomain(name)...); len(errs) > 0 {
+		return probe_command.KubesondeCommand{}, fmt.Errorf("%w: destination %q is not an IP address, a hostname nor a namespace/name: %s",
+			ErrInvalidProbe, probe.Destination, strings.Join(errs, ", "))
+	}
+	pod, err := client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		return probe_command.BuildCommandToPod(*source, *pod, probe.Port, protocol), nil
+	} else if !apierrors.IsNotFound(err) {
+		return probe_command.KubesondeCommand{}, err
+	}
+	service, err := client.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		return probe_command.BuildCommandToService(*source, *service, probe.Port, protocol), nil
+	} else if !apierrors.IsNotFound(err) {
+		return probe_command.KubesondeCommand{}, err
+	}
+	if !isPath {
+		// not a pod nor a service, probed as a hostname
+		return probe_command.BuildCommandToAddress(*source, probe.Destination, probe.Port, protocol), nil
+	}
+	return probe_command.KubesondeCommand{}, fmt.Errorf("%w: pod or service %s/%s", ErrEndpointNotFound, namespace, name)
+}
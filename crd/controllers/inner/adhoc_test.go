@@ -0,0 +1,87 @@
+package inner
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	kubesondev1 "kubesonde.io/api/v1"
+)
+
+func TestBuildAdhocCommand(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "src", Namespace: "default"}, Status: v1.PodStatus{PodIP: "10.0.0.1"}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "dst", Namespace: "other"}, Status: v1.PodStatus{PodIP: "10.0.0.2"}},
+		&v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}, Spec: v1.ServiceSpec{ClusterIP: "10.96.0.10"}},
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "restricted", Namespace: "default"}, Spec: v1.PodSpec{EphemeralContainers: []v1.EphemeralContainer{{
+			EphemeralContainerCommon: v1.EphemeralContainerCommon{Name: "debugger", SecurityContext: &v1.SecurityContext{RunAsNonRoot: lo.ToPtr(true)}},
+		}}}},
+	)
+	ctx := context.Background()
+
+	t.Run("Resolves pods", func(t *testing.T) {
+		command, err := BuildAdhocCommand(ctx, client, AdhocProbe{Source: "src", Destination: "other/dst", Port: 80})
+		assert.NoError(t, err)
+		assert.Equal(t, "src", command.SourcePodName)
+		assert.Equal(t, "default", command.Namespace)
+		assert.Equal(t, "dst", command.Destination)
+		assert.Equal(t, "10.0.0.2", command.DestinationIPAddress)
+		assert.Equal(t, kubesondev1.POD, command.DestinationType)
+		assert.Equal(t, "TCP", command.Protocol)
+	})
+
+	t.Run("Resolves services in the namespace of the source", func(t *testing.T) {
+		command, err := BuildAdhocCommand(ctx, client, AdhocProbe{Source: "default/src", Destination: "web", Port: 53, Protocol: "udp"})
+		assert.NoError(t, err)
+		assert.Equal(t, kubesondev1.SERVICE, command.DestinationType)
+		assert.Equal(t, "10.96.0.10", command.DestinationIPAddress)
+		assert.Equal(t, "UDP", command.Protocol)
+	})
+
+	t.Run("Probes addresses outside the cluster", func(t *testing.T) {
+		command, err := BuildAdhocCommand(ctx, client, AdhocProbe{Source: "src", Destination: "1.1.1.1", Port: 443})
+		assert.NoError(t, err)
+		assert.Equal(t, kubesondev1.INTERNET, command.DestinationType)
+		assert.Equal(t, "1.1.1.1", command.DestinationIPAddress)
+	})
+
+	t.Run("Probes hostnames as a single argument", func(t *testing.T) {
+		command, err := BuildAdhocCommand(ctx, client, AdhocProbe{Source: "src", Destination: "example.com", Port: 443})
+		assert.NoError(t, err)
+		assert.Equal(t, kubesondev1.INTERNET, command.DestinationType)
+		assert.Equal(t, "example.com", command.Args()[len(command.Args())-1])
+	})
+
+	t.Run("Rejects destinations that are not hostnames", func(t *testing.T) {
+		for _, destination := range []string{"example.com --script=http-title -oN /tmp/x", "-iL/etc/passwd", "Example_com"} {
+			_, err := BuildAdhocCommand(ctx, client, AdhocProbe{Source: "src", Destination: destination, Port: 80})
+			assert.True(t, errors.Is(err, ErrInvalidProbe), destination)
+		}
+	})
+
+	t.Run("Rejects invalid probes", func(t *testing.T) {
+		_, err := BuildAdhocCommand(ctx, client, AdhocProbe{Source: "src", Destination: "dst", Port: 0})
+		assert.True(t, errors.Is(err, ErrInvalidProbe))
+		_, err = BuildAdhocCommand(ctx, client, AdhocProbe{Source: "src", Destination: "dst", Port: 80, Protocol: "ICMP"})
+		assert.True(t, errors.Is(err, ErrInvalidProbe))
+	})
+
+	t.Run("Rejects the UDP probes of a debugger without raw sockets", func(t *testing.T) {
+		_, err := BuildAdhocCommand(ctx, client, AdhocProbe{Source: "restricted", Destination: "web", Port: 53, Protocol: "UDP"})
+		assert.True(t, errors.Is(err, ErrInvalidProbe))
+		_, err = BuildAdhocCommand(ctx, client, AdhocProbe{Source: "restricted", Destination: "web", Port: 80})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Reports unknown endpoints", func(t *testing.T) {
+		_, err := BuildAdhocCommand(ctx, client, AdhocProbe{Source: "unknown", Destination: "dst", Port: 80})
+		assert.True(t, errors.Is(err, ErrEndpointNotFound))
+		_, err = BuildAdhocCommand(ctx, client, AdhocProbe{Source: "src", Destination: "other/unknown", Port: 80})
+		assert.True(t, errors.Is(err, ErrEndpointNotFound))
+	})
+}
@@ -2,18 +2,22 @@ package inner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/samber/lo"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	v12 "kubesonde.io/api/v1"
 	debug_container "kubesonde.io/controllers/debug-container"
 	"kubesonde.io/controllers/probe_command"
 	"kubesonde.io/controllers/state"
+	"kubesonde.io/controllers/tracing"
 	"kubesonde.io/controllers/utils"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -114,7 +118,9 @@ func withDeploymentInformationSlow(client kubernetes.Interface, output v12.Probe
 
 	return output
 }
-func withDeploymentInformation(client kubernetes.Interface, output v12.ProbeOutputItem) v12.ProbeOutputItem {
+func withDeploymentInformation(ctx context.Context, client kubernetes.Interface, output v12.ProbeOutputItem) v12.ProbeOutputItem {
+	_, span := tracing.Tracer().Start(ctx, tracing.ENRICH_SPAN)
+	defer span.End()
 	// Source is always a pod
 	curr_state := state.GetProbeState().Items
 	src, source_in_state := lo.Find(curr_state, func(item v12.ProbeOutputItem) bool {
@@ -155,9 +161,11 @@ func withDeploymentInformation(client kubernetes.Interface, output v12.ProbeOutp
 	}
 
 	if (source_in_state || source_in_state_2) && (dst_in_state || dst_in_state_2) {
+		span.SetAttributes(tracing.SLOW_PATH_KEY.Bool(false))
 		return output
 	}
 
+	span.SetAttributes(tracing.SLOW_PATH_KEY.Bool(true))
 	return withDeploymentInformationSlow(client, output)
 }
 
@@ -176,62 +184,112 @@ func limitStringLength(s string) string {
 	return s
 }
 
-func InspectWithContinuousMode(mode KubesondeMode, commands []probe_command.KubesondeCommand) v12.ProbeOutput {
-	// runCommand, client := state.runCommand, state.getClient()
+// ErrSourceNotReady means that a probe cannot run because its source pod is
+// gone or its debug container is not running
+var ErrSourceNotReady = errors.New("the debug container of the source pod is not running")
+
+// runProbe runs a probe and returns its outcome, or the error of the probe command
+func runProbe(ctx context.Context, mode KubesondeMode, kubesondeCommand probe_command.KubesondeCommand) (v12.ProbeOutputItem, error) {
 	client := mode.getClient()
-	// FIXME: here I should return only the current probes.
-	for _, kubesondeCommand := range commands {
-		_, source_has_netinfo := lo.Find(state.GetNetstatPods(), func(item string) bool {
-			return item == kubesondeCommand.SourcePodName
-		})
-		if kubesondeCommand.SourceType == v12.POD && !source_has_netinfo {
-			pod, err := client.CoreV1().Pods(kubesondeCommand.Namespace).Get(context.TODO(), kubesondeCommand.SourcePodName, metav1.GetOptions{})
-			if err != nil {
-				continue
-			}
-
-			if !debug_container.EphemeralContainerExists(pod) || !debug_container.EphemeralContainersRunning(pod) {
-				continue
-			}
-		}
-		result, err := mode.runCommand(client, kubesondeCommand.Namespace, kubesondeCommand, kubesondeCommand.ProbeChecker)
-		command := fmt.Sprintf("wget --server-response --timeout=3 -O- http://%s:%s", kubesondeCommand.DestinationIPAddress, kubesondeCommand.DestinationPort)
-		debug_info := fmt.Sprintf("From: %s - Command: %s", kubesondeCommand.SourcePodName, command)
-
-		var output string
-
-		if kubesondeCommand.Protocol == "TCP" && kubesondeCommand.DestinationPort != "53" && kubesondeCommand.DestinationType != v12.INTERNET {
-			genericCommand := kubesondeCommand
-			genericCommand.Command = command
-			output, _ = mode.runGenericCommand(client, kubesondeCommand.Namespace, genericCommand)
-		} else {
-			output = "SKIP"
-		}
+	_, source_has_netinfo := lo.Find(state.GetNetstatPods(), func(item string) bool {
+		return item == kubesondeCommand.SourcePodName
+	})
+	if kubesondeCommand.SourceType == v12.POD && !source_has_netinfo {
+		pod, err := client.CoreV1().Pods(kubesondeCommand.Namespace).Get(context.TODO(), kubesondeCommand.SourcePodName, metav1.GetOptions{})
 		if err != nil {
-			errors := []v12.ProbeOutputError{toProbeError(kubesondeCommand, err)}
-			state.AppendErrors(&errors)
-			log.Info(fmt.Sprintf("Error when Probing: %s %s", kubesondeCommand.Command, errors[0].Reason))
-		} else if result {
-			probe_output := withDeploymentInformation(client, toProbeItem(kubesondeCommand, v12.ALLOW))
-			probe_output.DebugOutput = fixOutput(fmt.Sprintf("%s %s", debug_info, output))
-			probes := []v12.ProbeOutputItem{probe_output}
-			state.AppendProbes(&probes)
-		} else if !result {
-			probe_output := withDeploymentInformation(client, toProbeItem(kubesondeCommand, v12.DENY))
-			probe_output.DebugOutput = fixOutput(fmt.Sprintf("%s %s", debug_info, output))
-			probes := []v12.ProbeOutputItem{probe_output}
-			state.AppendProbes(&probes)
+			return v12.ProbeOutputItem{}, fmt.Errorf("%w: %v", ErrSourceNotReady, err)
+		}
+
+		if !debug_container.EphemeralContainerExists(pod) || !debug_container.EphemeralContainersRunning(pod) {
+			return v12.ProbeOutputItem{}, ErrSourceNotReady
 		}
 	}
+	execCtx, exec := tracing.Tracer().Start(ctx, tracing.EXEC_SPAN)
+	checker := func(output string) bool {
+		_, check := tracing.Tracer().Start(execCtx, tracing.CHECK_SPAN)
+		defer check.End()
+		allowed := kubesondeCommand.ProbeChecker(output)
+		check.SetAttributes(tracing.Verdict(lo.Ternary(allowed, v12.ALLOW, v12.DENY)))
+		return allowed
+	}
+	started := time.Now()
+	result, err := mode.runCommand(client, kubesondeCommand.Namespace, kubesondeCommand, checker)
+	latency := tracing.EXEC_LATENCY_KEY.Int64(time.Since(started).Milliseconds())
+	exec.SetAttributes(latency)
+	trace.SpanFromContext(ctx).SetAttributes(latency)
+	if err != nil {
+		exec.RecordError(err)
+		exec.SetStatus(codes.Error, err.Error())
+	}
+	exec.End()
+	command := fmt.Sprintf("wget --server-response --timeout=3 -O- http://%s:%s", kubesondeCommand.DestinationIPAddress, kubesondeCommand.DestinationPort)
+	debug_info := fmt.Sprintf("From: %s - Command: %s", kubesondeCommand.SourcePodName, command)
+
+	var output string
+
+	if kubesondeCommand.Protocol == "TCP" && kubesondeCommand.DestinationPort != "53" && kubesondeCommand.DestinationType != v12.INTERNET {
+		genericCommand := kubesondeCommand
+		genericCommand.Command = command
+		output, _ = mode.runGenericCommand(client, kubesondeCommand.Namespace, genericCommand)
+	} else {
+		output = "SKIP"
+	}
+	if err != nil {
+		return v12.ProbeOutputItem{}, err
+	}
+	probe_output := withDeploymentInformation(ctx, client, toProbeItem(kubesondeCommand, lo.Ternary(result, v12.ALLOW, v12.DENY)))
+	probe_output.DebugOutput = fixOutput(fmt.Sprintf("%s %s", debug_info, output))
+	return probe_output, nil
+}
+
+// runAndRecord runs a probe and stores its outcome or its error in the state
+func runAndRecord(ctx context.Context, mode KubesondeMode, kubesondeCommand probe_command.KubesondeCommand) (v12.ProbeOutputItem, error) {
+	ctx, span := tracing.Tracer().Start(ctx, tracing.RUN_SPAN, trace.WithAttributes(tracing.CommandAttributes(kubesondeCommand)...))
+	defer span.End()
+	probe, err := runProbe(ctx, mode, kubesondeCommand)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	if errors.Is(err, ErrSourceNotReady) {
+		return probe, err
+	}
+	_, store := tracing.Tracer().Start(ctx, tracing.STORE_SPAN)
+	defer store.End()
+	if err != nil {
+		probeErrors := []v12.ProbeOutputError{toProbeError(kubesondeCommand, err)}
+		state.AppendErrors(&probeErrors)
+		log.Info(fmt.Sprintf("Error when Probing: %s %s", kubesondeCommand.Command, probeErrors[0].Reason))
+		return probe, err
+	}
+	span.SetAttributes(tracing.Verdict(probe.ResultingAction))
+	probes := []v12.ProbeOutputItem{probe}
+	state.AppendProbes(&probes)
+	return probe, err
+}
+
+func InspectWithContinuousMode(ctx context.Context, mode KubesondeMode, commands []probe_command.KubesondeCommand) v12.ProbeOutput {
+	// FIXME: here I should return only the current probes.
+	for _, kubesondeCommand := range commands {
+		_, _ = runAndRecord(ctx, mode, kubesondeCommand)
+	}
 
 	return state.GetProbeState()
 }
 
-func InspectAndStoreResult(client kubernetes.Interface, probes []probe_command.KubesondeCommand) {
+// RunProbe runs a single probe synchronously, stores its outcome and returns it
+func RunProbe(client kubernetes.Interface, command probe_command.KubesondeCommand) (v12.ProbeOutputItem, error) {
+	probestate := new(KubesondeContinuousState)
+	probestate.Client = client
+	return runAndRecord(context.Background(), probestate, command)
+}
+
+// InspectAndStoreResult runs the probes, their spans are children of the span of ctx
+func InspectAndStoreResult(ctx context.Context, client kubernetes.Interface, probes []probe_command.KubesondeCommand) {
 	// log.Info("Probing...")
 	probestate := new(KubesondeContinuousState)
 	probestate.Client = client
-	probeOutput := InspectWithContinuousMode(probestate, probes)
+	probeOutput := InspectWithContinuousMode(ctx, probestate, probes)
 
 	state.AppendProbes(&probeOutput.Items)
 	state.AppendErrors(&probeOutput.Errors)
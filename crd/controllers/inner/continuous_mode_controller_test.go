@@ -1,12 +1,24 @@
 package inner
 
 import (
+	"context"
+	"errors"
 	"testing"
 
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 	"k8s.io/client-go/kubernetes/fake"
 	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/probe_command"
 	"kubesonde.io/controllers/state"
+	"kubesonde.io/controllers/tracing"
 )
 
 func TestWithDeploymentInformationFast(t *testing.T) {
@@ -59,7 +71,7 @@ func TestWithDeploymentInformationFast(t *testing.T) {
 		ResultingAction: v1.ALLOW,
 	}
 	client := fake.NewSimpleClientset()
-	updated := withDeploymentInformation(client, output)
+	updated := withDeploymentInformation(context.Background(), client, output)
 
 	assert.Equal(t, "SecondPod-replica-pod", updated.Source.Name)
 	assert.Equal(t, "SecondPod", updated.Source.DeploymentName)
@@ -71,3 +83,82 @@ func TestWithDeploymentInformationFast(t *testing.T) {
 	assert.Equal(t, "anotherkey=val2", updated.Source.Labels)
 
 }
+
+func TestRunAndRecordSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+	state.SetProbeState(&v1.ProbeOutput{Items: []v1.ProbeOutputItem{}, Errors: []v1.ProbeOutputError{}, PodNetworkingV2: make(v1.PodNetworkingInfoV2)})
+
+	command := probe_command.KubesondeCommand{
+		SourcePodName:   "web-1",
+		Namespace:       "shop",
+		Destination:     "db-1",
+		DestinationPort: "5432",
+		Protocol:        "UDP",
+		ProbeChecker:    func(output string) bool { return output == "open" },
+	}
+	mode := new(MockedCNIState)
+	mode.On("getClient").Return(fake.NewSimpleClientset())
+	mode.On("runCommand", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { args.Get(3).(func(string) bool)("open") }).
+		Return(true, nil)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+	probe, err := runAndRecord(ctx, mode, command)
+	parent.End()
+	assert.NoError(t, err)
+	assert.Equal(t, v1.ALLOW, probe.ResultingAction)
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	run := spans[tracing.RUN_SPAN]
+	assert.Equal(t, spans["parent"].SpanContext.SpanID(), run.Parent.SpanID())
+	for _, name := range []string{tracing.EXEC_SPAN, tracing.ENRICH_SPAN, tracing.STORE_SPAN} {
+		assert.Equal(t, run.SpanContext.SpanID(), spans[name].Parent.SpanID(), name)
+	}
+	assert.Equal(t, spans[tracing.EXEC_SPAN].SpanContext.SpanID(), spans[tracing.CHECK_SPAN].Parent.SpanID())
+
+	attributes := attribute.NewSet(run.Attributes...)
+	for key, expected := range map[attribute.Key]string{
+		tracing.SOURCE_KEY:      "shop/web-1",
+		tracing.DESTINATION_KEY: "db-1",
+		tracing.PORT_KEY:        "5432",
+		tracing.PROTOCOL_KEY:    "UDP",
+		tracing.VERDICT_KEY:     "Allow",
+	} {
+		value, found := attributes.Value(key)
+		assert.True(t, found, key)
+		assert.Equal(t, expected, value.AsString(), key)
+	}
+	_, found := attributes.Value(tracing.EXEC_LATENCY_KEY)
+	assert.True(t, found)
+	checkAttributes := attribute.NewSet(spans[tracing.CHECK_SPAN].Attributes...)
+	verdict, _ := checkAttributes.Value(tracing.VERDICT_KEY)
+	assert.Equal(t, "Allow", verdict.AsString())
+}
+
+func TestRunAndRecordSpansOfFailedProbes(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+	state.SetProbeState(&v1.ProbeOutput{Items: []v1.ProbeOutputItem{}, Errors: []v1.ProbeOutputError{}, PodNetworkingV2: make(v1.PodNetworkingInfoV2)})
+
+	mode := new(MockedCNIState)
+	mode.On("getClient").Return(fake.NewSimpleClientset())
+	mode.On("runCommand", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(false, errors.New("exec failed"))
+
+	_, err := runAndRecord(context.Background(), mode, probe_command.KubesondeCommand{SourcePodName: "web-1", Namespace: "shop"})
+	assert.EqualError(t, err, "exec failed")
+
+	names := lo.Map(exporter.GetSpans(), func(span tracetest.SpanStub, _ int) string { return span.Name })
+	assert.ElementsMatch(t, []string{tracing.EXEC_SPAN, tracing.STORE_SPAN, tracing.RUN_SPAN}, names)
+	for _, span := range exporter.GetSpans() {
+		if span.Name != tracing.STORE_SPAN {
+			assert.Equal(t, codes.Error, span.Status.Code, span.Name)
+		}
+	}
+}
@@ -81,7 +81,7 @@ var _ = Describe("ContinuousMode", func() {
 		state.On("runCommand", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 			Return(false, errors.New("this is an error"))
 
-		output := InspectWithContinuousMode(state, []probe_command.KubesondeCommand{command})
+		output := InspectWithContinuousMode(context.Background(), state, []probe_command.KubesondeCommand{command})
 
 		Expect(output.Errors).To(BeEquivalentTo(
 			[]v1.ProbeOutputError{
@@ -165,7 +165,7 @@ var _ = Describe("ContinuousMode", func() {
 
 		state.Mock.On("getClient").Return(client)
 
-		output := InspectWithContinuousMode(state, []probe_command.KubesondeCommand{errorCommand, successCommand})
+		output := InspectWithContinuousMode(context.Background(), state, []probe_command.KubesondeCommand{errorCommand, successCommand})
 
 		Expect(output.Errors).To(BeEquivalentTo(
 			[]v1.ProbeOutputError{
@@ -11,6 +11,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/remotecommand"
+	"kubesonde.io/controllers/audit"
 	"kubesonde.io/controllers/probe_command"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 )
@@ -38,8 +39,9 @@ func runGenericCommand(client kubernetes.Interface, namespace string, command pr
 		return err.Error(), err
 	}
 	parameterCodec := runtime.NewParameterCodec(scheme)
+	argv := command.Args()
 	req.VersionedParams(&v1.PodExecOptions{
-		Command:   strings.Fields(command.Command),
+		Command:   argv,
 		Container: command.ContainerName,
 		Stdin:     true,
 		Stdout:    true,
@@ -53,12 +55,14 @@ func runGenericCommand(client kubernetes.Interface, namespace string, command pr
 		return err.Error(), err
 	}
 	var stdout, stderr bytes.Buffer
+	start := time.Now()
 	err = exec.Stream(remotecommand.StreamOptions{
 		Stdin:  os.Stdin,
 		Stdout: &stdout,
 		Stderr: &stderr,
 		Tty:    false,
 	})
+	audit.Append(audit.Command(namespace, command.SourcePodName, command.ContainerName, argv, start, err))
 	if err != nil && !strings.Contains(command.Command, "wget") {
 		log.Info(fmt.Sprintf("Connection from %s to  %s (%s) returned an error code: %s", command.SourcePodName, command.Destination, command.DestinationIPAddress, command.Command))
 		/*log.Info(fmt.Sprintf(`
@@ -95,8 +99,9 @@ func runRemoteCommandWithErrorHandler(client kubernetes.Interface, namespace str
 		return false, err
 	}
 	parameterCodec := runtime.NewParameterCodec(scheme)
+	argv := command.Args()
 	req.VersionedParams(&v1.PodExecOptions{
-		Command:   strings.Fields(command.Command),
+		Command:   argv,
 		Container: command.ContainerName,
 		Stdin:     true,
 		Stdout:    true,
@@ -110,12 +115,14 @@ func runRemoteCommandWithErrorHandler(client kubernetes.Interface, namespace str
 		return false, err
 	}
 	var stdout, stderr bytes.Buffer
+	start := time.Now()
 	err = exec.Stream(remotecommand.StreamOptions{
 		Stdin:  os.Stdin,
 		Stdout: &stdout,
 		Stderr: &stderr,
 		Tty:    false,
 	})
+	audit.Append(audit.Command(namespace, command.SourcePodName, command.ContainerName, argv, start, err))
 	if err != nil {
 		/*log.Info(fmt.Sprintf(`
 		Namespace: %s,
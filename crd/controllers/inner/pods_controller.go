@@ -3,13 +3,13 @@ package inner
 import (
 	"bytes"
 	"os"
-	"strings"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/remotecommand"
+	"kubesonde.io/controllers/audit"
 	"kubesonde.io/controllers/probe_command"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 )
@@ -30,8 +30,9 @@ func runRemoteCommand(client kubernetes.Interface, namespace string, command pro
 		return false
 	}
 	parameterCodec := runtime.NewParameterCodec(scheme)
+	argv := command.Args()
 	req.VersionedParams(&v1.PodExecOptions{
-		Command:   strings.Fields(command.Command),
+		Command:   argv,
 		Container: command.ContainerName,
 		Stdin:     true,
 		Stdout:    true,
@@ -45,12 +46,14 @@ func runRemoteCommand(client kubernetes.Interface, namespace string, command pro
 		return false
 	}
 	var stdout, stderr bytes.Buffer
+	start := time.Now()
 	err = exec.Stream(remotecommand.StreamOptions{
 		Stdin:  os.Stdin,
 		Stdout: &stdout,
 		Stderr: &stderr,
 		Tty:    false,
 	})
+	audit.Append(audit.Command(namespace, command.SourcePodName, command.ContainerName, argv, start, err))
 	if err != nil {
 		/*log.Info(fmt.Sprintf(`
 		Namespace: %s,
@@ -30,4 +30,39 @@ var (
 		},
 		[]string{"from", "to", "label", "exists", "shouldExist"},
 	)
+
+	BaselineDriftSummary = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "kubesonde",
+			Name:      "baseline_drift",
+			Help:      "Number of edges that differ from the baseline, unexpected or missing",
+		},
+		[]string{"namespace", "name", "kind"},
+	)
+
+	WebhookDeliveriesSummary = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kubesonde",
+			Name:      "webhook_deliveries_total",
+			Help:      "Batches of findings posted to the webhooks, by result: delivered or failed",
+		},
+		[]string{"namespace", "name", "webhook", "result"},
+	)
+
+	CloudEventsSummary = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kubesonde",
+			Name:      "cloudevents_sent_total",
+			Help:      "Events sent to the CloudEvents sinks, by type and result: delivered or failed",
+		},
+		[]string{"namespace", "name", "type", "result"},
+	)
+
+	AuditSequenceSummary = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "kubesonde",
+			Name:      "audit_sequence",
+			Help:      "Sequence of the last record of the audit log, the head of its chain",
+		},
+	)
 )
@@ -40,7 +40,7 @@ func rebuildProbesForPod(pod v1.Pod) {
 // This function starts an infinite loop
 func RunMonitorContainers(client kubernetes.Interface) {
 	for {
-		var pods = eventstorage.GetActivePods() /*lo.Filter(GetActivePods(), func(pod v1.Pod, i int) bool {
+		var pods = eventstorage.GetSourcePods() /*lo.Filter(GetActivePods(), func(pod v1.Pod, i int) bool {
 			return PodWithEphemeralContainer(client, pod)
 		})*/
 		var currPodsWithNetstat = state.GetNetstatPods()
@@ -156,7 +156,7 @@ func buildProbesFromMonitorContainer(apiClient kubernetes.Interface, payload typ
 	state.AppendNetInfoV2(podname, &netInfoNotLoopback)
 
 	// Should also execute new probes if the port is not already in the storage
-	currPods := eventstorage.GetActivePods()
+	currPods := eventstorage.GetSourcePods()
 	if len(currPods) <= 1 {
 		return []probe_command.KubesondeCommand{}
 	}
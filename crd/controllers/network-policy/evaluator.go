@@ -0,0 +1,328 @@
+// The networkpolicy module predicts the outcome of a probe by statically
+// evaluating the NetworkPolicy objects of the cluster (v1 semantics).
+package networkpolicy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	kubesondev1 "kubesonde.io/api/v1"
+	"kubesonde.io/pkg/apitypes"
+)
+
+type Direction = apitypes.Direction
+
+const (
+	INGRESS = apitypes.INGRESS
+	EGRESS  = apitypes.EGRESS
+)
+
+// Endpoint is one side of a connection. Pod is nil for endpoints outside the
+// cluster, in which case only IP is used for matching ipBlock peers.
+type Endpoint struct {
+	Pod *v1.Pod
+	IP  string
+}
+
+// RuleReference identifies a single ingress or egress rule of a policy
+type RuleReference = apitypes.RuleReference
+
+// DirectionDecision describes how the policies of one side of the connection
+// affect it
+type DirectionDecision = apitypes.DirectionDecision
+
+// Decision is the predicted outcome of a connection
+type Decision = apitypes.Decision
+
+type Evaluator struct {
+	podsByName map[string]v1.Pod
+	podsByIP   map[string]v1.Pod
+	namespaces map[string]v1.Namespace
+	services   []v1.Service
+	policies   []networkingv1.NetworkPolicy
+}
+
+func podKey(namespace string, name string) string {
+	return fmt.Sprintf("%s/%s", namespace, name)
+}
+
+func NewEvaluator(pods []v1.Pod, namespaces []v1.Namespace, services []v1.Service, policies []networkingv1.NetworkPolicy) *Evaluator {
+	evaluator := &Evaluator{
+		podsByName: make(map[string]v1.Pod, len(pods)),
+		podsByIP:   make(map[string]v1.Pod, len(pods)),
+		namespaces: make(map[string]v1.Namespace, len(namespaces)),
+		services:   services,
+		policies:   policies,
+	}
+	for _, pod := range pods {
+		evaluator.podsByName[podKey(pod.Namespace, pod.Name)] = pod
+		if pod.Status.PodIP != "" && !pod.Spec.HostNetwork {
+			evaluator.podsByIP[pod.Status.PodIP] = pod
+		}
+	}
+	for _, namespace := range namespaces {
+		evaluator.namespaces[namespace.Name] = namespace
+	}
+	return evaluator
+}
+
+// NewEvaluatorFromCluster builds an evaluator from the objects currently stored in the cluster
+func NewEvaluatorFromCluster(client kubernetes.Interface) (*Evaluator, error) {
+	pods, err := client.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	namespaces, err := client.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	services, err := client.CoreV1().Services("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	policies, err := client.NetworkingV1().NetworkPolicies("").List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return NewEvaluator(pods.Items, namespaces.Items, services.Items, policies.Items), nil
+}
+
+func (e *Evaluator) GetPod(namespace string, name string) (v1.Pod, bool) {
+	pod, ok := e.podsByName[podKey(namespace, name)]
+	return pod, ok
+}
+
+func (e *Evaluator) GetPodByIP(ip string) (v1.Pod, bool) {
+	pod, ok := e.podsByIP[ip]
+	return pod, ok
+}
+
+// GetPolicies returns the policies known to the evaluator
+func (e *Evaluator) GetPolicies() []networkingv1.NetworkPolicy {
+	return e.policies
+}
+
+// Evaluate predicts whether a connection from src to dst on the given port and
+// protocol is allowed by the NetworkPolicies of the cluster
+func (e *Evaluator) Evaluate(src Endpoint, dst Endpoint, port int32, protocol string) Decision {
+	if protocol == "" {
+		protocol = string(v1.ProtocolTCP)
+	}
+	protocol = strings.ToUpper(protocol)
+	egress := e.evaluateDirection(EGRESS, src, dst, port, protocol)
+	ingress := e.evaluateDirection(INGRESS, dst, src, port, protocol)
+	action := kubesondev1.DENY
+	if egress.Allowed && ingress.Allowed {
+		action = kubesondev1.ALLOW
+	}
+	return Decision{
+		Action:  action,
+		Egress:  egress,
+		Ingress: ingress,
+	}
+}
+
+// evaluateDirection evaluates the policies selecting `subject` against `peer`.
+// For egress the subject is the source, for ingress it is the destination.
+func (e *Evaluator) evaluateDirection(direction Direction, subject Endpoint, peer Endpoint, port int32, protocol string) DirectionDecision {
+	decision := DirectionDecision{Allowed: true}
+	if subject.Pod == nil || subject.Pod.Spec.HostNetwork {
+		// Policies do not apply to external endpoints or host network pods
+		return decision
+	}
+	// The destination pod is the one exposing named ports
+	destination := peer.Pod
+	if direction == INGRESS {
+		destination = subject.Pod
+	}
+	for _, policy := range e.selectingPolicies(direction, *subject.Pod) {
+		decision.Policies = append(decision.Policies, podKey(policy.Namespace, policy.Name))
+		decision.Isolated = true
+		for index, rule := range policyRules(direction, policy) {
+			if e.rulePeersMatch(rule.peers, policy.Namespace, peer) && portsMatch(rule.ports, destination, port, protocol) {
+				decision.MatchedRules = append(decision.MatchedRules, RuleReference{
+					Policy:    policy.Name,
+					Namespace: policy.Namespace,
+					Direction: direction,
+					Index:     index,
+				})
+			}
+		}
+	}
+	decision.Allowed = !decision.Isolated || len(decision.MatchedRules) > 0
+	return decision
+}
+
+func (e *Evaluator) selectingPolicies(direction Direction, pod v1.Pod) []networkingv1.NetworkPolicy {
+	return lo.Filter(e.policies, func(policy networkingv1.NetworkPolicy, _ int) bool {
+		if policy.Namespace != pod.Namespace || !hasPolicyType(policy, direction) {
+			return false
+		}
+		return selectorMatches(&policy.Spec.PodSelector, pod.Labels)
+	})
+}
+
+// hasPolicyType applies the defaulting rules of the API server: Ingress is
+// always set, Egress only when egress rules exist
+func hasPolicyType(policy networkingv1.NetworkPolicy, direction Direction) bool {
+	if len(policy.Spec.PolicyTypes) == 0 {
+		if direction == INGRESS {
+			return true
+		}
+		return len(policy.Spec.Egress) > 0
+	}
+	return lo.Contains(policy.Spec.PolicyTypes, networkingv1.PolicyType(direction))
+}
+
+type rule struct {
+	peers []networkingv1.NetworkPolicyPeer
+	ports []networkingv1.NetworkPolicyPort
+}
+
+func policyRules(direction Direction, policy networkingv1.NetworkPolicy) []rule {
+	if direction == INGRESS {
+		return lo.Map(policy.Spec.Ingress, func(r networkingv1.NetworkPolicyIngressRule, _ int) rule {
+			return rule{peers: r.From, ports: r.Ports}
+		})
+	}
+	return lo.Map(policy.Spec.Egress, func(r networkingv1.NetworkPolicyEgressRule, _ int) rule {
+		return rule{peers: r.To, ports: r.Ports}
+	})
+}
+
+func (e *Evaluator) rulePeersMatch(peers []networkingv1.NetworkPolicyPeer, policyNamespace string, peer Endpoint) bool {
+	if len(peers) == 0 {
+		return true
+	}
+	return lo.SomeBy(peers, func(p networkingv1.NetworkPolicyPeer) bool {
+		return e.peerMatches(p, policyNamespace, peer)
+	})
+}
+
+func (e *Evaluator) peerMatches(peer networkingv1.NetworkPolicyPeer, policyNamespace string, endpoint Endpoint) bool {
+	if peer.IPBlock != nil {
+		return ipBlockMatches(*peer.IPBlock, endpoint.IP)
+	}
+	if endpoint.Pod == nil {
+		return false
+	}
+	if peer.NamespaceSelector == nil {
+		if endpoint.Pod.Namespace != policyNamespace {
+			return false
+		}
+	} else if !selectorMatches(peer.NamespaceSelector, e.namespaceLabels(endpoint.Pod.Namespace)) {
+		return false
+	}
+	if peer.PodSelector == nil {
+		return true
+	}
+	return selectorMatches(peer.PodSelector, endpoint.Pod.Labels)
+}
+
+func (e *Evaluator) namespaceLabels(name string) map[string]string {
+	result := map[string]string{}
+	if namespace, ok := e.namespaces[name]; ok {
+		for k, v := range namespace.Labels {
+			result[k] = v
+		}
+	}
+	// Set by the API server on every namespace
+	result["kubernetes.io/metadata.name"] = name
+	return result
+}
+
+func selectorMatches(selector *metav1.LabelSelector, target map[string]string) bool {
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		log.Info(fmt.Sprintf("Invalid label selector %v: %s", selector, err.Error()))
+		return false
+	}
+	return s.Matches(labels.Set(target))
+}
+
+func ipBlockMatches(block networkingv1.IPBlock, ip string) bool {
+	address := net.ParseIP(ip)
+	if address == nil {
+		return false
+	}
+	_, cidr, err := net.ParseCIDR(block.CIDR)
+	if err != nil || !cidr.Contains(address) {
+		return false
+	}
+	for _, except := range block.Except {
+		_, exceptCidr, err := net.ParseCIDR(except)
+		if err == nil && exceptCidr.Contains(address) {
+			return false
+		}
+	}
+	return true
+}
+
+func portsMatch(ports []networkingv1.NetworkPolicyPort, destination *v1.Pod, port int32, protocol string) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	return lo.SomeBy(ports, func(p networkingv1.NetworkPolicyPort) bool {
+		return portMatches(p, destination, port, protocol)
+	})
+}
+
+func portMatches(policyPort networkingv1.NetworkPolicyPort, destination *v1.Pod, port int32, protocol string) bool {
+	policyProtocol := string(v1.ProtocolTCP)
+	if policyPort.Protocol != nil {
+		policyProtocol = string(*policyPort.Protocol)
+	}
+	if policyProtocol != protocol {
+		return false
+	}
+	if policyPort.Port == nil {
+		return true
+	}
+	if policyPort.Port.Type == intstr.String {
+		return namedPortMatches(policyPort.Port.StrVal, destination, port, protocol)
+	}
+	start := policyPort.Port.IntVal
+	end := start
+	if policyPort.EndPort != nil {
+		end = *policyPort.EndPort
+	}
+	return port >= start && port <= end
+}
+
+func namedPortMatches(name string, destination *v1.Pod, port int32, protocol string) bool {
+	if destination == nil {
+		return false
+	}
+	containers := append(append([]v1.Container{}, destination.Spec.InitContainers...), destination.Spec.Containers...)
+	for _, container := range containers {
+		for _, containerPort := range container.Ports {
+			containerProtocol := string(containerPort.Protocol)
+			if containerProtocol == "" {
+				containerProtocol = string(v1.ProtocolTCP)
+			}
+			if containerPort.Name == name && containerPort.ContainerPort == port && containerProtocol == protocol {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// EvaluatorProvider builds an evaluator on demand so that callers always see
+// the current policies
+type EvaluatorProvider func() (*Evaluator, error)
+
+func ClusterEvaluatorProvider(client kubernetes.Interface) EvaluatorProvider {
+	return func() (*Evaluator, error) {
+		return NewEvaluatorFromCluster(client)
+	}
+}
@@ -0,0 +1,185 @@
+package networkpolicy
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	kubesondev1 "kubesonde.io/api/v1"
+)
+
+var tcp = v1.ProtocolTCP
+var udp = v1.ProtocolUDP
+
+func buildPod(name string, namespace string, ip string, labels map[string]string, ports ...v1.ContainerPort) v1.Pod {
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{Name: "main", Ports: ports}},
+		},
+		Status: v1.PodStatus{PodIP: ip},
+	}
+}
+
+func buildPolicy(name string, namespace string, selector map[string]string, spec networkingv1.NetworkPolicySpec) networkingv1.NetworkPolicy {
+	spec.PodSelector = metav1.LabelSelector{MatchLabels: selector}
+	return networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       spec,
+	}
+}
+
+func intPort(port int) *intstr.IntOrString {
+	value := intstr.FromInt32(int32(port))
+	return &value
+}
+
+func namedPort(name string) *intstr.IntOrString {
+	value := intstr.FromString(name)
+	return &value
+}
+
+var (
+	frontend = buildPod("frontend", "default", "10.0.0.1", map[string]string{"app": "frontend"})
+	backend  = buildPod("backend", "default", "10.0.0.2", map[string]string{"app": "backend"},
+		v1.ContainerPort{Name: "http", ContainerPort: 8080})
+	monitoring = buildPod("prometheus", "monitoring", "10.0.1.1", map[string]string{"app": "prometheus"})
+	namespaces = []v1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "monitoring", Labels: map[string]string{"team": "observability"}}},
+	}
+	denyAllIngress = buildPolicy("deny-all", "default", map[string]string{}, networkingv1.NetworkPolicySpec{})
+	denyAllEgress  = buildPolicy("deny-egress", "default", map[string]string{}, networkingv1.NetworkPolicySpec{
+		PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+	})
+	allowFrontend = buildPolicy("allow-frontend", "default", map[string]string{"app": "backend"}, networkingv1.NetworkPolicySpec{
+		Ingress: []networkingv1.NetworkPolicyIngressRule{{
+			From:  []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "frontend"}}}},
+			Ports: []networkingv1.NetworkPolicyPort{{Port: intPort(8080)}},
+		}},
+	})
+	allowNamedPort = buildPolicy("allow-named", "default", map[string]string{"app": "backend"}, networkingv1.NetworkPolicySpec{
+		Ingress: []networkingv1.NetworkPolicyIngressRule{{
+			Ports: []networkingv1.NetworkPolicyPort{{Port: namedPort("http")}},
+		}},
+	})
+	// An int port whose StrVal was left over, e.g. by a conversion, is not named
+	allowLeftoverStrVal = buildPolicy("allow-leftover", "default", map[string]string{"app": "backend"}, networkingv1.NetworkPolicySpec{
+		Ingress: []networkingv1.NetworkPolicyIngressRule{{
+			Ports: []networkingv1.NetworkPolicyPort{{Port: &intstr.IntOrString{Type: intstr.Int, IntVal: 9090, StrVal: "http"}}},
+		}},
+	})
+	allowPortRange = buildPolicy("allow-range", "default", map[string]string{"app": "backend"}, networkingv1.NetworkPolicySpec{
+		Ingress: []networkingv1.NetworkPolicyIngressRule{{
+			Ports: []networkingv1.NetworkPolicyPort{{Protocol: &udp, Port: intPort(9000), EndPort: func() *int32 { p := int32(9100); return &p }()}},
+		}},
+	})
+	allowMonitoringNamespace = buildPolicy("allow-monitoring", "default", map[string]string{"app": "backend"}, networkingv1.NetworkPolicySpec{
+		Ingress: []networkingv1.NetworkPolicyIngressRule{{
+			From: []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "observability"}}}},
+		}},
+	})
+	allowInternetEgress = buildPolicy("allow-internet", "default", map[string]string{"app": "frontend"}, networkingv1.NetworkPolicySpec{
+		PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeEgress},
+		Egress: []networkingv1.NetworkPolicyEgressRule{{
+			To:    []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "0.0.0.0/0", Except: []string{"10.0.0.0/8"}}}},
+			Ports: []networkingv1.NetworkPolicyPort{{Protocol: &tcp, Port: intPort(443)}},
+		}},
+	})
+)
+
+func endpointOf(pod v1.Pod) Endpoint {
+	return Endpoint{Pod: &pod, IP: pod.Status.PodIP}
+}
+
+var _ = Describe("Evaluate", func() {
+	pods := []v1.Pod{frontend, backend, monitoring}
+
+	DescribeTable("predicts the connectivity",
+		func(policies []networkingv1.NetworkPolicy, src Endpoint, dst Endpoint, port int32, protocol string, expected kubesondev1.ActionType) {
+			evaluator := NewEvaluator(pods, namespaces, nil, policies)
+			Expect(evaluator.Evaluate(src, dst, port, protocol).Action).To(Equal(expected))
+		},
+		Entry("no policies", nil, endpointOf(frontend), endpointOf(backend), int32(8080), "TCP", kubesondev1.ALLOW),
+		Entry("default deny ingress", []networkingv1.NetworkPolicy{denyAllIngress}, endpointOf(frontend), endpointOf(backend), int32(8080), "TCP", kubesondev1.DENY),
+		Entry("default deny egress", []networkingv1.NetworkPolicy{denyAllEgress}, endpointOf(frontend), endpointOf(backend), int32(8080), "TCP", kubesondev1.DENY),
+		Entry("pod selector allows", []networkingv1.NetworkPolicy{denyAllIngress, allowFrontend}, endpointOf(frontend), endpointOf(backend), int32(8080), "TCP", kubesondev1.ALLOW),
+		Entry("pod selector on another port", []networkingv1.NetworkPolicy{allowFrontend}, endpointOf(frontend), endpointOf(backend), int32(9090), "TCP", kubesondev1.DENY),
+		Entry("pod selector on another protocol", []networkingv1.NetworkPolicy{allowFrontend}, endpointOf(frontend), endpointOf(backend), int32(8080), "UDP", kubesondev1.DENY),
+		Entry("pod selector does not cross namespaces", []networkingv1.NetworkPolicy{allowFrontend}, endpointOf(monitoring), endpointOf(backend), int32(8080), "TCP", kubesondev1.DENY),
+		Entry("named port", []networkingv1.NetworkPolicy{allowNamedPort}, endpointOf(frontend), endpointOf(backend), int32(8080), "TCP", kubesondev1.ALLOW),
+		Entry("named port does not match other ports", []networkingv1.NetworkPolicy{allowNamedPort}, endpointOf(frontend), endpointOf(backend), int32(8081), "TCP", kubesondev1.DENY),
+		Entry("int port with a leftover name", []networkingv1.NetworkPolicy{allowLeftoverStrVal}, endpointOf(frontend), endpointOf(backend), int32(9090), "TCP", kubesondev1.ALLOW),
+		Entry("int port with a leftover name does not match the named port", []networkingv1.NetworkPolicy{allowLeftoverStrVal}, endpointOf(frontend), endpointOf(backend), int32(8080), "TCP", kubesondev1.DENY),
+		Entry("end port inside range", []networkingv1.NetworkPolicy{allowPortRange}, endpointOf(frontend), endpointOf(backend), int32(9050), "UDP", kubesondev1.ALLOW),
+		Entry("end port outside range", []networkingv1.NetworkPolicy{allowPortRange}, endpointOf(frontend), endpointOf(backend), int32(9101), "UDP", kubesondev1.DENY),
+		Entry("namespace selector", []networkingv1.NetworkPolicy{allowMonitoringNamespace}, endpointOf(monitoring), endpointOf(backend), int32(8080), "TCP", kubesondev1.ALLOW),
+		Entry("namespace selector excludes other namespaces", []networkingv1.NetworkPolicy{allowMonitoringNamespace}, endpointOf(frontend), endpointOf(backend), int32(8080), "TCP", kubesondev1.DENY),
+		Entry("ip block allows internet", []networkingv1.NetworkPolicy{allowInternetEgress}, endpointOf(frontend), Endpoint{IP: "8.8.8.8"}, int32(443), "TCP", kubesondev1.ALLOW),
+		Entry("ip block except", []networkingv1.NetworkPolicy{allowInternetEgress}, endpointOf(frontend), endpointOf(backend), int32(443), "TCP", kubesondev1.DENY),
+		Entry("ip block with hostname destination", []networkingv1.NetworkPolicy{allowInternetEgress}, endpointOf(frontend), Endpoint{IP: "google.com"}, int32(443), "TCP", kubesondev1.DENY),
+	)
+
+	It("Reports the policies and rules involved", func() {
+		evaluator := NewEvaluator(pods, namespaces, nil, []networkingv1.NetworkPolicy{denyAllIngress, allowFrontend})
+		decision := evaluator.Evaluate(endpointOf(frontend), endpointOf(backend), 8080, "TCP")
+		Expect(decision.Egress.Isolated).To(BeFalse())
+		Expect(decision.Ingress.Isolated).To(BeTrue())
+		Expect(decision.Ingress.Policies).To(ConsistOf("default/deny-all", "default/allow-frontend"))
+		Expect(decision.Ingress.MatchedRules).To(Equal([]RuleReference{
+			{Policy: "allow-frontend", Namespace: "default", Direction: INGRESS, Index: 0},
+		}))
+	})
+})
+
+var _ = Describe("FindMismatches", func() {
+	service := v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "backend", Namespace: "default"},
+		Spec: v1.ServiceSpec{
+			ClusterIP: "10.96.0.10",
+			Selector:  map[string]string{"app": "backend"},
+			Ports:     []v1.ServicePort{{Port: 80, TargetPort: intstr.FromString("http"), Protocol: v1.ProtocolTCP}},
+		},
+	}
+	probe := func(dst kubesondev1.ProbeEndpointInfo, port string, result kubesondev1.ActionType) kubesondev1.ProbeOutputItem {
+		return kubesondev1.ProbeOutputItem{
+			Type:            kubesondev1.PROBE,
+			Source:          kubesondev1.ProbeEndpointInfo{Type: kubesondev1.POD, Name: "frontend", Namespace: "default"},
+			Destination:     dst,
+			Port:            port,
+			Protocol:        "TCP",
+			ResultingAction: result,
+		}
+	}
+	backendInfo := kubesondev1.ProbeEndpointInfo{Type: kubesondev1.POD, Name: "backend", Namespace: "default"}
+	serviceInfo := kubesondev1.ProbeEndpointInfo{Type: kubesondev1.SERVICE, Name: "backend", Namespace: "default", IPAddress: "10.96.0.10"}
+
+	It("Flags probes whose outcome differs from the prediction", func() {
+		evaluator := NewEvaluator([]v1.Pod{frontend, backend}, namespaces, []v1.Service{service}, []networkingv1.NetworkPolicy{denyAllIngress})
+		items := []kubesondev1.ProbeOutputItem{
+			probe(backendInfo, "8080", kubesondev1.ALLOW),
+			probe(backendInfo, "9090", kubesondev1.DENY),
+			{Type: kubesondev1.INFO, ResultingAction: kubesondev1.ALLOW},
+		}
+		mismatches := evaluator.FindMismatches(items)
+		Expect(mismatches).To(HaveLen(1))
+		Expect(mismatches[0].Item.Port).To(Equal("8080"))
+		Expect(mismatches[0].PredictedAction).To(Equal(kubesondev1.DENY))
+	})
+
+	It("Resolves services to their backends", func() {
+		evaluator := NewEvaluator([]v1.Pod{frontend, backend}, namespaces, []v1.Service{service}, []networkingv1.NetworkPolicy{allowNamedPort})
+		decision, err := evaluator.EvaluateItem(probe(serviceInfo, "80", kubesondev1.ALLOW))
+		Expect(err).To(BeNil())
+		Expect(decision.Action).To(Equal(kubesondev1.ALLOW))
+		Expect(evaluator.FindMismatches([]kubesondev1.ProbeOutputItem{probe(serviceInfo, "80", kubesondev1.DENY)})).To(HaveLen(1))
+	})
+
+	It("Skips probes that cannot be evaluated", func() {
+		evaluator := NewEvaluator([]v1.Pod{frontend, backend}, namespaces, nil, nil)
+		Expect(evaluator.FindMismatches([]kubesondev1.ProbeOutputItem{probe(serviceInfo, "80", kubesondev1.DENY)})).To(BeEmpty())
+		Expect(evaluator.FindMismatches([]kubesondev1.ProbeOutputItem{probe(backendInfo, "abc", kubesondev1.DENY)})).To(BeEmpty())
+	})
+})
@@ -0,0 +1,91 @@
+package networkpolicy
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"kubesonde.io/pkg/apitypes"
+)
+
+// DirectionExplanation is a DirectionDecision with a human readable summary
+type DirectionExplanation = apitypes.DirectionExplanation
+
+// Explanation describes which policies and rules allow or block a connection
+type Explanation = apitypes.Explanation
+
+// ResolveTarget finds the endpoint referenced by `namespace/name`, by a pod
+// name or by an IP address. IP addresses not belonging to a pod are treated as
+// external endpoints.
+func (e *Evaluator) ResolveTarget(target string) (Endpoint, error) {
+	if namespace, name, found := strings.Cut(target, "/"); found {
+		if pod, ok := e.GetPod(namespace, name); ok {
+			return Endpoint{Pod: &pod, IP: pod.Status.PodIP}, nil
+		}
+		return Endpoint{}, fmt.Errorf("pod %s not found", target)
+	}
+	if net.ParseIP(target) != nil {
+		if pod, ok := e.GetPodByIP(target); ok {
+			return Endpoint{Pod: &pod, IP: pod.Status.PodIP}, nil
+		}
+		return Endpoint{IP: target}, nil
+	}
+	matches := lo.Filter(lo.Values(e.podsByName), func(pod v1.Pod, _ int) bool {
+		return pod.Name == target
+	})
+	if len(matches) == 0 {
+		return Endpoint{}, fmt.Errorf("pod %s not found", target)
+	}
+	if len(matches) > 1 {
+		return Endpoint{}, fmt.Errorf("pod name %s is ambiguous, use namespace/name", target)
+	}
+	return Endpoint{Pod: &matches[0], IP: matches[0].Status.PodIP}, nil
+}
+
+// Explain evaluates a connection and describes the policies involved
+func (e *Evaluator) Explain(src Endpoint, dst Endpoint, port int32, protocol string) Explanation {
+	decision := e.Evaluate(src, dst, port, protocol)
+	return Explanation{
+		Source:      endpointName(src),
+		Destination: endpointName(dst),
+		Port:        port,
+		Protocol:    strings.ToUpper(lo.Ternary(protocol == "", "TCP", protocol)),
+		Action:      decision.Action,
+		Egress:      explainDirection(EGRESS, src, decision.Egress),
+		Ingress:     explainDirection(INGRESS, dst, decision.Ingress),
+	}
+}
+
+func endpointName(endpoint Endpoint) string {
+	if endpoint.Pod == nil {
+		return endpoint.IP
+	}
+	return podKey(endpoint.Pod.Namespace, endpoint.Pod.Name)
+}
+
+func explainDirection(direction Direction, subject Endpoint, decision DirectionDecision) DirectionExplanation {
+	var summary string
+	switch {
+	case subject.Pod == nil:
+		summary = fmt.Sprintf("%s is outside the cluster, no %s policy applies", endpointName(subject), direction)
+	case subject.Pod.Spec.HostNetwork:
+		summary = fmt.Sprintf("%s uses the host network, no %s policy applies", endpointName(subject), direction)
+	case !decision.Isolated:
+		summary = fmt.Sprintf("No %s policy selects %s: allowed", direction, endpointName(subject))
+	case len(decision.MatchedRules) == 0:
+		summary = fmt.Sprintf("%s is isolated for %s by %s and no rule matches: denied by default-deny",
+			endpointName(subject), direction, strings.Join(decision.Policies, ", "))
+	default:
+		rules := lo.Map(decision.MatchedRules, func(r RuleReference, _ int) string {
+			return fmt.Sprintf("rule #%d of %s", r.Index, podKey(r.Namespace, r.Policy))
+		})
+		summary = fmt.Sprintf("%s is isolated for %s by %s: allowed by %s",
+			endpointName(subject), direction, strings.Join(decision.Policies, ", "), strings.Join(rules, ", "))
+	}
+	return DirectionExplanation{
+		DirectionDecision: decision,
+		Summary:           summary,
+	}
+}
@@ -0,0 +1,63 @@
+package networkpolicy
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	kubesondev1 "kubesonde.io/api/v1"
+)
+
+var _ = Describe("ResolveTarget", func() {
+	evaluator := NewEvaluator([]v1.Pod{frontend, backend, monitoring}, namespaces, nil, nil)
+
+	It("Resolves namespace/name", func() {
+		endpoint, err := evaluator.ResolveTarget("monitoring/prometheus")
+		Expect(err).To(BeNil())
+		Expect(endpoint.Pod.Name).To(Equal("prometheus"))
+	})
+	It("Resolves pod names", func() {
+		endpoint, err := evaluator.ResolveTarget("backend")
+		Expect(err).To(BeNil())
+		Expect(endpoint.IP).To(Equal("10.0.0.2"))
+	})
+	It("Resolves pod IPs", func() {
+		endpoint, err := evaluator.ResolveTarget("10.0.0.1")
+		Expect(err).To(BeNil())
+		Expect(endpoint.Pod.Name).To(Equal("frontend"))
+	})
+	It("Treats unknown IPs as external", func() {
+		endpoint, err := evaluator.ResolveTarget("8.8.8.8")
+		Expect(err).To(BeNil())
+		Expect(endpoint.Pod).To(BeNil())
+	})
+	It("Fails on unknown pods", func() {
+		_, err := evaluator.ResolveTarget("default/unknown")
+		Expect(err).NotTo(BeNil())
+		_, err = evaluator.ResolveTarget("unknown")
+		Expect(err).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("Explain", func() {
+	It("Explains default-deny", func() {
+		evaluator := NewEvaluator([]v1.Pod{frontend, backend}, namespaces, nil, []networkingv1.NetworkPolicy{denyAllIngress})
+		explanation := evaluator.Explain(endpointOf(frontend), endpointOf(backend), 8080, "tcp")
+		Expect(explanation.Action).To(Equal(kubesondev1.DENY))
+		Expect(explanation.Protocol).To(Equal("TCP"))
+		Expect(explanation.Egress.Summary).To(Equal("No Egress policy selects default/frontend: allowed"))
+		Expect(explanation.Ingress.Summary).To(Equal("default/backend is isolated for Ingress by default/deny-all and no rule matches: denied by default-deny"))
+	})
+	It("Explains the matching rule", func() {
+		evaluator := NewEvaluator([]v1.Pod{frontend, backend}, namespaces, nil, []networkingv1.NetworkPolicy{denyAllIngress, allowFrontend})
+		explanation := evaluator.Explain(endpointOf(frontend), endpointOf(backend), 8080, "")
+		Expect(explanation.Action).To(Equal(kubesondev1.ALLOW))
+		Expect(explanation.Ingress.Summary).To(Equal("default/backend is isolated for Ingress by default/deny-all, default/allow-frontend: allowed by rule #0 of default/allow-frontend"))
+	})
+	It("Explains external endpoints", func() {
+		evaluator := NewEvaluator([]v1.Pod{frontend}, namespaces, nil, nil)
+		explanation := evaluator.Explain(endpointOf(frontend), Endpoint{IP: "8.8.8.8"}, 53, "UDP")
+		Expect(explanation.Destination).To(Equal("8.8.8.8"))
+		Expect(explanation.Ingress.Summary).To(Equal("8.8.8.8 is outside the cluster, no Ingress policy applies"))
+	})
+})
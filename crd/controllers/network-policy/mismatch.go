@@ -0,0 +1,139 @@
+package networkpolicy
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/samber/lo"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	kubesondev1 "kubesonde.io/api/v1"
+	"kubesonde.io/pkg/apitypes"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = logf.Log.WithName("controllers.networkpolicy")
+
+// Mismatch is an observed probe whose outcome differs from the outcome
+// predicted by the NetworkPolicies. Mismatches usually indicate CNI bugs or
+// features not supported by the CNI.
+type Mismatch = apitypes.Mismatch
+
+// ResolveEndpoint maps an endpoint of a probe output to the pod it refers to, if known
+func (e *Evaluator) ResolveEndpoint(info kubesondev1.ProbeEndpointInfo) Endpoint {
+	if info.Type != kubesondev1.INTERNET {
+		if pod, ok := e.GetPod(info.Namespace, info.Name); ok {
+			return Endpoint{Pod: &pod, IP: pod.Status.PodIP}
+		}
+		if pod, ok := e.GetPodByIP(info.IPAddress); ok {
+			return Endpoint{Pod: &pod, IP: pod.Status.PodIP}
+		}
+	}
+	return Endpoint{IP: info.IPAddress}
+}
+
+// EvaluateItem predicts the outcome of a probe. Probes towards a service are
+// evaluated against the backend pods of the service: the probe is allowed when
+// at least one backend is reachable.
+func (e *Evaluator) EvaluateItem(item kubesondev1.ProbeOutputItem) (Decision, error) {
+	port, err := strconv.ParseInt(item.Port, 10, 32)
+	if err != nil {
+		return Decision{}, fmt.Errorf("invalid port %q", item.Port)
+	}
+	src := e.ResolveEndpoint(item.Source)
+	if item.Destination.Type != kubesondev1.SERVICE {
+		return e.Evaluate(src, e.ResolveEndpoint(item.Destination), int32(port), item.Protocol), nil
+	}
+	service, ok := lo.Find(e.services, func(s v1.Service) bool {
+		return s.Spec.ClusterIP == item.Destination.IPAddress && s.Spec.ClusterIP != ""
+	})
+	if !ok {
+		return Decision{}, fmt.Errorf("unknown service %s", item.Destination.IPAddress)
+	}
+	var decision Decision
+	backends := e.serviceBackends(service)
+	if len(backends) == 0 {
+		return Decision{}, fmt.Errorf("service %s has no backend pods", service.Name)
+	}
+	for i := range backends {
+		targetPort, found := serviceTargetPort(service, backends[i], int32(port), item.Protocol)
+		if !found {
+			continue
+		}
+		decision = e.Evaluate(src, Endpoint{Pod: &backends[i], IP: backends[i].Status.PodIP}, targetPort, item.Protocol)
+		if decision.Action == kubesondev1.ALLOW {
+			return decision, nil
+		}
+	}
+	if decision.Action == "" {
+		return Decision{}, fmt.Errorf("service %s does not expose port %s", service.Name, item.Port)
+	}
+	return decision, nil
+}
+
+func (e *Evaluator) serviceBackends(service v1.Service) []v1.Pod {
+	if len(service.Spec.Selector) == 0 {
+		return []v1.Pod{}
+	}
+	return lo.Filter(lo.Values(e.podsByName), func(pod v1.Pod, _ int) bool {
+		if pod.Namespace != service.Namespace {
+			return false
+		}
+		for k, v := range service.Spec.Selector {
+			if pod.Labels[k] != v {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func serviceTargetPort(service v1.Service, backend v1.Pod, port int32, protocol string) (int32, bool) {
+	for _, servicePort := range service.Spec.Ports {
+		servicePortProtocol := string(servicePort.Protocol)
+		if servicePortProtocol == "" {
+			servicePortProtocol = string(v1.ProtocolTCP)
+		}
+		if servicePort.Port != port || (protocol != "" && servicePortProtocol != protocol) {
+			continue
+		}
+		if servicePort.TargetPort.Type != intstr.String {
+			if servicePort.TargetPort.IntVal == 0 {
+				return servicePort.Port, true
+			}
+			return servicePort.TargetPort.IntVal, true
+		}
+		for _, container := range backend.Spec.Containers {
+			for _, containerPort := range container.Ports {
+				if containerPort.Name == servicePort.TargetPort.StrVal {
+					return containerPort.ContainerPort, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// FindMismatches compares the predicted outcome with each observed probe.
+// Information items and probes that cannot be evaluated are skipped.
+func (e *Evaluator) FindMismatches(items []kubesondev1.ProbeOutputItem) []Mismatch {
+	mismatches := []Mismatch{}
+	for _, item := range items {
+		if item.Type != kubesondev1.PROBE {
+			continue
+		}
+		decision, err := e.EvaluateItem(item)
+		if err != nil {
+			log.V(1).Info(fmt.Sprintf("Skipping probe from %s to %s: %s", item.Source.Name, item.Destination.Name, err.Error()))
+			continue
+		}
+		if decision.Action != item.ResultingAction {
+			mismatches = append(mismatches, Mismatch{
+				Item:            item,
+				PredictedAction: decision.Action,
+				Decision:        decision,
+			})
+		}
+	}
+	return mismatches
+}
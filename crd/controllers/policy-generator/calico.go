@@ -0,0 +1,179 @@
+package policygenerator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "kubesonde.io/api/v1"
+)
+
+// The following types mirror the subset of the projectcalico.org/v3 API used
+// by the exporter. They are defined here to avoid depending on the Calico modules.
+
+type CalicoNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              CalicoPolicySpec `json:"spec"`
+}
+
+type CalicoGlobalNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              CalicoPolicySpec `json:"spec"`
+}
+
+type CalicoPolicySpec struct {
+	Order             *float64     `json:"order,omitempty"`
+	Selector          string       `json:"selector"`
+	NamespaceSelector string       `json:"namespaceSelector,omitempty"`
+	Types             []string     `json:"types"`
+	Ingress           []CalicoRule `json:"ingress,omitempty"`
+	Egress            []CalicoRule `json:"egress,omitempty"`
+}
+
+type CalicoRule struct {
+	Action      string           `json:"action"`
+	Protocol    string           `json:"protocol,omitempty"`
+	Source      CalicoEntityRule `json:"source,omitempty"`
+	Destination CalicoEntityRule `json:"destination,omitempty"`
+}
+
+type CalicoEntityRule struct {
+	Selector          string   `json:"selector,omitempty"`
+	NamespaceSelector string   `json:"namespaceSelector,omitempty"`
+	Nets              []string `json:"nets,omitempty"`
+	// Domains are only supported by Calico Enterprise and Calico Cloud
+	Domains []string `json:"domains,omitempty"`
+	Ports   []int32  `json:"ports,omitempty"`
+}
+
+// calicoSelector converts labels to a Calico selector expression
+func calicoSelector(labels map[string]string) string {
+	keys := lo.Keys(labels)
+	sort.Strings(keys)
+	return strings.Join(lo.Map(keys, func(key string, _ int) string {
+		return fmt.Sprintf("%s == '%s'", key, labels[key])
+	}), " && ")
+}
+
+func calicoNamespaceSelector(namespace string) string {
+	return calicoSelector(map[string]string{NAMESPACE_LABEL: namespace})
+}
+
+// calicoRules builds one rule per protocol, Calico rules only match a single protocol
+func calicoRules(flows []flow, entity func(ports []int32) (CalicoEntityRule, CalicoEntityRule)) []CalicoRule {
+	byProtocol := lo.GroupBy(flows, func(f flow) string { return string(f.protocol) })
+	protocols := lo.Keys(byProtocol)
+	sort.Strings(protocols)
+	return lo.Map(protocols, func(protocol string, _ int) CalicoRule {
+		ports := lo.Uniq(lo.Map(byProtocol[protocol], func(f flow, _ int) int32 { return f.port }))
+		sort.Slice(ports, func(i, j int) bool { return ports[i] < ports[j] })
+		source, destination := entity(ports)
+		return CalicoRule{
+			Action:      "Allow",
+			Protocol:    protocol,
+			Source:      source,
+			Destination: destination,
+		}
+	})
+}
+
+func calicoDNSRules(flows []flow) []CalicoRule {
+	return calicoRules(flows, func(ports []int32) (CalicoEntityRule, CalicoEntityRule) {
+		return CalicoEntityRule{}, CalicoEntityRule{
+			Selector:          "k8s-app == 'kube-dns'",
+			NamespaceSelector: calicoNamespaceSelector("kube-system"),
+			Ports:             ports,
+		}
+	})
+}
+
+func buildCalicoPolicy(workload *Workload, m model) CalicoNetworkPolicy {
+	policy := CalicoNetworkPolicy{
+		TypeMeta: metav1.TypeMeta{APIVersion: "projectcalico.org/v3", Kind: "NetworkPolicy"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      policyName(workload.Name),
+			Namespace: workload.Namespace,
+			Labels:    map[string]string{MANAGED_BY_LABEL: MANAGED_BY_VALUE},
+		},
+		Spec: CalicoPolicySpec{
+			Selector: calicoSelector(workload.Labels),
+			Types:    []string{"Ingress", "Egress"},
+			Ingress:  []CalicoRule{},
+			Egress:   []CalicoRule{},
+		},
+	}
+
+	ingress := m.ingressFlows(workload)
+	for _, source := range sortedKeys(ingress) {
+		peer := m.workloads[source]
+		policy.Spec.Ingress = append(policy.Spec.Ingress, calicoRules(ingress[source], func(ports []int32) (CalicoEntityRule, CalicoEntityRule) {
+			return CalicoEntityRule{
+				Selector:          calicoSelector(peer.Labels),
+				NamespaceSelector: calicoNamespaceSelector(peer.Namespace),
+			}, CalicoEntityRule{Ports: ports}
+		})...)
+	}
+
+	dns, egress, external := m.egressFlows(workload)
+	policy.Spec.Egress = append(policy.Spec.Egress, calicoDNSRules(dns)...)
+	for _, destination := range sortedKeys(egress) {
+		peer := m.workloads[destination]
+		policy.Spec.Egress = append(policy.Spec.Egress, calicoRules(egress[destination], func(ports []int32) (CalicoEntityRule, CalicoEntityRule) {
+			return CalicoEntityRule{}, CalicoEntityRule{
+				Selector:          calicoSelector(peer.Labels),
+				NamespaceSelector: calicoNamespaceSelector(peer.Namespace),
+				Ports:             ports,
+			}
+		})...)
+	}
+	for _, address := range sortedAddresses(external) {
+		destination := CalicoEntityRule{Domains: fqdnsOf(address, m.hostnames[address])}
+		if peer, ok := externalPeer(address); ok {
+			destination.Nets = []string{peer.IPBlock.CIDR}
+		}
+		policy.Spec.Egress = append(policy.Spec.Egress, calicoRules(external[address], func(ports []int32) (CalicoEntityRule, CalicoEntityRule) {
+			rule := destination
+			rule.Ports = ports
+			return CalicoEntityRule{}, rule
+		})...)
+	}
+	return policy
+}
+
+// GenerateCalicoPolicies builds one Calico NetworkPolicy per workload
+func GenerateCalicoPolicies(output v1.ProbeOutput, namespace string) []CalicoNetworkPolicy {
+	m := buildModel(output)
+	return lo.Map(m.policyTargets(namespace), func(workload *Workload, _ int) CalicoNetworkPolicy {
+		return buildCalicoPolicy(workload, m)
+	})
+}
+
+// GenerateCalicoDefaultDeny builds a GlobalNetworkPolicy denying all the
+// traffic not allowed by the generated policies in the probed namespaces. DNS
+// towards kube-dns stays allowed. The policy is evaluated after the namespaced
+// policies thanks to its high order.
+func GenerateCalicoDefaultDeny(output v1.ProbeOutput, namespace string) CalicoGlobalNetworkPolicy {
+	m := buildModel(output)
+	namespaces := lo.Uniq(lo.Map(m.policyTargets(namespace), func(workload *Workload, _ int) string {
+		return fmt.Sprintf("'%s'", workload.Namespace)
+	}))
+	order := 2000.0
+	return CalicoGlobalNetworkPolicy{
+		TypeMeta: metav1.TypeMeta{APIVersion: "projectcalico.org/v3", Kind: "GlobalNetworkPolicy"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "kubesonde-default-deny",
+			Labels: map[string]string{MANAGED_BY_LABEL: MANAGED_BY_VALUE},
+		},
+		Spec: CalicoPolicySpec{
+			Order:             &order,
+			Selector:          "all()",
+			NamespaceSelector: fmt.Sprintf("%s in {%s}", NAMESPACE_LABEL, strings.Join(namespaces, ", ")),
+			Types:             []string{"Ingress", "Egress"},
+			Egress:            calicoDNSRules([]flow{{port: 53, protocol: "UDP"}, {port: 53, protocol: "TCP"}}),
+		},
+	}
+}
@@ -0,0 +1,171 @@
+package policygenerator
+
+import (
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "kubesonde.io/api/v1"
+)
+
+// Cilium identifies the namespace of an endpoint with this label
+const CILIUM_NAMESPACE_LABEL = "k8s:io.kubernetes.pod.namespace"
+
+// The following types mirror the subset of the cilium.io/v2 CRDs used by the
+// exporter. They are defined here to avoid depending on the Cilium modules.
+
+type CiliumNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              CiliumRule `json:"spec"`
+}
+
+type CiliumRule struct {
+	EndpointSelector metav1.LabelSelector `json:"endpointSelector"`
+	Ingress          []CiliumIngressRule  `json:"ingress"`
+	Egress           []CiliumEgressRule   `json:"egress"`
+}
+
+type CiliumIngressRule struct {
+	FromEndpoints []metav1.LabelSelector `json:"fromEndpoints,omitempty"`
+	ToPorts       []CiliumPortRule       `json:"toPorts,omitempty"`
+}
+
+type CiliumEgressRule struct {
+	ToEndpoints []metav1.LabelSelector `json:"toEndpoints,omitempty"`
+	ToCIDR      []string               `json:"toCIDR,omitempty"`
+	ToFQDNs     []CiliumFQDNSelector   `json:"toFQDNs,omitempty"`
+	ToPorts     []CiliumPortRule       `json:"toPorts,omitempty"`
+}
+
+type CiliumFQDNSelector struct {
+	MatchName string `json:"matchName,omitempty"`
+}
+
+type CiliumPortRule struct {
+	Ports []CiliumPortProtocol `json:"ports"`
+	Rules *CiliumL7Rules       `json:"rules,omitempty"`
+}
+
+type CiliumPortProtocol struct {
+	Port     string `json:"port"`
+	Protocol string `json:"protocol"`
+}
+
+type CiliumL7Rules struct {
+	DNS []CiliumDNSRule `json:"dns,omitempty"`
+}
+
+type CiliumDNSRule struct {
+	MatchPattern string `json:"matchPattern,omitempty"`
+}
+
+func ciliumPorts(flows []flow) []CiliumPortRule {
+	ports := lo.Map(portsOf(flows), func(p networkingv1.NetworkPolicyPort, _ int) CiliumPortProtocol {
+		return CiliumPortProtocol{Port: p.Port.String(), Protocol: string(*p.Protocol)}
+	})
+	return []CiliumPortRule{{Ports: ports}}
+}
+
+func ciliumEndpoint(workload *Workload) metav1.LabelSelector {
+	labels := lo.Assign(workload.Labels, map[string]string{CILIUM_NAMESPACE_LABEL: workload.Namespace})
+	return metav1.LabelSelector{MatchLabels: labels}
+}
+
+// fqdnsOf returns the hostnames of an external address without the trailing dot
+func fqdnsOf(address string, hostnames []string) []string {
+	names := hostnames
+	if net.ParseIP(address) == nil {
+		names = append([]string{address}, hostnames...)
+	}
+	names = lo.Uniq(lo.Map(names, func(name string, _ int) string {
+		return strings.TrimSuffix(name, ".")
+	}))
+	sort.Strings(names)
+	return names
+}
+
+// ciliumDNSRule allows DNS towards kube-dns. The DNS rules make the Cilium DNS
+// proxy learn the IPs of the FQDN selectors.
+func ciliumDNSRule(flows []flow) CiliumEgressRule {
+	rule := CiliumEgressRule{
+		ToEndpoints: []metav1.LabelSelector{{MatchLabels: map[string]string{
+			CILIUM_NAMESPACE_LABEL: "kube-system",
+			"k8s-app":              "kube-dns",
+		}}},
+		ToPorts: ciliumPorts(flows),
+	}
+	rule.ToPorts[0].Rules = &CiliumL7Rules{DNS: []CiliumDNSRule{{MatchPattern: "*"}}}
+	return rule
+}
+
+func buildCiliumPolicy(workload *Workload, m model) CiliumNetworkPolicy {
+	policy := CiliumNetworkPolicy{
+		TypeMeta: metav1.TypeMeta{APIVersion: "cilium.io/v2", Kind: "CiliumNetworkPolicy"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      policyName(workload.Name),
+			Namespace: workload.Namespace,
+			Labels:    map[string]string{MANAGED_BY_LABEL: MANAGED_BY_VALUE},
+		},
+		Spec: CiliumRule{
+			EndpointSelector: metav1.LabelSelector{MatchLabels: workload.Labels},
+			Ingress:          []CiliumIngressRule{},
+			Egress:           []CiliumEgressRule{},
+		},
+	}
+
+	ingress := m.ingressFlows(workload)
+	for _, source := range sortedKeys(ingress) {
+		policy.Spec.Ingress = append(policy.Spec.Ingress, CiliumIngressRule{
+			FromEndpoints: []metav1.LabelSelector{ciliumEndpoint(m.workloads[source])},
+			ToPorts:       ciliumPorts(ingress[source]),
+		})
+	}
+
+	dns, egress, external := m.egressFlows(workload)
+	for _, destination := range sortedKeys(egress) {
+		policy.Spec.Egress = append(policy.Spec.Egress, CiliumEgressRule{
+			ToEndpoints: []metav1.LabelSelector{ciliumEndpoint(m.workloads[destination])},
+			ToPorts:     ciliumPorts(egress[destination]),
+		})
+	}
+	fqdnRules := false
+	for _, address := range sortedAddresses(external) {
+		if peer, ok := externalPeer(address); ok {
+			policy.Spec.Egress = append(policy.Spec.Egress, CiliumEgressRule{
+				ToCIDR:  []string{peer.IPBlock.CIDR},
+				ToPorts: ciliumPorts(external[address]),
+			})
+		}
+		names := fqdnsOf(address, m.hostnames[address])
+		if len(names) == 0 {
+			continue
+		}
+		fqdnRules = true
+		policy.Spec.Egress = append(policy.Spec.Egress, CiliumEgressRule{
+			ToFQDNs: lo.Map(names, func(name string, _ int) CiliumFQDNSelector {
+				return CiliumFQDNSelector{MatchName: name}
+			}),
+			ToPorts: ciliumPorts(external[address]),
+		})
+	}
+	if len(dns) > 0 || fqdnRules {
+		if len(dns) == 0 {
+			dns = []flow{{port: 53, protocol: "UDP"}, {port: 53, protocol: "TCP"}}
+		}
+		policy.Spec.Egress = append([]CiliumEgressRule{ciliumDNSRule(dns)}, policy.Spec.Egress...)
+	}
+	return policy
+}
+
+// GenerateCiliumPolicies builds one CiliumNetworkPolicy per workload. Unlike
+// v1 NetworkPolicies, Internet destinations are also allowed by FQDN.
+func GenerateCiliumPolicies(output v1.ProbeOutput, namespace string) []CiliumNetworkPolicy {
+	m := buildModel(output)
+	return lo.Map(m.policyTargets(namespace), func(workload *Workload, _ int) CiliumNetworkPolicy {
+		return buildCiliumPolicy(workload, m)
+	})
+}
@@ -0,0 +1,129 @@
+package policygenerator
+
+import (
+	"context"
+	"strings"
+
+	"github.com/samber/lo"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"kubesonde.io/pkg/apitypes"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+var log = logf.Log.WithName("controllers.policygenerator")
+
+type DiffAction = apitypes.DiffAction
+
+const (
+	CREATE    = apitypes.CREATE
+	UPDATE    = apitypes.UPDATE
+	UNCHANGED = apitypes.UNCHANGED
+	// UNMANAGED marks existing policies that are not generated by Kubesonde
+	UNMANAGED = apitypes.UNMANAGED
+)
+
+// PolicyDiff compares a generated policy with the policy stored in the cluster
+type PolicyDiff = apitypes.PolicyDiff
+
+// lineDiff returns the lines removed from `a` prefixed by `-` and the lines
+// added in `b` prefixed by `+`, based on their longest common subsequence
+func lineDiff(a string, b string) string {
+	before := strings.Split(strings.TrimSuffix(a, "\n"), "\n")
+	after := strings.Split(strings.TrimSuffix(b, "\n"), "\n")
+	lcs := make([][]int, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			sb.WriteString("  " + before[i] + "\n")
+			i++
+			j++
+		case j < len(after) && (i == len(before) || lcs[i][j+1] >= lcs[i+1][j]):
+			sb.WriteString("+ " + after[j] + "\n")
+			j++
+		default:
+			sb.WriteString("- " + before[i] + "\n")
+			i++
+		}
+	}
+	return sb.String()
+}
+
+func specYAML(policy networkingv1.NetworkPolicy) string {
+	data, err := yaml.Marshal(policy.Spec)
+	if err != nil {
+		log.Error(err, "Could not render policy spec")
+		return ""
+	}
+	return string(data)
+}
+
+// DiffPolicies performs a dry-run of applying the generated policies on top of
+// the existing ones
+func DiffPolicies(existing []networkingv1.NetworkPolicy, generated []networkingv1.NetworkPolicy) []PolicyDiff {
+	existingByKey := lo.KeyBy(existing, func(p networkingv1.NetworkPolicy) string {
+		return p.Namespace + "/" + p.Name
+	})
+	namespaces := lo.Uniq(lo.Map(generated, func(p networkingv1.NetworkPolicy, _ int) string {
+		return p.Namespace
+	}))
+	diffs := []PolicyDiff{}
+	for _, policy := range generated {
+		current, ok := existingByKey[policy.Namespace+"/"+policy.Name]
+		diff := PolicyDiff{Name: policy.Name, Namespace: policy.Namespace}
+		switch {
+		case !ok:
+			diff.Action = CREATE
+		case specYAML(current) == specYAML(policy):
+			diff.Action = UNCHANGED
+		default:
+			diff.Action = UPDATE
+			diff.Diff = lineDiff(specYAML(current), specYAML(policy))
+		}
+		diffs = append(diffs, diff)
+	}
+	generatedKeys := lo.Map(generated, func(p networkingv1.NetworkPolicy, _ int) string {
+		return p.Namespace + "/" + p.Name
+	})
+	for _, policy := range existing {
+		if lo.Contains(generatedKeys, policy.Namespace+"/"+policy.Name) || !lo.Contains(namespaces, policy.Namespace) {
+			continue
+		}
+		diffs = append(diffs, PolicyDiff{Name: policy.Name, Namespace: policy.Namespace, Action: UNMANAGED})
+	}
+	return diffs
+}
+
+// ExistingPoliciesProvider returns the policies stored in a namespace
+type ExistingPoliciesProvider func(namespace string) ([]networkingv1.NetworkPolicy, error)
+
+func ClusterPoliciesProvider(client kubernetes.Interface) ExistingPoliciesProvider {
+	return func(namespace string) ([]networkingv1.NetworkPolicy, error) {
+		return GetExistingPolicies(client, namespace)
+	}
+}
+
+// GetExistingPolicies lists the NetworkPolicies of the cluster. An empty namespace lists every namespace.
+func GetExistingPolicies(client kubernetes.Interface, namespace string) ([]networkingv1.NetworkPolicy, error) {
+	policies, err := client.NetworkingV1().NetworkPolicies(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return policies.Items, nil
+}
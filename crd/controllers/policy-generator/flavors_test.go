@@ -0,0 +1,77 @@
+package policygenerator
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "kubesonde.io/api/v1"
+)
+
+var resolvedOutput = func() v1.ProbeOutput {
+	resolved := output
+	resolved.Items = append([]v1.ProbeOutputItem{}, output.Items...)
+	resolved.Items[4].DestinationHostnames = []string{"dns.google."}
+	return resolved
+}()
+
+var _ = Describe("GenerateCiliumPolicies", func() {
+	It("Selects the peers by labels and namespace", func() {
+		db := GenerateCiliumPolicies(output, "data")[0]
+		Expect(db.Kind).To(Equal("CiliumNetworkPolicy"))
+		Expect(db.Spec.EndpointSelector.MatchLabels).To(Equal(map[string]string{"app": "db"}))
+		Expect(db.Spec.Ingress).To(Equal([]CiliumIngressRule{{
+			FromEndpoints: []metav1.LabelSelector{{MatchLabels: map[string]string{
+				"app": "frontend", "tier": "web", CILIUM_NAMESPACE_LABEL: "shop",
+			}}},
+			ToPorts: []CiliumPortRule{{Ports: []CiliumPortProtocol{{Port: "5432", Protocol: "TCP"}}}},
+		}}))
+		Expect(db.Spec.Egress).To(BeEmpty())
+	})
+
+	It("Allows Internet destinations by FQDN", func() {
+		frontend := GenerateCiliumPolicies(resolvedOutput, "shop")[0]
+		Expect(frontend.Spec.Egress).To(HaveLen(4))
+		Expect(frontend.Spec.Egress[0].ToPorts[0].Rules.DNS).To(Equal([]CiliumDNSRule{{MatchPattern: "*"}}))
+		Expect(frontend.Spec.Egress[1].ToEndpoints[0].MatchLabels).To(HaveKeyWithValue("app", "db"))
+		Expect(frontend.Spec.Egress[2].ToCIDR).To(Equal([]string{"8.8.8.8/32"}))
+		Expect(frontend.Spec.Egress[3].ToFQDNs).To(Equal([]CiliumFQDNSelector{{MatchName: "dns.google"}}))
+	})
+})
+
+var _ = Describe("GenerateCalicoPolicies", func() {
+	It("Uses selector expressions", func() {
+		db := GenerateCalicoPolicies(output, "data")[0]
+		Expect(db.Kind).To(Equal("NetworkPolicy"))
+		Expect(db.APIVersion).To(Equal("projectcalico.org/v3"))
+		Expect(db.Spec.Selector).To(Equal("app == 'db'"))
+		Expect(db.Spec.Ingress).To(Equal([]CalicoRule{{
+			Action:   "Allow",
+			Protocol: "TCP",
+			Source: CalicoEntityRule{
+				Selector:          "app == 'frontend' && tier == 'web'",
+				NamespaceSelector: "kubernetes.io/metadata.name == 'shop'",
+			},
+			Destination: CalicoEntityRule{Ports: []int32{5432}},
+		}}))
+	})
+
+	It("Allows Internet destinations by net and domain", func() {
+		frontend := GenerateCalicoPolicies(resolvedOutput, "shop")[0]
+		Expect(frontend.Spec.Egress).To(HaveLen(3))
+		Expect(frontend.Spec.Egress[0].Destination.Selector).To(Equal("k8s-app == 'kube-dns'"))
+		Expect(frontend.Spec.Egress[2].Protocol).To(Equal("UDP"))
+		Expect(frontend.Spec.Egress[2].Destination).To(Equal(CalicoEntityRule{
+			Nets:    []string{"8.8.8.8/32"},
+			Domains: []string{"dns.google"},
+			Ports:   []int32{53},
+		}))
+	})
+
+	It("Denies everything else in the probed namespaces", func() {
+		deny := GenerateCalicoDefaultDeny(output, "")
+		Expect(deny.Kind).To(Equal("GlobalNetworkPolicy"))
+		Expect(deny.Spec.NamespaceSelector).To(Equal("kubernetes.io/metadata.name in {'data', 'shop'}"))
+		Expect(deny.Spec.Ingress).To(BeEmpty())
+		Expect(deny.Spec.Egress).To(HaveLen(2))
+	})
+})
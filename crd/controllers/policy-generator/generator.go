@@ -0,0 +1,341 @@
+// The policygenerator module synthesizes least-privilege NetworkPolicies from
+// the connectivity observed by Kubesonde
+package policygenerator
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/utils"
+)
+
+const (
+	MANAGED_BY_LABEL = "app.kubernetes.io/managed-by"
+	MANAGED_BY_VALUE = "kubesonde"
+	NAMESPACE_LABEL  = "kubernetes.io/metadata.name"
+	KUBE_DNS_ADDRESS = "kube-dns.kube-system.svc.cluster.local"
+	// UNREPRESENTABLE_ANNOTATION lists the observed egress towards hostnames,
+	// which v1 NetworkPolicies cannot allow, as host:port/protocol
+	UNREPRESENTABLE_ANNOTATION = "kubesonde.io/unrepresentable-egress"
+)
+
+// Workload groups the replicas of a Deployment or StatefulSet. Standalone pods
+// are workloads on their own.
+type Workload struct {
+	Name      string
+	Namespace string
+	// Labels are the labels shared by every replica
+	Labels map[string]string
+}
+
+type workloadKey struct {
+	name      string
+	namespace string
+}
+
+// flow is an allowed connection between two workloads or towards an external address
+type flow struct {
+	source      workloadKey
+	destination workloadKey
+	external    string
+	dns         bool
+	port        int32
+	protocol    corev1.Protocol
+}
+
+// listensOn reports whether the destination pod listens on the port. The
+// netstat output of the monitor container is preferred, the declared container
+// ports are used when the monitor did not report anything for the pod.
+func listensOn(output v1.ProbeOutput, pod string, port string, protocol string) bool {
+	matches := func(items []v1.PodNetworkingItem) bool {
+		return lo.SomeBy(items, func(item v1.PodNetworkingItem) bool {
+			return item.Port == port && (item.Protocol == "" || strings.EqualFold(item.Protocol, protocol))
+		})
+	}
+	if listening, ok := output.PodNetworkingV2[pod]; ok && len(listening) > 0 {
+		return matches(listening)
+	}
+	return matches(output.PodConfigurationNetworking[pod])
+}
+
+// isDesired selects the allowed probes that should be kept by the generated
+// policies: pod to pod flows towards a listening port and egress flows
+// towards DNS or the Internet
+func isDesired(output v1.ProbeOutput, item v1.ProbeOutputItem) bool {
+	if item.Type != v1.PROBE || item.ResultingAction != v1.ALLOW || item.Source.Type != v1.POD {
+		return false
+	}
+	switch item.Destination.Type {
+	case v1.POD:
+		return listensOn(output, item.Destination.Name, item.Port, item.Protocol)
+	case v1.INTERNET:
+		return true
+	}
+	// Services are translated to their backends by the CNI, the pod to pod
+	// probes towards the backends already cover them
+	return false
+}
+
+func intersectLabels(current map[string]string, labels map[string]string) map[string]string {
+	if current == nil {
+		return labels
+	}
+	return lo.PickBy(current, func(key string, value string) bool {
+		return labels[key] == value
+	})
+}
+
+// model is the observed connectivity: the workloads and the desired flows between them
+type model struct {
+	workloads map[workloadKey]*Workload
+	flows     []flow
+	// hostnames maps external addresses to the hostnames resolved for them
+	hostnames map[string][]string
+}
+
+func buildModel(output v1.ProbeOutput) model {
+	workloads := map[workloadKey]*Workload{}
+	hostnames := map[string][]string{}
+	register := func(endpoint v1.ProbeEndpointInfo) workloadKey {
+		key := workloadKey{name: endpoint.WorkloadName(), namespace: endpoint.Namespace}
+		workload, ok := workloads[key]
+		if !ok {
+			workload = &Workload{Name: key.name, Namespace: key.namespace}
+			workloads[key] = workload
+		}
+		workload.Labels = intersectLabels(workload.Labels, utils.StringToMap(endpoint.Labels))
+		return key
+	}
+
+	flows := []flow{}
+	// Only the latest verdict of a connection counts, a connection allowed
+	// once and denied since is not allowed
+	for _, item := range v1.LatestProbes(output.Items) {
+		if item.Type != v1.PROBE || item.Source.Type != v1.POD {
+			continue
+		}
+		source := register(item.Source)
+		if item.Destination.Type == v1.POD {
+			register(item.Destination)
+		}
+		if !isDesired(output, item) {
+			continue
+		}
+		port, err := strconv.ParseInt(item.Port, 10, 32)
+		if err != nil {
+			continue
+		}
+		current := flow{
+			source:   source,
+			port:     int32(port),
+			protocol: corev1.Protocol(strings.ToUpper(lo.Ternary(item.Protocol == "", "TCP", item.Protocol))),
+		}
+		switch {
+		case item.Destination.Type == v1.POD:
+			current.destination = workloadKey{name: item.Destination.WorkloadName(), namespace: item.Destination.Namespace}
+		case item.Destination.IPAddress == KUBE_DNS_ADDRESS:
+			current.dns = true
+		default:
+			current.external = item.Destination.IPAddress
+			hostnames[current.external] = lo.Uniq(append(hostnames[current.external], item.DestinationHostnames...))
+		}
+		flows = append(flows, current)
+	}
+	return model{workloads: workloads, flows: lo.Uniq(flows), hostnames: hostnames}
+}
+
+// policyTargets returns the workloads for which a policy is generated
+func (m model) policyTargets(namespace string) []*Workload {
+	targets := []*Workload{}
+	for _, key := range sortedKeys(m.workloads) {
+		if namespace != "" && key.namespace != namespace {
+			continue
+		}
+		workload := m.workloads[key]
+		if len(workload.Labels) == 0 {
+			log.Info(fmt.Sprintf("Workload %s/%s has no common labels, skipping policy generation", key.namespace, key.name))
+			continue
+		}
+		targets = append(targets, workload)
+	}
+	return targets
+}
+
+func (w *Workload) key() workloadKey {
+	return workloadKey{name: w.Name, namespace: w.Namespace}
+}
+
+// ingressFlows groups the flows reaching the workload by source workload. The
+// flows between the replicas of the workload have the workload as source.
+func (m model) ingressFlows(workload *Workload) map[workloadKey][]flow {
+	key := workload.key()
+	return lo.GroupBy(lo.Filter(m.flows, func(f flow, _ int) bool {
+		return f.destination == key && len(m.workloads[f.source].Labels) > 0
+	}), func(f flow) workloadKey { return f.source })
+}
+
+// egressFlows splits the flows leaving the workload into DNS flows, flows
+// grouped by destination workload, the workload itself included, and flows
+// grouped by external address
+func (m model) egressFlows(workload *Workload) ([]flow, map[workloadKey][]flow, map[string][]flow) {
+	key := workload.key()
+	outgoing := lo.Filter(m.flows, func(f flow, _ int) bool {
+		return f.source == key
+	})
+	dns := lo.Filter(outgoing, func(f flow, _ int) bool { return f.dns })
+	internal := lo.GroupBy(lo.Filter(outgoing, func(f flow, _ int) bool {
+		return !f.dns && f.external == "" && len(m.workloads[f.destination].Labels) > 0
+	}), func(f flow) workloadKey { return f.destination })
+	external := lo.GroupBy(lo.Filter(outgoing, func(f flow, _ int) bool {
+		return f.external != ""
+	}), func(f flow) string { return f.external })
+	return dns, internal, external
+}
+
+func policyName(workload string) string {
+	return fmt.Sprintf("kubesonde-%s", workload)
+}
+
+func portsOf(flows []flow) []networkingv1.NetworkPolicyPort {
+	unique := lo.UniqBy(flows, func(f flow) string {
+		return fmt.Sprintf("%s/%d", f.protocol, f.port)
+	})
+	sort.Slice(unique, func(i, j int) bool {
+		if unique[i].port == unique[j].port {
+			return unique[i].protocol < unique[j].protocol
+		}
+		return unique[i].port < unique[j].port
+	})
+	return lo.Map(unique, func(f flow, _ int) networkingv1.NetworkPolicyPort {
+		protocol := f.protocol
+		port := intstr.FromInt32(f.port)
+		return networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &port}
+	})
+}
+
+func workloadPeer(workload *Workload, policyNamespace string) networkingv1.NetworkPolicyPeer {
+	peer := networkingv1.NetworkPolicyPeer{
+		PodSelector: &metav1.LabelSelector{MatchLabels: workload.Labels},
+	}
+	if workload.Namespace != policyNamespace {
+		peer.NamespaceSelector = &metav1.LabelSelector{
+			MatchLabels: map[string]string{NAMESPACE_LABEL: workload.Namespace},
+		}
+	}
+	return peer
+}
+
+func externalPeer(address string) (networkingv1.NetworkPolicyPeer, bool) {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		// Hostnames cannot be expressed with v1 NetworkPolicies
+		return networkingv1.NetworkPolicyPeer{}, false
+	}
+	cidr := fmt.Sprintf("%s/32", ip.String())
+	if ip.To4() == nil {
+		cidr = fmt.Sprintf("%s/128", ip.String())
+	}
+	return networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}}, true
+}
+
+func dnsPeer() networkingv1.NetworkPolicyPeer {
+	return networkingv1.NetworkPolicyPeer{
+		NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{NAMESPACE_LABEL: "kube-system"}},
+		PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"k8s-app": "kube-dns"}},
+	}
+}
+
+func sortedKeys[T any](m map[workloadKey]T) []workloadKey {
+	keys := lo.Keys(m)
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].namespace == keys[j].namespace {
+			return keys[i].name < keys[j].name
+		}
+		return keys[i].namespace < keys[j].namespace
+	})
+	return keys
+}
+
+func buildPolicy(workload *Workload, m model) networkingv1.NetworkPolicy {
+	policy := networkingv1.NetworkPolicy{
+		TypeMeta: metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "NetworkPolicy"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      policyName(workload.Name),
+			Namespace: workload.Namespace,
+			Labels:    map[string]string{MANAGED_BY_LABEL: MANAGED_BY_VALUE},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: workload.Labels},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{},
+			Egress:      []networkingv1.NetworkPolicyEgressRule{},
+		},
+	}
+
+	ingress := m.ingressFlows(workload)
+	for _, source := range sortedKeys(ingress) {
+		policy.Spec.Ingress = append(policy.Spec.Ingress, networkingv1.NetworkPolicyIngressRule{
+			From:  []networkingv1.NetworkPolicyPeer{workloadPeer(m.workloads[source], workload.Namespace)},
+			Ports: portsOf(ingress[source]),
+		})
+	}
+
+	dns, egress, external := m.egressFlows(workload)
+	if len(dns) > 0 {
+		policy.Spec.Egress = append(policy.Spec.Egress, networkingv1.NetworkPolicyEgressRule{
+			To:    []networkingv1.NetworkPolicyPeer{dnsPeer()},
+			Ports: portsOf(dns),
+		})
+	}
+	for _, destination := range sortedKeys(egress) {
+		policy.Spec.Egress = append(policy.Spec.Egress, networkingv1.NetworkPolicyEgressRule{
+			To:    []networkingv1.NetworkPolicyPeer{workloadPeer(m.workloads[destination], workload.Namespace)},
+			Ports: portsOf(egress[destination]),
+		})
+	}
+	unrepresentable := []string{}
+	for _, address := range sortedAddresses(external) {
+		peer, ok := externalPeer(address)
+		if !ok {
+			for _, port := range portsOf(external[address]) {
+				unrepresentable = append(unrepresentable, fmt.Sprintf("%s:%s/%s", address, port.Port.String(), *port.Protocol))
+			}
+			continue
+		}
+		policy.Spec.Egress = append(policy.Spec.Egress, networkingv1.NetworkPolicyEgressRule{
+			To:    []networkingv1.NetworkPolicyPeer{peer},
+			Ports: portsOf(external[address]),
+		})
+	}
+	if len(unrepresentable) > 0 {
+		log.Info(fmt.Sprintf("The egress of %s/%s towards hostnames cannot be allowed by a NetworkPolicy", workload.Namespace, workload.Name), "egress", unrepresentable)
+		policy.Annotations = map[string]string{UNREPRESENTABLE_ANNOTATION: strings.Join(unrepresentable, ",")}
+	}
+	return policy
+}
+
+func sortedAddresses(m map[string][]flow) []string {
+	addresses := lo.Keys(m)
+	sort.Strings(addresses)
+	return addresses
+}
+
+// GeneratePolicies builds one NetworkPolicy per workload allowing only the
+// observed, desired flows. An empty namespace selects every namespace. The
+// egress towards hostnames is listed in the UNREPRESENTABLE_ANNOTATION of the
+// policy.
+func GeneratePolicies(output v1.ProbeOutput, namespace string) []networkingv1.NetworkPolicy {
+	m := buildModel(output)
+	return lo.Map(m.policyTargets(namespace), func(workload *Workload, _ int) networkingv1.NetworkPolicy {
+		return buildPolicy(workload, m)
+	})
+}
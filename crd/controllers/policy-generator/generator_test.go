@@ -0,0 +1,176 @@
+package policygenerator
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	v1 "kubesonde.io/api/v1"
+)
+
+var (
+	frontendA = v1.ProbeEndpointInfo{Type: v1.POD, Name: "frontend-abc-1", Namespace: "shop", DeploymentName: "frontend", Labels: "app=frontend;tier=web;"}
+	frontendB = v1.ProbeEndpointInfo{Type: v1.POD, Name: "frontend-abc-2", Namespace: "shop", DeploymentName: "frontend", Labels: "app=frontend;tier=web;version=2;"}
+	database  = v1.ProbeEndpointInfo{Type: v1.POD, Name: "db-0", Namespace: "data", DeploymentName: "db", Labels: "app=db;"}
+	google    = v1.ProbeEndpointInfo{Type: v1.INTERNET, Name: "Google DNS", IPAddress: "8.8.8.8"}
+	kubeDNS   = v1.ProbeEndpointInfo{Type: v1.INTERNET, Name: "KUBE DNS", IPAddress: KUBE_DNS_ADDRESS}
+)
+
+func probe(src v1.ProbeEndpointInfo, dst v1.ProbeEndpointInfo, port string, protocol string, result v1.ActionType) v1.ProbeOutputItem {
+	return v1.ProbeOutputItem{
+		Type:            v1.PROBE,
+		ExpectedAction:  v1.DENY,
+		ResultingAction: result,
+		Source:          src,
+		Destination:     dst,
+		Port:            port,
+		Protocol:        protocol,
+	}
+}
+
+func port(p int32, protocol corev1.Protocol) networkingv1.NetworkPolicyPort {
+	value := intstr.FromInt32(p)
+	return networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &value}
+}
+
+var output = v1.ProbeOutput{
+	Items: []v1.ProbeOutputItem{
+		probe(frontendA, database, "5432", "TCP", v1.ALLOW),
+		probe(frontendB, database, "5432", "TCP", v1.ALLOW),
+		// Not listening
+		probe(frontendA, database, "9187", "TCP", v1.ALLOW),
+		probe(database, frontendA, "8080", "TCP", v1.DENY),
+		probe(frontendA, google, "53", "UDP", v1.ALLOW),
+		probe(frontendA, kubeDNS, "53", "UDP", v1.ALLOW),
+		probe(database, google, "53", "UDP", v1.DENY),
+	},
+	PodNetworkingV2: v1.PodNetworkingInfoV2{
+		"db-0": {{Port: "5432", IP: "0.0.0.0", Protocol: "TCP"}},
+	},
+	PodConfigurationNetworking: v1.PodNetworkingInfoV2{
+		"db-0":           {{Port: "9187", IP: "0.0.0.0", Protocol: "TCP"}},
+		"frontend-abc-1": {{Port: "8080", IP: "0.0.0.0", Protocol: "TCP"}},
+	},
+}
+
+var _ = Describe("GeneratePolicies", func() {
+	It("Generates one policy per workload", func() {
+		policies := GeneratePolicies(output, "")
+		Expect(policies).To(HaveLen(2))
+		Expect(policies[0].Name).To(Equal("kubesonde-db"))
+		Expect(policies[0].Namespace).To(Equal("data"))
+		Expect(policies[1].Name).To(Equal("kubesonde-frontend"))
+		Expect(policies[1].Labels).To(Equal(map[string]string{MANAGED_BY_LABEL: MANAGED_BY_VALUE}))
+	})
+
+	It("Allows only the observed ingress towards listening ports", func() {
+		db := GeneratePolicies(output, "data")[0]
+		Expect(db.Spec.PodSelector.MatchLabels).To(Equal(map[string]string{"app": "db"}))
+		Expect(db.Spec.Ingress).To(Equal([]networkingv1.NetworkPolicyIngressRule{{
+			From: []networkingv1.NetworkPolicyPeer{{
+				PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "frontend", "tier": "web"}},
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{NAMESPACE_LABEL: "shop"}},
+			}},
+			Ports: []networkingv1.NetworkPolicyPort{port(5432, corev1.ProtocolTCP)},
+		}}))
+		Expect(db.Spec.Egress).To(BeEmpty())
+	})
+
+	It("Allows the observed egress", func() {
+		frontend := GeneratePolicies(output, "shop")[0]
+		Expect(frontend.Spec.PodSelector.MatchLabels).To(Equal(map[string]string{"app": "frontend", "tier": "web"}))
+		Expect(frontend.Spec.Ingress).To(BeEmpty())
+		Expect(frontend.Spec.Egress).To(HaveLen(3))
+		Expect(frontend.Spec.Egress[0].To[0].PodSelector.MatchLabels).To(Equal(map[string]string{"k8s-app": "kube-dns"}))
+		Expect(frontend.Spec.Egress[1].To[0].PodSelector.MatchLabels).To(Equal(map[string]string{"app": "db"}))
+		Expect(frontend.Spec.Egress[1].Ports).To(Equal([]networkingv1.NetworkPolicyPort{port(5432, corev1.ProtocolTCP)}))
+		Expect(frontend.Spec.Egress[2].To[0].IPBlock.CIDR).To(Equal("8.8.8.8/32"))
+		Expect(frontend.Spec.Egress[2].Ports).To(Equal([]networkingv1.NetworkPolicyPort{port(53, corev1.ProtocolUDP)}))
+	})
+
+	It("Allows the traffic between the replicas of a workload", func() {
+		replicas := v1.ProbeOutput{
+			Items: []v1.ProbeOutputItem{probe(frontendA, frontendB, "7946", "TCP", v1.ALLOW)},
+			PodConfigurationNetworking: v1.PodNetworkingInfoV2{
+				"frontend-abc-2": {{Port: "7946", IP: "0.0.0.0", Protocol: "TCP"}},
+			},
+		}
+		self := networkingv1.NetworkPolicyPeer{
+			PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "frontend", "tier": "web"}},
+		}
+		frontend := GeneratePolicies(replicas, "shop")[0]
+		Expect(frontend.Spec.Ingress).To(Equal([]networkingv1.NetworkPolicyIngressRule{{
+			From:  []networkingv1.NetworkPolicyPeer{self},
+			Ports: []networkingv1.NetworkPolicyPort{port(7946, corev1.ProtocolTCP)},
+		}}))
+		Expect(frontend.Spec.Egress).To(Equal([]networkingv1.NetworkPolicyEgressRule{{
+			To:    []networkingv1.NetworkPolicyPeer{self},
+			Ports: []networkingv1.NetworkPolicyPort{port(7946, corev1.ProtocolTCP)},
+		}}))
+	})
+
+	It("Reports the egress towards hostnames", func() {
+		hostname := v1.ProbeEndpointInfo{Type: v1.INTERNET, Name: "Google", IPAddress: "google.com"}
+		frontend := GeneratePolicies(v1.ProbeOutput{Items: []v1.ProbeOutputItem{
+			probe(frontendA, hostname, "443", "TCP", v1.ALLOW),
+			probe(frontendA, hostname, "80", "TCP", v1.ALLOW),
+		}}, "shop")[0]
+		Expect(frontend.Spec.Egress).To(BeEmpty())
+		Expect(frontend.Annotations).To(Equal(map[string]string{UNREPRESENTABLE_ANNOTATION: "google.com:80/TCP,google.com:443/TCP"}))
+		Expect(GeneratePolicies(output, "shop")[0].Annotations).To(BeEmpty())
+	})
+
+	It("Allows only the connections whose latest probe is allowed", func() {
+		flipped := output
+		flipped.Items = append(append([]v1.ProbeOutputItem{}, output.Items...),
+			probe(frontendB, database, "5432", "TCP", v1.DENY),
+			probe(frontendA, google, "53", "UDP", v1.DENY),
+		)
+		db := GeneratePolicies(flipped, "data")[0]
+		Expect(db.Spec.Ingress).To(HaveLen(1))
+		Expect(db.Spec.Ingress[0].From[0].PodSelector.MatchLabels).To(Equal(map[string]string{"app": "frontend", "tier": "web"}))
+		frontend := GeneratePolicies(flipped, "shop")[0]
+		Expect(frontend.Spec.Egress).To(HaveLen(2))
+		Expect(frontend.Spec.Egress[1].To[0].PodSelector.MatchLabels).To(Equal(map[string]string{"app": "db"}))
+
+		// Denied by every replica, the connection is no longer allowed
+		flipped.Items = append(flipped.Items, probe(frontendA, database, "5432", "TCP", v1.DENY))
+		Expect(GeneratePolicies(flipped, "data")[0].Spec.Ingress).To(BeEmpty())
+	})
+
+	It("Renders YAML", func() {
+		data, err := RenderYAML(GeneratePolicies(output, ""))
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(ContainSubstring("kind: NetworkPolicy"))
+		Expect(string(data)).To(ContainSubstring("name: kubesonde-db"))
+		Expect(string(data)).To(ContainSubstring("---\n"))
+	})
+})
+
+var _ = Describe("DiffPolicies", func() {
+	It("Reports the changes", func() {
+		generated := GeneratePolicies(output, "")
+		unchanged := generated[0]
+		outdated := generated[1].DeepCopy()
+		outdated.Spec.Egress = nil
+		manual := networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "manual", Namespace: "shop"}}
+		other := networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "elsewhere"}}
+
+		diffs := DiffPolicies([]networkingv1.NetworkPolicy{unchanged, *outdated, manual, other}, generated)
+		Expect(diffs).To(HaveLen(3))
+		Expect(diffs[0].Action).To(Equal(UNCHANGED))
+		Expect(diffs[1].Action).To(Equal(UPDATE))
+		Expect(diffs[1].Diff).To(ContainSubstring("8.8.8.8/32"))
+		Expect(diffs[2]).To(Equal(PolicyDiff{Name: "manual", Namespace: "shop", Action: UNMANAGED}))
+
+		Expect(DiffPolicies(nil, generated)[0].Action).To(Equal(CREATE))
+	})
+})
+
+var _ = Describe("lineDiff", func() {
+	It("Marks added and removed lines", func() {
+		Expect(lineDiff("a\nb\nc\n", "a\nc\nd\n")).To(Equal("  a\n- b\n  c\n+ d\n"))
+	})
+})
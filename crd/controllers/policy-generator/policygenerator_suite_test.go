@@ -0,0 +1,13 @@
+package policygenerator
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestPolicyGenerator(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Policy generator")
+}
@@ -0,0 +1,23 @@
+package policygenerator
+
+import (
+	"bytes"
+
+	"sigs.k8s.io/yaml"
+)
+
+// RenderYAML renders the objects as a multi-document YAML stream
+func RenderYAML[T any](objects []T) ([]byte, error) {
+	var buffer bytes.Buffer
+	for i, object := range objects {
+		data, err := yaml.Marshal(object)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buffer.WriteString("---\n")
+		}
+		buffer.Write(data)
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,37 @@
+package probequery
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/samber/lo"
+	v1 "kubesonde.io/api/v1"
+)
+
+// SelectFields keeps only the given top-level fields of the output, e.g.
+// `items` or `podNetworkingv2`. No fields means the whole output.
+func SelectFields(output v1.ProbeOutput, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return output, nil
+	}
+	data, err := json.Marshal(output)
+	if err != nil {
+		return nil, err
+	}
+	all := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	for _, field := range fields {
+		if !isField(field) {
+			return nil, fmt.Errorf("unknown field %s", field)
+		}
+	}
+	return lo.PickByKeys(all, fields), nil
+}
+
+func isField(field string) bool {
+	return lo.Contains([]string{
+		"items", "errors", "podNetworking", "podNetworkingv2", "podConfigurationNetworking", "start", "end", "skipped",
+	}, field)
+}
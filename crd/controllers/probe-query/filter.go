@@ -0,0 +1,155 @@
+package probequery
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/labels"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/utils"
+)
+
+// Filter selects the probe items matching all the non-empty criteria
+type Filter struct {
+	// Namespace matches the source or the destination namespace
+	Namespace   string
+	Source      string
+	Destination string
+	// SourceLabels and DestinationLabels are label selectors, e.g. `app=db,tier in (web)`
+	SourceLabels      labels.Selector
+	DestinationLabels labels.Selector
+	// Workload matches the deployment or the name of the source or the destination
+	Workload string
+	Port     string
+	Protocol string
+	Verdict  v1.ActionType
+	Type     v1.ProbeOutputItemType
+	// Since and Until bound the probe timestamp, in seconds since the epoch
+	Since int64
+	Until int64
+}
+
+// ParseFilter reads a Filter from the query parameters `namespace`, `source`,
+// `destination`, `sourceLabels`, `destinationLabels`, `workload`, `port`,
+// `protocol`, `verdict`, `type`, `since` and `until`. Timestamps are either
+// seconds since the epoch or RFC 3339 dates.
+func ParseFilter(query url.Values) (Filter, error) {
+	filter := Filter{
+		Namespace:   query.Get("namespace"),
+		Source:      query.Get("source"),
+		Destination: query.Get("destination"),
+		Workload:    query.Get("workload"),
+		Port:        query.Get("port"),
+		Protocol:    strings.ToUpper(query.Get("protocol")),
+	}
+	var err error
+	if filter.SourceLabels, err = parseSelector(query.Get("sourceLabels")); err != nil {
+		return Filter{}, fmt.Errorf("invalid sourceLabels: %w", err)
+	}
+	if filter.DestinationLabels, err = parseSelector(query.Get("destinationLabels")); err != nil {
+		return Filter{}, fmt.Errorf("invalid destinationLabels: %w", err)
+	}
+	if filter.Port != "" {
+		if _, err := strconv.ParseUint(filter.Port, 10, 16); err != nil {
+			return Filter{}, fmt.Errorf("invalid port %s", filter.Port)
+		}
+	}
+	if verdict := query.Get("verdict"); verdict != "" {
+		match, found := lo.Find([]v1.ActionType{v1.ALLOW, v1.DENY}, func(action v1.ActionType) bool {
+			return strings.EqualFold(string(action), verdict)
+		})
+		if !found {
+			return Filter{}, fmt.Errorf("invalid verdict %s", verdict)
+		}
+		filter.Verdict = match
+	}
+	if itemType := query.Get("type"); itemType != "" {
+		match, found := lo.Find([]v1.ProbeOutputItemType{v1.PROBE, v1.INFO}, func(t v1.ProbeOutputItemType) bool {
+			return strings.EqualFold(string(t), itemType)
+		})
+		if !found {
+			return Filter{}, fmt.Errorf("invalid type %s", itemType)
+		}
+		filter.Type = match
+	}
+	if filter.Since, err = parseTime(query.Get("since")); err != nil {
+		return Filter{}, fmt.Errorf("invalid since: %w", err)
+	}
+	if filter.Until, err = parseTime(query.Get("until")); err != nil {
+		return Filter{}, fmt.Errorf("invalid until: %w", err)
+	}
+	return filter, nil
+}
+
+func parseSelector(selector string) (labels.Selector, error) {
+	if selector == "" {
+		return nil, nil
+	}
+	return labels.Parse(selector)
+}
+
+func parseTime(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return seconds, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
+}
+
+func matchesLabels(selector labels.Selector, endpoint v1.ProbeEndpointInfo) bool {
+	return selector == nil || selector.Matches(labels.Set(utils.StringToMap(endpoint.Labels)))
+}
+
+func matchesWorkload(workload string, endpoint v1.ProbeEndpointInfo) bool {
+	return endpoint.DeploymentName == workload || endpoint.Name == workload
+}
+
+// Matches reports whether the item satisfies the filter
+func (f Filter) Matches(item v1.ProbeOutputItem) bool {
+	switch {
+	case f.Namespace != "" && item.Source.Namespace != f.Namespace && item.Destination.Namespace != f.Namespace:
+		return false
+	case f.Source != "" && item.Source.Name != f.Source:
+		return false
+	case f.Destination != "" && item.Destination.Name != f.Destination:
+		return false
+	case !matchesLabels(f.SourceLabels, item.Source) || !matchesLabels(f.DestinationLabels, item.Destination):
+		return false
+	case f.Workload != "" && !matchesWorkload(f.Workload, item.Source) && !matchesWorkload(f.Workload, item.Destination):
+		return false
+	case f.Port != "" && item.Port != f.Port:
+		return false
+	case f.Protocol != "" && !strings.EqualFold(item.Protocol, f.Protocol):
+		return false
+	case f.Verdict != "" && item.ResultingAction != f.Verdict:
+		return false
+	case f.Type != "" && item.Type != f.Type:
+		return false
+	case f.Since != 0 && item.Timestamp < f.Since:
+		return false
+	case f.Until != 0 && item.Timestamp > f.Until:
+		return false
+	}
+	return true
+}
+
+// Apply returns a copy of the output keeping only the matching items and errors
+func (f Filter) Apply(output v1.ProbeOutput) v1.ProbeOutput {
+	output.Items = lo.Filter(output.Items, func(item v1.ProbeOutputItem, _ int) bool {
+		return f.Matches(item)
+	})
+	output.Errors = lo.Filter(output.Errors, func(e v1.ProbeOutputError, _ int) bool {
+		return f.Matches(e.Value)
+	})
+	return output
+}
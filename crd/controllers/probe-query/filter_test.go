@@ -0,0 +1,118 @@
+package probequery
+
+import (
+	"net/url"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "kubesonde.io/api/v1"
+)
+
+var (
+	frontend = v1.ProbeEndpointInfo{Type: v1.POD, Name: "frontend-1", Namespace: "shop", DeploymentName: "frontend", Labels: "app=frontend;tier=web;"}
+	database = v1.ProbeEndpointInfo{Type: v1.POD, Name: "db-0", Namespace: "data", Labels: "app=db;"}
+	item     = v1.ProbeOutputItem{
+		Type:            v1.PROBE,
+		ResultingAction: v1.ALLOW,
+		Source:          frontend,
+		Destination:     database,
+		Port:            "5432",
+		Protocol:        "TCP",
+		Timestamp:       1700000000,
+	}
+)
+
+func matches(query string) bool {
+	values, err := url.ParseQuery(query)
+	Expect(err).To(BeNil())
+	filter, err := ParseFilter(values)
+	Expect(err).To(BeNil())
+	return filter.Matches(item)
+}
+
+var _ = Describe("Filter", func() {
+	DescribeTable("Matches", func(query string, expected bool) {
+		Expect(matches(query)).To(Equal(expected))
+	},
+		Entry("empty filter", "", true),
+		Entry("source namespace", "namespace=shop", true),
+		Entry("destination namespace", "namespace=data", true),
+		Entry("other namespace", "namespace=default", false),
+		Entry("source name", "source=frontend-1", true),
+		Entry("destination name", "destination=frontend-1", false),
+		Entry("source labels", "sourceLabels=app%3Dfrontend,tier+in+(web,api)", true),
+		Entry("destination labels", "destinationLabels=app%3Dfrontend", false),
+		Entry("workload by deployment", "workload=frontend", true),
+		Entry("workload by name", "workload=db-0", true),
+		Entry("port", "port=5432&protocol=tcp", true),
+		Entry("other port", "port=80", false),
+		Entry("verdict", "verdict=allow", true),
+		Entry("other verdict", "verdict=Deny", false),
+		Entry("type", "type=information", false),
+		Entry("time window", "since=1600000000&until=2023-11-15T00:00:00Z", true),
+		Entry("time window in the past", "until=1600000000", false),
+	)
+
+	It("Rejects invalid parameters", func() {
+		for _, query := range []string{"port=http", "verdict=maybe", "type=other", "since=yesterday", "sourceLabels=a%3D%3D%3Db"} {
+			values, _ := url.ParseQuery(query)
+			_, err := ParseFilter(values)
+			Expect(err).NotTo(BeNil(), query)
+		}
+	})
+
+	It("Filters items and errors", func() {
+		denied := item
+		denied.ResultingAction = v1.DENY
+		filter := Filter{Verdict: v1.DENY}
+		output := filter.Apply(v1.ProbeOutput{
+			Items:  []v1.ProbeOutputItem{item, denied},
+			Errors: []v1.ProbeOutputError{{Value: item}, {Value: denied}},
+		})
+		Expect(output.Items).To(Equal([]v1.ProbeOutputItem{denied}))
+		Expect(output.Errors).To(HaveLen(1))
+	})
+})
+
+var _ = Describe("Paginate", func() {
+	items := []int{1, 2, 3, 4, 5}
+
+	It("Walks through the pages", func() {
+		page, next, err := Paginate(items, "", 2)
+		Expect(err).To(BeNil())
+		Expect(page).To(Equal([]int{1, 2}))
+		page, next, err = Paginate(items, next, 2)
+		Expect(err).To(BeNil())
+		Expect(page).To(Equal([]int{3, 4}))
+		page, next, err = Paginate(items, next, 2)
+		Expect(err).To(BeNil())
+		Expect(page).To(Equal([]int{5}))
+		Expect(next).To(BeEmpty())
+	})
+
+	It("Returns everything without a limit", func() {
+		page, next, err := Paginate(items, "", 0)
+		Expect(err).To(BeNil())
+		Expect(page).To(Equal(items))
+		Expect(next).To(BeEmpty())
+	})
+
+	It("Rejects invalid cursors", func() {
+		_, _, err := Paginate(items, "garbage", 2)
+		Expect(err).NotTo(BeNil())
+	})
+})
+
+var _ = Describe("SelectFields", func() {
+	It("Keeps only the selected fields", func() {
+		selected, err := SelectFields(v1.ProbeOutput{Items: []v1.ProbeOutputItem{item}}, []string{"items"})
+		Expect(err).To(BeNil())
+		Expect(selected).To(HaveLen(1))
+		Expect(selected).To(HaveKey("items"))
+	})
+
+	It("Rejects unknown fields", func() {
+		_, err := SelectFields(v1.ProbeOutput{}, []string{"secrets"})
+		Expect(err).NotTo(BeNil())
+	})
+})
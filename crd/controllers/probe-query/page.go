@@ -0,0 +1,50 @@
+package probequery
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CURSOR_PREFIX versions the cursor format
+const CURSOR_PREFIX = "v1:"
+
+// EncodeCursor returns an opaque cursor pointing at the given offset
+func EncodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(CURSOR_PREFIX + strconv.Itoa(offset)))
+}
+
+// DecodeCursor returns the offset of a cursor, the empty cursor is the first page
+func DecodeCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(data), CURSOR_PREFIX) {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(data), CURSOR_PREFIX))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	return offset, nil
+}
+
+// Paginate returns up to limit items starting at the cursor and the cursor of
+// the next page, empty on the last page. A limit of zero disables pagination.
+// Probes are only ever appended to the state, so cursors stay valid while new
+// probes arrive.
+func Paginate[T any](items []T, cursor string, limit int) ([]T, string, error) {
+	offset, err := DecodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	if offset > len(items) {
+		offset = len(items)
+	}
+	if limit <= 0 || offset+limit >= len(items) {
+		return items[offset:], "", nil
+	}
+	return items[offset : offset+limit], EncodeCursor(offset + limit), nil
+}
@@ -0,0 +1,13 @@
+package probequery
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestProbeQuery(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Probe query")
+}
@@ -1,10 +1,16 @@
 package probe_command
 
-import v1 "kubesonde.io/api/v1"
+import (
+	"strings"
+
+	v1 "kubesonde.io/api/v1"
+)
 
 type KubesondeCommand struct {
-	Action               v1.ActionType
-	Command              string               `json:"command"`
+	Action  v1.ActionType
+	Command string `json:"command"`
+	// Argv is the exec argv of the command, Command split on spaces when empty
+	Argv                 []string             `json:"argv,omitempty"`
 	ContainerName        string               `json:"ContainerName"`
 	ProbeChecker         func(string) bool    `json:"checker"`
 	Namespace            string               `json:"sourceNamespace"`
@@ -22,6 +28,14 @@ type KubesondeCommand struct {
 	SourceLabels         string               `json:"sourceLabels"`
 }
 
+// Args returns the exec argv of the command
+func (item KubesondeCommand) Args() []string {
+	if len(item.Argv) > 0 {
+		return item.Argv
+	}
+	return strings.Fields(item.Command)
+}
+
 type ComparableKubesondeCommand struct {
 	Command              string               `json:"command"`
 	ContainerName        string               `json:"ContainerName"`
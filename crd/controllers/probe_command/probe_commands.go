@@ -138,9 +138,59 @@ func getAllPortsAndProtocolsFromPodSelector(pod v1.Pod) []PortAndProtocol {
 	return portProto
 }
 
-func generateNmapCommand(cmd string, ip string, port int32) string {
-	command := fmt.Sprintf(cmd, port, ip)
-	return command
+// generateNmapCommand returns the argv scanning the port of the address. The
+// address is a single argument, it cannot add options to nmap.
+func generateNmapCommand(cmd string, ip string, port int32) []string {
+	return append(strings.Fields(fmt.Sprintf(cmd, port, "")), ip)
+}
+
+// RawSockets tells whether the debugger container of the pod can open raw
+// sockets, as nmap needs for the UDP, SCTP and SYN scans. Pods without
+// debugger yet are assumed to.
+func RawSockets(pod v1.Pod) bool {
+	debugger, found := lo.Find(pod.Spec.EphemeralContainers, func(container v1.EphemeralContainer) bool {
+		return container.Name == "debugger"
+	})
+	if !found || debugger.SecurityContext == nil {
+		return true
+	}
+	security := debugger.SecurityContext
+	if security.Privileged != nil && *security.Privileged {
+		return true
+	}
+	if (security.RunAsNonRoot != nil && *security.RunAsNonRoot) || (security.RunAsUser != nil && *security.RunAsUser != 0) {
+		return false
+	}
+	if security.Capabilities == nil || lo.Contains(security.Capabilities.Add, "NET_RAW") {
+		return true
+	}
+	return !lo.Contains(security.Capabilities.Drop, "ALL") && !lo.Contains(security.Capabilities.Drop, "NET_RAW")
+}
+
+// NO_RAW_SOCKETS is the reason recorded for the pods whose debugger cannot
+// open raw sockets: their UDP and SCTP probes are skipped
+const NO_RAW_SOCKETS = "NoRawSockets"
+
+// Scannable tells whether the debugger of the source can probe the protocol.
+// The UDP and SCTP scans of nmap need raw sockets.
+func Scannable(source v1.Pod, protocol string) bool {
+	return RawSockets(source) || (protocol != "UDP" && protocol != "SCTP")
+}
+
+// nmapCommandFor scans the port of the protocol. Without raw sockets the
+// probes of other protocols than UDP and SCTP fall back to a TCP connect scan,
+// the UDP and SCTP probes are not built.
+func nmapCommandFor(source v1.Pod, protocol string, ip string, port int32) []string {
+	switch protocol {
+	case "TCP":
+		return generateNmapCommand(nmapTCPCommand, ip, port)
+	case "UDP":
+		return generateNmapCommand(nmapUDPCommand, ip, port)
+	case "SCTP":
+		return generateNmapCommand(nmapSCTPCommand, ip, port)
+	default:
+		return generateNmapCommand(lo.Ternary(RawSockets(source), nmapCommand, nmapTCPCommand), ip, port)
+	}
 }
 
 func buildServiceCommand(source v1.Pod, dest v1.Service, port int32, protocol string, destType v12.ProbeEndpointType, srcType v12.ProbeEndpointType) KubesondeCommand {
@@ -158,22 +208,13 @@ func buildServiceCommand(source v1.Pod, dest v1.Service, port int32, protocol st
 		addresses = []string{}
 	}
 
-	var cmd string
-	switch protocol {
-	case "TCP":
-		cmd = generateNmapCommand(nmapTCPCommand, destinationAddressForService, port)
-	case "UDP":
-		cmd = generateNmapCommand(nmapUDPCommand, destinationAddressForService, port)
-	case "SCTP":
-		cmd = generateNmapCommand(nmapSCTPCommand, destinationAddressForService, port)
-	default:
-		cmd = generateNmapCommand(nmapCommand, destinationAddressForService, port)
-	}
+	argv := nmapCommandFor(source, protocol, destinationAddressForService, port)
 	return KubesondeCommand{
 		Action:               v12.DENY,
 		ContainerName:        "debugger",
 		Namespace:            namespace,
-		Command:              cmd,
+		Command:              strings.Join(argv, " "),
+		Argv:                 argv,
 		Protocol:             protocol,
 		Destination:          dest.Name,
 		DestinationPort:      strconv.Itoa(int(port)),
@@ -197,22 +238,13 @@ func buildCommand(source v1.Pod, dest v1.Pod, port int32, protocol string, destT
 		addresses = []string{}
 	}
 
-	var cmd string
-	switch protocol {
-	case "TCP":
-		cmd = generateNmapCommand(nmapTCPCommand, dest.Status.PodIP, port)
-	case "UDP":
-		cmd = generateNmapCommand(nmapUDPCommand, dest.Status.PodIP, port)
-	case "SCTP":
-		cmd = generateNmapCommand(nmapSCTPCommand, dest.Status.PodIP, port)
-	default:
-		cmd = generateNmapCommand(nmapCommand, dest.Status.PodIP, port)
-	}
+	argv := nmapCommandFor(source, protocol, dest.Status.PodIP, port)
 	return KubesondeCommand{
 		Action:               v12.DENY,
 		ContainerName:        "debugger",
 		Namespace:            namespace,
-		Command:              cmd,
+		Command:              strings.Join(argv, " "),
+		Argv:                 argv,
 		Protocol:             protocol,
 		Destination:          dest.Name,
 		DestinationPort:      strconv.Itoa(int(port)),
@@ -243,23 +275,14 @@ func buildCommandBase(source v1.Pod,
 		addresses = []string{}
 	}
 
-	var cmd string
-	switch protocol {
-	case "TCP":
-		cmd = generateNmapCommand(nmapTCPCommand, destIP, destPort)
-	case "UDP":
-		cmd = generateNmapCommand(nmapUDPCommand, destIP, destPort)
-	case "SCTP":
-		cmd = generateNmapCommand(nmapSCTPCommand, destIP, destPort)
-	default:
-		cmd = generateNmapCommand(nmapCommand, destIP, destPort)
-	}
+	argv := nmapCommandFor(source, protocol, destIP, destPort)
 
 	return KubesondeCommand{
 		Action:               v12.DENY,
 		ContainerName:        "debugger",
 		Namespace:            namespace,
-		Command:              cmd,
+		Command:              strings.Join(argv, " "),
+		Argv:                 argv,
 		Protocol:             protocol,
 		Destination:          dest,
 		DestinationPort:      strconv.Itoa(int(destPort)),
@@ -303,7 +326,9 @@ func BuildCommandsToServices(pod v1.Pod, services []v1.Service) []KubesondeComma
 	for _, destination := range services {
 		if destination.Name != "kubernetes" {
 			for _, portProto := range getAllPortsAndProtocolsFromService(destination) {
-				commands = append(commands, buildServiceCommand(source, destination, portProto.port, portProto.protocol, v12.SERVICE, v12.POD))
+				if Scannable(source, portProto.protocol) {
+					commands = append(commands, buildServiceCommand(source, destination, portProto.port, portProto.protocol, v12.SERVICE, v12.POD))
+				}
 			}
 
 		}
@@ -318,7 +343,9 @@ func BuildCommandsFromPodSelectors(pods []v1.Pod, _ string) []KubesondeCommand {
 		for _, destination := range pods {
 			if !cmp.Equal(destination, source) {
 				for _, portProto := range getAllPortsAndProtocolsFromPodSelector(destination) {
-					commands = append(commands, buildCommand(source, destination, portProto.port, portProto.protocol, v12.POD, v12.POD))
+					if Scannable(source, portProto.protocol) {
+						commands = append(commands, buildCommand(source, destination, portProto.port, portProto.protocol, v12.POD, v12.POD))
+					}
 
 				}
 
@@ -453,10 +480,14 @@ func BuildTargetedCommands(target v1.Pod, availablePods []v1.Pod) []KubesondeCom
 
 	for _, source := range availablePods {
 		for _, sourcePortProto := range getAllPortsAndProtocolsFromPodSelector(source) {
-			commands = append(commands, buildCommand(target, source, sourcePortProto.port, sourcePortProto.protocol, v12.POD, v12.POD))
+			if Scannable(target, sourcePortProto.protocol) {
+				commands = append(commands, buildCommand(target, source, sourcePortProto.port, sourcePortProto.protocol, v12.POD, v12.POD))
+			}
 		}
 		for _, targetPortProto := range targetPortsProto {
-			commands = append(commands, buildCommand(source, target, targetPortProto.port, targetPortProto.protocol, v12.POD, v12.POD))
+			if Scannable(source, targetPortProto.protocol) {
+				commands = append(commands, buildCommand(source, target, targetPortProto.port, targetPortProto.protocol, v12.POD, v12.POD))
+			}
 		}
 		other_commands := BuildCommandsToOutsideWorld(source)
 		commands = append(commands, other_commands...)
@@ -468,6 +499,27 @@ func BuildTargetedCommands(target v1.Pod, availablePods []v1.Pod) []KubesondeCom
 	return commands
 }
 
+// Creates probe commands from each source to the ports of each destination. The
+// destinations are not probe sources themselves.
+func BuildCommandsBetween(sources []v1.Pod, destinations []v1.Pod) []KubesondeCommand {
+	var commands []KubesondeCommand
+
+	for _, source := range sources {
+		for _, destination := range destinations {
+			if source.Namespace == destination.Namespace && source.Name == destination.Name {
+				continue
+			}
+			for _, portProto := range getAllPortsAndProtocolsFromPodSelector(destination) {
+				if Scannable(source, portProto.protocol) {
+					commands = append(commands, buildCommand(source, destination, portProto.port, portProto.protocol, v12.POD, v12.POD))
+				}
+			}
+		}
+	}
+
+	return commands
+}
+
 // Creates probe commends where target is the target of the probe and each available pod
 // is the source. The specified ports will be used as destination ports
 func BuildTargetedCommandsToDestination(availablePods []v1.Pod, probeDestination v1.Pod, probeDestinationPorts []int32, protocol []string) []KubesondeCommand {
@@ -476,7 +528,9 @@ func BuildTargetedCommandsToDestination(availablePods []v1.Pod, probeDestination
 	for _, source := range availablePods {
 		for idx := range probeDestinationPorts {
 			if source.Name != probeDestination.Name {
-				commands = append(commands, buildCommand(source, probeDestination, probeDestinationPorts[idx], protocol[idx], v12.POD, v12.POD))
+				if Scannable(source, protocol[idx]) {
+					commands = append(commands, buildCommand(source, probeDestination, probeDestinationPorts[idx], protocol[idx], v12.POD, v12.POD))
+				}
 			}
 		}
 	}
@@ -490,11 +544,26 @@ func BuildCommandFromService(availablePods []v1.Pod, probeDestination v1.Service
 
 	for _, source := range availablePods {
 		for _, port := range probeDestination.Spec.Ports {
-			commands = append(commands, buildCommandBase(source, probeDestination.Name, probeDestination.Namespace, probeDestination.Spec.ClusterIP, port.Port, "TCP", v12.SERVICE, v12.POD))
-			commands = append(commands, buildCommandBase(source, probeDestination.Name, probeDestination.Namespace, probeDestination.Spec.ClusterIP, port.Port, "UDP", v12.SERVICE, v12.POD))
-			commands = append(commands, buildCommandBase(source, probeDestination.Name, probeDestination.Namespace, probeDestination.Spec.ClusterIP, port.Port, "SCTP", v12.SERVICE, v12.POD))
+			for _, protocol := range lo.Filter([]string{"TCP", "UDP", "SCTP"}, func(protocol string, _ int) bool { return Scannable(source, protocol) }) {
+				commands = append(commands, buildCommandBase(source, probeDestination.Name, probeDestination.Namespace, probeDestination.Spec.ClusterIP, port.Port, protocol, v12.SERVICE, v12.POD))
+			}
 		}
 	}
 
 	return commands
 }
+
+// BuildCommandToPod creates a single probe from a pod to another pod
+func BuildCommandToPod(source v1.Pod, destination v1.Pod, port int32, protocol string) KubesondeCommand {
+	return buildCommand(source, destination, port, protocol, v12.POD, v12.POD)
+}
+
+// BuildCommandToService creates a single probe from a pod to a service
+func BuildCommandToService(source v1.Pod, destination v1.Service, port int32, protocol string) KubesondeCommand {
+	return buildServiceCommand(source, destination, port, protocol, v12.SERVICE, v12.POD)
+}
+
+// BuildCommandToAddress creates a single probe from a pod to an address outside the cluster
+func BuildCommandToAddress(source v1.Pod, address string, port int32, protocol string) KubesondeCommand {
+	return buildCommandBase(source, address, "", address, port, protocol, v12.INTERNET, v12.POD)
+}
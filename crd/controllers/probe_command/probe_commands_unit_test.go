@@ -2,12 +2,14 @@ package probe_command
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/samber/lo"
 	. "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestPodsController(t *testing.T) {
@@ -133,3 +135,61 @@ var _ = Describe("Build targeted commands from pod", func() {
 		Expect(len(output)).To(Equal(15))
 	})
 })
+
+var _ = Describe("Build commands between pods", func() {
+	It("Probes only the ports of the destinations", func() {
+		source := buildTestPod([]Container{buildContainers([]int32{80})}, "10.0.0.1")
+		source.Name = "source"
+		destination := buildTestPod([]Container{buildContainers([]int32{8080, 9090})}, "10.0.0.2")
+		destination.Name = "destination"
+
+		output := BuildCommandsBetween([]Pod{source, destination}, []Pod{destination})
+		Expect(output).To(HaveLen(2))
+		for _, command := range output {
+			Expect(command.SourcePodName).To(Equal("source"))
+			Expect(command.Destination).To(Equal("destination"))
+		}
+	})
+})
+
+var _ = Describe("RawSockets", func() {
+	withDebugger := func(security *SecurityContext) Pod {
+		return Pod{Spec: PodSpec{EphemeralContainers: []EphemeralContainer{{
+			EphemeralContainerCommon: EphemeralContainerCommon{Name: "debugger", SecurityContext: security},
+		}}}}
+	}
+
+	It("Assumes raw sockets before the debugger is installed", func() {
+		Expect(RawSockets(Pod{})).To(BeTrue())
+		Expect(RawSockets(withDebugger(nil))).To(BeTrue())
+	})
+
+	It("Reads the capabilities of the debugger", func() {
+		Expect(RawSockets(withDebugger(&SecurityContext{Privileged: lo.ToPtr(true)}))).To(BeTrue())
+		Expect(RawSockets(withDebugger(&SecurityContext{Capabilities: &Capabilities{Drop: []Capability{"ALL"}, Add: []Capability{"NET_RAW"}}}))).To(BeTrue())
+		Expect(RawSockets(withDebugger(&SecurityContext{Capabilities: &Capabilities{Drop: []Capability{"ALL"}}}))).To(BeFalse())
+		Expect(RawSockets(withDebugger(&SecurityContext{RunAsNonRoot: lo.ToPtr(true), Capabilities: &Capabilities{Add: []Capability{"NET_RAW"}}}))).To(BeFalse())
+	})
+
+	It("Falls back to a connect scan without raw sockets", func() {
+		restricted := withDebugger(&SecurityContext{RunAsNonRoot: lo.ToPtr(true), Capabilities: &Capabilities{Drop: []Capability{"ALL"}}})
+		Expect(strings.Join(nmapCommandFor(restricted, "", "10.0.0.1", 80), " ")).To(Equal("nmap --open --version-intensity=0 --max-retries=3 -T5 -n -sT -Pn -p 80 10.0.0.1"))
+		Expect(strings.Join(nmapCommandFor(Pod{}, "", "10.0.0.1", 80), " ")).To(Equal("nmap --open --version-intensity=0 --max-retries=3 -T5 -n -sSU -p 80 10.0.0.1"))
+		Expect(strings.Join(nmapCommandFor(restricted, "UDP", "10.0.0.1", 53), " ")).To(Equal("nmap --open --version-intensity=0 --max-retries=3 -T5 -n -sU -p 53 10.0.0.1"))
+	})
+
+	It("Skips the UDP and SCTP probes without raw sockets", func() {
+		restricted := withDebugger(&SecurityContext{RunAsNonRoot: lo.ToPtr(true), Capabilities: &Capabilities{Drop: []Capability{"ALL"}}})
+		restricted.Name = "restricted"
+		destination := Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "dns"},
+			Spec: PodSpec{Containers: []Container{{Ports: []ContainerPort{
+				{ContainerPort: 53, Protocol: ProtocolUDP},
+				{ContainerPort: 53, Protocol: ProtocolTCP},
+			}}}},
+		}
+		commands := BuildCommandsBetween([]Pod{restricted}, []Pod{destination})
+		Expect(lo.Map(commands, func(command KubesondeCommand, _ int) string { return command.Protocol })).To(Equal([]string{"TCP"}))
+		Expect(BuildCommandsBetween([]Pod{{ObjectMeta: metav1.ObjectMeta{Name: "web"}}}, []Pod{destination})).To(HaveLen(2))
+	})
+})
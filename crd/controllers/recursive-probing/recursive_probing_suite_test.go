@@ -0,0 +1,13 @@
+package recursiveprobing
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestRecursiveProbing(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Recursive probing")
+}
@@ -0,0 +1,65 @@
+package recursiveprobing
+
+import (
+	"fmt"
+
+	"github.com/samber/lo"
+	kubesondev1 "kubesonde.io/api/v1"
+	kubesondeDispatcher "kubesonde.io/controllers/dispatcher"
+	eventstorage "kubesonde.io/controllers/event-storage"
+	"kubesonde.io/controllers/probe_command"
+	"kubesonde.io/pkg/apitypes"
+)
+
+// Scope restricts a round to the probes whose source or destination matches
+// all the fields set. The zero value selects all the probes.
+type Scope = apitypes.Scope
+
+type endpoint struct {
+	name      string
+	namespace string
+	isPod     bool
+}
+
+func workloadOf(pod string) string {
+	record := eventstorage.GetActivePodByName(pod)
+	return lo.CoalesceOrEmpty(record.DeploymentName, record.ReplicaSetName, pod)
+}
+
+func matchesEndpoint(s Scope, e endpoint) bool {
+	if s.Namespace != "" && e.namespace != s.Namespace {
+		return false
+	}
+	if s.Pod != "" && (!e.isPod || e.name != s.Pod) {
+		return false
+	}
+	if s.Workload != "" && (!e.isPod || workloadOf(e.name) != s.Workload) {
+		return false
+	}
+	return true
+}
+
+// Matches reports whether the source or the destination of the probe is in the scope
+func Matches(s Scope, command probe_command.KubesondeCommand) bool {
+	source := endpoint{name: command.SourcePodName, namespace: command.Namespace, isPod: command.SourceType != kubesondev1.INTERNET}
+	destination := endpoint{
+		name:      command.Destination,
+		namespace: lo.CoalesceOrEmpty(command.DestinationNamespace, command.Namespace),
+		isPod:     command.DestinationType == kubesondev1.POD,
+	}
+	return matchesEndpoint(s, source) || matchesEndpoint(s, destination)
+}
+
+// RunScopedProbing queues the known probes of the scope ahead of the running
+// round and returns how many were queued
+func RunScopedProbing(scope Scope) int {
+	probes := lo.Filter(eventstorage.GetProbes(), func(command probe_command.KubesondeCommand, _ int) bool {
+		return Matches(scope, command)
+	})
+	if len(probes) == 0 {
+		return 0
+	}
+	log.Info(fmt.Sprintf("Running %d probes on demand", len(probes)), "scope", scope)
+	kubesondeDispatcher.SendToQueue(probes, kubesondeDispatcher.HIGH)
+	return len(probes)
+}
@@ -0,0 +1,54 @@
+package recursiveprobing
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	kubesondev1 "kubesonde.io/api/v1"
+	eventstorage "kubesonde.io/controllers/event-storage"
+	"kubesonde.io/controllers/probe_command"
+)
+
+var _ = Describe("Scope", func() {
+	toService := probe_command.KubesondeCommand{
+		SourcePodName:        "web-1",
+		SourceType:           kubesondev1.POD,
+		Namespace:            "shop",
+		Destination:          "db",
+		DestinationNamespace: "data",
+		DestinationType:      kubesondev1.SERVICE,
+	}
+	toPod := probe_command.KubesondeCommand{
+		SourcePodName:        "cron",
+		SourceType:           kubesondev1.POD,
+		Namespace:            "data",
+		Destination:          "web-1",
+		DestinationNamespace: "shop",
+		DestinationType:      kubesondev1.POD,
+	}
+
+	BeforeEach(func() {
+		eventstorage.AddActivePod("web-1", eventstorage.CreatedPodRecord{DeploymentName: "web"})
+	})
+
+	AfterEach(func() {
+		eventstorage.ClearEventStorage()
+	})
+
+	It("Selects all the probes by default", func() {
+		Expect(Matches(Scope{}, toService)).To(BeTrue())
+		Expect(Matches(Scope{}, toPod)).To(BeTrue())
+	})
+
+	It("Matches the source or the destination", func() {
+		Expect(Matches(Scope{Namespace: "data"}, toService)).To(BeTrue())
+		Expect(Matches(Scope{Pod: "web-1"}, toPod)).To(BeTrue())
+		Expect(Matches(Scope{Workload: "web"}, toService)).To(BeTrue())
+		Expect(Matches(Scope{Workload: "web"}, toPod)).To(BeTrue())
+		Expect(Matches(Scope{Pod: "db"}, toService)).To(BeFalse())
+	})
+
+	It("Requires all the fields to match the same endpoint", func() {
+		Expect(Matches(Scope{Namespace: "shop", Pod: "web-1"}, toPod)).To(BeTrue())
+		Expect(Matches(Scope{Namespace: "data", Workload: "web"}, toPod)).To(BeFalse())
+	})
+})
@@ -0,0 +1,180 @@
+// The report module evaluates probe outputs against the expected actions and
+// a baseline, and writes the outcome for CI systems
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/samber/lo"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/baseline"
+)
+
+const (
+	EXPECTED_ACTION_SUITE = "expected actions"
+	BASELINE_SUITE        = "baseline"
+)
+
+// TestCase is an asserted edge, it fails when Failure is set
+type TestCase struct {
+	Suite string `json:"suite"`
+	// Classname is the source of the edge
+	Classname string `json:"classname"`
+	Name      string `json:"name"`
+	Failure   string `json:"failure,omitempty"`
+}
+
+func (c TestCase) Failed() bool {
+	return c.Failure != ""
+}
+
+// Failures returns the failed test cases
+func Failures(cases []TestCase) []TestCase {
+	return lo.Filter(cases, func(c TestCase, _ int) bool { return c.Failed() })
+}
+
+func formatInfo(info v1.ProbeEndpointInfo) string {
+	name := lo.CoalesceOrEmpty(info.Name, info.IPAddress)
+	if info.Namespace == "" {
+		return name
+	}
+	return info.Namespace + "/" + name
+}
+
+func edgeName(destination string, port string, protocol string) string {
+	return fmt.Sprintf("%s %s/%s", destination, port, lo.CoalesceOrEmpty(protocol, "TCP"))
+}
+
+type probeKey struct {
+	source      string
+	destination string
+	port        string
+	protocol    string
+}
+
+// latestExpected returns the latest probe of each probed edge with an
+// expected action
+func latestExpected(output v1.ProbeOutput) map[probeKey]v1.ProbeOutputItem {
+	latest := map[probeKey]v1.ProbeOutputItem{}
+	for _, item := range output.Items {
+		if item.Type != v1.PROBE || item.ExpectedAction == "" {
+			continue
+		}
+		key := probeKey{formatInfo(item.Source), formatInfo(item.Destination), item.Port, item.Protocol}
+		if previous, found := latest[key]; !found || item.Timestamp >= previous.Timestamp {
+			latest[key] = item
+		}
+	}
+	return latest
+}
+
+// ExpectedActionCases returns one test case per probed edge with an expected
+// action. Edges probed several times are evaluated on their latest probe.
+func ExpectedActionCases(output v1.ProbeOutput) []TestCase {
+	latest := latestExpected(output)
+	cases := lo.MapToSlice(latest, func(key probeKey, item v1.ProbeOutputItem) TestCase {
+		testCase := TestCase{
+			Suite:     EXPECTED_ACTION_SUITE,
+			Classname: key.source,
+			Name:      fmt.Sprintf("%s is %s", edgeName(key.destination, key.port, key.protocol), item.ExpectedAction),
+		}
+		if item.ResultingAction != item.ExpectedAction {
+			testCase.Failure = fmt.Sprintf("expected %s, got %s", item.ExpectedAction, lo.CoalesceOrEmpty(item.ResultingAction, "no result"))
+		}
+		return testCase
+	})
+	sortCases(cases)
+	return cases
+}
+
+// BaselineCases compares the workload edges of the output to the baseline.
+// The allowed edges missing from the baseline fail, and so do the edges of the
+// baseline that are denied or were not probed. A single passing case is
+// returned when nothing drifted.
+func BaselineCases(approved baseline.Baseline, output v1.ProbeOutput) []TestCase {
+	cases := lo.Map(baseline.Compare(approved, output).Edges(), func(edge baseline.DriftEdge, _ int) TestCase {
+		failure := "allowed, not in the baseline"
+		if edge.Kind == baseline.MISSING {
+			failure = lo.Ternary(edge.Action == "", "allowed in the baseline, not probed", "denied, allowed in the baseline")
+		}
+		return TestCase{Suite: BASELINE_SUITE, Classname: edge.From, Name: edgeName(edge.To, edge.Port, edge.Protocol), Failure: failure}
+	})
+	if len(cases) == 0 {
+		return []TestCase{{Suite: BASELINE_SUITE, Classname: BASELINE_SUITE, Name: "matches the baseline"}}
+	}
+	sortCases(cases)
+	return cases
+}
+
+// Cases returns the expected action cases, followed by the baseline cases
+// when a baseline is given
+func Cases(output v1.ProbeOutput, approved *baseline.Baseline) []TestCase {
+	cases := ExpectedActionCases(output)
+	if approved != nil {
+		cases = append(cases, BaselineCases(*approved, output)...)
+	}
+	return cases
+}
+
+func sortCases(cases []TestCase) {
+	sort.SliceStable(cases, func(i, j int) bool {
+		if cases[i].Classname != cases[j].Classname {
+			return cases[i].Classname < cases[j].Classname
+		}
+		return cases[i].Name < cases[j].Name
+	})
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitTestCase struct {
+	Classname string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+// WriteJUnit writes the test cases as JUnit XML, one test suite per Suite
+func WriteJUnit(w io.Writer, name string, cases []TestCase) error {
+	suites := junitTestSuites{Name: name, Tests: len(cases), Failures: len(Failures(cases)), TestSuites: []junitTestSuite{}}
+	for _, group := range lo.PartitionBy(cases, func(c TestCase) string { return c.Suite }) {
+		suite := junitTestSuite{Name: group[0].Suite, Tests: len(group), Failures: len(Failures(group))}
+		for _, c := range group {
+			testCase := junitTestCase{Classname: c.Classname, Name: c.Name}
+			if c.Failed() {
+				testCase.Failure = &junitFailure{Message: c.Failure}
+			}
+			suite.TestCases = append(suite.TestCases, testCase)
+		}
+		suites.TestSuites = append(suites.TestSuites, suite)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
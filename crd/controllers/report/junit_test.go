@@ -0,0 +1,77 @@
+package report
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/baseline"
+)
+
+var _ = Describe("Report", func() {
+	pod := func(name string, deployment string) v1.ProbeEndpointInfo {
+		return v1.ProbeEndpointInfo{Type: v1.POD, Name: name, Namespace: "default", DeploymentName: deployment}
+	}
+	probe := func(source v1.ProbeEndpointInfo, destination v1.ProbeEndpointInfo, port string, expected v1.ActionType, action v1.ActionType, timestamp int64) v1.ProbeOutputItem {
+		return v1.ProbeOutputItem{
+			Type: v1.PROBE, Source: source, Destination: destination, Port: port, Protocol: "TCP",
+			ExpectedAction: expected, ResultingAction: action, Timestamp: timestamp,
+		}
+	}
+
+	It("Evaluates the latest probe of each edge with an expected action", func() {
+		output := v1.ProbeOutput{Items: []v1.ProbeOutputItem{
+			probe(pod("a", "web"), pod("b", "db"), "5432", v1.DENY, v1.DENY, 1),
+			probe(pod("a", "web"), pod("b", "db"), "5432", v1.DENY, v1.ALLOW, 2),
+			probe(pod("a", "web"), pod("c", "api"), "80", v1.ALLOW, v1.ALLOW, 1),
+			probe(pod("c", "api"), pod("b", "db"), "5432", "", v1.ALLOW, 1),
+		}}
+
+		Expect(ExpectedActionCases(output)).To(Equal([]TestCase{
+			{Suite: EXPECTED_ACTION_SUITE, Classname: "default/a", Name: "default/b 5432/TCP is Deny", Failure: "expected Deny, got Allow"},
+			{Suite: EXPECTED_ACTION_SUITE, Classname: "default/a", Name: "default/c 80/TCP is Allow"},
+		}))
+	})
+
+	It("Fails on the edges that drifted from the baseline", func() {
+		approved := baseline.Baseline{Edges: []baseline.Edge{
+			{From: "default/web", To: "default/api", Ports: []string{"80/TCP", "8080/TCP"}},
+			{From: "default/api", To: "default/db", Ports: []string{"5432/TCP"}},
+		}}
+		output := v1.ProbeOutput{Items: []v1.ProbeOutputItem{
+			probe(pod("a", "web"), pod("b", "db"), "5432", "", v1.ALLOW, 2),
+			probe(pod("a", "web"), pod("c", "api"), "80", "", v1.ALLOW, 2),
+			probe(pod("a", "web"), pod("c", "api"), "8080", "", v1.DENY, 2),
+		}}
+
+		Expect(BaselineCases(approved, output)).To(Equal([]TestCase{
+			{Suite: BASELINE_SUITE, Classname: "default/api", Name: "default/db 5432/TCP", Failure: "allowed in the baseline, not probed"},
+			{Suite: BASELINE_SUITE, Classname: "default/web", Name: "default/api 8080/TCP", Failure: "denied, allowed in the baseline"},
+			{Suite: BASELINE_SUITE, Classname: "default/web", Name: "default/db 5432/TCP", Failure: "allowed, not in the baseline"},
+		}))
+		Expect(Failures(BaselineCases(baseline.FromOutput(output), output))).To(BeEmpty())
+	})
+
+	It("Writes JUnit XML", func() {
+		var buffer bytes.Buffer
+		Expect(WriteJUnit(&buffer, "kubesonde", []TestCase{
+			{Suite: EXPECTED_ACTION_SUITE, Classname: "default/a", Name: "default/b 80/TCP is Deny", Failure: "expected Deny, got Allow"},
+			{Suite: EXPECTED_ACTION_SUITE, Classname: "default/a", Name: "default/c 80/TCP is Allow"},
+			{Suite: BASELINE_SUITE, Classname: BASELINE_SUITE, Name: "matches the baseline"},
+		})).To(Succeed())
+		Expect(buffer.String()).To(Equal(`<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="kubesonde" tests="3" failures="1">
+  <testsuite name="expected actions" tests="2" failures="1">
+    <testcase classname="default/a" name="default/b 80/TCP is Deny">
+      <failure message="expected Deny, got Allow"></failure>
+    </testcase>
+    <testcase classname="default/a" name="default/c 80/TCP is Allow"></testcase>
+  </testsuite>
+  <testsuite name="baseline" tests="1" failures="0">
+    <testcase classname="baseline" name="matches the baseline"></testcase>
+  </testsuite>
+</testsuites>
+`))
+	})
+})
@@ -0,0 +1,215 @@
+package report
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/analysis"
+	"kubesonde.io/controllers/baseline"
+	"kubesonde.io/pkg/apitypes"
+)
+
+const (
+	SARIF_VERSION = "2.1.0"
+	SARIF_SCHEMA  = "https://json.schemastore.org/sarif-2.1.0.json"
+	// SARIF_FINGERPRINT is the partial fingerprint identifying a finding across scans
+	SARIF_FINGERPRINT = "kubesonde/v1"
+)
+
+// Rule IDs of the findings
+const (
+	INTERNET_EGRESS_RULE         = "internet-egress-allowed"
+	METADATA_REACHABLE_RULE      = "metadata-reachable"
+	UNDECLARED_PORT_RULE         = "undeclared-port"
+	CROSS_NAMESPACE_RULE         = "cross-namespace-reachable"
+	EXPECTED_DENY_VIOLATION_RULE = "expected-deny-violation"
+)
+
+// SARIF levels
+const (
+	ERROR_LEVEL   = "error"
+	WARNING_LEVEL = "warning"
+	NOTE_LEVEL    = "note"
+)
+
+// METADATA_ADDRESSES are the cloud instance metadata services
+var METADATA_ADDRESSES = []string{"169.254.169.254", "fd00:ec2::254", "100.100.100.200", "metadata.google.internal"}
+
+// The SARIF 2.1.0 subset written by Kubesonde is a response of the results server
+type (
+	SarifLog             = apitypes.SarifLog
+	SarifRun             = apitypes.SarifRun
+	SarifTool            = apitypes.SarifTool
+	SarifDriver          = apitypes.SarifDriver
+	SarifMessage         = apitypes.SarifMessage
+	SarifConfiguration   = apitypes.SarifConfiguration
+	SarifRule            = apitypes.SarifRule
+	SarifLogicalLocation = apitypes.SarifLogicalLocation
+	SarifLocation        = apitypes.SarifLocation
+	SarifResult          = apitypes.SarifResult
+)
+
+// RULES are the finding classes, in the order of the rule indexes
+var RULES = []SarifRule{
+	{
+		ID:                   INTERNET_EGRESS_RULE,
+		Name:                 "InternetEgressAllowed",
+		ShortDescription:     SarifMessage{Text: "A workload reaches the Internet"},
+		FullDescription:      SarifMessage{Text: "A probe from the workload to a destination outside the cluster succeeded. Restrict the egress of the workload with a NetworkPolicy when it does not need the Internet."},
+		DefaultConfiguration: SarifConfiguration{Level: WARNING_LEVEL},
+	},
+	{
+		ID:                   METADATA_REACHABLE_RULE,
+		Name:                 "MetadataReachable",
+		ShortDescription:     SarifMessage{Text: "A workload reaches the cloud metadata service"},
+		FullDescription:      SarifMessage{Text: "A probe from the workload to the instance metadata service succeeded. The metadata service may expose the credentials of the node."},
+		DefaultConfiguration: SarifConfiguration{Level: ERROR_LEVEL},
+	},
+	{
+		ID:                   UNDECLARED_PORT_RULE,
+		Name:                 "UndeclaredPort",
+		ShortDescription:     SarifMessage{Text: "A pod listens on a port that is not declared"},
+		FullDescription:      SarifMessage{Text: "The pod listens on a port that is not a container port of its spec. NetworkPolicies and reviews based on the spec miss it."},
+		DefaultConfiguration: SarifConfiguration{Level: WARNING_LEVEL},
+	},
+	{
+		ID:                   CROSS_NAMESPACE_RULE,
+		Name:                 "CrossNamespaceReachable",
+		ShortDescription:     SarifMessage{Text: "A workload reaches another namespace"},
+		FullDescription:      SarifMessage{Text: "A probe from the workload to a pod or a service of another namespace succeeded. Namespaces are not isolated without NetworkPolicies."},
+		DefaultConfiguration: SarifConfiguration{Level: NOTE_LEVEL},
+	},
+	{
+		ID:                   EXPECTED_DENY_VIOLATION_RULE,
+		Name:                 "ExpectedDenyViolation",
+		ShortDescription:     SarifMessage{Text: "A connection expected to be denied is allowed"},
+		FullDescription:      SarifMessage{Text: "The latest probe of a connection included with an expected deny succeeded."},
+		DefaultConfiguration: SarifConfiguration{Level: ERROR_LEVEL},
+	},
+}
+
+func isMetadata(info v1.ProbeEndpointInfo) bool {
+	return lo.Contains(METADATA_ADDRESSES, info.IPAddress) || lo.Contains(METADATA_ADDRESSES, info.Name)
+}
+
+// isClusterAddress matches the in-cluster names probed as external
+// destinations, e.g. the cluster DNS
+func isClusterAddress(info v1.ProbeEndpointInfo) bool {
+	if strings.HasSuffix(info.IPAddress, ".cluster.local") {
+		return true
+	}
+	ip := net.ParseIP(info.IPAddress)
+	return ip != nil && (ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast())
+}
+
+// IsInternet tells whether the endpoint is outside the cluster. The cluster
+// DNS, the private addresses and the cloud metadata service are not.
+func IsInternet(info v1.ProbeEndpointInfo) bool {
+	return info.Type == v1.INTERNET && !isClusterAddress(info) && !isMetadata(info)
+}
+
+func formatDestination(info v1.ProbeEndpointInfo) string {
+	name := baseline.EndpointName(info)
+	if info.Type == v1.INTERNET && info.IPAddress != "" && info.IPAddress != name {
+		return fmt.Sprintf("%s (%s)", name, info.IPAddress)
+	}
+	return name
+}
+
+type finding struct {
+	rule     string
+	location string
+	kind     string
+	message  string
+}
+
+// probeFindings returns the findings of the allowed probes, one per workload
+// edge
+func probeFindings(output v1.ProbeOutput) []finding {
+	findings := []finding{}
+	for _, item := range v1.LatestProbes(output.Items) {
+		if item.Type != v1.PROBE || item.ResultingAction != v1.ALLOW || item.Source.Type != v1.POD {
+			continue
+		}
+		source := baseline.EndpointName(item.Source)
+		port := formatPort(item.Port, item.Protocol)
+		destination := formatDestination(item.Destination)
+		switch {
+		case isMetadata(item.Destination):
+			findings = append(findings, finding{METADATA_REACHABLE_RULE, source, "workload",
+				fmt.Sprintf("%s reaches the cloud metadata service %s on %s", source, destination, port)})
+		case IsInternet(item.Destination):
+			findings = append(findings, finding{INTERNET_EGRESS_RULE, source, "workload",
+				fmt.Sprintf("%s reaches %s on %s", source, destination, port)})
+		case item.Destination.Namespace != "" && item.Source.Namespace != "" && item.Destination.Namespace != item.Source.Namespace:
+			findings = append(findings, finding{CROSS_NAMESPACE_RULE, source, "workload",
+				fmt.Sprintf("%s reaches %s %s on %s", source, strings.ToLower(string(item.Destination.Type)), destination, port)})
+		}
+	}
+	return findings
+}
+
+func formatPort(port string, protocol string) string {
+	return port + "/" + lo.CoalesceOrEmpty(protocol, "TCP")
+}
+
+// Findings returns the SARIF results of the output, sorted by rule and location
+func Findings(output v1.ProbeOutput) []SarifResult {
+	findings := probeFindings(output)
+	for _, port := range analysis.UndeclaredPorts(output) {
+		findings = append(findings, finding{UNDECLARED_PORT_RULE, port.Pod, "pod",
+			fmt.Sprintf("%s listens on %s (%s), which is not declared in the pod spec", port.Pod, formatPort(port.Port, port.Protocol), port.IP)})
+	}
+	for key, item := range latestExpected(output) {
+		if item.ExpectedAction == v1.DENY && item.ResultingAction == v1.ALLOW {
+			findings = append(findings, finding{EXPECTED_DENY_VIOLATION_RULE, key.source, "pod",
+				fmt.Sprintf("%s reaches %s on %s, expected to be denied", key.source, key.destination, formatPort(key.port, key.protocol))})
+		}
+	}
+
+	indexes := map[string]int{}
+	for i, rule := range RULES {
+		indexes[rule.ID] = i
+	}
+	results := lo.Map(lo.Uniq(findings), func(f finding, _ int) SarifResult {
+		name := f.location[strings.LastIndex(f.location, "/")+1:]
+		return SarifResult{
+			RuleID:    f.rule,
+			RuleIndex: indexes[f.rule],
+			Level:     RULES[indexes[f.rule]].DefaultConfiguration.Level,
+			Message:   SarifMessage{Text: f.message},
+			Locations: []SarifLocation{{LogicalLocations: []SarifLogicalLocation{
+				{Name: name, FullyQualifiedName: f.location, Kind: f.kind},
+			}}},
+			PartialFingerprints: map[string]string{SARIF_FINGERPRINT: f.rule + ":" + f.message},
+		}
+	})
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.RuleIndex != b.RuleIndex {
+			return a.RuleIndex < b.RuleIndex
+		}
+		return a.Message.Text < b.Message.Text
+	})
+	return results
+}
+
+// SARIF returns a SARIF log with a run of the findings of the output
+func SARIF(output v1.ProbeOutput) SarifLog {
+	return SarifLog{
+		Version: SARIF_VERSION,
+		Schema:  SARIF_SCHEMA,
+		Runs: []SarifRun{{
+			Tool: SarifTool{Driver: SarifDriver{
+				Name:           "Kubesonde",
+				InformationURI: "https://github.com/kubesonde/kubesonde",
+				Rules:          RULES,
+			}},
+			Results: Findings(output),
+		}},
+	}
+}
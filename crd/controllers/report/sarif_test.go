@@ -0,0 +1,83 @@
+package report
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+	v1 "kubesonde.io/api/v1"
+)
+
+var _ = Describe("SARIF", func() {
+	pod := func(name string, namespace string, deployment string) v1.ProbeEndpointInfo {
+		return v1.ProbeEndpointInfo{Type: v1.POD, Name: name, Namespace: namespace, DeploymentName: deployment}
+	}
+	external := func(name string, address string) v1.ProbeEndpointInfo {
+		return v1.ProbeEndpointInfo{Type: v1.INTERNET, Name: name, IPAddress: address}
+	}
+	probe := func(source v1.ProbeEndpointInfo, destination v1.ProbeEndpointInfo, port string, action v1.ActionType) v1.ProbeOutputItem {
+		return v1.ProbeOutputItem{Type: v1.PROBE, Source: source, Destination: destination, Port: port, Protocol: "TCP", ResultingAction: action}
+	}
+	web1, web2 := pod("web-1", "shop", "web"), pod("web-2", "shop", "web")
+	db := pod("db-1", "shop", "db")
+	billing := pod("api-1", "billing", "api")
+	expectDeny := probe(web1, db, "5432", v1.ALLOW)
+	expectDeny.ExpectedAction = v1.DENY
+	output := v1.ProbeOutput{
+		Items: []v1.ProbeOutputItem{
+			probe(web1, external("Google", "google.com"), "443", v1.ALLOW),
+			probe(web2, external("Google", "google.com"), "443", v1.ALLOW),
+			probe(db, external("Google", "google.com"), "443", v1.DENY),
+			probe(web1, external("KUBE DNS", "kube-dns.kube-system.svc.cluster.local"), "53", v1.ALLOW),
+			probe(web1, external("169.254.169.254", "169.254.169.254"), "80", v1.ALLOW),
+			probe(web1, billing, "8080", v1.ALLOW),
+			probe(web1, pod("db-2", "shop", "db"), "5432", v1.ALLOW),
+			expectDeny,
+		},
+		PodNetworkingV2:            v1.PodNetworkingInfoV2{"db-1": {{Port: "9090", IP: "0.0.0.0", Protocol: "TCP"}, {Port: "5432", IP: "0.0.0.0", Protocol: "TCP"}}},
+		PodConfigurationNetworking: v1.PodNetworkingInfoV2{"db-1": {{Port: "5432", Protocol: "TCP"}}},
+	}
+
+	It("Reports a result per finding", func() {
+		results := Findings(output)
+		Expect(lo.Map(results, func(r SarifResult, _ int) string { return r.RuleID + ": " + r.Message.Text })).To(Equal([]string{
+			"internet-egress-allowed: shop/web reaches Google (google.com) on 443/TCP",
+			"metadata-reachable: shop/web reaches the cloud metadata service 169.254.169.254 on 80/TCP",
+			"undeclared-port: shop/db-1 listens on 9090/TCP (0.0.0.0), which is not declared in the pod spec",
+			"cross-namespace-reachable: shop/web reaches pod billing/api on 8080/TCP",
+			"expected-deny-violation: shop/web-1 reaches shop/db-1 on 5432/TCP, expected to be denied",
+		}))
+		Expect(results[1]).To(Equal(SarifResult{
+			RuleID:    METADATA_REACHABLE_RULE,
+			RuleIndex: 1,
+			Level:     ERROR_LEVEL,
+			Message:   SarifMessage{Text: "shop/web reaches the cloud metadata service 169.254.169.254 on 80/TCP"},
+			Locations: []SarifLocation{{LogicalLocations: []SarifLogicalLocation{{Name: "web", FullyQualifiedName: "shop/web", Kind: "workload"}}}},
+			PartialFingerprints: map[string]string{
+				SARIF_FINGERPRINT: "metadata-reachable:shop/web reaches the cloud metadata service 169.254.169.254 on 80/TCP",
+			},
+		}))
+	})
+
+	It("Describes the rules", func() {
+		log := SARIF(output)
+		Expect(log.Version).To(Equal(SARIF_VERSION))
+		Expect(log.Runs).To(HaveLen(1))
+		rules := log.Runs[0].Tool.Driver.Rules
+		Expect(lo.Map(rules, func(r SarifRule, _ int) string { return r.ID })).To(Equal([]string{
+			INTERNET_EGRESS_RULE, METADATA_REACHABLE_RULE, UNDECLARED_PORT_RULE, CROSS_NAMESPACE_RULE, EXPECTED_DENY_VIOLATION_RULE,
+		}))
+		for _, result := range log.Runs[0].Results {
+			Expect(rules[result.RuleIndex].ID).To(Equal(result.RuleID))
+		}
+		Expect(SARIF(v1.ProbeOutput{}).Runs[0].Results).To(BeEmpty())
+	})
+
+	It("Reports the latest probe of each connection", func() {
+		allowed := probe(web1, external("Google", "google.com"), "443", v1.ALLOW)
+		allowed.Timestamp = 1
+		denied := probe(web1, external("Google", "google.com"), "443", v1.DENY)
+		denied.Timestamp = 2
+		Expect(Findings(v1.ProbeOutput{Items: []v1.ProbeOutputItem{allowed, denied}})).To(BeEmpty())
+		Expect(Findings(v1.ProbeOutput{Items: []v1.ProbeOutputItem{denied, allowed}})).To(BeEmpty())
+	})
+})
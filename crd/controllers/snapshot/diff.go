@@ -0,0 +1,172 @@
+package snapshot
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/samber/lo"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/pkg/apitypes"
+)
+
+// Endpoint is a workload, a service or an external address
+type Endpoint = apitypes.SnapshotEndpoint
+
+// Edge is the aggregated outcome of the probes between two endpoints
+type Edge = apitypes.SnapshotEdge
+
+// ChangedEdge is an edge whose verdict changed
+type ChangedEdge = apitypes.ChangedEdge
+
+// WorkloadDiff groups the changes of a workload
+type WorkloadDiff = apitypes.WorkloadDiff
+
+// Diff lists what changed between two probe outputs
+type Diff = apitypes.SnapshotDiff
+
+type edgeKey struct {
+	source      Endpoint
+	destination Endpoint
+	port        string
+	protocol    string
+}
+
+func endpointOf(info v1.ProbeEndpointInfo) Endpoint {
+	name := info.WorkloadName()
+	if name == "" {
+		name = info.IPAddress
+	}
+	return Endpoint{Type: info.Type, Namespace: info.Namespace, Name: name}
+}
+
+// edgesOf returns the verdict of each workload edge, allowed when the latest
+// probe of one of the connections between the workloads is
+func edgesOf(output v1.ProbeOutput) map[edgeKey]v1.ActionType {
+	edges := map[edgeKey]v1.ActionType{}
+	for _, item := range v1.LatestProbes(output.Items) {
+		if item.Type != v1.PROBE {
+			continue
+		}
+		key := edgeKey{
+			source:      endpointOf(item.Source),
+			destination: endpointOf(item.Destination),
+			port:        item.Port,
+			protocol:    item.Protocol,
+		}
+		if edges[key] != v1.ALLOW {
+			edges[key] = item.ResultingAction
+		}
+	}
+	return edges
+}
+
+// portsOf returns the listening ports of each workload, the pods are mapped to
+// their workload through the probed endpoints. The listening ports are keyed
+// by pod name only: the pods whose name is used in several namespaces are not
+// mapped to a workload.
+func portsOf(output v1.ProbeOutput) map[Endpoint][]v1.PodNetworkingItem {
+	pods := map[string][]Endpoint{}
+	for _, item := range output.Items {
+		for _, info := range []v1.ProbeEndpointInfo{item.Source, item.Destination} {
+			if info.Type == v1.POD {
+				pods[info.Name] = lo.Union(pods[info.Name], []Endpoint{endpointOf(info)})
+			}
+		}
+	}
+	ports := map[Endpoint][]v1.PodNetworkingItem{}
+	for pod, items := range output.PodNetworkingV2 {
+		workload := Endpoint{Type: v1.POD, Name: pod}
+		if len(pods[pod]) == 1 {
+			workload = pods[pod][0]
+		}
+		ports[workload] = lo.Union(ports[workload], items)
+	}
+	return ports
+}
+
+func edgeOf(key edgeKey, action v1.ActionType) Edge {
+	return Edge{Source: key.source, Destination: key.destination, Port: key.port, Protocol: key.protocol, Action: action}
+}
+
+// Compare returns the edges added, removed or whose verdict changed between the
+// two outputs, and the listening ports opened or closed, grouped by workload
+func Compare(from v1.ProbeOutput, to v1.ProbeOutput) []WorkloadDiff {
+	diffs := map[Endpoint]*WorkloadDiff{}
+	diffOf := func(workload Endpoint) *WorkloadDiff {
+		if _, ok := diffs[workload]; !ok {
+			diffs[workload] = &WorkloadDiff{Namespace: workload.Namespace, Workload: workload.Name}
+		}
+		return diffs[workload]
+	}
+
+	before, after := edgesOf(from), edgesOf(to)
+	for key, action := range after {
+		previous, found := before[key]
+		switch {
+		case !found:
+			diffOf(key.source).Added = append(diffOf(key.source).Added, edgeOf(key, action))
+		case previous != action:
+			diffOf(key.source).Changed = append(diffOf(key.source).Changed, ChangedEdge{SnapshotEdge: edgeOf(key, action), PreviousAction: previous})
+		}
+	}
+	for key, action := range before {
+		if _, found := after[key]; !found {
+			diffOf(key.source).Removed = append(diffOf(key.source).Removed, edgeOf(key, action))
+		}
+	}
+
+	portsBefore, portsAfter := portsOf(from), portsOf(to)
+	for _, workload := range lo.Union(lo.Keys(portsBefore), lo.Keys(portsAfter)) {
+		opened, closed := lo.Difference(portsAfter[workload], portsBefore[workload])
+		if len(opened) > 0 {
+			diffOf(workload).OpenedPorts = sortedPorts(opened)
+		}
+		if len(closed) > 0 {
+			diffOf(workload).ClosedPorts = sortedPorts(closed)
+		}
+	}
+
+	result := lo.Map(lo.Values(diffs), func(diff *WorkloadDiff, _ int) WorkloadDiff {
+		sortEdges(diff.Added)
+		sortEdges(diff.Removed)
+		sort.Slice(diff.Changed, func(i, j int) bool { return edgeLess(diff.Changed[i].SnapshotEdge, diff.Changed[j].SnapshotEdge) })
+		return *diff
+	})
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Workload < result[j].Workload
+	})
+	return result
+}
+
+func edgeLess(a Edge, b Edge) bool {
+	keyA := []string{a.Destination.Namespace, a.Destination.Name, a.Port, a.Protocol}
+	keyB := []string{b.Destination.Namespace, b.Destination.Name, b.Port, b.Protocol}
+	for i := range keyA {
+		if keyA[i] != keyB[i] {
+			return keyA[i] < keyB[i]
+		}
+	}
+	return false
+}
+
+func sortEdges(edges []Edge) {
+	sort.Slice(edges, func(i, j int) bool { return edgeLess(edges[i], edges[j]) })
+}
+
+func sortedPorts(ports []v1.PodNetworkingItem) []v1.PodNetworkingItem {
+	sort.Slice(ports, func(i, j int) bool {
+		if ports[i].Port != ports[j].Port {
+			a, _ := strconv.Atoi(ports[i].Port)
+			b, _ := strconv.Atoi(ports[j].Port)
+			return a < b
+		}
+		if ports[i].Protocol != ports[j].Protocol {
+			return ports[i].Protocol < ports[j].Protocol
+		}
+		return ports[i].IP < ports[j].IP
+	})
+	return ports
+}
@@ -0,0 +1,128 @@
+package snapshot
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "kubesonde.io/api/v1"
+)
+
+var (
+	frontendA = v1.ProbeEndpointInfo{Type: v1.POD, Name: "frontend-abc-1", Namespace: "shop", DeploymentName: "frontend"}
+	frontendB = v1.ProbeEndpointInfo{Type: v1.POD, Name: "frontend-def-1", Namespace: "shop", DeploymentName: "frontend"}
+	database  = v1.ProbeEndpointInfo{Type: v1.POD, Name: "db-0", Namespace: "data"}
+	google    = v1.ProbeEndpointInfo{Type: v1.INTERNET, IPAddress: "8.8.8.8"}
+)
+
+func probe(src v1.ProbeEndpointInfo, dst v1.ProbeEndpointInfo, port string, result v1.ActionType) v1.ProbeOutputItem {
+	return v1.ProbeOutputItem{
+		Type:            v1.PROBE,
+		ResultingAction: result,
+		Source:          src,
+		Destination:     dst,
+		Port:            port,
+		Protocol:        "TCP",
+	}
+}
+
+var _ = Describe("Store", func() {
+	It("Keeps the latest snapshots", func() {
+		store := NewStore(2)
+		first := store.Create("first", v1.ProbeOutput{Items: []v1.ProbeOutputItem{probe(frontendA, database, "5432", v1.ALLOW)}})
+		Expect(first.Items).To(Equal(1))
+		Expect(first.ID).NotTo(BeEmpty())
+		store.Create("second", v1.ProbeOutput{})
+		third := store.Create("third", v1.ProbeOutput{})
+
+		Expect(store.List()).To(HaveLen(2))
+		_, found := store.Get(first.ID)
+		Expect(found).To(BeFalse())
+		snapshot, found := store.Get("third")
+		Expect(found).To(BeTrue())
+		Expect(snapshot.ID).To(Equal(third.ID))
+	})
+})
+
+var _ = Describe("Compare", func() {
+	before := v1.ProbeOutput{
+		Items: []v1.ProbeOutputItem{
+			probe(frontendA, database, "5432", v1.ALLOW),
+			probe(frontendA, database, "9187", v1.ALLOW),
+			probe(frontendA, google, "443", v1.DENY),
+		},
+		PodNetworkingV2: v1.PodNetworkingInfoV2{
+			"db-0": {{Port: "5432", IP: "0.0.0.0", Protocol: "TCP"}, {Port: "9187", IP: "0.0.0.0", Protocol: "TCP"}},
+		},
+	}
+	// After a deploy the frontend pods have new names
+	after := v1.ProbeOutput{
+		Items: []v1.ProbeOutputItem{
+			probe(frontendB, database, "5432", v1.ALLOW),
+			probe(frontendB, google, "443", v1.ALLOW),
+			probe(database, frontendB, "8080", v1.DENY),
+		},
+		PodNetworkingV2: v1.PodNetworkingInfoV2{
+			"db-0":           {{Port: "5432", IP: "0.0.0.0", Protocol: "TCP"}},
+			"frontend-def-1": {{Port: "8080", IP: "0.0.0.0", Protocol: "TCP"}},
+		},
+	}
+
+	It("Reports nothing for identical outputs", func() {
+		Expect(Compare(before, before)).To(BeEmpty())
+	})
+
+	It("Groups the changes by workload", func() {
+		diffs := Compare(before, after)
+		Expect(diffs).To(HaveLen(2))
+
+		db := diffs[0]
+		Expect(db.Workload).To(Equal("db-0"))
+		Expect(db.Added).To(HaveLen(1))
+		Expect(db.Added[0].Destination).To(Equal(Endpoint{Type: v1.POD, Namespace: "shop", Name: "frontend"}))
+		Expect(db.ClosedPorts).To(Equal([]v1.PodNetworkingItem{{Port: "9187", IP: "0.0.0.0", Protocol: "TCP"}}))
+
+		frontend := diffs[1]
+		Expect(frontend.Workload).To(Equal("frontend"))
+		Expect(frontend.Added).To(BeEmpty())
+		Expect(frontend.Removed).To(HaveLen(1))
+		Expect(frontend.Removed[0].Port).To(Equal("9187"))
+		Expect(frontend.Changed).To(Equal([]ChangedEdge{{
+			SnapshotEdge: Edge{
+				Source:      Endpoint{Type: v1.POD, Namespace: "shop", Name: "frontend"},
+				Destination: Endpoint{Type: v1.INTERNET, Name: "8.8.8.8"},
+				Port:        "443",
+				Protocol:    "TCP",
+				Action:      v1.ALLOW,
+			},
+			PreviousAction: v1.DENY,
+		}}))
+		Expect(frontend.OpenedPorts).To(Equal([]v1.PodNetworkingItem{{Port: "8080", IP: "0.0.0.0", Protocol: "TCP"}}))
+	})
+
+	It("Compares the latest probe of each connection", func() {
+		denied := probe(frontendA, database, "5432", v1.DENY)
+		denied.Timestamp = 2
+		later := before
+		later.Items = append(append([]v1.ProbeOutputItem{}, before.Items...), denied)
+
+		diffs := Compare(before, later)
+		Expect(diffs).To(HaveLen(1))
+		Expect(diffs[0].Changed).To(HaveLen(1))
+		Expect(diffs[0].Changed[0].Action).To(Equal(v1.DENY))
+		Expect(diffs[0].Changed[0].PreviousAction).To(Equal(v1.ALLOW))
+	})
+
+	It("Keeps the ports of pods whose name is used in several namespaces apart", func() {
+		staging := v1.ProbeEndpointInfo{Type: v1.POD, Name: "db-0", Namespace: "staging"}
+		output := func(ports ...v1.PodNetworkingItem) v1.ProbeOutput {
+			return v1.ProbeOutput{
+				Items:           []v1.ProbeOutputItem{probe(frontendA, database, "5432", v1.ALLOW), probe(frontendA, staging, "5432", v1.ALLOW)},
+				PodNetworkingV2: v1.PodNetworkingInfoV2{"db-0": ports},
+			}
+		}
+
+		diffs := Compare(output(), output(v1.PodNetworkingItem{Port: "5432", IP: "0.0.0.0", Protocol: "TCP"}))
+		Expect(diffs).To(HaveLen(1))
+		Expect(diffs[0].Namespace).To(BeEmpty())
+		Expect(diffs[0].Workload).To(Equal("db-0"))
+	})
+})
@@ -0,0 +1,91 @@
+package snapshot
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/pkg/apitypes"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// MAX_SNAPSHOTS is the number of snapshots kept in memory, the oldest ones are dropped first
+const MAX_SNAPSHOTS = 50
+
+var (
+	log          = logf.Log.WithName("controllers.snapshot")
+	defaultStore = NewStore(MAX_SNAPSHOTS)
+)
+
+// Snapshot is a frozen copy of the probe output
+type Snapshot struct {
+	Summary `json:",inline"`
+	Output  v1.ProbeOutput `json:"output"`
+}
+
+// Summary describes a snapshot without its content
+type Summary = apitypes.SnapshotSummary
+
+// Store keeps the snapshots in memory
+type Store struct {
+	mu        sync.RWMutex
+	snapshots []Snapshot
+	max       int
+}
+
+func NewStore(max int) *Store {
+	return &Store{snapshots: []Snapshot{}, max: max}
+}
+
+// GetDefaultStore returns the store shared by the REST endpoints
+func GetDefaultStore() *Store {
+	return defaultStore
+}
+
+// Create freezes the output under a new ID
+func (s *Store) Create(label string, output v1.ProbeOutput) Summary {
+	snapshot := Snapshot{
+		Summary: Summary{
+			ID:        string(uuid.NewUUID()),
+			Label:     label,
+			CreatedAt: time.Now().Unix(),
+			Items:     len(output.Items),
+			Errors:    len(output.Errors),
+		},
+		Output: output,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots = append(s.snapshots, snapshot)
+	if len(s.snapshots) > s.max {
+		log.Info(fmt.Sprintf("Dropping snapshot %s", s.snapshots[0].ID))
+		s.snapshots = s.snapshots[len(s.snapshots)-s.max:]
+	}
+	return snapshot.Summary
+}
+
+// List returns the snapshots from the oldest to the newest
+func (s *Store) List() []Summary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return lo.Map(s.snapshots, func(snapshot Snapshot, _ int) Summary {
+		return snapshot.Summary
+	})
+}
+
+// Get returns the snapshot with the given ID or label
+func (s *Store) Get(id string) (Snapshot, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	// The latest snapshot wins when a label is reused
+	for i := len(s.snapshots) - 1; i >= 0; i-- {
+		if s.snapshots[i].ID == id || s.snapshots[i].Label == id {
+			return s.snapshots[i], true
+		}
+	}
+	return Snapshot{}, false
+}
@@ -0,0 +1,123 @@
+package state
+
+import (
+	"time"
+
+	v1 "kubesonde.io/api/v1"
+)
+
+type ChangeKind string
+
+const (
+	// PROBE_CHANGE is a new probe item or a probe whose outcome changed
+	PROBE_CHANGE ChangeKind = "probe"
+	// ERROR_CHANGE is a new probe error
+	ERROR_CHANGE ChangeKind = "error"
+	// NETSTAT_CHANGE is an update of the ports a pod listens on
+	NETSTAT_CHANGE ChangeKind = "netstat"
+	// RESET_CHANGE means that the state was replaced or cleared, or that the
+	// changes requested by a subscriber are no longer retained. Consumers
+	// should fetch the whole state again.
+	RESET_CHANGE ChangeKind = "reset"
+)
+
+const (
+	// defaultChangeRetention is the number of changes kept to resume subscriptions
+	defaultChangeRetention = 10000
+	// subscriberBuffer is the number of changes buffered per subscriber. Slow
+	// subscribers are dropped and must resume from their last sequence number.
+	subscriberBuffer = 256
+)
+
+// Change is an update of the probe state. Sequence numbers strictly increase
+// for the lifetime of the StateManager, also across resets.
+type Change struct {
+	Sequence   uint64                 `json:"sequence"`
+	Kind       ChangeKind             `json:"kind"`
+	Timestamp  int64                  `json:"timestamp"`
+	Probe      *v1.ProbeOutputItem    `json:"probe,omitempty"`
+	Error      *v1.ProbeOutputError   `json:"error,omitempty"`
+	Pod        string                 `json:"pod,omitempty"`
+	Networking []v1.PodNetworkingItem `json:"networking,omitempty"`
+}
+
+// Subscription delivers the changes of the state. The channel is closed when
+// the subscription is cancelled or when the subscriber does not keep up.
+type Subscription struct {
+	Changes <-chan Change
+	changes chan Change
+	sm      *StateManager
+}
+
+// Cancel stops the subscription
+func (s *Subscription) Cancel() {
+	s.sm.mu.Lock()
+	defer s.sm.mu.Unlock()
+	s.sm.unsubscribe(s)
+}
+
+// publish records a change and notifies the subscribers. Must be called with sm.mu held.
+func (sm *StateManager) publish(change Change) {
+	sm.sequence++
+	change.Sequence = sm.sequence
+	change.Timestamp = time.Now().Unix()
+	sm.changes = append(sm.changes, change)
+	if len(sm.changes) > sm.changeRetention {
+		sm.changes = append([]Change{}, sm.changes[len(sm.changes)-sm.changeRetention:]...)
+	}
+	for subscription := range sm.subscribers {
+		select {
+		case subscription.changes <- change:
+		default:
+			log.Info("Dropping slow state subscriber", "sequence", change.Sequence)
+			sm.unsubscribe(subscription)
+		}
+	}
+}
+
+// unsubscribe must be called with sm.mu held
+func (sm *StateManager) unsubscribe(subscription *Subscription) {
+	if _, ok := sm.subscribers[subscription]; ok {
+		delete(sm.subscribers, subscription)
+		close(subscription.changes)
+	}
+}
+
+// Subscribe returns the retained changes following the sequence number `after`
+// and a subscription to the next ones. When the changes following `after` are
+// no longer retained the backlog starts with a RESET_CHANGE.
+func (sm *StateManager) Subscribe(after uint64) ([]Change, *Subscription) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	var backlog []Change
+	if after > sm.sequence {
+		// The subscriber followed a previous instance of the state
+		backlog = append(backlog, Change{Sequence: sm.sequence, Kind: RESET_CHANGE, Timestamp: time.Now().Unix()})
+	} else if after < sm.sequence {
+		oldest := sm.sequence + 1
+		if len(sm.changes) > 0 {
+			oldest = sm.changes[0].Sequence
+		}
+		if after+1 < oldest {
+			backlog = append(backlog, Change{Sequence: oldest - 1, Kind: RESET_CHANGE, Timestamp: time.Now().Unix()})
+		}
+		for _, change := range sm.changes {
+			if change.Sequence > after {
+				backlog = append(backlog, change)
+			}
+		}
+	}
+
+	changes := make(chan Change, subscriberBuffer)
+	subscription := &Subscription{Changes: changes, changes: changes, sm: sm}
+	sm.subscribers[subscription] = struct{}{}
+	return backlog, subscription
+}
+
+// LastSequence returns the sequence number of the latest change
+func (sm *StateManager) LastSequence() uint64 {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.sequence
+}
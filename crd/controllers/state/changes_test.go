@@ -0,0 +1,106 @@
+package state
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "kubesonde.io/api/v1"
+)
+
+func TestStateManagerChanges(t *testing.T) {
+	probe := func(name string, action v1.ActionType) v1.ProbeOutputItem {
+		return v1.ProbeOutputItem{
+			Type:            v1.PROBE,
+			ResultingAction: action,
+			Source:          v1.ProbeEndpointInfo{Name: name},
+			Port:            "80",
+		}
+	}
+
+	t.Run("Test new probes are published", func(t *testing.T) {
+		sm := NewStateManager()
+		_, subscription := sm.Subscribe(sm.LastSequence())
+		defer subscription.Cancel()
+
+		assert.NoError(t, sm.AppendProbes(&[]v1.ProbeOutputItem{probe("a", v1.ALLOW), probe("b", v1.ALLOW)}))
+		// Duplicates are not published, changed outcomes are
+		assert.NoError(t, sm.AppendProbes(&[]v1.ProbeOutputItem{probe("a", v1.ALLOW), probe("a", v1.DENY)}))
+
+		for _, expected := range []v1.ProbeOutputItem{probe("a", v1.ALLOW), probe("b", v1.ALLOW), probe("a", v1.DENY)} {
+			change := <-subscription.Changes
+			assert.Equal(t, PROBE_CHANGE, change.Kind)
+			assert.Equal(t, expected, *change.Probe)
+		}
+		assert.Empty(t, subscription.Changes)
+		assert.Equal(t, uint64(3), sm.LastSequence())
+	})
+
+	t.Run("Test errors and netstat updates are published", func(t *testing.T) {
+		sm := NewStateManager()
+		_, subscription := sm.Subscribe(0)
+		defer subscription.Cancel()
+
+		assert.NoError(t, sm.AppendErrors(&[]v1.ProbeOutputError{{Value: probe("a", v1.DENY), Reason: "timeout"}}))
+		items := []v1.PodNetworkingItem{{Port: "80", IP: "0.0.0.0", Protocol: "TCP"}}
+		assert.NoError(t, sm.AppendNetInfoV2("pod", &items))
+		assert.NoError(t, sm.AppendNetInfoV2("pod", &items))
+		assert.NoError(t, sm.SetNetInfoV2("pod", &items))
+
+		change := <-subscription.Changes
+		assert.Equal(t, ERROR_CHANGE, change.Kind)
+		assert.Equal(t, "timeout", change.Error.Reason)
+		change = <-subscription.Changes
+		assert.Equal(t, NETSTAT_CHANGE, change.Kind)
+		assert.Equal(t, "pod", change.Pod)
+		assert.Equal(t, items, change.Networking)
+		assert.Empty(t, subscription.Changes)
+	})
+
+	t.Run("Test subscribers resume from a sequence number", func(t *testing.T) {
+		sm := NewStateManager()
+		assert.NoError(t, sm.AppendProbes(&[]v1.ProbeOutputItem{probe("a", v1.ALLOW), probe("b", v1.ALLOW)}))
+		sm.Clear()
+
+		backlog, subscription := sm.Subscribe(1)
+		subscription.Cancel()
+		assert.Len(t, backlog, 2)
+		assert.Equal(t, uint64(2), backlog[0].Sequence)
+		assert.Equal(t, "b", backlog[0].Probe.Source.Name)
+		assert.Equal(t, RESET_CHANGE, backlog[1].Kind)
+
+		_, open := <-subscription.Changes
+		assert.False(t, open)
+	})
+
+	t.Run("Test subscribers are reset when the changes are gone", func(t *testing.T) {
+		sm := NewStateManager()
+		sm.changeRetention = 1
+		assert.NoError(t, sm.AppendProbes(&[]v1.ProbeOutputItem{probe("a", v1.ALLOW), probe("b", v1.ALLOW), probe("c", v1.ALLOW)}))
+
+		backlog, subscription := sm.Subscribe(1)
+		subscription.Cancel()
+		assert.Len(t, backlog, 2)
+		assert.Equal(t, RESET_CHANGE, backlog[0].Kind)
+		assert.Equal(t, uint64(2), backlog[0].Sequence)
+		assert.Equal(t, "c", backlog[1].Probe.Source.Name)
+
+		backlog, subscription = sm.Subscribe(10)
+		subscription.Cancel()
+		assert.Len(t, backlog, 1)
+		assert.Equal(t, RESET_CHANGE, backlog[0].Kind)
+	})
+
+	t.Run("Test slow subscribers are dropped", func(t *testing.T) {
+		sm := NewStateManager()
+		_, subscription := sm.Subscribe(0)
+		for i := 0; i <= subscriberBuffer; i++ {
+			assert.NoError(t, sm.AppendProbes(&[]v1.ProbeOutputItem{probe(strconv.Itoa(i), v1.ALLOW)}))
+		}
+		received := 0
+		for range subscription.Changes {
+			received++
+		}
+		assert.Equal(t, subscriberBuffer, received)
+	})
+}
@@ -51,11 +51,18 @@ func ResetDefaultManager() {
 
 // StateManager handles concurrent access to probe state
 type StateManager struct {
-	mu                  sync.RWMutex
-	probeOutput         v1.ProbeOutput
+	mu          sync.RWMutex
+	probeOutput v1.ProbeOutput
+	// verdicts is the latest verdict of every probed connection, guarded by mu
+	verdicts            map[v1.ComparableProbeOutputItem]v1.ActionType
 	podsWithNetstat     []string
 	podsWithNetstatLock sync.RWMutex
 	lockTimeout         time.Duration
+	// sequence, changes and subscribers are guarded by mu
+	sequence        uint64
+	changes         []Change
+	changeRetention int
+	subscribers     map[*Subscription]struct{}
 }
 
 // NewStateManager creates a new state manager instance
@@ -68,8 +75,12 @@ func NewStateManager() *StateManager {
 			PodNetworkingV2:            make(v1.PodNetworkingInfoV2),
 			PodConfigurationNetworking: make(v1.PodNetworkingInfoV2),
 		},
+		verdicts:        map[v1.ComparableProbeOutputItem]v1.ActionType{},
 		podsWithNetstat: []string{},
 		lockTimeout:     defaultLockTimeout,
+		changes:         []Change{},
+		changeRetention: defaultChangeRetention,
+		subscribers:     map[*Subscription]struct{}{},
 	}
 }
 
@@ -110,6 +121,8 @@ func (sm *StateManager) SetProbeState(probes *v1.ProbeOutput) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 	sm.probeOutput = *probes
+	sm.verdicts = latestVerdicts(probes.Items)
+	sm.publish(Change{Kind: RESET_CHANGE})
 	return nil
 }
 
@@ -126,10 +139,22 @@ func (sm *StateManager) GetProbeState() v1.ProbeOutput {
 		PodConfigurationNetworking: copyNetworkingMapV2(sm.probeOutput.PodConfigurationNetworking),
 		Start:                      sm.probeOutput.Start,
 		End:                        sm.probeOutput.End,
+		Skipped:                    append([]v1.SkippedPod(nil), sm.probeOutput.Skipped...),
 	}
 }
 
-// AppendProbes adds unique probe items to the state
+// latestVerdicts returns the verdict of the latest probe of every connection
+func latestVerdicts(items []v1.ProbeOutputItem) map[v1.ComparableProbeOutputItem]v1.ActionType {
+	verdicts := map[v1.ComparableProbeOutputItem]v1.ActionType{}
+	for _, item := range v1.LatestProbes(items) {
+		verdicts[item.Connection()] = item.ResultingAction
+	}
+	return verdicts
+}
+
+// AppendProbes adds unique probe items to the state. A probe whose verdict
+// returns to one seen before is a duplicate: its item is refreshed with the
+// new timestamp and published again, so that the flip is not lost.
 func (sm *StateManager) AppendProbes(items *[]v1.ProbeOutputItem) error {
 	if items == nil {
 		return fmt.Errorf("items cannot be nil")
@@ -138,10 +163,28 @@ func (sm *StateManager) AppendProbes(items *[]v1.ProbeOutputItem) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	newItems := append(sm.probeOutput.Items, *items...)
-	sm.probeOutput.Items = lo.UniqBy(newItems, func(poi v1.ProbeOutputItem) v1.ComparableProbeOutputItem {
-		return poi.ToComparableProbe()
-	})
+	positions := make(map[v1.ComparableProbeOutputItem]int, len(sm.probeOutput.Items))
+	for i, item := range sm.probeOutput.Items {
+		if _, found := positions[item.ToComparableProbe()]; !found {
+			positions[item.ToComparableProbe()] = i
+		}
+	}
+	for _, item := range *items {
+		previous, probed := sm.verdicts[item.Connection()]
+		sm.verdicts[item.Connection()] = item.ResultingAction
+		if position, found := positions[item.ToComparableProbe()]; found {
+			if probed && previous != item.ResultingAction {
+				existing := &sm.probeOutput.Items[position]
+				existing.Timestamp = max(existing.Timestamp, item.Timestamp)
+				flipped := *existing
+				sm.publish(Change{Kind: PROBE_CHANGE, Probe: &flipped})
+			}
+			continue
+		}
+		positions[item.ToComparableProbe()] = len(sm.probeOutput.Items)
+		sm.probeOutput.Items = append(sm.probeOutput.Items, item)
+		sm.publish(Change{Kind: PROBE_CHANGE, Probe: &item})
+	}
 
 	return nil
 }
@@ -155,14 +198,49 @@ func (sm *StateManager) AppendErrors(items *[]v1.ProbeOutputError) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
+	previous := len(sm.probeOutput.Errors)
 	newItems := append(sm.probeOutput.Errors, *items...)
 	sm.probeOutput.Errors = lo.UniqBy(newItems, func(poe v1.ProbeOutputError) v1.ComparableProbeOutputItem {
 		return poe.Value.ToComparableProbe()
 	})
+	for i := previous; i < len(sm.probeOutput.Errors); i++ {
+		item := sm.probeOutput.Errors[i]
+		sm.publish(Change{Kind: ERROR_CHANGE, Error: &item})
+	}
+
+	return nil
+}
+
+// AppendSkipped adds the skipped pods to the state, replacing the previous
+// reason of a pod
+func (sm *StateManager) AppendSkipped(items *[]v1.SkippedPod) error {
+	if items == nil {
+		return fmt.Errorf("items cannot be nil")
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
 
+	for _, item := range *items {
+		sm.probeOutput.Skipped = append(lo.Reject(sm.probeOutput.Skipped, func(pod v1.SkippedPod, _ int) bool {
+			return pod.Namespace == item.Namespace && pod.Name == item.Name
+		}), item)
+	}
 	return nil
 }
 
+// DeleteSkipped removes a pod from the skipped pods and reports whether it was skipped
+func (sm *StateManager) DeleteSkipped(namespace, name string) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	previous := len(sm.probeOutput.Skipped)
+	sm.probeOutput.Skipped = lo.Reject(sm.probeOutput.Skipped, func(pod v1.SkippedPod, _ int) bool {
+		return pod.Namespace == namespace && pod.Name == name
+	})
+	return len(sm.probeOutput.Skipped) != previous
+}
+
 // AppendNetInfoV2 adds networking items to a specific key (union operation)
 func (sm *StateManager) AppendNetInfoV2(key string, items *[]v1.PodNetworkingItem) error {
 	if items == nil {
@@ -175,7 +253,11 @@ func (sm *StateManager) AppendNetInfoV2(key string, items *[]v1.PodNetworkingIte
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
+	previous := len(sm.probeOutput.PodNetworkingV2[key])
 	sm.probeOutput.PodNetworkingV2[key] = lo.Union(sm.probeOutput.PodNetworkingV2[key], *items)
+	if len(sm.probeOutput.PodNetworkingV2[key]) != previous {
+		sm.publishNetstat(key)
+	}
 	return nil
 }
 
@@ -207,7 +289,11 @@ func (sm *StateManager) SetNetInfoV2(key string, items *[]v1.PodNetworkingItem)
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
+	changed := !lo.ElementsMatch(sm.probeOutput.PodNetworkingV2[key], *items)
 	sm.probeOutput.PodNetworkingV2[key] = *items
+	if changed {
+		sm.publishNetstat(key)
+	}
 	return nil
 }
 
@@ -228,6 +314,8 @@ func (sm *StateManager) Clear() {
 		PodNetworkingV2:            make(v1.PodNetworkingInfoV2),
 		PodConfigurationNetworking: make(v1.PodNetworkingInfoV2),
 	}
+	sm.verdicts = map[v1.ComparableProbeOutputItem]v1.ActionType{}
+	sm.publish(Change{Kind: RESET_CHANGE})
 	sm.mu.Unlock()
 
 	sm.podsWithNetstatLock.Lock()
@@ -237,6 +325,15 @@ func (sm *StateManager) Clear() {
 	eventstorage.ClearEventStorage()
 }
 
+// publishNetstat must be called with sm.mu held
+func (sm *StateManager) publishNetstat(pod string) {
+	sm.publish(Change{
+		Kind:       NETSTAT_CHANGE,
+		Pod:        pod,
+		Networking: append([]v1.PodNetworkingItem{}, sm.probeOutput.PodNetworkingV2[pod]...),
+	})
+}
+
 // Helper function to deep copy networking map
 func copyNetworkingMapV2(src v1.PodNetworkingInfoV2) v1.PodNetworkingInfoV2 {
 	if src == nil {
@@ -285,6 +382,16 @@ func AppendErrors(items *[]v1.ProbeOutputError) {
 	}
 }
 
+func AppendSkipped(items *[]v1.SkippedPod) {
+	if err := GetDefaultManager().AppendSkipped(items); err != nil {
+		log.Error(err, "Failed to append skipped pods")
+	}
+}
+
+func DeleteSkipped(namespace, name string) bool {
+	return GetDefaultManager().DeleteSkipped(namespace, name)
+}
+
 func AppendNetInfoV2(key string, items *[]v1.PodNetworkingItem) {
 	if err := GetDefaultManager().AppendNetInfoV2(key, items); err != nil {
 		log.Error(err, "Failed to append net info v2")
@@ -20,6 +20,7 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	v1 "kubesonde.io/api/v1"
 )
@@ -218,6 +219,31 @@ func TestStateManagerAppendOperations(t *testing.T) {
 		assert.Contains(t, err.Error(), "items cannot be nil")
 	})
 
+	t.Run("Test AppendProbes publishes the verdicts flipping back", func(t *testing.T) {
+		sm := NewStateManager()
+		probe := func(action v1.ActionType, timestamp int64) v1.ProbeOutputItem {
+			return v1.ProbeOutputItem{
+				Type:            v1.PROBE,
+				Source:          v1.ProbeEndpointInfo{Type: v1.POD, Name: "web"},
+				Destination:     v1.ProbeEndpointInfo{Type: v1.POD, Name: "db"},
+				Protocol:        "TCP",
+				Port:            "5432",
+				ResultingAction: action,
+				Timestamp:       timestamp,
+			}
+		}
+		for _, item := range []v1.ProbeOutputItem{probe(v1.ALLOW, 1), probe(v1.DENY, 2), probe(v1.ALLOW, 3), probe(v1.ALLOW, 4)} {
+			assert.NoError(t, sm.AppendProbes(&[]v1.ProbeOutputItem{item}))
+		}
+
+		changes, subscription := sm.Subscribe(0)
+		subscription.Cancel()
+		verdicts := lo.Map(changes, func(change Change, _ int) v1.ActionType { return change.Probe.ResultingAction })
+		assert.Equal(t, []v1.ActionType{v1.ALLOW, v1.DENY, v1.ALLOW}, verdicts)
+		assert.Equal(t, int64(3), changes[2].Probe.Timestamp)
+		assert.Equal(t, []int64{3, 2}, lo.Map(sm.GetProbeState().Items, func(item v1.ProbeOutputItem, _ int) int64 { return item.Timestamp }))
+	})
+
 	t.Run("Test AppendErrors", func(t *testing.T) {
 		sm := NewStateManager()
 
@@ -276,6 +302,33 @@ func TestStateManagerAppendOperations(t *testing.T) {
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "items cannot be nil")
 	})
+
+	t.Run("Test AppendSkipped", func(t *testing.T) {
+		sm := NewStateManager()
+
+		err := sm.AppendSkipped(&[]v1.SkippedPod{
+			{Name: "etcd", Namespace: "kube-system", Reason: "KubeSystem"},
+			{Name: "proxy", Namespace: "default", Reason: "HostNetwork"},
+		})
+		assert.NoError(t, err)
+		err = sm.AppendSkipped(&[]v1.SkippedPod{{Name: "etcd", Namespace: "kube-system", Reason: "StaticPod"}})
+		assert.NoError(t, err)
+
+		state := sm.GetProbeState()
+		assert.Equal(t, []v1.SkippedPod{
+			{Name: "proxy", Namespace: "default", Reason: "HostNetwork"},
+			{Name: "etcd", Namespace: "kube-system", Reason: "StaticPod"},
+		}, state.Skipped)
+	})
+
+	t.Run("Test DeleteSkipped", func(t *testing.T) {
+		sm := NewStateManager()
+		assert.NoError(t, sm.AppendSkipped(&[]v1.SkippedPod{{Name: "proxy", Namespace: "default", Reason: "HostNetwork"}}))
+
+		assert.False(t, sm.DeleteSkipped("other", "proxy"))
+		assert.True(t, sm.DeleteSkipped("default", "proxy"))
+		assert.Empty(t, sm.GetProbeState().Skipped)
+	})
 }
 
 func TestStateManagerNetInfoOperations(t *testing.T) {
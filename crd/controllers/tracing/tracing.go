@@ -0,0 +1,128 @@
+// Package tracing instruments the probe pipeline with OpenTelemetry spans:
+// a pod event builds the probes, which are queued, dequeued, executed in the
+// source pod, checked, enriched with the workloads and stored.
+package tracing
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/probe_command"
+)
+
+const (
+	TRACER_NAME  = "kubesonde.io"
+	SERVICE_NAME = "kubesonde"
+)
+
+// Spans of the probe pipeline
+const (
+	// POD_EVENT_SPAN is a pod added to the namespace probed, it is the root of
+	// the spans of the probes the pod triggered
+	POD_EVENT_SPAN = "kubesonde.pod.event"
+	BUILD_SPAN     = "kubesonde.probes.build"
+	ENQUEUE_SPAN   = "kubesonde.probes.enqueue"
+	// DEQUEUE_SPAN starts when the probe is queued and ends when it is dequeued
+	DEQUEUE_SPAN = "kubesonde.probe.dequeue"
+	// RUN_SPAN is the parent of the exec, check, enrich and store spans
+	RUN_SPAN    = "kubesonde.probe.run"
+	EXEC_SPAN   = "kubesonde.probe.exec"
+	CHECK_SPAN  = "kubesonde.probe.check"
+	ENRICH_SPAN = "kubesonde.probe.enrich"
+	STORE_SPAN  = "kubesonde.probe.store"
+)
+
+// Attributes of the spans
+const (
+	KUBESONDE_KEY    = attribute.Key("kubesonde.name")
+	POD_KEY          = attribute.Key("k8s.pod.name")
+	NAMESPACE_KEY    = attribute.Key("k8s.namespace.name")
+	PROBES_KEY       = attribute.Key("kubesonde.probes")
+	PRIORITY_KEY     = attribute.Key("kubesonde.queue.priority")
+	QUEUE_WAIT_KEY   = attribute.Key("kubesonde.queue.wait_ms")
+	SOURCE_KEY       = attribute.Key("kubesonde.probe.source")
+	DESTINATION_KEY  = attribute.Key("kubesonde.probe.destination")
+	PORT_KEY         = attribute.Key("kubesonde.probe.port")
+	PROTOCOL_KEY     = attribute.Key("kubesonde.probe.protocol")
+	VERDICT_KEY      = attribute.Key("kubesonde.probe.verdict")
+	EXEC_LATENCY_KEY = attribute.Key("kubesonde.probe.exec_latency_ms")
+	// SLOW_PATH_KEY tells whether the workloads were read from the API server
+	// instead of the probe state
+	SLOW_PATH_KEY = attribute.Key("kubesonde.probe.enrich.api_lookup")
+)
+
+// Tracer returns the tracer of the global provider, a no-op until Setup or a
+// test registers one
+func Tracer() trace.Tracer {
+	return otel.Tracer(TRACER_NAME)
+}
+
+// CommandAttributes describe the probe of a command
+func CommandAttributes(command probe_command.KubesondeCommand) []attribute.KeyValue {
+	destination := command.Destination
+	if destination == "" {
+		destination = command.DestinationIPAddress
+	}
+	return []attribute.KeyValue{
+		SOURCE_KEY.String(command.Namespace + "/" + command.SourcePodName),
+		DESTINATION_KEY.String(destination),
+		PORT_KEY.String(command.DestinationPort),
+		PROTOCOL_KEY.String(command.Protocol),
+	}
+}
+
+// Verdict is the outcome of a probe
+func Verdict(action v1.ActionType) attribute.KeyValue {
+	return VERDICT_KEY.String(string(action))
+}
+
+type Options struct {
+	// Endpoint is the host:port of the OTLP gRPC collector. The
+	// OTEL_EXPORTER_OTLP_* variables configure the exporter when it is empty.
+	Endpoint string
+	// Insecure disables TLS towards the collector
+	Insecure bool
+}
+
+// Enabled tells whether a collector is configured
+func (o Options) Enabled() bool {
+	return o.Endpoint != "" || os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// Setup exports the spans over OTLP when a collector is configured. The
+// returned function flushes the pending spans.
+func Setup(ctx context.Context, options Options) (func(context.Context) error, error) {
+	if !options.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporterOptions := []otlptracegrpc.Option{}
+	if options.Endpoint != "" {
+		exporterOptions = append(exporterOptions, otlptracegrpc.WithEndpoint(options.Endpoint))
+	}
+	if options.Insecure {
+		exporterOptions = append(exporterOptions, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, exporterOptions...)
+	if err != nil {
+		return nil, err
+	}
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the service name
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", SERVICE_NAME)),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
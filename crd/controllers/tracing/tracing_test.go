@@ -0,0 +1,46 @@
+package tracing
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"kubesonde.io/controllers/probe_command"
+)
+
+var _ = Describe("Tracing", func() {
+	AfterEach(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+	})
+
+	It("Describes the probe of a command", func() {
+		command := probe_command.KubesondeCommand{SourcePodName: "web-1", Namespace: "shop", DestinationIPAddress: "10.0.0.2", DestinationPort: "80", Protocol: "TCP"}
+		Expect(CommandAttributes(command)).To(Equal([]attribute.KeyValue{
+			SOURCE_KEY.String("shop/web-1"),
+			DESTINATION_KEY.String("10.0.0.2"),
+			PORT_KEY.String("80"),
+			PROTOCOL_KEY.String("TCP"),
+		}))
+	})
+
+	It("Does not export without a collector", func() {
+		GinkgoT().Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+		GinkgoT().Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+		Expect(Options{}.Enabled()).To(BeFalse())
+		shutdown, err := Setup(context.Background(), Options{})
+		Expect(err).To(BeNil())
+		Expect(shutdown(context.Background())).To(Succeed())
+		Expect(otel.GetTracerProvider()).To(Equal(noop.NewTracerProvider()))
+	})
+
+	It("Exports to the collector", func() {
+		shutdown, err := Setup(context.Background(), Options{Endpoint: "localhost:4317", Insecure: true})
+		Expect(err).To(BeNil())
+		Expect(otel.GetTracerProvider()).To(BeAssignableToTypeOf(&sdktrace.TracerProvider{}))
+		Expect(shutdown(context.Background())).To(Succeed())
+	})
+})
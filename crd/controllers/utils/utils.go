@@ -3,9 +3,11 @@ package utils
 import (
 	"context"
 	"errors"
+	"os"
 	"slices"
 	"sort"
 	"strings"
+	"sync"
 
 	lo "github.com/samber/lo"
 	v1 "k8s.io/api/apps/v1"
@@ -17,6 +19,21 @@ import (
 
 var ALLOW_ALL = "all"
 
+// NAMESPACE_FILE holds the namespace of the service account of the controller
+const NAMESPACE_FILE = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// OwnNamespace is the namespace Kubesonde runs in, empty outside of a cluster
+var OwnNamespace = sync.OnceValue(func() string {
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		return namespace
+	}
+	namespace, err := os.ReadFile(NAMESPACE_FILE)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(namespace))
+})
+
 func FilterPodsByStatus(pods *k8sAPI.PodList, status k8sAPI.PodPhase) k8sAPI.PodList {
 	filteredPods := lo.Filter(pods.Items, func(p k8sAPI.Pod, i int) bool {
 		return p.Status.Phase == status
@@ -203,3 +220,16 @@ func MapToString(m map[string]string) string {
 	}
 	return sb.String()
 }
+
+// StringToMap parses the labels produced by MapToString
+func StringToMap(s string) map[string]string {
+	m := map[string]string{}
+	for _, pair := range strings.Split(s, ";") {
+		key, value, found := strings.Cut(pair, "=")
+		if !found || key == "" {
+			continue
+		}
+		m[key] = value
+	}
+	return m
+}
@@ -171,3 +171,13 @@ var _ = Describe("SourcePodMatchesKubesondeSpec", func() {
 		Expect(SourcePodMatchesKubesondeSpec(ksonde, pod)).To(BeFalse())
 	})
 })
+
+var _ = Describe("StringToMap", func() {
+	It("Parses the output of MapToString", func() {
+		labels := map[string]string{"app": "frontend", "tier": "web"}
+		Expect(StringToMap(MapToString(labels))).To(Equal(labels))
+	})
+	It("Returns an empty map for empty strings", func() {
+		Expect(StringToMap("")).To(Equal(map[string]string{}))
+	})
+})
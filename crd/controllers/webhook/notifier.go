@@ -0,0 +1,151 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	"kubesonde.io/controllers/delivery"
+	"kubesonde.io/controllers/findings"
+	kubesondemetrics "kubesonde.io/controllers/metrics"
+	"kubesonde.io/controllers/state"
+)
+
+// QUEUE_SIZE bounds the findings waiting for a webhook, newer ones are dropped
+const QUEUE_SIZE = 1024
+
+// sender batches the findings of a webhook and posts them
+type sender struct {
+	target   Target
+	findings chan findings.Finding
+	done     chan struct{}
+}
+
+func (s *sender) run(ctx context.Context, client *http.Client) {
+	defer close(s.done)
+	ticker := time.NewTicker(s.target.BatchInterval)
+	defer ticker.Stop()
+	batch := []findings.Finding{}
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		namespace, name, _ := strings.Cut(s.target.Kubesonde, "/")
+		result := "delivered"
+		if err := s.target.Deliver(ctx, client, batch); err != nil {
+			log.Error(err, "Failed to post the findings", "kubesonde", s.target.Kubesonde, "webhook", s.target.Name, "findings", len(batch))
+			result = "failed"
+		}
+		kubesondemetrics.WebhookDeliveriesSummary.WithLabelValues(namespace, name, s.target.Name, result).Inc()
+		batch = []findings.Finding{}
+	}
+	for {
+		select {
+		case finding, open := <-s.findings:
+			if !open {
+				flush()
+				return
+			}
+			batch = append(batch, finding)
+			if len(batch) >= s.target.MaxBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// Notifier posts the findings of the probe state to the webhooks of the
+// Kubesonde objects. It is a handler of the delivery feed.
+type Notifier struct {
+	client *http.Client
+	ctx    context.Context
+	cancel context.CancelFunc
+	mu     sync.Mutex
+	// senders are the webhooks by namespace/name of the Kubesonde object
+	senders map[string][]*sender
+}
+
+func NewNotifier() *Notifier {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Notifier{
+		client:  &http.Client{Timeout: delivery.DELIVERY_TIMEOUT},
+		ctx:     ctx,
+		cancel:  cancel,
+		senders: map[string][]*sender{},
+	}
+}
+
+// Configure replaces the webhooks of a Kubesonde object, the pending findings
+// of the previous ones are posted first
+func (n *Notifier) Configure(kubesonde string, targets []Target) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, s := range n.senders[kubesonde] {
+		close(s.findings)
+	}
+	delete(n.senders, kubesonde)
+	for _, target := range targets {
+		s := &sender{target: target, findings: make(chan findings.Finding, QUEUE_SIZE), done: make(chan struct{})}
+		go s.run(n.ctx, n.client)
+		n.senders[kubesonde] = append(n.senders[kubesonde], s)
+	}
+}
+
+// Targets returns the configured webhooks
+func (n *Notifier) Targets() []Target {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return lo.FlatMap(lo.Values(n.senders), func(senders []*sender, _ int) []Target {
+		return lo.Map(senders, func(s *sender, _ int) Target { return s.target })
+	})
+}
+
+// Notify queues the findings for the webhooks accepting them
+func (n *Notifier) Notify(batch []findings.Finding) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, senders := range n.senders {
+		for _, s := range senders {
+			for _, finding := range batch {
+				if !s.target.Accepts(finding.Kind) {
+					continue
+				}
+				select {
+				case s.findings <- finding:
+				default:
+					log.Info("Dropping a finding, the webhook does not keep up", "kubesonde", s.target.Kubesonde, "webhook", s.target.Name, "kind", finding.Kind)
+				}
+			}
+		}
+	}
+}
+
+// Handle queues the findings of a change of the probe state
+func (n *Notifier) Handle(_ state.Change, detected []findings.Finding) {
+	n.Notify(detected)
+}
+
+// Stop posts the pending findings and waits for the webhooks
+func (n *Notifier) Stop() {
+	n.mu.Lock()
+	senders := lo.Flatten(lo.Values(n.senders))
+	n.senders = map[string][]*sender{}
+	n.mu.Unlock()
+	for _, s := range senders {
+		close(s.findings)
+	}
+	timeout := time.After(delivery.DELIVERY_TIMEOUT)
+	for _, s := range senders {
+		select {
+		case <-s.done:
+		case <-timeout:
+			n.cancel()
+		}
+	}
+	n.cancel()
+}
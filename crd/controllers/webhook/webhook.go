@@ -0,0 +1,208 @@
+// Package webhook posts the findings to the HTTP endpoints declared in the
+// Kubesonde objects, batched, signed and retried.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/samber/lo"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/delivery"
+	"kubesonde.io/controllers/findings"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = logf.Log.WithName("Webhook")
+
+// Formats of the payloads
+const (
+	// GENERIC_FORMAT is a JSON Payload
+	GENERIC_FORMAT = "generic"
+	// SLACK_FORMAT is a message for Slack incoming webhooks
+	SLACK_FORMAT = "slack"
+)
+
+const (
+	// SIGNATURE_HEADER is "sha256=" followed by the hex encoded HMAC-SHA256 of
+	// the TIMESTAMP_HEADER value, a dot and the body
+	SIGNATURE_HEADER = "X-Kubesonde-Signature"
+	// TIMESTAMP_HEADER is the time of the delivery in seconds since the epoch,
+	// receivers should reject old ones to prevent replays
+	TIMESTAMP_HEADER = "X-Kubesonde-Timestamp"
+	// EVENT_HEADER lists the kinds of the findings of the payload
+	EVENT_HEADER = "X-Kubesonde-Event"
+)
+
+const (
+	DEFAULT_BATCH_INTERVAL = 10 * time.Second
+	DEFAULT_MAX_BATCH_SIZE = 50
+	DEFAULT_MAX_RETRIES    = 5
+)
+
+// Target is a webhook of a Kubesonde object, with its secrets resolved
+type Target struct {
+	// Kubesonde is the namespace/name of the object declaring the webhook
+	Kubesonde string
+	Name      string
+	URL       string
+	Format    string
+	// Template renders the payload instead of the format when set
+	Template *template.Template
+	// Secret signs the payloads when set
+	Secret []byte
+	// Kinds are the findings posted, all of them when empty
+	Kinds         []findings.Kind
+	BatchInterval time.Duration
+	MaxBatchSize  int
+	MaxRetries    int
+}
+
+// Payload is the body of the generic format and the data of the templates
+type Payload struct {
+	// Kubesonde is the namespace/name of the object declaring the webhook
+	Kubesonde string             `json:"kubesonde"`
+	Webhook   string             `json:"webhook"`
+	Findings  []findings.Finding `json:"findings"`
+}
+
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// marshal does not escape <, > and &, which chat tools would show as is
+func marshal(value any) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
+
+var templateFuncs = template.FuncMap{
+	// json quotes a value, e.g. {"text": {{ json .Kubesonde }}}
+	"json": func(value any) (string, error) {
+		data, err := marshal(value)
+		return string(data), err
+	},
+}
+
+// NewTarget checks the spec of a webhook and applies its defaults. The URL and
+// the signing secret are read from the spec or its Secrets by the caller.
+func NewTarget(kubesonde string, spec v1.Webhook, endpoint string, secret []byte) (Target, error) {
+	target := Target{
+		Kubesonde:     kubesonde,
+		Name:          spec.Name,
+		URL:           endpoint,
+		Format:        lo.CoalesceOrEmpty(spec.Format, GENERIC_FORMAT),
+		Secret:        secret,
+		BatchInterval: DEFAULT_BATCH_INTERVAL,
+		MaxBatchSize:  lo.Ternary(spec.MaxBatchSize > 0, int(spec.MaxBatchSize), DEFAULT_MAX_BATCH_SIZE),
+		MaxRetries:    DEFAULT_MAX_RETRIES,
+	}
+	if spec.Name == "" {
+		return Target{}, errors.New("webhook without name")
+	}
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return Target{}, fmt.Errorf("webhook %s: invalid URL, expected an http or https URL", spec.Name)
+	}
+	if target.Format != GENERIC_FORMAT && target.Format != SLACK_FORMAT {
+		return Target{}, fmt.Errorf("webhook %s: invalid format %s, expected %s or %s", spec.Name, target.Format, GENERIC_FORMAT, SLACK_FORMAT)
+	}
+	if spec.Template != "" {
+		if target.Template, err = template.New(spec.Name).Funcs(templateFuncs).Parse(spec.Template); err != nil {
+			return Target{}, fmt.Errorf("webhook %s: invalid template: %w", spec.Name, err)
+		}
+	}
+	for _, value := range spec.Findings {
+		kind, err := findings.ParseKind(value)
+		if err != nil {
+			return Target{}, fmt.Errorf("webhook %s: %w", spec.Name, err)
+		}
+		target.Kinds = append(target.Kinds, kind)
+	}
+	if spec.BatchInterval != nil && spec.BatchInterval.Duration > 0 {
+		target.BatchInterval = spec.BatchInterval.Duration
+	}
+	if spec.MaxRetries != nil {
+		target.MaxRetries = max(int(*spec.MaxRetries), 0)
+	}
+	return target, nil
+}
+
+// Accepts tells whether the webhook is notified of the kind of findings
+func (t Target) Accepts(kind findings.Kind) bool {
+	return len(t.Kinds) == 0 || lo.Contains(t.Kinds, kind)
+}
+
+// Render returns the body posted for the findings
+func (t Target) Render(batch []findings.Finding) ([]byte, error) {
+	payload := Payload{Kubesonde: t.Kubesonde, Webhook: t.Name, Findings: batch}
+	if t.Template != nil {
+		var buffer bytes.Buffer
+		if err := t.Template.Execute(&buffer, payload); err != nil {
+			return nil, fmt.Errorf("failed to render the template of webhook %s: %w", t.Name, err)
+		}
+		return buffer.Bytes(), nil
+	}
+	if t.Format == SLACK_FORMAT {
+		lines := []string{fmt.Sprintf("*Kubesonde %s*: %d findings", t.Kubesonde, len(batch))}
+		for _, finding := range batch {
+			lines = append(lines, fmt.Sprintf("• `%s` %s", finding.Kind, finding.Message))
+		}
+		return marshal(slackMessage{Text: strings.Join(lines, "\n")})
+	}
+	return marshal(payload)
+}
+
+// Sign returns the SIGNATURE_HEADER value of a body
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (t Target) post(ctx context.Context, client *http.Client, kinds string, body []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EVENT_HEADER, kinds)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set(TIMESTAMP_HEADER, timestamp)
+	if len(t.Secret) > 0 {
+		request.Header.Set(SIGNATURE_HEADER, Sign(t.Secret, timestamp, body))
+	}
+	return delivery.Post(client, request)
+}
+
+// Deliver renders and posts the findings. Failed deliveries are retried with
+// an exponential backoff, up to MaxRetries times.
+func (t Target) Deliver(ctx context.Context, client *http.Client, batch []findings.Finding) error {
+	body, err := t.Render(batch)
+	if err != nil {
+		return err
+	}
+	kinds := strings.Join(lo.Uniq(lo.Map(batch, func(f findings.Finding, _ int) string { return string(f.Kind) })), ",")
+	return delivery.Retry(ctx, t.MaxRetries, func() error { return t.post(ctx, client, kinds, body) }, func(attempt int, err error) {
+		log.Info("Retrying the webhook", "kubesonde", t.Kubesonde, "webhook", t.Name, "attempt", attempt, "error", err.Error())
+	})
+}
@@ -0,0 +1,204 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/delivery"
+	"kubesonde.io/controllers/findings"
+	"kubesonde.io/controllers/state"
+)
+
+// receiver records the requests posted to it and answers with the statuses,
+// then with 200
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	headers  []http.Header
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+	body, _ := io.ReadAll(request.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.headers = append(r.headers, request.Header.Clone())
+	r.bodies = append(r.bodies, body)
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (r *receiver) requests() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]byte{}, r.bodies...)
+}
+
+var _ = Describe("Webhook", func() {
+	var server *httptest.Server
+	var received *receiver
+	batch := []findings.Finding{
+		{Kind: findings.VERDICT_FLIP, Message: "shop/web-1 -> shop/db-1 5432/TCP changed from Deny to Allow", Action: v1.ALLOW},
+		{Kind: findings.INTERNET_REACHABLE, Message: "shop/web-1 reaches the Internet: shop/web-1 -> Google 443/TCP", Action: v1.ALLOW},
+	}
+
+	BeforeEach(func() {
+		received = &receiver{}
+		server = httptest.NewServer(received)
+		delivery.INITIAL_BACKOFF = time.Millisecond
+	})
+
+	AfterEach(func() {
+		server.Close()
+		delivery.INITIAL_BACKOFF = time.Second
+	})
+
+	It("Applies the defaults of the spec", func() {
+		target, err := NewTarget("default/kubesonde", v1.Webhook{Name: "ops"}, server.URL, nil)
+		Expect(err).To(BeNil())
+		Expect(target.Format).To(Equal(GENERIC_FORMAT))
+		Expect(target.BatchInterval).To(Equal(DEFAULT_BATCH_INTERVAL))
+		Expect(target.MaxBatchSize).To(Equal(DEFAULT_MAX_BATCH_SIZE))
+		Expect(target.MaxRetries).To(Equal(DEFAULT_MAX_RETRIES))
+		Expect(target.Accepts(findings.BASELINE_DRIFT)).To(BeTrue())
+
+		target, err = NewTarget("default/kubesonde", v1.Webhook{
+			Name:          "ops",
+			Findings:      []string{"verdict-flip"},
+			BatchInterval: &metav1.Duration{Duration: time.Minute},
+			MaxRetries:    lo.ToPtr(int32(0)),
+		}, server.URL, nil)
+		Expect(err).To(BeNil())
+		Expect(target.BatchInterval).To(Equal(time.Minute))
+		Expect(target.MaxRetries).To(Equal(0))
+		Expect(target.Accepts(findings.VERDICT_FLIP)).To(BeTrue())
+		Expect(target.Accepts(findings.BASELINE_DRIFT)).To(BeFalse())
+	})
+
+	It("Rejects invalid specs", func() {
+		for _, spec := range []v1.Webhook{
+			{},
+			{Name: "ops", Format: "teams"},
+			{Name: "ops", Template: "{{ .Missing"},
+			{Name: "ops", Findings: []string{"flip"}},
+		} {
+			_, err := NewTarget("default/kubesonde", spec, server.URL, nil)
+			Expect(err).NotTo(BeNil())
+		}
+		_, err := NewTarget("default/kubesonde", v1.Webhook{Name: "ops"}, "ftp://example.com", nil)
+		Expect(err).To(MatchError(ContainSubstring("invalid URL")))
+	})
+
+	It("Renders the payloads", func() {
+		target, _ := NewTarget("default/kubesonde", v1.Webhook{Name: "ops"}, server.URL, nil)
+		body, err := target.Render(batch)
+		Expect(err).To(BeNil())
+		var payload Payload
+		Expect(json.Unmarshal(body, &payload)).To(Succeed())
+		Expect(payload).To(Equal(Payload{Kubesonde: "default/kubesonde", Webhook: "ops", Findings: batch}))
+
+		target, _ = NewTarget("default/kubesonde", v1.Webhook{Name: "ops", Format: SLACK_FORMAT}, server.URL, nil)
+		body, err = target.Render(batch)
+		Expect(err).To(BeNil())
+		Expect(string(body)).To(Equal(`{"text":"*Kubesonde default/kubesonde*: 2 findings\n• ` + "`verdict-flip`" + ` shop/web-1 -> shop/db-1 5432/TCP changed from Deny to Allow\n• ` + "`internet-reachable`" + ` shop/web-1 reaches the Internet: shop/web-1 -> Google 443/TCP"}`))
+
+		target, _ = NewTarget("default/kubesonde", v1.Webhook{
+			Name:     "ops",
+			Template: `{"summary": {{ json .Kubesonde }}, "count": {{ len .Findings }}, "first": {{ json (index .Findings 0).Kind }}}`,
+		}, server.URL, nil)
+		body, err = target.Render(batch)
+		Expect(err).To(BeNil())
+		Expect(string(body)).To(Equal(`{"summary": "default/kubesonde", "count": 2, "first": "verdict-flip"}`))
+	})
+
+	It("Signs the payloads", func() {
+		target, _ := NewTarget("default/kubesonde", v1.Webhook{Name: "ops"}, server.URL, []byte("s3cr3t"))
+		Expect(target.Deliver(context.Background(), http.DefaultClient, batch)).To(Succeed())
+
+		Expect(received.requests()).To(HaveLen(1))
+		header := received.headers[0]
+		Expect(header.Get("Content-Type")).To(Equal("application/json"))
+		Expect(header.Get(EVENT_HEADER)).To(Equal("verdict-flip,internet-reachable"))
+		Expect(header.Get(SIGNATURE_HEADER)).To(Equal(Sign([]byte("s3cr3t"), header.Get(TIMESTAMP_HEADER), received.bodies[0])))
+		Expect(header.Get(SIGNATURE_HEADER)).NotTo(Equal(Sign([]byte("other"), header.Get(TIMESTAMP_HEADER), received.bodies[0])))
+	})
+
+	It("Retries the failed deliveries", func() {
+		received.statuses = []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}
+		target, _ := NewTarget("default/kubesonde", v1.Webhook{Name: "ops"}, server.URL, nil)
+		Expect(target.Deliver(context.Background(), http.DefaultClient, batch)).To(Succeed())
+		Expect(received.requests()).To(HaveLen(3))
+
+		received.statuses = []int{http.StatusBadGateway, http.StatusBadGateway}
+		target.MaxRetries = 1
+		Expect(target.Deliver(context.Background(), http.DefaultClient, batch)).To(MatchError("unexpected status 502"))
+		Expect(received.requests()).To(HaveLen(5))
+
+		received.statuses = []int{http.StatusBadRequest}
+		Expect(target.Deliver(context.Background(), http.DefaultClient, batch)).To(MatchError("unexpected status 400"))
+		Expect(received.requests()).To(HaveLen(6))
+	})
+
+	It("Posts the findings of the probe state in batches", func() {
+		sm := state.NewStateManager()
+		notifier := NewNotifier()
+		feed := delivery.NewFeed(sm, notifier)
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			Expect(feed.Start(ctx)).To(Succeed())
+		}()
+
+		target, _ := NewTarget("default/kubesonde", v1.Webhook{Name: "ops", BatchInterval: &metav1.Duration{Duration: time.Hour}, MaxBatchSize: 2}, server.URL, nil)
+		flips, _ := NewTarget("default/kubesonde", v1.Webhook{Name: "flips", Findings: []string{"verdict-flip"}}, server.URL, nil)
+		notifier.Configure("default/kubesonde", []Target{target, flips})
+		Expect(notifier.Targets()).To(HaveLen(2))
+
+		web := v1.ProbeEndpointInfo{Type: v1.POD, Name: "web-1", Namespace: "shop"}
+		google := v1.ProbeEndpointInfo{Type: v1.INTERNET, Name: "Google", IPAddress: "google.com"}
+		db := v1.ProbeEndpointInfo{Type: v1.POD, Name: "db-1", Namespace: "shop"}
+		Expect(sm.AppendProbes(&[]v1.ProbeOutputItem{
+			{Type: v1.PROBE, Source: web, Destination: google, Port: "443", Protocol: "TCP", ResultingAction: v1.ALLOW},
+			{Type: v1.PROBE, Source: web, Destination: db, Port: "5432", Protocol: "TCP", ResultingAction: v1.ALLOW, ExpectedAction: v1.DENY},
+		})).To(Succeed())
+
+		Eventually(received.requests).Should(HaveLen(1))
+		var payload Payload
+		Expect(json.Unmarshal(received.requests()[0], &payload)).To(Succeed())
+		Expect(payload.Webhook).To(Equal("ops"))
+		Expect(lo.Map(payload.Findings, func(f findings.Finding, _ int) findings.Kind { return f.Kind })).To(Equal([]findings.Kind{
+			findings.INTERNET_REACHABLE, findings.EXPECTED_ACTION_VIOLATION,
+		}))
+
+		// The pending findings are posted when the webhooks are replaced
+		Expect(sm.AppendProbes(&[]v1.ProbeOutputItem{
+			{Type: v1.PROBE, Source: web, Destination: db, Port: "5432", Protocol: "TCP", ResultingAction: v1.DENY, ExpectedAction: v1.DENY},
+		})).To(Succeed())
+		Consistently(received.requests, "100ms").Should(HaveLen(1))
+		notifier.Configure("default/kubesonde", nil)
+		Eventually(received.requests).Should(HaveLen(3))
+		kinds := lo.Map(received.requests()[1:], func(body []byte, _ int) []findings.Kind {
+			var payload Payload
+			Expect(json.Unmarshal(body, &payload)).To(Succeed())
+			return lo.Map(payload.Findings, func(f findings.Finding, _ int) findings.Kind { return f.Kind })
+		})
+		Expect(kinds).To(ConsistOf([]findings.Kind{findings.VERDICT_FLIP}, []findings.Kind{findings.VERDICT_FLIP}))
+
+		cancel()
+		Eventually(stopped).Should(BeClosed())
+	})
+})
@@ -0,0 +1,134 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	kubesondev1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/baseline"
+	kubesondemetrics "kubesonde.io/controllers/metrics"
+	"kubesonde.io/controllers/state"
+)
+
+const (
+	// DEFAULT_DRIFT_INTERVAL is the period the probes are compared to the baseline on
+	DEFAULT_DRIFT_INTERVAL = 30 * time.Second
+	// MAX_STATUS_EDGES bounds the drifted edges listed in the status, /drift lists all of them
+	MAX_STATUS_EDGES = 20
+)
+
+// BaselineReconciler compares the probes to the baseline referenced by the
+// Kubesonde objects and reports the drift in their status and in the metrics
+type BaselineReconciler struct {
+	client.Client
+	Log logr.Logger
+	// Interval defaults to DEFAULT_DRIFT_INTERVAL
+	Interval time.Duration
+	// Results defaults to the probes of the state manager
+	Results func() kubesondev1.ProbeOutput
+}
+
+func (r *BaselineReconciler) loadBaseline(ctx context.Context, kubesonde kubesondev1.Kubesonde) (baseline.Current, error) {
+	source := kubesonde.Spec.Baseline
+	key := lo.CoalesceOrEmpty(source.Key, baseline.DEFAULT_KEY)
+	current := baseline.Current{
+		Kubesonde: kubesonde.Namespace + "/" + kubesonde.Name,
+		Source:    fmt.Sprintf("%s/%s/%s", kubesonde.Namespace, source.ConfigMap, key),
+	}
+	var configMap corev1.ConfigMap
+	if err := r.Get(ctx, client.ObjectKey{Namespace: kubesonde.Namespace, Name: source.ConfigMap}, &configMap); err != nil {
+		return current, fmt.Errorf("failed to get ConfigMap %s: %w", source.ConfigMap, err)
+	}
+	data, found := configMap.Data[key]
+	if !found {
+		return current, fmt.Errorf("ConfigMap %s has no key %s", source.ConfigMap, key)
+	}
+	var err error
+	if current.Baseline, err = baseline.Parse([]byte(data)); err != nil {
+		return current, fmt.Errorf("invalid baseline in %s: %w", current.Source, err)
+	}
+	return current, nil
+}
+
+func (r *BaselineReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("Kubesonde-baseline", req.NamespacedName)
+	owner := req.Namespace + "/" + req.Name
+	driftLabels := prometheus.Labels{"namespace": req.Namespace, "name": req.Name}
+
+	var kubesonde kubesondev1.Kubesonde
+	if err := r.Get(ctx, req.NamespacedName, &kubesonde); err != nil {
+		baseline.ClearCurrent(owner)
+		kubesondemetrics.BaselineDriftSummary.DeletePartialMatch(driftLabels)
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if kubesonde.Spec.Baseline == nil {
+		baseline.ClearCurrent(owner)
+		kubesondemetrics.BaselineDriftSummary.DeletePartialMatch(driftLabels)
+		if kubesonde.Status.Drift == nil {
+			return ctrl.Result{}, nil
+		}
+		kubesonde.Status.Drift = nil
+		return ctrl.Result{}, r.Status().Update(ctx, &kubesonde)
+	}
+
+	status := &kubesondev1.DriftStatus{LastCheckTime: lo.ToPtr(metav1.Now())}
+	current, err := r.loadBaseline(ctx, kubesonde)
+	if err != nil {
+		log.Error(err, "unable to load the baseline")
+		baseline.ClearCurrent(owner)
+		kubesondemetrics.BaselineDriftSummary.DeletePartialMatch(driftLabels)
+		status.Error = err.Error()
+	} else {
+		baseline.SetCurrent(current)
+		results := lo.Ternary(r.Results != nil, r.Results, state.GetProbeState)
+		drift := baseline.Compare(current.Baseline, results())
+		status.Unexpected = len(drift.Unexpected)
+		status.Missing = len(drift.Missing)
+		edges := drift.Edges()
+		status.Edges = lo.Map(edges[:min(len(edges), MAX_STATUS_EDGES)], func(edge baseline.DriftEdge, _ int) string { return edge.String() })
+		kubesondemetrics.BaselineDriftSummary.WithLabelValues(req.Namespace, req.Name, string(baseline.UNEXPECTED)).Set(float64(status.Unexpected))
+		kubesondemetrics.BaselineDriftSummary.WithLabelValues(req.Namespace, req.Name, string(baseline.MISSING)).Set(float64(status.Missing))
+	}
+
+	kubesonde.Status.Drift = status
+	if err := r.Status().Update(ctx, &kubesonde); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: lo.CoalesceOrEmpty(r.Interval, DEFAULT_DRIFT_INTERVAL)}, nil
+}
+
+func (r *BaselineReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// The status updates do not change the generation and are skipped, the
+	// drift is checked again after the interval
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("baseline").
+		For(&kubesondev1.Kubesonde{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
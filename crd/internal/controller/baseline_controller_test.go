@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kubesondev1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/baseline"
+	kubesondemetrics "kubesonde.io/controllers/metrics"
+)
+
+func TestBaselineReconciler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = kubesondev1.AddToScheme(scheme)
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Name: "shop", Namespace: "default"}}
+	pod := func(name string, deployment string) kubesondev1.ProbeEndpointInfo {
+		return kubesondev1.ProbeEndpointInfo{Type: kubesondev1.POD, Name: name, Namespace: "shop", DeploymentName: deployment}
+	}
+	results := func() kubesondev1.ProbeOutput {
+		return kubesondev1.ProbeOutput{Items: []kubesondev1.ProbeOutputItem{
+			{Type: kubesondev1.PROBE, Source: pod("web-1", "web"), Destination: pod("api-1", "api"), Port: "8080", Protocol: "TCP", ResultingAction: kubesondev1.ALLOW},
+			{Type: kubesondev1.PROBE, Source: pod("web-1", "web"), Destination: pod("db-1", "db"), Port: "5432", Protocol: "TCP", ResultingAction: kubesondev1.ALLOW},
+		}}
+	}
+	newKubesonde := func(source *kubesondev1.BaselineSource) *kubesondev1.Kubesonde {
+		return &kubesondev1.Kubesonde{
+			ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "default"},
+			Spec:       kubesondev1.KubesondeSpec{Namespace: "shop", Baseline: source},
+		}
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "approved", Namespace: "default"},
+		Data: map[string]string{baseline.DEFAULT_KEY: `
+edges:
+- from: shop/web
+  to: shop/api
+  ports: [8080/TCP]
+- from: shop/api
+  to: shop/db
+  ports: [5432/TCP]
+`},
+	}
+
+	t.Run("Reports the drift in the status and the metrics", func(t *testing.T) {
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(newKubesonde(&kubesondev1.BaselineSource{ConfigMap: "approved"}), configMap).
+			WithStatusSubresource(&kubesondev1.Kubesonde{}).Build()
+		reconciler := &BaselineReconciler{Client: fakeClient, Log: logr.Discard(), Results: results}
+
+		result, err := reconciler.Reconcile(context.Background(), request)
+		assert.NoError(t, err)
+		assert.Equal(t, DEFAULT_DRIFT_INTERVAL, result.RequeueAfter)
+
+		var kubesonde kubesondev1.Kubesonde
+		assert.NoError(t, fakeClient.Get(context.Background(), request.NamespacedName, &kubesonde))
+		drift := kubesonde.Status.Drift
+		assert.NotNil(t, drift.LastCheckTime)
+		assert.Equal(t, 1, drift.Unexpected)
+		assert.Equal(t, 1, drift.Missing)
+		assert.Equal(t, []string{"+ shop/web -> shop/db 5432/TCP", "- shop/api -> shop/db 5432/TCP"}, drift.Edges)
+		assert.Empty(t, drift.Error)
+		assert.Equal(t, 1.0, testutil.ToFloat64(kubesondemetrics.BaselineDriftSummary.WithLabelValues("default", "shop", "unexpected")))
+
+		current, found := baseline.GetCurrent()
+		assert.True(t, found)
+		assert.Equal(t, "default/shop", current.Kubesonde)
+		assert.Equal(t, "default/approved/baseline.yaml", current.Source)
+	})
+
+	t.Run("Reports a missing baseline in the status", func(t *testing.T) {
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(newKubesonde(&kubesondev1.BaselineSource{ConfigMap: "approved", Key: "missing.yaml"}), configMap).
+			WithStatusSubresource(&kubesondev1.Kubesonde{}).Build()
+		reconciler := &BaselineReconciler{Client: fakeClient, Log: logr.Discard(), Results: results}
+
+		_, err := reconciler.Reconcile(context.Background(), request)
+		assert.NoError(t, err)
+
+		var kubesonde kubesondev1.Kubesonde
+		assert.NoError(t, fakeClient.Get(context.Background(), request.NamespacedName, &kubesonde))
+		assert.Equal(t, "ConfigMap approved has no key missing.yaml", kubesonde.Status.Drift.Error)
+		_, found := baseline.GetCurrent()
+		assert.False(t, found)
+	})
+
+	t.Run("Clears the drift when the baseline is removed", func(t *testing.T) {
+		kubesonde := newKubesonde(nil)
+		kubesonde.Status.Drift = &kubesondev1.DriftStatus{Unexpected: 1}
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(kubesonde).
+			WithStatusSubresource(&kubesondev1.Kubesonde{}).Build()
+		reconciler := &BaselineReconciler{Client: fakeClient, Log: logr.Discard(), Results: results}
+
+		result, err := reconciler.Reconcile(context.Background(), request)
+		assert.NoError(t, err)
+		assert.Equal(t, ctrl.Result{}, result)
+		assert.NoError(t, fakeClient.Get(context.Background(), request.NamespacedName, kubesonde))
+		assert.Nil(t, kubesonde.Status.Drift)
+	})
+}
@@ -0,0 +1,68 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	kubesondev1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/cloudevents"
+)
+
+// CloudEventsReconciler configures the publisher with the CloudEvents sinks
+// of the Kubesonde objects
+type CloudEventsReconciler struct {
+	client.Client
+	Log       logr.Logger
+	Publisher *cloudevents.Publisher
+}
+
+func (r *CloudEventsReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("Kubesonde-cloudevents", req.NamespacedName)
+	owner := req.Namespace + "/" + req.Name
+
+	var kubesonde kubesondev1.Kubesonde
+	if err := r.Get(ctx, req.NamespacedName, &kubesonde); err != nil {
+		r.Publisher.Configure(owner, nil)
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if kubesonde.Spec.CloudEvents == nil {
+		r.Publisher.Configure(owner, nil)
+		return ctrl.Result{}, nil
+	}
+	sink, err := cloudevents.NewSink(req.Namespace, req.Name, *kubesonde.Spec.CloudEvents)
+	if err != nil {
+		log.Error(err, "unable to configure the CloudEvents sink")
+		r.Publisher.Configure(owner, nil)
+		return ctrl.Result{}, err
+	}
+	r.Publisher.Configure(owner, &sink)
+	return ctrl.Result{}, nil
+}
+
+func (r *CloudEventsReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("cloudevents").
+		For(&kubesondev1.Kubesonde{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
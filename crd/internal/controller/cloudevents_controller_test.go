@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kubesondev1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/cloudevents"
+)
+
+func TestCloudEventsReconciler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = kubesondev1.AddToScheme(scheme)
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Name: "shop", Namespace: "default"}}
+	newKubesonde := func(sink *kubesondev1.CloudEventsSink) *kubesondev1.Kubesonde {
+		return &kubesondev1.Kubesonde{
+			ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "default"},
+			Spec:       kubesondev1.KubesondeSpec{Namespace: "shop", CloudEvents: sink},
+		}
+	}
+
+	t.Run("Configures the sink of the spec", func(t *testing.T) {
+		sink := &kubesondev1.CloudEventsSink{URL: "http://broker-ingress.knative-eventing/default/default", Mode: cloudevents.STRUCTURED_MODE}
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newKubesonde(sink)).Build()
+		reconciler := &CloudEventsReconciler{Client: fakeClient, Log: logr.Discard(), Publisher: cloudevents.NewPublisher()}
+
+		_, err := reconciler.Reconcile(context.Background(), request)
+		assert.NoError(t, err)
+		sinks := reconciler.Publisher.Sinks()
+		assert.Len(t, sinks, 1)
+		assert.Equal(t, "default/shop", sinks[0].Kubesonde)
+		assert.Equal(t, sink.URL, sinks[0].URL)
+		assert.Equal(t, cloudevents.STRUCTURED_MODE, sinks[0].Mode)
+
+		assert.NoError(t, fakeClient.Delete(context.Background(), newKubesonde(nil)))
+		_, err = reconciler.Reconcile(context.Background(), request)
+		assert.NoError(t, err)
+		assert.Empty(t, reconciler.Publisher.Sinks())
+	})
+
+	t.Run("Reports an invalid sink", func(t *testing.T) {
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newKubesonde(&kubesondev1.CloudEventsSink{URL: "broker"})).Build()
+		reconciler := &CloudEventsReconciler{Client: fakeClient, Log: logr.Discard(), Publisher: cloudevents.NewPublisher()}
+
+		_, err := reconciler.Reconcile(context.Background(), request)
+		assert.ErrorContains(t, err, "invalid CloudEvents sink URL")
+		assert.Empty(t, reconciler.Publisher.Sinks())
+	})
+}
@@ -0,0 +1,118 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	kubesondev1 "kubesonde.io/api/v1"
+	debugcontainer "kubesonde.io/controllers/debug-container"
+)
+
+const (
+	// DEFAULT_INSTALLATION_INTERVAL is the period the installation failures are reported on
+	DEFAULT_INSTALLATION_INTERVAL = 30 * time.Second
+	// MAX_STATUS_FAILURES bounds the installation failures listed in the status
+	MAX_STATUS_FAILURES = 50
+)
+
+// InstallationReconciler reports in the status of the Kubesonde objects the
+// pods the debug containers could not be installed in or do not run in
+type InstallationReconciler struct {
+	client.Client
+	Log logr.Logger
+	// Interval defaults to DEFAULT_INSTALLATION_INTERVAL
+	Interval time.Duration
+	// Failures defaults to the failures of the installations
+	Failures func() []kubesondev1.InstallationFailure
+}
+
+func (r *InstallationReconciler) failures(ctx context.Context, kubesonde kubesondev1.Kubesonde) ([]kubesondev1.InstallationFailure, error) {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(kubesonde.Spec.Namespace)); err != nil {
+		return nil, err
+	}
+	existing := lo.SliceToMap(pods.Items, func(pod corev1.Pod) (string, corev1.Pod) { return pod.Namespace + "/" + pod.Name, pod })
+	installations := lo.Ternary(r.Failures != nil, r.Failures, debugcontainer.InstallationFailures)
+	// The failures of the deleted pods are dropped
+	failures := lo.Filter(installations(), func(failure kubesondev1.InstallationFailure, _ int) bool {
+		_, found := existing[failure.Pod]
+		return found
+	})
+	failed := lo.SliceToMap(failures, func(failure kubesondev1.InstallationFailure) (string, bool) { return failure.Pod, true })
+	for _, pod := range pods.Items {
+		if failure, found := debugcontainer.ContainerFailure(&pod); found && !failed[failure.Pod] {
+			failures = append(failures, failure)
+		}
+	}
+	// The failures already reported keep their time
+	previous := lo.SliceToMap(kubesonde.Status.InstallationFailures, func(failure kubesondev1.InstallationFailure) (string, kubesondev1.InstallationFailure) {
+		return failure.Pod + "/" + failure.Reason, failure
+	})
+	failures = lo.Map(failures, func(failure kubesondev1.InstallationFailure, _ int) kubesondev1.InstallationFailure {
+		if reported, found := previous[failure.Pod+"/"+failure.Reason]; found {
+			failure.Time = reported.Time
+		}
+		return failure
+	})
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Pod < failures[j].Pod })
+	return failures[:min(len(failures), MAX_STATUS_FAILURES)], nil
+}
+
+func (r *InstallationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("Kubesonde-installation", req.NamespacedName)
+
+	var kubesonde kubesondev1.Kubesonde
+	if err := r.Get(ctx, req.NamespacedName, &kubesonde); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	failures, err := r.failures(ctx, kubesonde)
+	if err != nil {
+		log.Error(err, "unable to list the pods")
+		return ctrl.Result{}, err
+	}
+	if len(failures) == 0 {
+		failures = nil
+	}
+	if !reflect.DeepEqual(failures, kubesonde.Status.InstallationFailures) {
+		kubesonde.Status.InstallationFailures = failures
+		if err := r.Status().Update(ctx, &kubesonde); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{RequeueAfter: lo.CoalesceOrEmpty(r.Interval, DEFAULT_INSTALLATION_INTERVAL)}, nil
+}
+
+func (r *InstallationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// The status updates do not change the generation and are skipped, the
+	// failures are reported again after the interval
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("installation").
+		For(&kubesondev1.Kubesonde{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kubesondev1 "kubesonde.io/api/v1"
+	debugcontainer "kubesonde.io/controllers/debug-container"
+)
+
+func TestInstallationReconciler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = kubesondev1.AddToScheme(scheme)
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Name: "shop", Namespace: "default"}}
+	kubesonde := &kubesondev1.Kubesonde{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "default"},
+		Spec:       kubesondev1.KubesondeSpec{Namespace: "shop"},
+	}
+	web := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "shop"}}
+	db := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-1", Namespace: "shop"},
+		Status: corev1.PodStatus{EphemeralContainerStatuses: []corev1.ContainerStatus{
+			{Name: "debugger", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ErrImagePull"}}},
+		}},
+	}
+	observed := metav1.NewTime(time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC))
+	failures := func() []kubesondev1.InstallationFailure {
+		return []kubesondev1.InstallationFailure{
+			{Pod: "shop/web-1", Reason: debugcontainer.PATCH_FAILED, Message: "forbidden", Time: observed},
+			{Pod: "shop/deleted-1", Reason: debugcontainer.PATCH_FAILED, Message: "forbidden", Time: observed},
+		}
+	}
+
+	t.Run("Reports the failures of the existing pods in the status", func(t *testing.T) {
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(kubesonde.DeepCopy(), web.DeepCopy(), db.DeepCopy()).
+			WithStatusSubresource(&kubesondev1.Kubesonde{}).Build()
+		reconciler := &InstallationReconciler{Client: fakeClient, Log: logr.Discard(), Failures: failures}
+
+		result, err := reconciler.Reconcile(context.Background(), request)
+		assert.NoError(t, err)
+		assert.Equal(t, DEFAULT_INSTALLATION_INTERVAL, result.RequeueAfter)
+
+		var updated kubesondev1.Kubesonde
+		assert.NoError(t, fakeClient.Get(context.Background(), request.NamespacedName, &updated))
+		reported := updated.Status.InstallationFailures
+		assert.Len(t, reported, 2)
+		assert.Equal(t, "shop/db-1", reported[0].Pod)
+		assert.Equal(t, "ErrImagePull", reported[0].Reason)
+		assert.Equal(t, "shop/web-1", reported[1].Pod)
+		assert.Equal(t, debugcontainer.PATCH_FAILED, reported[1].Reason)
+
+		// The failures keep the time they were first reported at
+		_, err = reconciler.Reconcile(context.Background(), request)
+		assert.NoError(t, err)
+		var again kubesondev1.Kubesonde
+		assert.NoError(t, fakeClient.Get(context.Background(), request.NamespacedName, &again))
+		assert.Equal(t, updated.ResourceVersion, again.ResourceVersion)
+	})
+
+	t.Run("Clears the failures", func(t *testing.T) {
+		failed := kubesonde.DeepCopy()
+		failed.Status.InstallationFailures = failures()
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(failed, web.DeepCopy()).
+			WithStatusSubresource(&kubesondev1.Kubesonde{}).Build()
+		none := func() []kubesondev1.InstallationFailure { return nil }
+		reconciler := &InstallationReconciler{Client: fakeClient, Log: logr.Discard(), Failures: none}
+
+		_, err := reconciler.Reconcile(context.Background(), request)
+		assert.NoError(t, err)
+
+		var updated kubesondev1.Kubesonde
+		assert.NoError(t, fakeClient.Get(context.Background(), request.NamespacedName, &updated))
+		assert.Empty(t, updated.Status.InstallationFailures)
+	})
+}
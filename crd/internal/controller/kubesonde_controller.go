@@ -31,8 +31,10 @@ import (
 	"k8s.io/client-go/kubernetes"
 	recursiveprobing "kubesonde.io/controllers/recursive-probing"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
 // KubesondeReconciler reconciles a Kubesonde object
@@ -80,8 +82,9 @@ func (r *KubesondeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 }
 
 func (r *KubesondeReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// Reconciling starts the probing goroutines again, status updates are skipped
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&kubesondev1.Kubesonde{}).
+		For(&kubesondev1.Kubesonde{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Complete(r)
 }
 
@@ -90,4 +93,8 @@ func init() {
 	metrics.Registry.MustRegister(kubesondemetrics.MetricsSummary)
 	metrics.Registry.MustRegister(kubesondemetrics.DurationSummary)
 	metrics.Registry.MustRegister(kubesondemetrics.TargetedMetricsSummary)
+	metrics.Registry.MustRegister(kubesondemetrics.BaselineDriftSummary)
+	metrics.Registry.MustRegister(kubesondemetrics.WebhookDeliveriesSummary)
+	metrics.Registry.MustRegister(kubesondemetrics.CloudEventsSummary)
+	metrics.Registry.MustRegister(kubesondemetrics.AuditSequenceSummary)
 }
@@ -0,0 +1,135 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	kubesondev1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/webhook"
+)
+
+// WebhookReconciler configures the notifier with the webhooks of the Kubesonde
+// objects, their URL and signing secret read from Secrets
+type WebhookReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Notifier *webhook.Notifier
+}
+
+func (r *WebhookReconciler) readSecret(ctx context.Context, namespace string, selector *corev1.SecretKeySelector) ([]byte, error) {
+	var secret corev1.Secret
+	if err := r.Get(ctx, client.ObjectKey{Namespace: namespace, Name: selector.Name}, &secret); err != nil {
+		if selector.Optional != nil && *selector.Optional {
+			return nil, client.IgnoreNotFound(err)
+		}
+		return nil, fmt.Errorf("failed to get Secret %s: %w", selector.Name, err)
+	}
+	data, found := secret.Data[selector.Key]
+	if !found && (selector.Optional == nil || !*selector.Optional) {
+		return nil, fmt.Errorf("secret %s has no key %s", selector.Name, selector.Key)
+	}
+	return data, nil
+}
+
+func (r *WebhookReconciler) target(ctx context.Context, kubesonde kubesondev1.Kubesonde, spec kubesondev1.Webhook) (webhook.Target, error) {
+	owner := kubesonde.Namespace + "/" + kubesonde.Name
+	endpoint := spec.URL
+	if spec.URLSecretRef != nil {
+		data, err := r.readSecret(ctx, kubesonde.Namespace, spec.URLSecretRef)
+		if err != nil {
+			return webhook.Target{}, fmt.Errorf("webhook %s: %w", spec.Name, err)
+		}
+		endpoint = lo.CoalesceOrEmpty(string(data), endpoint)
+	}
+	var secret []byte
+	if spec.SigningSecretRef != nil {
+		var err error
+		if secret, err = r.readSecret(ctx, kubesonde.Namespace, spec.SigningSecretRef); err != nil {
+			return webhook.Target{}, fmt.Errorf("webhook %s: %w", spec.Name, err)
+		}
+	}
+	return webhook.NewTarget(owner, spec, endpoint, secret)
+}
+
+func (r *WebhookReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("Kubesonde-webhook", req.NamespacedName)
+	owner := req.Namespace + "/" + req.Name
+
+	var kubesonde kubesondev1.Kubesonde
+	if err := r.Get(ctx, req.NamespacedName, &kubesonde); err != nil {
+		r.Notifier.Configure(owner, nil)
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// The valid webhooks are notified even when others are not
+	targets := []webhook.Target{}
+	errs := []error{}
+	for _, spec := range kubesonde.Spec.Webhooks {
+		target, err := r.target(ctx, kubesonde, spec)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		targets = append(targets, target)
+	}
+	r.Notifier.Configure(owner, targets)
+	if err := errors.Join(errs...); err != nil {
+		log.Error(err, "unable to configure the webhooks")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// kubesondesOfSecret returns the Kubesonde objects referencing a Secret from
+// their webhooks
+func (r *WebhookReconciler) kubesondesOfSecret(ctx context.Context, object client.Object) []reconcile.Request {
+	var kubesondes kubesondev1.KubesondeList
+	if err := r.List(ctx, &kubesondes, client.InNamespace(object.GetNamespace())); err != nil {
+		r.Log.Error(err, "unable to list the Kubesonde objects", "namespace", object.GetNamespace())
+		return nil
+	}
+	references := func(selector *corev1.SecretKeySelector) bool {
+		return selector != nil && selector.Name == object.GetName()
+	}
+	return lo.FilterMap(kubesondes.Items, func(kubesonde kubesondev1.Kubesonde, _ int) (reconcile.Request, bool) {
+		found := lo.ContainsBy(kubesonde.Spec.Webhooks, func(spec kubesondev1.Webhook) bool {
+			return references(spec.URLSecretRef) || references(spec.SigningSecretRef)
+		})
+		return reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&kubesonde)}, found
+	})
+}
+
+func (r *WebhookReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("webhook").
+		For(&kubesondev1.Kubesonde{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.kubesondesOfSecret)).
+		Complete(r)
+}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kubesondev1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/findings"
+	"kubesonde.io/controllers/webhook"
+)
+
+func TestWebhookReconciler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = kubesondev1.AddToScheme(scheme)
+	request := ctrl.Request{NamespacedName: types.NamespacedName{Name: "shop", Namespace: "default"}}
+	newKubesonde := func(webhooks ...kubesondev1.Webhook) *kubesondev1.Kubesonde {
+		return &kubesondev1.Kubesonde{
+			ObjectMeta: metav1.ObjectMeta{Name: "shop", Namespace: "default"},
+			Spec:       kubesondev1.KubesondeSpec{Namespace: "shop", Webhooks: webhooks},
+		}
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "alerts", Namespace: "default"},
+		Data: map[string][]byte{
+			"url":    []byte("https://hooks.slack.com/services/T0/B0/X"),
+			"secret": []byte("s3cr3t"),
+		},
+	}
+	slack := kubesondev1.Webhook{
+		Name:             "slack",
+		URLSecretRef:     &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "alerts"}, Key: "url"},
+		Format:           webhook.SLACK_FORMAT,
+		SigningSecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "alerts"}, Key: "secret"},
+		Findings:         []string{string(findings.INTERNET_REACHABLE)},
+	}
+
+	t.Run("Resolves the URL and the signing secret from the Secret", func(t *testing.T) {
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newKubesonde(slack), secret).Build()
+		reconciler := &WebhookReconciler{Client: fakeClient, Log: logr.Discard(), Notifier: webhook.NewNotifier()}
+
+		_, err := reconciler.Reconcile(context.Background(), request)
+		assert.NoError(t, err)
+		targets := reconciler.Notifier.Targets()
+		assert.Len(t, targets, 1)
+		assert.Equal(t, "default/shop", targets[0].Kubesonde)
+		assert.Equal(t, "https://hooks.slack.com/services/T0/B0/X", targets[0].URL)
+		assert.Equal(t, []byte("s3cr3t"), targets[0].Secret)
+		assert.Equal(t, []findings.Kind{findings.INTERNET_REACHABLE}, targets[0].Kinds)
+	})
+
+	t.Run("Configures the valid webhooks and reports the others", func(t *testing.T) {
+		missing := kubesondev1.Webhook{
+			Name:         "missing",
+			URLSecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "missing"}, Key: "url"},
+		}
+		generic := kubesondev1.Webhook{Name: "generic", URL: "http://receiver.monitoring:8080/kubesonde"}
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newKubesonde(missing, generic)).Build()
+		reconciler := &WebhookReconciler{Client: fakeClient, Log: logr.Discard(), Notifier: webhook.NewNotifier()}
+
+		_, err := reconciler.Reconcile(context.Background(), request)
+		assert.ErrorContains(t, err, "webhook missing: failed to get Secret missing")
+		targets := reconciler.Notifier.Targets()
+		assert.Len(t, targets, 1)
+		assert.Equal(t, "generic", targets[0].Name)
+		assert.Nil(t, targets[0].Secret)
+	})
+
+	t.Run("Removes the webhooks of a deleted Kubesonde", func(t *testing.T) {
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newKubesonde(slack), secret).Build()
+		reconciler := &WebhookReconciler{Client: fakeClient, Log: logr.Discard(), Notifier: webhook.NewNotifier()}
+		_, err := reconciler.Reconcile(context.Background(), request)
+		assert.NoError(t, err)
+
+		assert.NoError(t, fakeClient.Delete(context.Background(), newKubesonde()))
+		_, err = reconciler.Reconcile(context.Background(), request)
+		assert.NoError(t, err)
+		assert.Empty(t, reconciler.Notifier.Targets())
+	})
+
+	t.Run("Maps a Secret to the Kubesonde objects referencing it", func(t *testing.T) {
+		other := newKubesonde(kubesondev1.Webhook{Name: "generic", URL: "http://receiver:8080"})
+		other.Name = "other"
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newKubesonde(slack), other, secret).Build()
+		reconciler := &WebhookReconciler{Client: fakeClient, Log: logr.Discard(), Notifier: webhook.NewNotifier()}
+
+		requests := reconciler.kubesondesOfSecret(context.Background(), secret)
+		assert.Equal(t, []types.NamespacedName{request.NamespacedName}, lo.Map(requests, func(r ctrl.Request, _ int) types.NamespacedName { return r.NamespacedName }))
+	})
+}
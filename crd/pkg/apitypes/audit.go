@@ -0,0 +1,52 @@
+package apitypes
+
+import (
+	"github.com/samber/lo"
+)
+
+type AuditRecord struct {
+	// Sequence starts at 1 and grows by 1 with every record of the chain
+	Sequence uint64 `json:"sequence"`
+	// Timestamp is the start of the command, RFC 3339 with nanoseconds
+	Timestamp string `json:"timestamp"`
+	// Kubesonde is the namespace/name of the object that instrumented the pod
+	Kubesonde string `json:"kubesonde"`
+	// Pod is the namespace/name of the pod the command runs in
+	Pod        string   `json:"pod"`
+	Container  string   `json:"container"`
+	Argv       []string `json:"argv"`
+	ExitStatus int      `json:"exitStatus"`
+	DurationMs int64    `json:"durationMs"`
+	// Result is succeeded, failed, error or broken
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+	// PreviousHash is the hash of the previous record, empty for the first one
+	PreviousHash string `json:"previousHash"`
+	// Hash is the hex HMAC-SHA256 of the record marshalled with an empty hash,
+	// the plain SHA-256 when the log has no key
+	Hash string `json:"hash"`
+}
+
+// AuditQuery selects records, the zero value selects all of them
+type AuditQuery struct {
+	// Kubesonde is the namespace/name of the object
+	Kubesonde string
+	// Pod is the namespace/name of the pod
+	Pod string
+	// After skips the records up to this sequence
+	After uint64
+	// Limit keeps the first records, 0 keeps all of them
+	Limit int
+}
+
+func (q AuditQuery) Apply(records []AuditRecord) []AuditRecord {
+	selected := lo.Filter(records, func(record AuditRecord, _ int) bool {
+		return record.Sequence > q.After &&
+			(q.Kubesonde == "" || record.Kubesonde == q.Kubesonde) &&
+			(q.Pod == "" || record.Pod == q.Pod)
+	})
+	if q.Limit > 0 && len(selected) > q.Limit {
+		selected = selected[:q.Limit]
+	}
+	return selected
+}
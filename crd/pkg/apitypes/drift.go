@@ -0,0 +1,54 @@
+package apitypes
+
+import (
+	"fmt"
+
+	"github.com/samber/lo"
+	v1 "kubesonde.io/api/v1"
+)
+
+type DriftKind string
+
+const (
+	// UNEXPECTED edges are allowed but not in the baseline
+	UNEXPECTED DriftKind = "unexpected"
+	// MISSING edges are in the baseline but denied or not probed
+	MISSING DriftKind = "missing"
+)
+
+// DriftEdge is a port of an edge that differs from the baseline
+type DriftEdge struct {
+	Kind     DriftKind `json:"kind"`
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	Port     string    `json:"port"`
+	Protocol string    `json:"protocol"`
+	// Action is the observed verdict, empty when the edge was not probed
+	Action v1.ActionType `json:"action,omitempty"`
+}
+
+// String formats the edge as in the status, + for the unexpected edges and -
+// for the missing ones
+func (e DriftEdge) String() string {
+	return fmt.Sprintf("%s %s -> %s %s/%s", lo.Ternary(e.Kind == UNEXPECTED, "+", "-"), e.From, e.To, e.Port, e.Protocol)
+}
+
+// Drift lists the edges that differ from the baseline
+type Drift struct {
+	Unexpected []DriftEdge `json:"unexpected"`
+	Missing    []DriftEdge `json:"missing"`
+}
+
+// Edges returns the unexpected edges followed by the missing ones
+func (d Drift) Edges() []DriftEdge {
+	return append(append([]DriftEdge{}, d.Unexpected...), d.Missing...)
+}
+
+// DriftResponse compares the probes to the baseline of a Kubesonde object
+type DriftResponse struct {
+	// Kubesonde is the namespace/name of the object referencing the baseline
+	Kubesonde string `json:"kubesonde"`
+	// Source is the namespace/name/key of the ConfigMap holding the baseline
+	Source string `json:"source"`
+	Drift  `json:",inline"`
+}
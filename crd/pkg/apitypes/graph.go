@@ -0,0 +1,66 @@
+package apitypes
+
+import (
+	v1 "kubesonde.io/api/v1"
+)
+
+type GraphLevel string
+
+const (
+	// POD_LEVEL draws one node per pod
+	POD_LEVEL GraphLevel = "pod"
+	// WORKLOAD_LEVEL collapses the replicas of a deployment or a replica set
+	WORKLOAD_LEVEL GraphLevel = "workload"
+	// NAMESPACE_LEVEL collapses all the pods and services of a namespace
+	NAMESPACE_LEVEL GraphLevel = "namespace"
+)
+
+type Verdict string
+
+const (
+	ALLOWED Verdict = "Allow"
+	DENIED  Verdict = "Deny"
+	// MIXED means that some of the merged probes were allowed and some denied
+	MIXED Verdict = "Mixed"
+)
+
+// GraphNode is a pod, a workload or a namespace depending on the level.
+// Services and Internet endpoints are never collapsed.
+type GraphNode struct {
+	ID        string               `json:"id"`
+	Label     string               `json:"label"`
+	Type      v1.ProbeEndpointType `json:"type"`
+	Namespace string               `json:"namespace,omitempty"`
+	// Workload is the deployment or replica set of a pod
+	Workload string `json:"workload,omitempty"`
+	// Members are the pods collapsed in the node
+	Members []string `json:"members,omitempty"`
+}
+
+// VerdictSummary counts the probes merged in an edge or a port
+type VerdictSummary struct {
+	Verdict Verdict `json:"verdict"`
+	Allowed int     `json:"allowed"`
+	Denied  int     `json:"denied"`
+}
+
+type GraphPort struct {
+	Port           string `json:"port"`
+	Protocol       string `json:"protocol,omitempty"`
+	VerdictSummary `json:",inline"`
+}
+
+// GraphEdge merges all the probes between two nodes
+type GraphEdge struct {
+	ID             string      `json:"id"`
+	Source         string      `json:"source"`
+	Target         string      `json:"target"`
+	Ports          []GraphPort `json:"ports"`
+	VerdictSummary `json:",inline"`
+}
+
+type Graph struct {
+	Level GraphLevel  `json:"level"`
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
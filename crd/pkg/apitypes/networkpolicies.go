@@ -0,0 +1,87 @@
+package apitypes
+
+import (
+	v1 "kubesonde.io/api/v1"
+)
+
+type Direction string
+
+const (
+	INGRESS Direction = "Ingress"
+	EGRESS  Direction = "Egress"
+)
+
+// RuleReference identifies a single ingress or egress rule of a policy
+type RuleReference struct {
+	Policy    string    `json:"policy"`
+	Namespace string    `json:"namespace"`
+	Direction Direction `json:"direction"`
+	Index     int       `json:"index"`
+}
+
+// DirectionDecision describes how the policies of one side of the connection
+// affect it
+type DirectionDecision struct {
+	// Policies lists the policies selecting the pod for this direction
+	Policies []string `json:"policies,omitempty"`
+	// Isolated is true when at least one policy selects the pod, i.e. default-deny applies
+	Isolated bool `json:"isolated"`
+	// Allowed is the outcome for this direction only
+	Allowed bool `json:"allowed"`
+	// MatchedRules lists the rules allowing the connection
+	MatchedRules []RuleReference `json:"matchedRules,omitempty"`
+}
+
+// Decision is the predicted outcome of a connection
+type Decision struct {
+	Action  v1.ActionType     `json:"action"`
+	Egress  DirectionDecision `json:"egress"`
+	Ingress DirectionDecision `json:"ingress"`
+}
+
+// Mismatch is an observed probe whose outcome differs from the outcome
+// predicted by the NetworkPolicies. Mismatches usually indicate CNI bugs or
+// features not supported by the CNI.
+type Mismatch struct {
+	Item            v1.ProbeOutputItem `json:"item"`
+	PredictedAction v1.ActionType      `json:"predictedAction"`
+	Decision        Decision           `json:"decision"`
+}
+
+// DirectionExplanation is a DirectionDecision with a human readable summary
+type DirectionExplanation struct {
+	DirectionDecision `json:",inline"`
+	Summary           string `json:"summary"`
+}
+
+// Explanation describes which policies and rules allow or block a connection
+type Explanation struct {
+	Source      string               `json:"source"`
+	Destination string               `json:"destination"`
+	Port        int32                `json:"port"`
+	Protocol    string               `json:"protocol"`
+	Action      v1.ActionType        `json:"action"`
+	Egress      DirectionExplanation `json:"egress"`
+	Ingress     DirectionExplanation `json:"ingress"`
+	// ObservedAction is the outcome of the matching probe, if any
+	ObservedAction v1.ActionType `json:"observedAction,omitempty"`
+}
+
+type DiffAction string
+
+const (
+	CREATE    DiffAction = "Create"
+	UPDATE    DiffAction = "Update"
+	UNCHANGED DiffAction = "Unchanged"
+	// UNMANAGED marks existing policies that are not generated by Kubesonde
+	UNMANAGED DiffAction = "Unmanaged"
+)
+
+// PolicyDiff compares a generated policy with the policy stored in the cluster
+type PolicyDiff struct {
+	Name      string     `json:"name"`
+	Namespace string     `json:"namespace"`
+	Action    DiffAction `json:"action"`
+	// Diff is a line diff of the YAML specs, set on updates
+	Diff string `json:"diff,omitempty"`
+}
@@ -0,0 +1,41 @@
+// Package apitypes holds the paths and the request and response bodies of the
+// results server. Both the server and the client import it, it does not
+// depend on the controllers.
+package apitypes
+
+// DEFAULT_PORT is the port the results server listens on by default
+const DEFAULT_PORT = "2709"
+
+// Paths of the endpoints of the results server
+const (
+	GET_PROBES_PATH        = "/probes"
+	GET_PROBES_STREAM_PATH = "/probes/stream"
+	POST_PROBES_CLEAR_PATH = "/probes/clear"
+	POST_PROBES_RUN_PATH   = "/probes/run"
+	POST_PROBES_ADHOC_PATH = "/probes/adhoc"
+	GET_MISMATCHES_PATH    = "/mismatches"
+	GET_EXPLAIN_PATH       = "/explain"
+	GET_POLICIES_PATH      = "/policies"
+	GET_GRAPH_PATH         = "/graph"
+	GET_QUEUE_PATH         = "/queue"
+	GET_PLAN_PATH          = "/plan"
+	GET_DRIFT_PATH         = "/drift"
+	GET_REPORT_PATH        = "/report"
+	SNAPSHOTS_PATH         = "/snapshots"
+	GET_DIFF_PATH          = "/diff"
+	GET_AUDIT_PATH         = "/audit"
+	OPENAPI_PATH           = "/openapi.json"
+	UI_PATH                = "/ui/"
+)
+
+// NEXT_CURSOR_HEADER carries the cursor of the next page of probes
+const NEXT_CURSOR_HEADER = "X-Next-Cursor"
+
+// CURRENT_SNAPSHOT refers to the live probe output in /diff
+const CURRENT_SNAPSHOT = "current"
+
+// Formats of GET /report
+const (
+	SARIF_FORMAT = "sarif"
+	JUNIT_FORMAT = "junit"
+)
@@ -0,0 +1,84 @@
+package apitypes
+
+// PlanItem is a probe the controller knows about
+type PlanItem struct {
+	// Source is the namespace/name of the source pod
+	Source               string `json:"source"`
+	Destination          string `json:"destination"`
+	DestinationNamespace string `json:"destinationNamespace,omitempty"`
+	DestinationIPAddress string `json:"destinationIPAddress,omitempty"`
+	DestinationPort      string `json:"destinationPort"`
+	Protocol             string `json:"protocol,omitempty"`
+	// LastExecution is in seconds since the epoch, absent when the probe never ran
+	LastExecution int64 `json:"lastExecution,omitempty"`
+}
+
+// Scope restricts a round to the probes whose source or destination matches
+// all the fields set. The zero value selects all the probes.
+type Scope struct {
+	Namespace string `json:"namespace,omitempty"`
+	// Workload is the deployment or the replica set of a pod
+	Workload string `json:"workload,omitempty"`
+	Pod      string `json:"pod,omitempty"`
+}
+
+// RunProbesResponse tells how many probes were queued
+type RunProbesResponse struct {
+	Scope  Scope `json:"scope"`
+	Queued int   `json:"queued"`
+}
+
+// AdhocProbe is a single probe requested on demand
+type AdhocProbe struct {
+	// Source is the `namespace/name` of the source pod, or a pod name in the default namespace
+	Source string `json:"source"`
+	// Destination is the `namespace/name` of a pod or a service, a name in the
+	// namespace of the source, or an IP address or a hostname outside the cluster
+	Destination string `json:"destination"`
+	Port        int32  `json:"port"`
+	// Protocol is TCP, UDP or SCTP, defaults to TCP
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// InFlightProbe is a probe the dispatcher is running
+type InFlightProbe struct {
+	// Source is the namespace/name of the source pod
+	Source          string `json:"source"`
+	Destination     string `json:"destination"`
+	DestinationPort string `json:"destinationPort"`
+	Protocol        string `json:"protocol,omitempty"`
+	// StartedAt is in seconds since the epoch
+	StartedAt int64 `json:"startedAt"`
+}
+
+// RoundProgress describes a round that did not complete yet
+type RoundProgress struct {
+	ID        int `json:"id"`
+	Total     int `json:"total"`
+	Remaining int `json:"remaining"`
+	// Skipped is the number of probes dropped because their pod was deleted
+	Skipped int `json:"skipped"`
+	// StartedAt is in seconds since the epoch
+	StartedAt int64 `json:"startedAt"`
+}
+
+// QueueStats describes the state of the dispatcher
+type QueueStats struct {
+	Pending int `json:"pending"`
+	// ByPriority counts the pending probes by priority: "high" or "low"
+	ByPriority map[string]int `json:"byPriority"`
+	// BySourcePod counts the pending probes by namespace/name of the source pod
+	BySourcePod map[string]int `json:"bySourcePod"`
+	// ByNamespace counts the pending probes by namespace of the source pod
+	ByNamespace map[string]int `json:"byNamespace"`
+	// OldestItemAgeSeconds is the time the oldest pending probe has been waiting
+	OldestItemAgeSeconds float64         `json:"oldestItemAgeSeconds"`
+	InFlight             []InFlightProbe `json:"inFlight"`
+	// ThroughputPerSecond is the rate of probes completed during the last minute
+	ThroughputPerSecond float64 `json:"throughputPerSecond"`
+	// EstimatedDrainSeconds is the time needed to run the pending probes at the
+	// current throughput. It is absent when no probe completed recently.
+	EstimatedDrainSeconds *float64 `json:"estimatedDrainSeconds,omitempty"`
+	// Rounds are the rounds that did not complete yet
+	Rounds []RoundProgress `json:"rounds"`
+}
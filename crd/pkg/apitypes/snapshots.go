@@ -0,0 +1,60 @@
+package apitypes
+
+import (
+	v1 "kubesonde.io/api/v1"
+)
+
+type CreateSnapshotRequest struct {
+	Label string `json:"label,omitempty"`
+}
+
+// SnapshotSummary describes a snapshot without its content
+type SnapshotSummary struct {
+	ID    string `json:"id"`
+	Label string `json:"label,omitempty"`
+	// CreatedAt is in seconds since the epoch
+	CreatedAt int64 `json:"createdAt"`
+	Items     int   `json:"items"`
+	Errors    int   `json:"errors"`
+}
+
+// SnapshotEndpoint is a workload, a service or an external address
+type SnapshotEndpoint struct {
+	Type      v1.ProbeEndpointType `json:"type,omitempty"`
+	Namespace string               `json:"namespace,omitempty"`
+	Name      string               `json:"name"`
+}
+
+// SnapshotEdge is the aggregated outcome of the probes between two endpoints.
+// A workload reaches a destination when at least one of its pods does.
+type SnapshotEdge struct {
+	Source      SnapshotEndpoint `json:"source"`
+	Destination SnapshotEndpoint `json:"destination"`
+	Port        string           `json:"port"`
+	Protocol    string           `json:"protocol,omitempty"`
+	Action      v1.ActionType    `json:"action"`
+}
+
+// ChangedEdge is an edge whose verdict changed
+type ChangedEdge struct {
+	SnapshotEdge   `json:",inline"`
+	PreviousAction v1.ActionType `json:"previousAction"`
+}
+
+// WorkloadDiff groups the changes of a workload. Edges are grouped by their source.
+type WorkloadDiff struct {
+	Namespace   string                 `json:"namespace,omitempty"`
+	Workload    string                 `json:"workload"`
+	Added       []SnapshotEdge         `json:"added,omitempty"`
+	Removed     []SnapshotEdge         `json:"removed,omitempty"`
+	Changed     []ChangedEdge          `json:"changed,omitempty"`
+	OpenedPorts []v1.PodNetworkingItem `json:"openedPorts,omitempty"`
+	ClosedPorts []v1.PodNetworkingItem `json:"closedPorts,omitempty"`
+}
+
+// SnapshotDiff lists what changed between two probe outputs
+type SnapshotDiff struct {
+	From      string         `json:"from"`
+	To        string         `json:"to"`
+	Workloads []WorkloadDiff `json:"workloads"`
+}
@@ -1,34 +1,199 @@
 package restapis
 
 import (
+	"context"
+	"crypto/tls"
+	"fmt"
 	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	certutil "k8s.io/client-go/util/cert"
+	"kubesonde.io/pkg/apitypes"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 )
 
 var log = logf.Log.WithName("controller-runtime.probe-api")
 
-func ServeHTTP() {
+const DEFAULT_BIND_ADDRESS = ":" + apitypes.DEFAULT_PORT
 
+// ServerOptions configures the probes server. The TLS and filter options
+// behave like the ones of the controller-runtime metrics server.
+type ServerOptions struct {
+	// BindAddress is the address the server listens on, DEFAULT_BIND_ADDRESS when empty
+	BindAddress string
+	// SecureServing enables TLS. The certificate is read from CertDir and
+	// reloaded when it changes; a self-signed certificate is generated when
+	// CertDir does not contain one.
+	SecureServing bool
+	CertDir       string
+	CertName      string
+	KeyName       string
+	TLSOpts       []func(*tls.Config)
+	// FilterProvider wraps all the endpoints, e.g. with
+	// filters.WithAuthenticationAndAuthorization to authenticate the bearer
+	// tokens with TokenReviews and authorize the requests with
+	// SubjectAccessReviews on the request path and verb
+	FilterProvider func(c *rest.Config, httpClient *http.Client) (metricsserver.Filter, error)
+	// RestConfig is used by the FilterProvider
+	RestConfig *rest.Config
+}
+
+// ENDPOINT_PATHS lists the paths registered by NewServeMux
+var ENDPOINT_PATHS = []string{
+	GET_PROBES_PATH,
+	GET_PROBES_STREAM_PATH,
+	POST_PROBES_CLEAR_PATH,
+	POST_PROBES_RUN_PATH,
+	POST_PROBES_ADHOC_PATH,
+	GET_MISMATCHES_PATH,
+	GET_EXPLAIN_PATH,
+	GET_POLICIES_PATH,
+	GET_GRAPH_PATH,
+	GET_QUEUE_PATH,
+	GET_PLAN_PATH,
+	GET_DRIFT_PATH,
+	GET_REPORT_PATH,
+	SNAPSHOTS_PATH,
+	GET_DIFF_PATH,
+	GET_AUDIT_PATH,
+	UI_PATH,
+	OPENAPI_PATH,
+}
+
+// NewServeMux registers all the endpoints
+func NewServeMux(client kubernetes.Interface) *http.ServeMux {
 	mux := http.NewServeMux()
 	mux.Handle(GET_PROBES_PATH, GetProbesHandler())
+	mux.Handle(GET_PROBES_STREAM_PATH, GetProbesStreamHandler())
 	mux.Handle(POST_PROBES_CLEAR_PATH, PostProbesClearHandler())
+	mux.Handle(POST_PROBES_RUN_PATH, PostProbesRunHandler())
+	mux.Handle(POST_PROBES_ADHOC_PATH, PostProbesAdhocHandler(client))
+	mux.Handle(GET_MISMATCHES_PATH, GetMismatchesHandler(client))
+	mux.Handle(GET_EXPLAIN_PATH, GetExplainHandler(client))
+	mux.Handle(GET_POLICIES_PATH, GetPoliciesHandler(client))
+	mux.Handle(GET_GRAPH_PATH, GetGraphHandler())
+	mux.Handle(GET_QUEUE_PATH, GetQueueHandler())
+	mux.Handle(GET_PLAN_PATH, GetPlanHandler())
+	mux.Handle(GET_DRIFT_PATH, GetDriftHandler())
+	mux.Handle(GET_REPORT_PATH, GetReportHandler())
+	mux.Handle(SNAPSHOTS_PATH, SnapshotsHandler())
+	mux.Handle(GET_DIFF_PATH, GetDiffHandler())
+	mux.Handle(GET_AUDIT_PATH, GetAuditHandler())
+	mux.Handle(UI_PATH, GetUIHandler())
+	mux.Handle(OPENAPI_PATH, GetOpenAPIHandler())
+	return mux
+}
+
+// NewHandler returns the endpoints wrapped by the filter of the options
+func NewHandler(client kubernetes.Interface, options ServerOptions) (http.Handler, error) {
+	var handler http.Handler = NewServeMux(client)
+	if options.FilterProvider == nil {
+		return handler, nil
+	}
+	httpClient, err := rest.HTTPClientFor(options.RestConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http client: %w", err)
+	}
+	filter, err := options.FilterProvider(options.RestConfig, httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create filter: %w", err)
+	}
+	filtered, err := filter(log, handler)
+	if err != nil {
+		return nil, err
+	}
+	// The static assets of the viewer hold no results, a browser loads them
+	// without a token. The viewer then sends its token to /probes.
+	mux := http.NewServeMux()
+	mux.Handle(UI_PATH, GetUIHandler())
+	mux.Handle("/", filtered)
+	return mux, nil
+}
+
+func ServeHTTP(client kubernetes.Interface, options ServerOptions) error {
+	if options.BindAddress == "" {
+		options.BindAddress = DEFAULT_BIND_ADDRESS
+	}
+	handler, err := NewHandler(client, options)
+	if err != nil {
+		return err
+	}
+	listener, err := createListener(context.Background(), options)
+	if err != nil {
+		return err
+	}
+
 	server := http.Server{
-		Handler:           mux,
+		Handler:           handler,
 		ReadHeaderTimeout: 2 * time.Second,
 	}
 	// Run the server
 	go func() {
-		log.Info("starting probes server", "paths", []string{GET_PROBES_PATH, POST_PROBES_CLEAR_PATH})
-		listener, err := net.Listen("tcp", ":2709") // #nosec G102
-		if err != nil {
-			log.Error(err, "Could not listen the given address")
-		}
+		log.Info("starting probes server", "address", listener.Addr().String(), "secure", options.SecureServing, "paths", ENDPOINT_PATHS)
 		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
 			log.Error(err, "Could not serve endpoint")
 		}
 	}()
+	return nil
+}
+
+func createListener(ctx context.Context, options ServerOptions) (net.Listener, error) {
+	listener, err := net.Listen("tcp", options.BindAddress) // #nosec G102
+	if err != nil {
+		return nil, fmt.Errorf("could not listen on %s: %w", options.BindAddress, err)
+	}
+	if !options.SecureServing {
+		return listener, nil
+	}
+
+	cfg := &tls.Config{
+		NextProtos: []string{"h2"},
+		MinVersion: tls.VersionTLS12,
+	}
+	for _, op := range options.TLSOpts {
+		op(cfg)
+	}
+
+	if cfg.GetCertificate == nil && options.CertDir != "" {
+		certPath := filepath.Join(options.CertDir, options.CertName)
+		keyPath := filepath.Join(options.CertDir, options.KeyName)
+		_, certErr := os.Stat(certPath)
+		_, keyErr := os.Stat(keyPath)
+		if certErr == nil && keyErr == nil {
+			certWatcher, err := certwatcher.New(certPath, keyPath)
+			if err != nil {
+				_ = listener.Close()
+				return nil, err
+			}
+			cfg.GetCertificate = certWatcher.GetCertificate
+			go func() {
+				if err := certWatcher.Start(ctx); err != nil {
+					log.Error(err, "certificate watcher error")
+				}
+			}()
+		}
+	}
+
+	if cfg.GetCertificate == nil {
+		cert, key, err := certutil.GenerateSelfSignedCertKeyWithFixtures("localhost", []net.IP{{127, 0, 0, 1}}, nil, "")
+		if err != nil {
+			_ = listener.Close()
+			return nil, fmt.Errorf("failed to generate self-signed certificate for probes server: %w", err)
+		}
+		keyPair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			_ = listener.Close()
+			return nil, fmt.Errorf("failed to create self-signed key pair for probes server: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{keyPair}
+	}
 
+	return tls.NewListener(listener, cfg), nil
 }
@@ -0,0 +1,95 @@
+package restapis
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+var _ = Describe("Server", func() {
+	It("Wraps the endpoints with the filter", func() {
+		// Only allows reads, like a token bound to the results viewer role
+		readOnly := func(*rest.Config, *http.Client) (metricsserver.Filter, error) {
+			return func(_ logr.Logger, handler http.Handler) (http.Handler, error) {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.Method != http.MethodGet {
+						http.Error(w, "Forbidden", http.StatusForbidden)
+						return
+					}
+					handler.ServeHTTP(w, r)
+				}), nil
+			}, nil
+		}
+		handler, err := NewHandler(fake.NewSimpleClientset(), ServerOptions{
+			FilterProvider: readOnly,
+			RestConfig:     &rest.Config{Host: "https://localhost:6443"},
+		})
+		Expect(err).To(BeNil())
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost:2709/probes", nil))
+		Expect(w.Code).To(Equal(200))
+
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("POST", "http://localhost:2709/probes/clear", nil))
+		Expect(w.Code).To(Equal(403))
+	})
+
+	It("Serves the assets of the viewer without a token", func() {
+		authenticated := func(*rest.Config, *http.Client) (metricsserver.Filter, error) {
+			return func(_ logr.Logger, handler http.Handler) (http.Handler, error) {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.Header.Get("Authorization") == "" {
+						http.Error(w, "Unauthorized", http.StatusUnauthorized)
+						return
+					}
+					handler.ServeHTTP(w, r)
+				}), nil
+			}, nil
+		}
+		handler, err := NewHandler(fake.NewSimpleClientset(), ServerOptions{
+			FilterProvider: authenticated,
+			RestConfig:     &rest.Config{Host: "https://localhost:6443"},
+		})
+		Expect(err).To(BeNil())
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost:2709/ui/", nil))
+		Expect(w.Code).NotTo(Equal(http.StatusUnauthorized))
+
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost:2709/probes", nil))
+		Expect(w.Code).To(Equal(http.StatusUnauthorized))
+
+		w = httptest.NewRecorder()
+		request := httptest.NewRequest("GET", "http://localhost:2709/probes", nil)
+		request.Header.Set("Authorization", "Bearer token")
+		handler.ServeHTTP(w, request)
+		Expect(w.Code).To(Equal(http.StatusOK))
+	})
+
+	It("Serves TLS with a self-signed certificate", func() {
+		listener, err := createListener(context.Background(), ServerOptions{BindAddress: "127.0.0.1:0", SecureServing: true})
+		Expect(err).To(BeNil())
+		server := &http.Server{Handler: NewServeMux(fake.NewSimpleClientset())}
+		go func() { _ = server.Serve(listener) }()
+		defer server.Close()
+
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // #nosec G402
+		}}
+		resp, err := client.Get("https://" + listener.Addr().String() + "/probes")
+		Expect(err).To(BeNil())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(resp.TLS).NotTo(BeNil())
+	})
+})
@@ -0,0 +1,55 @@
+package restapis
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"kubesonde.io/controllers/audit"
+	"kubesonde.io/pkg/apitypes"
+)
+
+const GET_AUDIT_PATH = apitypes.GET_AUDIT_PATH
+
+func GetAuditHandler() http.Handler {
+	return GetAuditHandlerWithLog(audit.GetDefaultLog)
+}
+
+// GetAuditHandlerWithLog returns the last audit records of the commands run
+// in the pods, oldest first. The query parameters are:
+//   - kubesonde: namespace/name of the object that ran the commands
+//   - pod: namespace/name of the pod the commands ran in
+//   - after: skips the records up to this sequence
+//   - limit: maximum number of records to return
+func GetAuditHandlerWithLog(auditLog func() *audit.Log) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		selection := audit.Query{Kubesonde: query.Get("kubesonde"), Pod: query.Get("pod")}
+		var err error
+		if query.Get("after") != "" {
+			if selection.After, err = strconv.ParseUint(query.Get("after"), 10, 64); err != nil {
+				http.Error(w, "Invalid after", http.StatusBadRequest)
+				return
+			}
+		}
+		if query.Get("limit") != "" {
+			if selection.Limit, err = strconv.Atoi(query.Get("limit")); err != nil || selection.Limit < 0 {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+
+		data, err := json.MarshalIndent(selection.Apply(auditLog().Records()), "", "  ")
+		if err != nil {
+			log.Error(err, "[GET /audit] Failed to marshal audit records")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		writeCacheable(w, r, GET_AUDIT_PATH, "application/json", data)
+	})
+}
@@ -0,0 +1,54 @@
+package restapis
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"kubesonde.io/controllers/audit"
+)
+
+var _ = Describe("GetAudit", func() {
+	var auditLog *audit.Log
+
+	BeforeEach(func() {
+		auditLog = audit.NewLog(nil)
+		auditLog.Attribute("shop/web-1", "default/shop")
+		auditLog.Attribute("shop/db-1", "default/shop")
+		for _, pod := range []string{"web-1", "db-1", "web-1"} {
+			_, err := auditLog.Append(audit.Record{Pod: "shop/" + pod, Container: "debugger", Argv: []string{"nc", "-z", "api", "8080"}, Result: audit.SUCCEEDED})
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		GetAuditHandlerWithLog(func() *audit.Log { return auditLog }).ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w
+	}
+
+	It("Returns the records", func() {
+		w := get("http://localhost:2709/audit")
+
+		Expect(w.Code).To(Equal(200))
+		var records []audit.Record
+		Expect(json.Unmarshal(w.Body.Bytes(), &records)).To(Succeed())
+		Expect(records).To(Equal(auditLog.Records()))
+	})
+
+	It("Filters the records", func() {
+		w := get("http://localhost:2709/audit?kubesonde=default/shop&pod=shop/web-1&after=1&limit=5")
+
+		Expect(w.Code).To(Equal(200))
+		var records []audit.Record
+		Expect(json.Unmarshal(w.Body.Bytes(), &records)).To(Succeed())
+		Expect(records).To(HaveLen(1))
+		Expect(records[0].Sequence).To(Equal(uint64(3)))
+	})
+
+	It("Rejects invalid parameters", func() {
+		Expect(get("http://localhost:2709/audit?after=-1").Code).To(Equal(400))
+		Expect(get("http://localhost:2709/audit?limit=x").Code).To(Equal(400))
+	})
+})
@@ -0,0 +1,73 @@
+package restapis
+
+import (
+	"encoding/json"
+	"net/http"
+
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/snapshot"
+	"kubesonde.io/controllers/state"
+	"kubesonde.io/pkg/apitypes"
+)
+
+const GET_DIFF_PATH = apitypes.GET_DIFF_PATH
+
+// CURRENT_SNAPSHOT refers to the live probe output in /diff
+const CURRENT_SNAPSHOT = apitypes.CURRENT_SNAPSHOT
+
+func GetDiffHandler() http.Handler {
+	return GetDiffHandlerWithManager(state.GetDefaultManager(), snapshot.GetDefaultStore())
+}
+
+// GetDiffHandlerWithManager compares two snapshots. The `from` and `to` query
+// parameters are snapshot IDs or labels; `to` defaults to `current`, the live
+// probe output.
+func GetDiffHandlerWithManager(sm *state.StateManager, store *snapshot.Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		if query.Get("from") == "" {
+			http.Error(w, "Missing from parameter", http.StatusBadRequest)
+			return
+		}
+		to := query.Get("to")
+		if to == "" {
+			to = CURRENT_SNAPSHOT
+		}
+		outputs := map[string]v1.ProbeOutput{}
+		for _, id := range []string{query.Get("from"), to} {
+			if id == CURRENT_SNAPSHOT {
+				outputs[id] = sm.GetProbeState()
+				continue
+			}
+			found, ok := store.Get(id)
+			if !ok {
+				http.Error(w, "Snapshot "+id+" not found", http.StatusNotFound)
+				return
+			}
+			outputs[id] = found.Output
+		}
+
+		diff := snapshot.Diff{
+			From:      query.Get("from"),
+			To:        to,
+			Workloads: snapshot.Compare(outputs[query.Get("from")], outputs[to]),
+		}
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			log.Error(err, "[GET /diff] Failed to marshal diff")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(data); err != nil {
+			log.Error(err, "[GET /diff] Failed to write response")
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package restapis
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"kubesonde.io/controllers/baseline"
+	"kubesonde.io/controllers/state"
+	"kubesonde.io/pkg/apitypes"
+)
+
+const GET_DRIFT_PATH = apitypes.GET_DRIFT_PATH
+
+// DriftResponse compares the probes to the baseline of a Kubesonde object
+type DriftResponse = apitypes.DriftResponse
+
+func GetDriftHandler() http.Handler {
+	return GetDriftHandlerWithManager(state.GetDefaultManager(), baseline.GetCurrent)
+}
+
+// GetDriftHandlerWithManager lists the allowed edges missing from the baseline
+// and the edges of the baseline that are denied or were not probed
+func GetDriftHandlerWithManager(sm *state.StateManager, current func() (baseline.Current, bool)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		approved, found := current()
+		if !found {
+			http.Error(w, "No baseline configured", http.StatusNotFound)
+			return
+		}
+		response := DriftResponse{
+			Kubesonde: approved.Kubesonde,
+			Source:    approved.Source,
+			Drift:     baseline.Compare(approved.Baseline, sm.GetProbeState()),
+		}
+		data, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			log.Error(err, "[GET /drift] Failed to marshal drift")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		writeCacheable(w, r, GET_DRIFT_PATH, "application/json", data)
+	})
+}
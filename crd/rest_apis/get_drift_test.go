@@ -0,0 +1,57 @@
+package restapis
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/baseline"
+	"kubesonde.io/controllers/state"
+)
+
+var _ = Describe("GetDrift", func() {
+	var stateManager *state.StateManager
+
+	BeforeEach(func() {
+		stateManager = state.NewStateManager()
+		web := v1.ProbeEndpointInfo{Type: v1.POD, Name: "web-1", Namespace: "shop", DeploymentName: "web"}
+		db := v1.ProbeEndpointInfo{Type: v1.POD, Name: "db-1", Namespace: "shop", DeploymentName: "db"}
+		Expect(stateManager.AppendProbes(&[]v1.ProbeOutputItem{
+			{Type: v1.PROBE, ResultingAction: v1.ALLOW, Source: web, Destination: db, Port: "5432", Protocol: "TCP"},
+		})).To(Succeed())
+	})
+
+	It("Compares the probes to the baseline", func() {
+		current := func() (baseline.Current, bool) {
+			return baseline.Current{
+				Kubesonde: "default/shop",
+				Source:    "default/approved/baseline.yaml",
+				Baseline:  baseline.Baseline{Edges: []baseline.Edge{{From: "shop/web", To: "shop/api", Ports: []string{"8080"}}}},
+			}, true
+		}
+		w := httptest.NewRecorder()
+		GetDriftHandlerWithManager(stateManager, current).ServeHTTP(w, httptest.NewRequest("GET", "http://localhost:2709/drift", nil))
+
+		Expect(w.Code).To(Equal(200))
+		var response DriftResponse
+		Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+		Expect(response.Kubesonde).To(Equal("default/shop"))
+		Expect(response.Source).To(Equal("default/approved/baseline.yaml"))
+		Expect(response.Unexpected).To(Equal([]baseline.DriftEdge{
+			{Kind: baseline.UNEXPECTED, From: "shop/web", To: "shop/db", Port: "5432", Protocol: "TCP", Action: v1.ALLOW},
+		}))
+		Expect(response.Missing).To(Equal([]baseline.DriftEdge{
+			{Kind: baseline.MISSING, From: "shop/web", To: "shop/api", Port: "8080", Protocol: "TCP"},
+		}))
+	})
+
+	It("Returns 404 without baseline", func() {
+		none := func() (baseline.Current, bool) { return baseline.Current{}, false }
+		w := httptest.NewRecorder()
+		GetDriftHandlerWithManager(stateManager, none).ServeHTTP(w, httptest.NewRequest("GET", "http://localhost:2709/drift", nil))
+
+		Expect(w.Code).To(Equal(404))
+	})
+})
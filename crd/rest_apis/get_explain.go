@@ -0,0 +1,96 @@
+package restapis
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+	"k8s.io/client-go/kubernetes"
+	v1 "kubesonde.io/api/v1"
+	networkpolicy "kubesonde.io/controllers/network-policy"
+	"kubesonde.io/controllers/state"
+	"kubesonde.io/pkg/apitypes"
+)
+
+const GET_EXPLAIN_PATH = apitypes.GET_EXPLAIN_PATH
+
+func GetExplainHandler(client kubernetes.Interface) http.Handler {
+	return GetExplainHandlerWithManager(state.GetDefaultManager(), networkpolicy.ClusterEvaluatorProvider(client))
+}
+
+// GetExplainHandlerWithManager explains which NetworkPolicy rules allow or block
+// the connection described by the `source`, `destination`, `port` and
+// `protocol` query parameters. Source and destination are either
+// `namespace/name`, a pod name or an IP address.
+func GetExplainHandlerWithManager(sm *state.StateManager, provider networkpolicy.EvaluatorProvider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		source, destination := query.Get("source"), query.Get("destination")
+		protocol := strings.ToUpper(lo.Ternary(query.Get("protocol") == "", "TCP", query.Get("protocol")))
+		port, err := strconv.ParseInt(query.Get("port"), 10, 32)
+		if source == "" || destination == "" || err != nil {
+			http.Error(w, "source, destination and port are required", http.StatusBadRequest)
+			return
+		}
+
+		evaluator, err := provider()
+		if err != nil {
+			log.Error(err, "[GET /explain] Failed to load network policies")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		src, err := evaluator.ResolveTarget(source)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		dst, err := evaluator.ResolveTarget(destination)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		explanation := evaluator.Explain(src, dst, int32(port), protocol)
+		if observed, found := findObservedProbe(sm.GetProbeState().Items, src, dst, fmt.Sprint(port), protocol); found {
+			explanation.ObservedAction = observed.ResultingAction
+		}
+
+		data, err := json.MarshalIndent(explanation, "", "  ")
+		if err != nil {
+			log.Error(err, "[GET /explain] Failed to marshal explanation")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(data); err != nil {
+			log.Error(err, "[GET /explain] Failed to write response")
+		}
+	})
+}
+
+func endpointMatches(info v1.ProbeEndpointInfo, endpoint networkpolicy.Endpoint) bool {
+	if endpoint.Pod != nil {
+		return info.Name == endpoint.Pod.Name && info.Namespace == endpoint.Pod.Namespace
+	}
+	return info.IPAddress == endpoint.IP
+}
+
+func findObservedProbe(items []v1.ProbeOutputItem, src networkpolicy.Endpoint, dst networkpolicy.Endpoint, port string, protocol string) (v1.ProbeOutputItem, bool) {
+	return lo.Find(items, func(item v1.ProbeOutputItem) bool {
+		return item.Type == v1.PROBE &&
+			item.Port == port &&
+			strings.EqualFold(item.Protocol, protocol) &&
+			endpointMatches(item.Source, src) &&
+			endpointMatches(item.Destination, dst)
+	})
+}
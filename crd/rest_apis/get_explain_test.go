@@ -0,0 +1,81 @@
+package restapis
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "kubesonde.io/api/v1"
+	networkpolicy "kubesonde.io/controllers/network-policy"
+	"kubesonde.io/controllers/state"
+)
+
+var _ = Describe("GetExplain", func() {
+	var stateManager *state.StateManager
+	var provider networkpolicy.EvaluatorProvider
+
+	BeforeEach(func() {
+		stateManager = state.NewStateManager()
+		innerState := v1.ProbeOutput{
+			Items: []v1.ProbeOutputItem{
+				{
+					Type:            v1.PROBE,
+					Source:          v1.ProbeEndpointInfo{Type: v1.POD, Name: "src", Namespace: "default"},
+					Destination:     v1.ProbeEndpointInfo{Type: v1.POD, Name: "dst", Namespace: "default"},
+					Port:            "80",
+					Protocol:        "TCP",
+					ResultingAction: v1.ALLOW,
+				},
+			},
+			Errors:                     []v1.ProbeOutputError{},
+			PodNetworking:              []v1.PodNetworkingInfo{},
+			PodNetworkingV2:            make(v1.PodNetworkingInfoV2),
+			PodConfigurationNetworking: make(v1.PodNetworkingInfoV2),
+		}
+		Expect(stateManager.SetProbeState(&innerState)).To(Succeed())
+		pods := []corev1.Pod{
+			{ObjectMeta: metav1.ObjectMeta{Name: "src", Namespace: "default"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "dst", Namespace: "default"}},
+		}
+		policies := []networkingv1.NetworkPolicy{
+			{ObjectMeta: metav1.ObjectMeta{Name: "deny-all", Namespace: "default"}},
+		}
+		provider = func() (*networkpolicy.Evaluator, error) {
+			return networkpolicy.NewEvaluator(pods, nil, nil, policies), nil
+		}
+	})
+
+	It("Explains a connection", func() {
+		req := httptest.NewRequest("GET", "http://localhost:2709/explain?source=default/src&destination=dst&port=80", nil)
+		w := httptest.NewRecorder()
+		GetExplainHandlerWithManager(stateManager, provider).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(200))
+		var explanation networkpolicy.Explanation
+		Expect(json.Unmarshal(w.Body.Bytes(), &explanation)).To(Succeed())
+		Expect(explanation.Action).To(Equal(v1.DENY))
+		Expect(explanation.ObservedAction).To(Equal(v1.ALLOW))
+		Expect(explanation.Ingress.Isolated).To(BeTrue())
+		Expect(explanation.Ingress.Policies).To(Equal([]string{"default/deny-all"}))
+	})
+
+	It("Returns 400 on missing parameters", func() {
+		req := httptest.NewRequest("GET", "http://localhost:2709/explain?source=default/src", nil)
+		w := httptest.NewRecorder()
+		GetExplainHandlerWithManager(stateManager, provider).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(400))
+	})
+
+	It("Returns 404 on unknown pods", func() {
+		req := httptest.NewRequest("GET", "http://localhost:2709/explain?source=default/unknown&destination=dst&port=80", nil)
+		w := httptest.NewRecorder()
+		GetExplainHandlerWithManager(stateManager, provider).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(404))
+	})
+})
@@ -0,0 +1,49 @@
+package restapis
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"kubesonde.io/controllers/graph"
+	probequery "kubesonde.io/controllers/probe-query"
+	"kubesonde.io/controllers/state"
+	"kubesonde.io/pkg/apitypes"
+)
+
+const GET_GRAPH_PATH = apitypes.GET_GRAPH_PATH
+
+func GetGraphHandler() http.Handler {
+	return GetGraphHandlerWithManager(state.GetDefaultManager())
+}
+
+// GetGraphHandlerWithManager returns the probes aggregated as nodes and edges.
+// The `level` query parameter is `pod` (default), `workload` or `namespace`;
+// the /probes filters select the probes to aggregate.
+func GetGraphHandlerWithManager(sm *state.StateManager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		level, err := graph.ParseLevel(query.Get("level"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter, err := probequery.ParseFilter(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		data, err := json.MarshalIndent(graph.Build(filter.Apply(sm.GetProbeState()), level), "", "  ")
+		if err != nil {
+			log.Error(err, "[GET /graph] Failed to marshal graph")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		writeCacheable(w, r, GET_GRAPH_PATH, "application/json", data)
+	})
+}
@@ -0,0 +1,49 @@
+package restapis
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/graph"
+	"kubesonde.io/controllers/state"
+)
+
+var _ = Describe("GetGraph", func() {
+	var stateManager *state.StateManager
+
+	BeforeEach(func() {
+		stateManager = state.NewStateManager()
+		replica := func(name string) v1.ProbeEndpointInfo {
+			return v1.ProbeEndpointInfo{Type: v1.POD, Name: name, Namespace: "default", DeploymentName: "src"}
+		}
+		dst := v1.ProbeEndpointInfo{Type: v1.POD, Name: "dst", Namespace: "default"}
+		Expect(stateManager.AppendProbes(&[]v1.ProbeOutputItem{
+			{Type: v1.PROBE, ResultingAction: v1.ALLOW, Source: replica("src-1"), Destination: dst, Port: "80", Protocol: "TCP"},
+			{Type: v1.PROBE, ResultingAction: v1.ALLOW, Source: replica("src-2"), Destination: dst, Port: "80", Protocol: "TCP"},
+		})).To(Succeed())
+	})
+
+	It("Aggregates the workloads", func() {
+		req := httptest.NewRequest("GET", "http://localhost:2709/graph?level=workload", nil)
+		w := httptest.NewRecorder()
+		GetGraphHandlerWithManager(stateManager).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(200))
+		var g graph.Graph
+		Expect(json.Unmarshal(w.Body.Bytes(), &g)).To(Succeed())
+		Expect(g.Level).To(Equal(graph.WORKLOAD_LEVEL))
+		Expect(g.Nodes).To(HaveLen(2))
+		Expect(g.Edges).To(HaveLen(1))
+		Expect(g.Edges[0].Allowed).To(Equal(2))
+	})
+
+	It("Rejects unknown levels", func() {
+		req := httptest.NewRequest("GET", "http://localhost:2709/graph?level=cluster", nil)
+		w := httptest.NewRecorder()
+		GetGraphHandlerWithManager(stateManager).ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(400))
+	})
+})
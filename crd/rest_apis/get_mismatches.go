@@ -0,0 +1,49 @@
+package restapis
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"k8s.io/client-go/kubernetes"
+	networkpolicy "kubesonde.io/controllers/network-policy"
+	"kubesonde.io/controllers/state"
+	"kubesonde.io/pkg/apitypes"
+)
+
+const GET_MISMATCHES_PATH = apitypes.GET_MISMATCHES_PATH
+
+func GetMismatchesHandler(client kubernetes.Interface) http.Handler {
+	return GetMismatchesHandlerWithManager(state.GetDefaultManager(), networkpolicy.ClusterEvaluatorProvider(client))
+}
+
+// GetMismatchesHandlerWithManager returns the probes whose outcome differs from
+// the outcome predicted by the NetworkPolicies of the cluster
+func GetMismatchesHandlerWithManager(sm *state.StateManager, provider networkpolicy.EvaluatorProvider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		evaluator, err := provider()
+		if err != nil {
+			log.Error(err, "[GET /mismatches] Failed to load network policies")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		mismatches := evaluator.FindMismatches(sm.GetProbeState().Items)
+
+		data, err := json.MarshalIndent(mismatches, "", "  ")
+		if err != nil {
+			log.Error(err, "[GET /mismatches] Failed to marshal mismatches")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(data); err != nil {
+			log.Error(err, "[GET /mismatches] Failed to write response")
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package restapis
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "kubesonde.io/api/v1"
+	networkpolicy "kubesonde.io/controllers/network-policy"
+	"kubesonde.io/controllers/state"
+)
+
+var _ = Describe("GetMismatches", func() {
+	var stateManager *state.StateManager
+
+	BeforeEach(func() {
+		stateManager = state.NewStateManager()
+		innerState := v1.ProbeOutput{
+			Items: []v1.ProbeOutputItem{
+				{
+					Type:            v1.PROBE,
+					Source:          v1.ProbeEndpointInfo{Type: v1.POD, Name: "src", Namespace: "default"},
+					Destination:     v1.ProbeEndpointInfo{Type: v1.POD, Name: "dst", Namespace: "default"},
+					Port:            "80",
+					Protocol:        "TCP",
+					ResultingAction: v1.ALLOW,
+				},
+			},
+			Errors:                     []v1.ProbeOutputError{},
+			PodNetworking:              []v1.PodNetworkingInfo{},
+			PodNetworkingV2:            make(v1.PodNetworkingInfoV2),
+			PodConfigurationNetworking: make(v1.PodNetworkingInfoV2),
+		}
+		Expect(stateManager.SetProbeState(&innerState)).To(Succeed())
+	})
+
+	It("Returns the probes contradicting the policies", func() {
+		pods := []corev1.Pod{
+			{ObjectMeta: metav1.ObjectMeta{Name: "src", Namespace: "default"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "dst", Namespace: "default"}},
+		}
+		policies := []networkingv1.NetworkPolicy{
+			{ObjectMeta: metav1.ObjectMeta{Name: "deny-all", Namespace: "default"}},
+		}
+		provider := func() (*networkpolicy.Evaluator, error) {
+			return networkpolicy.NewEvaluator(pods, nil, nil, policies), nil
+		}
+
+		req := httptest.NewRequest("GET", "http://localhost:2709/mismatches", nil)
+		w := httptest.NewRecorder()
+		GetMismatchesHandlerWithManager(stateManager, provider).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(200))
+		var mismatches []networkpolicy.Mismatch
+		Expect(json.Unmarshal(w.Body.Bytes(), &mismatches)).To(Succeed())
+		Expect(mismatches).To(HaveLen(1))
+		Expect(mismatches[0].PredictedAction).To(Equal(v1.DENY))
+		Expect(mismatches[0].Decision.Ingress.Policies).To(Equal([]string{"default/deny-all"}))
+	})
+
+	It("Returns 500 when the policies cannot be loaded", func() {
+		provider := func() (*networkpolicy.Evaluator, error) {
+			return nil, errors.New("forbidden")
+		}
+		req := httptest.NewRequest("GET", "http://localhost:2709/mismatches", nil)
+		w := httptest.NewRecorder()
+		GetMismatchesHandlerWithManager(stateManager, provider).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(500))
+	})
+})
@@ -0,0 +1,71 @@
+package restapis
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/samber/lo"
+	eventstorage "kubesonde.io/controllers/event-storage"
+	"kubesonde.io/controllers/probe_command"
+	"kubesonde.io/pkg/apitypes"
+)
+
+const GET_PLAN_PATH = apitypes.GET_PLAN_PATH
+
+// PlanItem is a probe the controller knows about
+type PlanItem = apitypes.PlanItem
+
+func GetPlanHandler() http.Handler {
+	return GetPlanHandlerWithStorage(eventstorage.GetProbes, eventstorage.GetLastExecution)
+}
+
+// GetPlanHandlerWithStorage lists the probe commands known to the controller,
+// sorted by source and destination, with the time they last ran.
+func GetPlanHandlerWithStorage(
+	probes func() []probe_command.KubesondeCommand,
+	lastExecution func(probe_command.KubesondeCommand) (time.Time, bool),
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		plan := lo.Map(probes(), func(command probe_command.KubesondeCommand, _ int) PlanItem {
+			item := PlanItem{
+				Source:               fmt.Sprintf("%s/%s", command.Namespace, command.SourcePodName),
+				Destination:          command.Destination,
+				DestinationNamespace: command.DestinationNamespace,
+				DestinationIPAddress: command.DestinationIPAddress,
+				DestinationPort:      command.DestinationPort,
+				Protocol:             command.Protocol,
+			}
+			if at, ok := lastExecution(command); ok {
+				item.LastExecution = at.Unix()
+			}
+			return item
+		})
+		slices.SortFunc(plan, func(a, b PlanItem) int {
+			return cmp.Or(
+				cmp.Compare(a.Source, b.Source),
+				cmp.Compare(a.DestinationNamespace, b.DestinationNamespace),
+				cmp.Compare(a.Destination, b.Destination),
+				cmp.Compare(a.DestinationIPAddress, b.DestinationIPAddress),
+				cmp.Compare(a.DestinationPort, b.DestinationPort),
+				cmp.Compare(a.Protocol, b.Protocol),
+			)
+		})
+
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			log.Error(err, "[GET /plan] Failed to marshal plan")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		writeCacheable(w, r, GET_PLAN_PATH, "application/json", data)
+	})
+}
@@ -0,0 +1,50 @@
+package restapis
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"kubesonde.io/controllers/probe_command"
+)
+
+var _ = Describe("GetPlan", func() {
+	probes := func() []probe_command.KubesondeCommand {
+		return []probe_command.KubesondeCommand{
+			{SourcePodName: "b", Namespace: "default", Destination: "dst", DestinationPort: "80", Protocol: "TCP"},
+			{SourcePodName: "a", Namespace: "default", Destination: "dst", DestinationPort: "443", Protocol: "TCP"},
+		}
+	}
+	lastExecution := func(command probe_command.KubesondeCommand) (time.Time, bool) {
+		if command.SourcePodName == "a" {
+			return time.Unix(1700000000, 0), true
+		}
+		return time.Time{}, false
+	}
+
+	It("Lists the probes with their last execution", func() {
+		req := httptest.NewRequest("GET", "http://localhost:2709/plan", nil)
+		w := httptest.NewRecorder()
+		GetPlanHandlerWithStorage(probes, lastExecution).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(200))
+		var plan []PlanItem
+		Expect(json.Unmarshal(w.Body.Bytes(), &plan)).To(Succeed())
+		Expect(plan).To(Equal([]PlanItem{
+			{Source: "default/a", Destination: "dst", DestinationPort: "443", Protocol: "TCP", LastExecution: 1700000000},
+			{Source: "default/b", Destination: "dst", DestinationPort: "80", Protocol: "TCP"},
+		}))
+	})
+
+	It("Returns an empty list without probes", func() {
+		none := func() []probe_command.KubesondeCommand { return nil }
+		req := httptest.NewRequest("GET", "http://localhost:2709/plan", nil)
+		w := httptest.NewRecorder()
+		GetPlanHandlerWithStorage(none, lastExecution).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(200))
+		Expect(w.Body.String()).To(Equal("[]"))
+	})
+})
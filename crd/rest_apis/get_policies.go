@@ -0,0 +1,92 @@
+package restapis
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/samber/lo"
+
+	"k8s.io/client-go/kubernetes"
+	policygenerator "kubesonde.io/controllers/policy-generator"
+	"kubesonde.io/controllers/state"
+	"kubesonde.io/pkg/apitypes"
+)
+
+const GET_POLICIES_PATH = apitypes.GET_POLICIES_PATH
+
+func GetPoliciesHandler(client kubernetes.Interface) http.Handler {
+	return GetPoliciesHandlerWithManager(state.GetDefaultManager(), policygenerator.ClusterPoliciesProvider(client))
+}
+
+// GetPoliciesHandlerWithManager generates least-privilege NetworkPolicies from
+// the observed traffic. Query parameters:
+//   - namespace: restricts the generation to a namespace
+//   - format: `yaml` (default) or `json`
+//   - flavor: `kubernetes` (default), `cilium` or `calico`
+//   - dryRun: when `true` returns the diff against the existing policies, only
+//     supported by the `kubernetes` flavor
+func GetPoliciesHandlerWithManager(sm *state.StateManager, existing policygenerator.ExistingPoliciesProvider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		namespace := query.Get("namespace")
+		output := sm.GetProbeState()
+		policies := policygenerator.GeneratePolicies(output, namespace)
+
+		flavor := lo.Ternary(query.Get("flavor") == "", "kubernetes", query.Get("flavor"))
+		var objects []any
+		switch flavor {
+		case "kubernetes":
+			objects = lo.ToAnySlice(policies)
+		case "cilium":
+			objects = lo.ToAnySlice(policygenerator.GenerateCiliumPolicies(output, namespace))
+		case "calico":
+			objects = lo.ToAnySlice(policygenerator.GenerateCalicoPolicies(output, namespace))
+			objects = append(objects, policygenerator.GenerateCalicoDefaultDeny(output, namespace))
+		default:
+			http.Error(w, "Unsupported flavor", http.StatusBadRequest)
+			return
+		}
+
+		var data []byte
+		var err error
+		contentType := "application/json"
+		switch {
+		case query.Get("dryRun") == "true":
+			if flavor != "kubernetes" {
+				http.Error(w, "dryRun is only supported by the kubernetes flavor", http.StatusBadRequest)
+				return
+			}
+			current, listErr := existing(namespace)
+			if listErr != nil {
+				log.Error(listErr, "[GET /policies] Failed to list network policies")
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			data, err = json.MarshalIndent(policygenerator.DiffPolicies(current, policies), "", "  ")
+		case query.Get("format") == "json":
+			data, err = json.MarshalIndent(objects, "", "  ")
+		case query.Get("format") == "" || query.Get("format") == "yaml":
+			contentType = "application/yaml"
+			data, err = policygenerator.RenderYAML(objects)
+		default:
+			http.Error(w, "Unsupported format", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Error(err, "[GET /policies] Failed to marshal policies")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(data); err != nil {
+			log.Error(err, "[GET /policies] Failed to write response")
+		}
+	})
+}
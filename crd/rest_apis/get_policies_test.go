@@ -0,0 +1,108 @@
+package restapis
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+	v1 "kubesonde.io/api/v1"
+	policygenerator "kubesonde.io/controllers/policy-generator"
+	"kubesonde.io/controllers/state"
+)
+
+var _ = Describe("GetPolicies", func() {
+	var stateManager *state.StateManager
+	noPolicies := func(string) ([]networkingv1.NetworkPolicy, error) {
+		return []networkingv1.NetworkPolicy{}, nil
+	}
+
+	BeforeEach(func() {
+		stateManager = state.NewStateManager()
+		innerState := v1.ProbeOutput{
+			Items: []v1.ProbeOutputItem{
+				{
+					Type:            v1.PROBE,
+					Source:          v1.ProbeEndpointInfo{Type: v1.POD, Name: "src", Namespace: "default", Labels: "app=src;"},
+					Destination:     v1.ProbeEndpointInfo{Type: v1.POD, Name: "dst", Namespace: "default", Labels: "app=dst;"},
+					Port:            "80",
+					Protocol:        "TCP",
+					ResultingAction: v1.ALLOW,
+				},
+			},
+			Errors:                     []v1.ProbeOutputError{},
+			PodNetworking:              []v1.PodNetworkingInfo{},
+			PodNetworkingV2:            v1.PodNetworkingInfoV2{"dst": {{Port: "80", IP: "0.0.0.0", Protocol: "TCP"}}},
+			PodConfigurationNetworking: make(v1.PodNetworkingInfoV2),
+		}
+		Expect(stateManager.SetProbeState(&innerState)).To(Succeed())
+	})
+
+	It("Returns YAML by default", func() {
+		req := httptest.NewRequest("GET", "http://localhost:2709/policies", nil)
+		w := httptest.NewRecorder()
+		GetPoliciesHandlerWithManager(stateManager, noPolicies).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(200))
+		Expect(w.Header().Get("Content-Type")).To(Equal("application/yaml"))
+		Expect(w.Body.String()).To(ContainSubstring("name: kubesonde-dst"))
+	})
+
+	It("Returns JSON", func() {
+		req := httptest.NewRequest("GET", "http://localhost:2709/policies?format=json&namespace=default", nil)
+		w := httptest.NewRecorder()
+		GetPoliciesHandlerWithManager(stateManager, noPolicies).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(200))
+		var policies []networkingv1.NetworkPolicy
+		Expect(json.Unmarshal(w.Body.Bytes(), &policies)).To(Succeed())
+		Expect(policies).To(HaveLen(2))
+	})
+
+	It("Returns the dry-run diff", func() {
+		req := httptest.NewRequest("GET", "http://localhost:2709/policies?dryRun=true", nil)
+		w := httptest.NewRecorder()
+		GetPoliciesHandlerWithManager(stateManager, noPolicies).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(200))
+		var diffs []policygenerator.PolicyDiff
+		Expect(json.Unmarshal(w.Body.Bytes(), &diffs)).To(Succeed())
+		Expect(diffs).To(HaveLen(2))
+		Expect(diffs[0].Action).To(Equal(policygenerator.CREATE))
+	})
+
+	It("Rejects unknown formats", func() {
+		req := httptest.NewRequest("GET", "http://localhost:2709/policies?format=xml", nil)
+		w := httptest.NewRecorder()
+		GetPoliciesHandlerWithManager(stateManager, noPolicies).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(400))
+	})
+	It("Returns Cilium policies", func() {
+		req := httptest.NewRequest("GET", "http://localhost:2709/policies?flavor=cilium", nil)
+		w := httptest.NewRecorder()
+		GetPoliciesHandlerWithManager(stateManager, noPolicies).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(200))
+		Expect(w.Body.String()).To(ContainSubstring("kind: CiliumNetworkPolicy"))
+	})
+
+	It("Returns Calico policies with a default deny", func() {
+		req := httptest.NewRequest("GET", "http://localhost:2709/policies?flavor=calico", nil)
+		w := httptest.NewRecorder()
+		GetPoliciesHandlerWithManager(stateManager, noPolicies).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(200))
+		Expect(w.Body.String()).To(ContainSubstring("apiVersion: projectcalico.org/v3"))
+		Expect(w.Body.String()).To(ContainSubstring("kind: GlobalNetworkPolicy"))
+	})
+
+	It("Rejects unknown flavors", func() {
+		req := httptest.NewRequest("GET", "http://localhost:2709/policies?flavor=antrea", nil)
+		w := httptest.NewRecorder()
+		GetPoliciesHandlerWithManager(stateManager, noPolicies).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(400))
+	})
+})
@@ -3,16 +3,35 @@ package restapis
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"kubesonde.io/controllers/export"
+	probequery "kubesonde.io/controllers/probe-query"
 	"kubesonde.io/controllers/state"
+	"kubesonde.io/pkg/apitypes"
 )
 
-const GET_PROBES_PATH = "/probes"
+const GET_PROBES_PATH = apitypes.GET_PROBES_PATH
+
+// NEXT_CURSOR_HEADER carries the cursor of the next page of probes
+const NEXT_CURSOR_HEADER = apitypes.NEXT_CURSOR_HEADER
 
 func GetProbesHandler() http.Handler {
 	return GetProbesHandlerWithManager(state.GetDefaultManager())
 }
 
+// GetProbesHandlerWithManager returns the probe output. Besides the filters
+// accepted by probequery.ParseFilter, the query parameters are:
+//   - fields: comma separated top-level fields to return, e.g. `items,errors`
+//   - limit: maximum number of items to return
+//   - cursor: the value of the X-Next-Cursor header of the previous page
+//   - format: `json` (default), `csv`, `dot`, `mermaid`, `graphml` or
+//     `cytoscape`; also negotiated with the Accept header. Other formats than
+//     JSON render all the filtered probes, without pagination.
+//   - group: `pod` (default) or `deployment`, the nodes of the graph formats
+//
+// Responses carry an ETag and are compressed when the client accepts gzip.
 func GetProbesHandlerWithManager(sm *state.StateManager) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -21,20 +40,74 @@ func GetProbesHandlerWithManager(sm *state.StateManager) http.Handler {
 			return
 		}
 
-		currState := sm.GetProbeState()
-		w.Header().Set("Content-Type", "application/json")
+		query := r.URL.Query()
+		filter, err := probequery.ParseFilter(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		limit := 0
+		if query.Get("limit") != "" {
+			if limit, err = strconv.Atoi(query.Get("limit")); err != nil || limit < 0 {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+
+		format := export.NegotiateFormat(r.Header.Get("Accept"))
+		if query.Get("format") != "" {
+			if format, err = export.ParseFormat(query.Get("format")); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		w.Header().Add("Vary", "Accept")
+		grouping, err := export.ParseGrouping(query.Get("group"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		currState := filter.Apply(sm.GetProbeState())
+		if format != export.JSON {
+			data, err := export.Render(currState, format, grouping)
+			if err != nil {
+				log.Error(err, "[GET /probes] Failed to render probe state", "format", format)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			writeCacheable(w, r, GET_PROBES_PATH, format.ContentType(), data)
+			return
+		}
+
+		page, next, err := probequery.Paginate(currState.Items, query.Get("cursor"), limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		currState.Items = page
+
+		var fields []string
+		if query.Get("fields") != "" {
+			fields = strings.Split(query.Get("fields"), ",")
+		}
+		selected, err := probequery.SelectFields(currState, fields)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		// Marshal state to JSON with indentation
-		data, err := json.MarshalIndent(currState, "", "  ")
+		data, err := json.MarshalIndent(selected, "", "  ")
 		if err != nil {
 			log.Error(err, "[GET /probes] Failed to marshal probe state")
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 
-		w.WriteHeader(http.StatusOK)
-		if _, err := w.Write(data); err != nil {
-			log.Error(err, "[GET /probes] Failed to write response")
+		if next != "" {
+			w.Header().Set(NEXT_CURSOR_HEADER, next)
 		}
+		writeCacheable(w, r, GET_PROBES_PATH, "application/json", data)
 	})
 }
@@ -0,0 +1,133 @@
+package restapis
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	probequery "kubesonde.io/controllers/probe-query"
+	"kubesonde.io/controllers/state"
+	"kubesonde.io/pkg/apitypes"
+)
+
+const GET_PROBES_STREAM_PATH = apitypes.GET_PROBES_STREAM_PATH
+
+// STREAM_HEARTBEAT_INTERVAL keeps idle connections open through proxies
+const STREAM_HEARTBEAT_INTERVAL = 15 * time.Second
+
+func GetProbesStreamHandler() http.Handler {
+	return GetProbesStreamHandlerWithManager(state.GetDefaultManager(), STREAM_HEARTBEAT_INTERVAL)
+}
+
+// GetProbesStreamHandlerWithManager streams the state changes as Server-Sent
+// Events. The event id is the sequence number of the change and the event name
+// its kind: `probe`, `error`, `netstat` or `reset`. A `reset` event means the
+// client should fetch /probes again.
+//
+// Clients resume with the `Last-Event-ID` header or the `since` query
+// parameter; without them only the new changes are streamed. The `kinds` query
+// parameter restricts the streamed kinds, and the /probes filters apply to the
+// probe and error events.
+func GetProbesStreamHandlerWithManager(sm *state.StateManager, heartbeat time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+			return
+		}
+
+		query := r.URL.Query()
+		filter, err := probequery.ParseFilter(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var kinds []state.ChangeKind
+		if query.Get("kinds") != "" {
+			kinds = lo.Map(strings.Split(query.Get("kinds"), ","), func(kind string, _ int) state.ChangeKind {
+				return state.ChangeKind(kind)
+			})
+		}
+		after := sm.LastSequence()
+		resume := lo.Ternary(r.Header.Get("Last-Event-ID") != "", r.Header.Get("Last-Event-ID"), query.Get("since"))
+		if resume != "" {
+			if after, err = strconv.ParseUint(resume, 10, 64); err != nil {
+				http.Error(w, "Invalid sequence number", http.StatusBadRequest)
+				return
+			}
+		}
+
+		backlog, subscription := sm.Subscribe(after)
+		defer subscription.Cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		send := func(change state.Change) bool {
+			if !streamed(change, kinds, filter) {
+				return true
+			}
+			data, err := json.Marshal(change)
+			if err != nil {
+				log.Error(err, "[GET /probes/stream] Failed to marshal change")
+				return true
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", change.Sequence, change.Kind, data); err != nil {
+				return false
+			}
+			flusher.Flush()
+			return true
+		}
+
+		for _, change := range backlog {
+			if !send(change) {
+				return
+			}
+		}
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case change, open := <-subscription.Changes:
+				if !open {
+					// Too slow, the client resumes from the last event id
+					return
+				}
+				if !send(change) {
+					return
+				}
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	})
+}
+
+func streamed(change state.Change, kinds []state.ChangeKind, filter probequery.Filter) bool {
+	if change.Kind != state.RESET_CHANGE && len(kinds) > 0 && !lo.Contains(kinds, change.Kind) {
+		return false
+	}
+	switch change.Kind {
+	case state.PROBE_CHANGE:
+		return filter.Matches(*change.Probe)
+	case state.ERROR_CHANGE:
+		return filter.Matches(change.Error.Value)
+	}
+	return true
+}
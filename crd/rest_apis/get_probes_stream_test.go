@@ -0,0 +1,121 @@
+package restapis
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/state"
+)
+
+type streamEvent struct {
+	id     string
+	name   string
+	change state.Change
+}
+
+func readEvent(reader *bufio.Reader) streamEvent {
+	var event streamEvent
+	for {
+		line, err := reader.ReadString('\n')
+		Expect(err).To(BeNil())
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && event.id != "":
+			return event
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			Expect(json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.change)).To(Succeed())
+		}
+	}
+}
+
+var _ = Describe("GetProbesStream", func() {
+	var stateManager *state.StateManager
+	var server *httptest.Server
+	probe := func(name string, action v1.ActionType) v1.ProbeOutputItem {
+		return v1.ProbeOutputItem{
+			Type:            v1.PROBE,
+			ResultingAction: action,
+			Source:          v1.ProbeEndpointInfo{Type: v1.POD, Name: name, Namespace: "default"},
+			Port:            "80",
+		}
+	}
+	connect := func(path string, lastEventID string) (*http.Response, *bufio.Reader) {
+		req, err := http.NewRequest("GET", server.URL+path, nil)
+		Expect(err).To(BeNil())
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+		return resp, bufio.NewReader(resp.Body)
+	}
+
+	BeforeEach(func() {
+		stateManager = state.NewStateManager()
+		server = httptest.NewServer(GetProbesStreamHandlerWithManager(stateManager, time.Hour))
+	})
+
+	AfterEach(func() {
+		server.CloseClientConnections()
+		server.Close()
+	})
+
+	It("Streams the new changes", func() {
+		resp, reader := connect("/probes/stream", "")
+		defer resp.Body.Close()
+
+		Expect(stateManager.AppendProbes(&[]v1.ProbeOutputItem{probe("a", v1.ALLOW)})).To(Succeed())
+		items := []v1.PodNetworkingItem{{Port: "80", IP: "0.0.0.0", Protocol: "TCP"}}
+		Expect(stateManager.AppendNetInfoV2("a", &items)).To(Succeed())
+
+		event := readEvent(reader)
+		Expect(event.id).To(Equal("1"))
+		Expect(event.name).To(Equal("probe"))
+		Expect(event.change.Probe.Source.Name).To(Equal("a"))
+		event = readEvent(reader)
+		Expect(event.name).To(Equal("netstat"))
+		Expect(event.change.Networking).To(Equal(items))
+	})
+
+	It("Resumes from the last event id", func() {
+		Expect(stateManager.AppendProbes(&[]v1.ProbeOutputItem{probe("a", v1.ALLOW), probe("b", v1.DENY)})).To(Succeed())
+
+		resp, reader := connect("/probes/stream", "1")
+		defer resp.Body.Close()
+
+		event := readEvent(reader)
+		Expect(event.id).To(Equal("2"))
+		Expect(event.change.Probe.Source.Name).To(Equal("b"))
+	})
+
+	It("Filters the probes", func() {
+		resp, reader := connect("/probes/stream?since=0&verdict=deny", "")
+		defer resp.Body.Close()
+
+		Expect(stateManager.AppendProbes(&[]v1.ProbeOutputItem{probe("a", v1.ALLOW), probe("b", v1.DENY)})).To(Succeed())
+
+		event := readEvent(reader)
+		Expect(event.id).To(Equal("2"))
+		Expect(event.change.Probe.Source.Name).To(Equal("b"))
+	})
+
+	It("Rejects invalid sequence numbers", func() {
+		resp, err := http.Get(server.URL + "/probes/stream?since=abc")
+		Expect(err).To(BeNil())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(400))
+	})
+})
@@ -1,6 +1,7 @@
 package restapis
 
 import (
+	"compress/gzip"
 	"encoding/json"
 	"io"
 	"net/http/httptest"
@@ -139,3 +140,118 @@ var _ = Describe("GetProbes with default manager", func() {
 		Expect(dst.End).To(BeEmpty())
 	})
 })
+
+var _ = Describe("GetProbes with query parameters", func() {
+	var stateManager *state.StateManager
+	probe := func(name string, action v1.ActionType) v1.ProbeOutputItem {
+		return v1.ProbeOutputItem{
+			Type:            v1.PROBE,
+			ResultingAction: action,
+			Source:          v1.ProbeEndpointInfo{Type: v1.POD, Name: name, Namespace: "default"},
+			Destination:     v1.ProbeEndpointInfo{Type: v1.POD, Name: "dst", Namespace: "default"},
+			Port:            "80",
+			Protocol:        "TCP",
+		}
+	}
+	get := func(url string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		GetProbesHandlerWithManager(stateManager).ServeHTTP(w, req)
+		return w
+	}
+
+	BeforeEach(func() {
+		stateManager = state.NewStateManager()
+		innerState := v1.ProbeOutput{
+			Items:                      []v1.ProbeOutputItem{probe("a", v1.ALLOW), probe("b", v1.DENY), probe("c", v1.ALLOW)},
+			Errors:                     []v1.ProbeOutputError{},
+			PodNetworking:              []v1.PodNetworkingInfo{{PodName: "a", Netstat: "somestring"}},
+			PodNetworkingV2:            make(v1.PodNetworkingInfoV2),
+			PodConfigurationNetworking: make(v1.PodNetworkingInfoV2),
+		}
+		Expect(stateManager.SetProbeState(&innerState)).To(Succeed())
+	})
+
+	It("Filters the items", func() {
+		w := get("http://localhost:2709/probes?verdict=allow", nil)
+		Expect(w.Code).To(Equal(200))
+		var dst v1.ProbeOutput
+		Expect(json.Unmarshal(w.Body.Bytes(), &dst)).To(Succeed())
+		Expect(dst.Items).To(HaveLen(2))
+		Expect(dst.PodNetworking).To(HaveLen(1))
+	})
+
+	It("Rejects invalid filters", func() {
+		Expect(get("http://localhost:2709/probes?port=http", nil).Code).To(Equal(400))
+		Expect(get("http://localhost:2709/probes?limit=-1", nil).Code).To(Equal(400))
+		Expect(get("http://localhost:2709/probes?fields=secrets", nil).Code).To(Equal(400))
+	})
+
+	It("Selects the fields", func() {
+		w := get("http://localhost:2709/probes?fields=items", nil)
+		Expect(w.Code).To(Equal(200))
+		var dst map[string]json.RawMessage
+		Expect(json.Unmarshal(w.Body.Bytes(), &dst)).To(Succeed())
+		Expect(dst).To(HaveLen(1))
+		Expect(dst).To(HaveKey("items"))
+	})
+
+	It("Paginates the items", func() {
+		w := get("http://localhost:2709/probes?limit=2", nil)
+		var dst v1.ProbeOutput
+		Expect(json.Unmarshal(w.Body.Bytes(), &dst)).To(Succeed())
+		Expect(dst.Items).To(HaveLen(2))
+		cursor := w.Header().Get(NEXT_CURSOR_HEADER)
+		Expect(cursor).NotTo(BeEmpty())
+
+		w = get("http://localhost:2709/probes?limit=2&cursor="+cursor, nil)
+		Expect(json.Unmarshal(w.Body.Bytes(), &dst)).To(Succeed())
+		Expect(dst.Items).To(HaveLen(1))
+		Expect(dst.Items[0].Source.Name).To(Equal("c"))
+		Expect(w.Header().Get(NEXT_CURSOR_HEADER)).To(BeEmpty())
+	})
+
+	It("Supports conditional requests", func() {
+		w := get("http://localhost:2709/probes", nil)
+		etag := w.Header().Get("ETag")
+		Expect(etag).NotTo(BeEmpty())
+
+		w = get("http://localhost:2709/probes", map[string]string{"If-None-Match": etag})
+		Expect(w.Code).To(Equal(304))
+		Expect(w.Body.Len()).To(BeZero())
+
+		Expect(stateManager.AppendProbes(&[]v1.ProbeOutputItem{probe("d", v1.DENY)})).To(Succeed())
+		w = get("http://localhost:2709/probes", map[string]string{"If-None-Match": etag})
+		Expect(w.Code).To(Equal(200))
+	})
+
+	It("Compresses the response", func() {
+		w := get("http://localhost:2709/probes", map[string]string{"Accept-Encoding": "gzip"})
+		Expect(w.Code).To(Equal(200))
+		Expect(w.Header().Get("Content-Encoding")).To(Equal("gzip"))
+		reader, err := gzip.NewReader(w.Body)
+		Expect(err).To(BeNil())
+		var dst v1.ProbeOutput
+		Expect(json.NewDecoder(reader).Decode(&dst)).To(Succeed())
+		Expect(dst.Items).To(HaveLen(3))
+	})
+	It("Renders other formats", func() {
+		w := get("http://localhost:2709/probes?format=csv&verdict=deny", nil)
+		Expect(w.Code).To(Equal(200))
+		Expect(w.Header().Get("Content-Type")).To(Equal("text/csv"))
+		Expect(w.Body.String()).To(Equal("Source,Target,Port,Protocol,Action,SourceNamespace,TargetNamespace,TargetType\ndefault/b,default/dst,80,TCP,Deny,default,default,Pod\n"))
+
+		w = get("http://localhost:2709/probes", map[string]string{"Accept": "text/vnd.graphviz"})
+		Expect(w.Code).To(Equal(200))
+		Expect(w.Header().Get("Content-Type")).To(Equal("text/vnd.graphviz"))
+		Expect(w.Body.String()).To(HavePrefix("digraph kubesonde {"))
+	})
+
+	It("Rejects unknown formats", func() {
+		Expect(get("http://localhost:2709/probes?format=pdf", nil).Code).To(Equal(400))
+		Expect(get("http://localhost:2709/probes?format=dot&group=namespace", nil).Code).To(Equal(400))
+	})
+})
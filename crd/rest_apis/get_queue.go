@@ -0,0 +1,42 @@
+package restapis
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"kubesonde.io/controllers/dispatcher"
+	"kubesonde.io/pkg/apitypes"
+)
+
+const GET_QUEUE_PATH = apitypes.GET_QUEUE_PATH
+
+func GetQueueHandler() http.Handler {
+	return GetQueueHandlerWithStats(dispatcher.Stats)
+}
+
+// GetQueueHandlerWithStats returns the state of the dispatcher: the pending
+// probes by priority, source pod and namespace, the age of the oldest one, the
+// running probes, the recent throughput and the estimated time to drain the
+// queue.
+func GetQueueHandlerWithStats(stats func() dispatcher.QueueStats) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		data, err := json.MarshalIndent(stats(), "", "  ")
+		if err != nil {
+			log.Error(err, "[GET /queue] Failed to marshal queue stats")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(data); err != nil {
+			log.Error(err, "[GET /queue] Failed to write response")
+		}
+	})
+}
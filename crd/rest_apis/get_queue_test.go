@@ -0,0 +1,36 @@
+package restapis
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"kubesonde.io/controllers/dispatcher"
+)
+
+var _ = Describe("GetQueue", func() {
+	It("Returns the dispatcher state", func() {
+		stats := func() dispatcher.QueueStats {
+			return dispatcher.QueueStats{Pending: 3, ByPriority: map[string]int{"low": 3}, ThroughputPerSecond: 1.5}
+		}
+		req := httptest.NewRequest("GET", "http://localhost:2709/queue", nil)
+		w := httptest.NewRecorder()
+		GetQueueHandlerWithStats(stats).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(200))
+		Expect(w.Header().Get("Cache-Control")).To(Equal("no-store"))
+		var result dispatcher.QueueStats
+		Expect(json.Unmarshal(w.Body.Bytes(), &result)).To(Succeed())
+		Expect(result.Pending).To(Equal(3))
+		Expect(result.ByPriority).To(Equal(map[string]int{"low": 3}))
+		Expect(result.EstimatedDrainSeconds).To(BeNil())
+	})
+
+	It("Only allows GET", func() {
+		req := httptest.NewRequest("POST", "http://localhost:2709/queue", nil)
+		w := httptest.NewRecorder()
+		GetQueueHandlerWithStats(dispatcher.Stats).ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(405))
+	})
+})
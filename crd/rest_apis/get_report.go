@@ -0,0 +1,80 @@
+package restapis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"kubesonde.io/controllers/baseline"
+	probequery "kubesonde.io/controllers/probe-query"
+	"kubesonde.io/controllers/report"
+	"kubesonde.io/controllers/state"
+	"kubesonde.io/pkg/apitypes"
+)
+
+const GET_REPORT_PATH = apitypes.GET_REPORT_PATH
+
+// Formats of GET /report
+const (
+	SARIF_FORMAT = apitypes.SARIF_FORMAT
+	JUNIT_FORMAT = apitypes.JUNIT_FORMAT
+)
+
+const SARIF_CONTENT_TYPE = "application/sarif+json"
+
+func GetReportHandler() http.Handler {
+	return GetReportHandlerWithManager(state.GetDefaultManager(), baseline.GetCurrent)
+}
+
+// GetReportHandlerWithManager renders the probes for security tooling. The
+// `format` query parameter is `sarif` (default), the findings of the probes,
+// or `junit`, one test case per edge with an expected action and per edge of
+// the baseline. The /probes filters select the probes to report on.
+func GetReportHandlerWithManager(sm *state.StateManager, current func() (baseline.Current, bool)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		format := query.Get("format")
+		if format == "" {
+			format = SARIF_FORMAT
+		}
+		if format != SARIF_FORMAT && format != JUNIT_FORMAT {
+			http.Error(w, fmt.Sprintf("Invalid format %s, expected %s or %s", format, SARIF_FORMAT, JUNIT_FORMAT), http.StatusBadRequest)
+			return
+		}
+		filter, err := probequery.ParseFilter(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		output := filter.Apply(sm.GetProbeState())
+
+		if format == SARIF_FORMAT {
+			data, err := json.MarshalIndent(report.SARIF(output), "", "  ")
+			if err != nil {
+				log.Error(err, "[GET /report] Failed to marshal SARIF log")
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			writeCacheable(w, r, GET_REPORT_PATH, SARIF_CONTENT_TYPE, data)
+			return
+		}
+
+		var approved *baseline.Baseline
+		if configured, found := current(); found {
+			approved = &configured.Baseline
+		}
+		var buffer bytes.Buffer
+		if err := report.WriteJUnit(&buffer, "kubesonde", report.Cases(output, approved)); err != nil {
+			log.Error(err, "[GET /report] Failed to write JUnit report")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		writeCacheable(w, r, GET_REPORT_PATH, "application/xml", buffer.Bytes())
+	})
+}
@@ -0,0 +1,74 @@
+package restapis
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/baseline"
+	"kubesonde.io/controllers/report"
+	"kubesonde.io/controllers/state"
+)
+
+var _ = Describe("GetReport", func() {
+	var stateManager *state.StateManager
+	none := func() (baseline.Current, bool) { return baseline.Current{}, false }
+
+	BeforeEach(func() {
+		stateManager = state.NewStateManager()
+		web := v1.ProbeEndpointInfo{Type: v1.POD, Name: "web-1", Namespace: "shop", DeploymentName: "web"}
+		db := v1.ProbeEndpointInfo{Type: v1.POD, Name: "db-1", Namespace: "shop", DeploymentName: "db"}
+		google := v1.ProbeEndpointInfo{Type: v1.INTERNET, Name: "Google", IPAddress: "google.com"}
+		Expect(stateManager.AppendProbes(&[]v1.ProbeOutputItem{
+			{Type: v1.PROBE, ResultingAction: v1.ALLOW, ExpectedAction: v1.DENY, Source: web, Destination: db, Port: "5432", Protocol: "TCP"},
+			{Type: v1.PROBE, ResultingAction: v1.ALLOW, Source: db, Destination: google, Port: "443", Protocol: "TCP"},
+		})).To(Succeed())
+	})
+
+	It("Returns the SARIF findings by default", func() {
+		w := httptest.NewRecorder()
+		GetReportHandlerWithManager(stateManager, none).ServeHTTP(w, httptest.NewRequest("GET", "http://localhost:2709/report", nil))
+
+		Expect(w.Code).To(Equal(200))
+		Expect(w.Header().Get("Content-Type")).To(Equal(SARIF_CONTENT_TYPE))
+		var log report.SarifLog
+		Expect(json.Unmarshal(w.Body.Bytes(), &log)).To(Succeed())
+		Expect(log.Runs[0].Results).To(HaveLen(2))
+		Expect(log.Runs[0].Results[0].RuleID).To(Equal(report.INTERNET_EGRESS_RULE))
+		Expect(log.Runs[0].Results[1].RuleID).To(Equal(report.EXPECTED_DENY_VIOLATION_RULE))
+	})
+
+	It("Filters the probes", func() {
+		w := httptest.NewRecorder()
+		GetReportHandlerWithManager(stateManager, none).ServeHTTP(w, httptest.NewRequest("GET", "http://localhost:2709/report?port=443", nil))
+
+		var log report.SarifLog
+		Expect(json.Unmarshal(w.Body.Bytes(), &log)).To(Succeed())
+		Expect(log.Runs[0].Results).To(HaveLen(1))
+		Expect(log.Runs[0].Results[0].RuleID).To(Equal(report.INTERNET_EGRESS_RULE))
+	})
+
+	It("Returns a JUnit test case per asserted edge", func() {
+		current := func() (baseline.Current, bool) {
+			return baseline.Current{Baseline: baseline.Baseline{Edges: []baseline.Edge{{From: "shop/web", To: "shop/db", Ports: []string{"5432"}}}}}, true
+		}
+		w := httptest.NewRecorder()
+		GetReportHandlerWithManager(stateManager, current).ServeHTTP(w, httptest.NewRequest("GET", "http://localhost:2709/report?format=junit", nil))
+
+		Expect(w.Code).To(Equal(200))
+		Expect(w.Header().Get("Content-Type")).To(Equal("application/xml"))
+		Expect(w.Body.String()).To(ContainSubstring(`<testsuites name="kubesonde" tests="2" failures="2">`))
+		Expect(w.Body.String()).To(ContainSubstring(`<testsuite name="expected actions" tests="1" failures="1">`))
+		Expect(w.Body.String()).To(ContainSubstring(`<testsuite name="baseline" tests="1" failures="1">`))
+		Expect(w.Body.String()).To(ContainSubstring(`<failure message="allowed, not in the baseline">`))
+	})
+
+	It("Rejects unknown formats", func() {
+		w := httptest.NewRecorder()
+		GetReportHandlerWithManager(stateManager, none).ServeHTTP(w, httptest.NewRequest("GET", "http://localhost:2709/report?format=html", nil))
+
+		Expect(w.Code).To(Equal(400))
+	})
+})
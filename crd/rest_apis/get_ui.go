@@ -0,0 +1,50 @@
+package restapis
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+
+	"kubesonde.io/pkg/apitypes"
+	"kubesonde.io/ui"
+)
+
+const UI_PATH = apitypes.UI_PATH
+
+func GetUIHandler() http.Handler {
+	return GetUIHandlerWithAssets(ui.Assets())
+}
+
+// GetUIHandlerWithAssets serves the results viewer. The viewer routes its pages
+// on the client, so the paths that are not assets are answered with index.html.
+func GetUIHandlerWithAssets(assets fs.FS) http.Handler {
+	fileServer := http.StripPrefix(strings.TrimSuffix(UI_PATH, "/"), http.FileServer(http.FS(assets)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := strings.TrimPrefix(path.Clean(strings.TrimPrefix(r.URL.Path, UI_PATH)), "/")
+		if info, err := fs.Stat(assets, name); err == nil && !info.IsDir() && name != "index.html" {
+			// Vite adds a content hash to the names of the bundled assets
+			if strings.HasPrefix(name, "assets/") {
+				w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+			}
+			fileServer.ServeHTTP(w, r)
+			return
+		}
+
+		index, err := fs.ReadFile(assets, "index.html")
+		if err != nil {
+			http.Error(w, "The UI was not built in this binary, run `make ui` before building the manager", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		if _, err := w.Write(index); err != nil {
+			log.Error(err, "[GET /ui] Failed to write response")
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package restapis
+
+import (
+	"net/http/httptest"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GetUI", func() {
+	assets := fstest.MapFS{
+		"index.html":         {Data: []byte("<html>viewer</html>")},
+		"assets/index-1a.js": {Data: []byte("console.log()")},
+		"favicon.ico":        {Data: []byte("icon")},
+	}
+
+	get := func(url string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		GetUIHandlerWithAssets(assets).ServeHTTP(w, req)
+		return w
+	}
+
+	It("Serves the assets", func() {
+		w := get("http://localhost:2709/ui/assets/index-1a.js")
+		Expect(w.Code).To(Equal(200))
+		Expect(w.Body.String()).To(Equal("console.log()"))
+		Expect(w.Header().Get("Cache-Control")).To(ContainSubstring("immutable"))
+
+		w = get("http://localhost:2709/ui/favicon.ico")
+		Expect(w.Code).To(Equal(200))
+		Expect(w.Body.String()).To(Equal("icon"))
+	})
+
+	It("Serves index.html for the client routes", func() {
+		for _, url := range []string{"http://localhost:2709/ui/", "http://localhost:2709/ui/graph/current", "http://localhost:2709/ui/index.html"} {
+			w := get(url)
+			Expect(w.Code).To(Equal(200))
+			Expect(w.Body.String()).To(Equal("<html>viewer</html>"))
+			Expect(w.Header().Get("Cache-Control")).To(Equal("no-cache"))
+		}
+	})
+
+	It("Returns 404 when the UI was not built", func() {
+		req := httptest.NewRequest("GET", "http://localhost:2709/ui/", nil)
+		w := httptest.NewRecorder()
+		GetUIHandlerWithAssets(fstest.MapFS{}).ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(404))
+	})
+
+	It("Rejects other methods", func() {
+		req := httptest.NewRequest("POST", "http://localhost:2709/ui/", nil)
+		w := httptest.NewRecorder()
+		GetUIHandlerWithAssets(assets).ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(405))
+	})
+})
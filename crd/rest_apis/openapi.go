@@ -0,0 +1,391 @@
+package restapis
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/audit"
+	"kubesonde.io/controllers/cloudevents"
+	"kubesonde.io/controllers/dispatcher"
+	"kubesonde.io/controllers/findings"
+	"kubesonde.io/controllers/graph"
+	"kubesonde.io/controllers/inner"
+	networkpolicy "kubesonde.io/controllers/network-policy"
+	policygenerator "kubesonde.io/controllers/policy-generator"
+	"kubesonde.io/controllers/report"
+	"kubesonde.io/controllers/snapshot"
+	"kubesonde.io/controllers/state"
+	"kubesonde.io/pkg/apitypes"
+	"kubesonde.io/rest_apis/openapi"
+)
+
+const OPENAPI_PATH = apitypes.OPENAPI_PATH
+
+// API_VERSION is the version of the OpenAPI document, bumped on breaking changes
+const API_VERSION = "v1"
+
+func stringParameter(name string, description string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: &openapi.Schema{Type: "string"}}
+}
+
+func enumParameter(name string, description string, values ...string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: &openapi.Schema{Type: "string", Enum: values}}
+}
+
+func jsonContent(schema *openapi.Schema) map[string]openapi.MediaType {
+	return map[string]openapi.MediaType{"application/json": {Schema: schema}}
+}
+
+func textContent(contentTypes ...string) map[string]openapi.MediaType {
+	content := map[string]openapi.MediaType{}
+	for _, contentType := range contentTypes {
+		content[contentType] = openapi.MediaType{Schema: &openapi.Schema{Type: "string"}}
+	}
+	return content
+}
+
+func errorResponse(description string) openapi.Response {
+	return openapi.Response{Description: description, Content: textContent("text/plain")}
+}
+
+// probeFilterParameters are the filters of probequery.ParseFilter
+func probeFilterParameters() []openapi.Parameter {
+	return []openapi.Parameter{
+		stringParameter("namespace", "Namespace of the source or the destination"),
+		stringParameter("source", "Name or IP address of the source"),
+		stringParameter("destination", "Name or IP address of the destination"),
+		stringParameter("sourceLabels", "Label selector of the source"),
+		stringParameter("destinationLabels", "Label selector of the destination"),
+		stringParameter("workload", "Deployment or replica set of the source or the destination"),
+		stringParameter("port", "Probed port"),
+		stringParameter("protocol", "Probed protocol"),
+		enumParameter("verdict", "Outcome of the probe", "allow", "deny"),
+		enumParameter("type", "Type of the item", "probe", "information"),
+		stringParameter("since", "Seconds since the epoch or RFC 3339 date of the oldest item"),
+		stringParameter("until", "Seconds since the epoch or RFC 3339 date of the newest item"),
+	}
+}
+
+var cacheableHeaders = map[string]openapi.Header{
+	"ETag": {Description: "Hash of the response, send it back in If-None-Match", Schema: &openapi.Schema{Type: "string"}},
+}
+
+// OpenAPIDocument describes all the endpoints. The schemas are generated from
+// the types the handlers marshal.
+func OpenAPIDocument() *openapi.Document {
+	g := openapi.NewGenerator()
+	openapi.ProbeOutputEnums(g)
+	openapi.Enum(g, graph.POD_LEVEL, graph.WORKLOAD_LEVEL, graph.NAMESPACE_LEVEL)
+	openapi.Enum(g, graph.ALLOWED, graph.DENIED, graph.MIXED)
+	openapi.Enum(g, state.PROBE_CHANGE, state.ERROR_CHANGE, state.NETSTAT_CHANGE, state.RESET_CHANGE)
+	openapi.Enum(g, networkpolicy.INGRESS, networkpolicy.EGRESS)
+	openapi.Enum(g, policygenerator.CREATE, policygenerator.UPDATE, policygenerator.UNCHANGED, policygenerator.UNMANAGED)
+	openapi.Enum(g, findings.KINDS...)
+	// The events sent to the CloudEvents sinks are not served, their schemas
+	// are documented for the consumers
+	g.SchemaOf(cloudevents.ProbeResultEvent{})
+	g.SchemaOf(cloudevents.FindingEvent{})
+
+	probes := &openapi.Operation{
+		OperationID: "getProbes",
+		Summary:     "Probe results",
+		Description: "Formats other than JSON render all the filtered probes, without pagination.",
+		Parameters: append(probeFilterParameters(),
+			stringParameter("fields", "Comma separated top-level fields to return, e.g. items,errors"),
+			openapi.Parameter{Name: "limit", In: "query", Description: "Maximum number of items to return", Schema: &openapi.Schema{Type: "integer", Format: "int64"}},
+			stringParameter("cursor", "The "+NEXT_CURSOR_HEADER+" header of the previous page"),
+			enumParameter("format", "Rendering of the probes, also negotiated with the Accept header", "json", "csv", "dot", "mermaid", "graphml", "cytoscape"),
+			enumParameter("group", "Nodes of the graph formats", "pod", "deployment"),
+		),
+		Responses: map[string]openapi.Response{
+			"200": {
+				Description: "The probe output",
+				Headers: map[string]openapi.Header{
+					"ETag":             cacheableHeaders["ETag"],
+					NEXT_CURSOR_HEADER: {Description: "Cursor of the next page, absent on the last page", Schema: &openapi.Schema{Type: "string"}},
+				},
+				Content: func() map[string]openapi.MediaType {
+					content := textContent("text/csv", "text/vnd.graphviz", "text/vnd.mermaid", "application/graphml+xml", "application/vnd.cytoscape+json")
+					output := g.SchemaOf(v1.ProbeOutput{})
+					// the fields parameter drops the other top-level fields
+					partial := *g.Schemas()[strings.TrimPrefix(output.Ref, openapi.SCHEMA_REF_PREFIX)]
+					partial.Required = nil
+					partial.Description = "The top-level fields selected with the fields parameter"
+					content["application/json"] = openapi.MediaType{Schema: &openapi.Schema{AnyOf: []*openapi.Schema{output, &partial}}}
+					return content
+				}(),
+			},
+			"304": {Description: "The probe output did not change since the ETag sent in If-None-Match"},
+			"400": errorResponse("Invalid parameters"),
+		},
+	}
+
+	stream := &openapi.Operation{
+		OperationID: "streamProbes",
+		Summary:     "Server-Sent Events stream of the state changes",
+		Description: "The data of every event is a change, the event id is its sequence number. A reset event means that /probes should be fetched again.",
+		Parameters: append(probeFilterParameters(),
+			openapi.Parameter{Name: "Last-Event-ID", In: "header", Description: "Sequence of the last event received", Schema: &openapi.Schema{Type: "string"}},
+			stringParameter("since", "Sequence of the last event received, when Last-Event-ID is not set"),
+			stringParameter("kinds", "Comma separated kinds of the changes to stream"),
+		),
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The event stream", Content: map[string]openapi.MediaType{"text/event-stream": {Schema: g.SchemaOf(state.Change{})}}},
+			"400": errorResponse("Invalid parameters"),
+		},
+	}
+
+	clearProbes := &openapi.Operation{
+		OperationID: "clearProbes",
+		Summary:     "Clears the probe results",
+		Responses:   map[string]openapi.Response{"200": {Description: "The results were cleared", Content: textContent("text/plain")}},
+	}
+
+	runProbes := &openapi.Operation{
+		OperationID: "runProbes",
+		Summary:     "Queues a round of probes ahead of the running one",
+		Description: "The probes run asynchronously, their results show up in /probes and /probes/stream.",
+		Parameters: []openapi.Parameter{
+			stringParameter("namespace", "Only the probes from or to this namespace"),
+			stringParameter("workload", "Only the probes from or to the pods of this deployment or replica set"),
+			stringParameter("pod", "Only the probes from or to this pod"),
+		},
+		Responses: map[string]openapi.Response{
+			"202": {Description: "The probes were queued", Content: jsonContent(g.SchemaOf(RunProbesResponse{}))},
+		},
+	}
+
+	adhocProbe := &openapi.Operation{
+		OperationID: "adhocProbe",
+		Summary:     "Runs a single probe synchronously",
+		Description: "The probe output item is also recorded with the other results.",
+		RequestBody: &openapi.RequestBody{Required: true, Content: jsonContent(g.SchemaOf(inner.AdhocProbe{}))},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The outcome of the probe", Content: jsonContent(g.SchemaOf(v1.ProbeOutputItem{}))},
+			"400": errorResponse("Invalid probe"),
+			"404": errorResponse("Unknown source or destination"),
+			"409": errorResponse("The debug container of the source is not running"),
+			"502": errorResponse("The probe command failed"),
+		},
+	}
+
+	mismatches := &openapi.Operation{
+		OperationID: "getMismatches",
+		Summary:     "Probes whose outcome differs from the one predicted by the NetworkPolicies",
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The mismatches", Content: jsonContent(g.SchemaOf([]networkpolicy.Mismatch{}))},
+		},
+	}
+
+	explain := &openapi.Operation{
+		OperationID: "explain",
+		Summary:     "NetworkPolicies and rules allowing or blocking a connection",
+		Parameters: []openapi.Parameter{
+			{Name: "source", In: "query", Required: true, Description: "namespace/name, pod name or IP address", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "destination", In: "query", Required: true, Description: "namespace/name, pod name or IP address", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "port", In: "query", Required: true, Schema: &openapi.Schema{Type: "integer", Format: "int32"}},
+			stringParameter("protocol", "Defaults to TCP"),
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The explanation", Content: jsonContent(g.SchemaOf(networkpolicy.Explanation{}))},
+			"400": errorResponse("Missing parameters"),
+			"404": errorResponse("Unknown source or destination"),
+		},
+	}
+
+	policies := &openapi.Operation{
+		OperationID: "getPolicies",
+		Summary:     "Least-privilege NetworkPolicies allowing the observed traffic",
+		Parameters: []openapi.Parameter{
+			stringParameter("namespace", "Restricts the generation to a namespace"),
+			enumParameter("format", "Defaults to yaml", "yaml", "json"),
+			enumParameter("flavor", "Defaults to kubernetes", "kubernetes", "cilium", "calico"),
+			enumParameter("dryRun", "Returns the changes to the existing policies, kubernetes flavor only", "true", "false"),
+		},
+		Responses: map[string]openapi.Response{
+			"200": {
+				Description: "The policies, or the changes to the existing policies on dry runs",
+				Content: map[string]openapi.MediaType{
+					"application/yaml": {Schema: &openapi.Schema{Type: "string"}},
+					"application/json": {Schema: &openapi.Schema{AnyOf: []*openapi.Schema{
+						g.SchemaOf([]policygenerator.PolicyDiff{}),
+						{Type: "array", Items: &openapi.Schema{Type: "object", Description: "A NetworkPolicy of the flavor"}},
+					}}},
+				},
+			},
+			"400": errorResponse("Invalid parameters"),
+		},
+	}
+
+	graphOperation := &openapi.Operation{
+		OperationID: "getGraph",
+		Summary:     "Probes aggregated as nodes and edges",
+		Parameters:  append([]openapi.Parameter{enumParameter("level", "Defaults to pod", "pod", "workload", "namespace")}, probeFilterParameters()...),
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The graph", Headers: cacheableHeaders, Content: jsonContent(g.SchemaOf(graph.Graph{}))},
+			"304": {Description: "The graph did not change since the ETag sent in If-None-Match"},
+			"400": errorResponse("Invalid parameters"),
+		},
+	}
+
+	queue := &openapi.Operation{
+		OperationID: "getQueue",
+		Summary:     "Pending and running probes, throughput and estimated time to drain the queue",
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The queue statistics", Content: jsonContent(g.SchemaOf(dispatcher.QueueStats{}))},
+		},
+	}
+
+	plan := &openapi.Operation{
+		OperationID: "getPlan",
+		Summary:     "Probes known to the controller and the time they last ran",
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The probes", Headers: cacheableHeaders, Content: jsonContent(g.SchemaOf([]PlanItem{}))},
+			"304": {Description: "The plan did not change since the ETag sent in If-None-Match"},
+		},
+	}
+
+	drift := &openapi.Operation{
+		OperationID: "getDrift",
+		Summary:     "Edges that differ from the baseline of the Kubesonde object",
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The unexpected and the missing edges", Headers: cacheableHeaders, Content: jsonContent(g.SchemaOf(DriftResponse{}))},
+			"304": {Description: "The drift did not change since the ETag sent in If-None-Match"},
+			"404": errorResponse("No Kubesonde object references a baseline"),
+		},
+	}
+
+	reportOperation := &openapi.Operation{
+		OperationID: "getReport",
+		Summary:     "Security findings as SARIF, or asserted edges as JUnit",
+		Description: "The JUnit report has one test case per edge with an expected action and, when a Kubesonde object references a baseline, per edge of the baseline.",
+		Parameters:  append([]openapi.Parameter{enumParameter("format", "Defaults to sarif", SARIF_FORMAT, JUNIT_FORMAT)}, probeFilterParameters()...),
+		Responses: map[string]openapi.Response{
+			"200": {
+				Description: "The report",
+				Headers:     cacheableHeaders,
+				Content: map[string]openapi.MediaType{
+					SARIF_CONTENT_TYPE: {Schema: g.SchemaOf(report.SarifLog{})},
+					"application/xml":  {Schema: &openapi.Schema{Type: "string"}},
+				},
+			},
+			"304": {Description: "The report did not change since the ETag sent in If-None-Match"},
+			"400": errorResponse("Invalid parameters"),
+		},
+	}
+
+	listSnapshots := &openapi.Operation{
+		OperationID: "listSnapshots",
+		Summary:     "Snapshots of the probe results",
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The snapshots", Content: jsonContent(g.SchemaOf([]snapshot.Summary{}))},
+		},
+	}
+
+	createSnapshot := &openapi.Operation{
+		OperationID: "createSnapshot",
+		Summary:     "Freezes the current probe results",
+		RequestBody: &openapi.RequestBody{Content: jsonContent(g.SchemaOf(CreateSnapshotRequest{}))},
+		Responses: map[string]openapi.Response{
+			"201": {Description: "The snapshot", Content: jsonContent(g.SchemaOf(snapshot.Summary{}))},
+			"400": errorResponse("Invalid request body"),
+		},
+	}
+
+	diff := &openapi.Operation{
+		OperationID: "getDiff",
+		Summary:     "Changes between two snapshots, grouped by workload",
+		Parameters: []openapi.Parameter{
+			{Name: "from", In: "query", Required: true, Description: "Snapshot ID or label", Schema: &openapi.Schema{Type: "string"}},
+			stringParameter("to", "Snapshot ID or label, defaults to "+CURRENT_SNAPSHOT+", the live results"),
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The diff", Content: jsonContent(g.SchemaOf(snapshot.Diff{}))},
+			"400": errorResponse("Missing from parameter"),
+			"404": errorResponse("Unknown snapshot"),
+		},
+	}
+
+	auditOperation := &openapi.Operation{
+		OperationID: "getAudit",
+		Summary:     "Audit records of the commands run in the pods, oldest first",
+		Description: "The controller keeps the last records in memory, the --audit-log file keeps all of them.",
+		Parameters: []openapi.Parameter{
+			stringParameter("kubesonde", "namespace/name of the object that ran the commands"),
+			stringParameter("pod", "namespace/name of the pod the commands ran in"),
+			{Name: "after", In: "query", Description: "Skips the records up to this sequence", Schema: &openapi.Schema{Type: "integer", Format: "int64"}},
+			{Name: "limit", In: "query", Description: "Maximum number of records", Schema: &openapi.Schema{Type: "integer", Format: "int64"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The records", Headers: cacheableHeaders, Content: jsonContent(g.SchemaOf([]audit.Record{}))},
+			"304": {Description: "No command ran since the ETag sent in If-None-Match"},
+			"400": errorResponse("Invalid parameters"),
+		},
+	}
+
+	ui := &openapi.Operation{
+		OperationID: "getUI",
+		Summary:     "The results viewer",
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The viewer", Content: textContent("text/html")},
+			"404": errorResponse("The viewer was not built in the binary"),
+		},
+	}
+
+	spec := &openapi.Operation{
+		OperationID: "getOpenAPI",
+		Summary:     "This document",
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The OpenAPI document", Headers: cacheableHeaders, Content: jsonContent(&openapi.Schema{Type: "object"})},
+		},
+	}
+
+	return &openapi.Document{
+		OpenAPI: openapi.VERSION,
+		Info: openapi.Info{
+			Title:       "Kubesonde results",
+			Description: "Probe results of the Kubesonde controller and the analyses built on them",
+			Version:     API_VERSION,
+		},
+		Paths: map[string]openapi.PathItem{
+			GET_PROBES_PATH:        {"get": probes},
+			GET_PROBES_STREAM_PATH: {"get": stream},
+			POST_PROBES_CLEAR_PATH: {"post": clearProbes},
+			POST_PROBES_RUN_PATH:   {"post": runProbes},
+			POST_PROBES_ADHOC_PATH: {"post": adhocProbe},
+			GET_MISMATCHES_PATH:    {"get": mismatches},
+			GET_EXPLAIN_PATH:       {"get": explain},
+			GET_POLICIES_PATH:      {"get": policies},
+			GET_GRAPH_PATH:         {"get": graphOperation},
+			GET_QUEUE_PATH:         {"get": queue},
+			GET_PLAN_PATH:          {"get": plan},
+			GET_DRIFT_PATH:         {"get": drift},
+			GET_REPORT_PATH:        {"get": reportOperation},
+			SNAPSHOTS_PATH:         {"get": listSnapshots, "post": createSnapshot},
+			GET_DIFF_PATH:          {"get": diff},
+			GET_AUDIT_PATH:         {"get": auditOperation},
+			UI_PATH:                {"get": ui},
+			OPENAPI_PATH:           {"get": spec},
+		},
+		Components: openapi.Components{Schemas: g.Schemas()},
+	}
+}
+
+// GetOpenAPIHandler serves the OpenAPI document
+func GetOpenAPIHandler() http.Handler {
+	data, err := json.MarshalIndent(OpenAPIDocument(), "", "  ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err != nil {
+			log.Error(err, "[GET /openapi.json] Failed to marshal document")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		writeCacheable(w, r, OPENAPI_PATH, "application/json", data)
+	})
+}
@@ -0,0 +1,114 @@
+package openapi
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Generator builds schemas from Go types following the encoding/json rules:
+// named structs become components referenced with $ref, embedded structs are
+// inlined and the fields without omitempty are required.
+type Generator struct {
+	schemas map[string]*Schema
+	enums   map[reflect.Type][]string
+}
+
+func NewGenerator() *Generator {
+	return &Generator{schemas: map[string]*Schema{}, enums: map[reflect.Type][]string{}}
+}
+
+// Enum registers the values of a string type, reflection cannot list constants
+func Enum[T ~string](g *Generator, values ...T) {
+	names := make([]string, len(values))
+	for i, value := range values {
+		names[i] = string(value)
+	}
+	g.enums[reflect.TypeOf(values).Elem()] = names
+}
+
+// SchemaOf returns the schema of the type of v
+func (g *Generator) SchemaOf(v any) *Schema {
+	return g.schemaOf(reflect.TypeOf(v))
+}
+
+// Schemas returns the components registered so far
+func (g *Generator) Schemas() map[string]*Schema {
+	return g.schemas
+}
+
+func (g *Generator) schemaOf(t reflect.Type) *Schema {
+	if values, ok := g.enums[t]; ok {
+		return &Schema{Type: "string", Enum: values}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schemaOf(t.Elem())
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		// nil slices are encoded as null
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem()), Nullable: t.Kind() == reflect.Slice}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := t.String()
+		if _, ok := g.schemas[name]; !ok {
+			// registered before the fields to stop recursive types
+			g.schemas[name] = &Schema{}
+			*g.schemas[name] = *g.structSchema(t)
+		}
+		return &Schema{Ref: SCHEMA_REF_PREFIX + name}
+	}
+	// interfaces accept any value
+	return &Schema{}
+}
+
+func (g *Generator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		// the fields of embedded structs are promoted even when the struct is unexported
+		if !field.IsExported() && !(field.Anonymous && field.Type.Kind() == reflect.Struct) {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			embedded := g.structSchema(field.Type)
+			for property, fieldSchema := range embedded.Properties {
+				schema.Properties[property] = fieldSchema
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = g.schemaOf(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	sort.Strings(schema.Required)
+	return schema
+}
@@ -0,0 +1,83 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "kubesonde.io/api/v1"
+)
+
+type color string
+
+type inner struct {
+	Name string `json:"name"`
+}
+
+type embedded struct {
+	ID string `json:"id"`
+}
+
+type outer struct {
+	embedded `json:",inline"`
+	Color    color             `json:"color"`
+	Inner    inner             `json:"inner,omitempty"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Count    int32             `json:"count,omitempty"`
+	Ignored  string            `json:"-"`
+	hidden   string
+}
+
+var _ = Describe("Generator", func() {
+	var document *Document
+
+	BeforeEach(func() {
+		g := NewGenerator()
+		Enum(g, color("red"), color("blue"))
+		schema := g.SchemaOf(outer{})
+		Expect(schema.Ref).To(Equal(SCHEMA_REF_PREFIX + "openapi.outer"))
+		document = &Document{Components: Components{Schemas: g.Schemas()}}
+		document.Paths = map[string]PathItem{"/outer": {"get": &Operation{Responses: map[string]Response{
+			"200": {Content: map[string]MediaType{"application/json": {Schema: schema}}},
+		}}}}
+	})
+
+	It("Follows the encoding/json rules", func() {
+		schema := document.Components.Schemas["openapi.outer"]
+		Expect(schema.Properties).To(HaveKey("id"))
+		Expect(schema.Properties).NotTo(HaveKey("Ignored"))
+		Expect(schema.Properties).NotTo(HaveKey("hidden"))
+		Expect(schema.Required).To(Equal([]string{"color", "id", "tags"}))
+		Expect(schema.Properties["color"].Enum).To(Equal([]string{"red", "blue"}))
+		Expect(schema.Properties["inner"].Ref).To(Equal(SCHEMA_REF_PREFIX + "openapi.inner"))
+		Expect(schema.Properties["tags"].Nullable).To(BeTrue())
+		Expect(schema.Properties["labels"].AdditionalProperties.Type).To(Equal("string"))
+		Expect(schema.Properties["count"].Format).To(Equal("int32"))
+	})
+
+	It("Validates documents", func() {
+		schema := document.Operation("/outer", "GET").Responses["200"].Content["application/json"].Schema
+		Expect(document.Validate(schema, []byte(`{"id":"a","color":"red","tags":null,"inner":{"name":"b"},"count":2}`))).To(Succeed())
+		Expect(document.Validate(schema, []byte(`{"id":"a","color":"red"}`))).To(MatchError(ContainSubstring("missing required property tags")))
+		Expect(document.Validate(schema, []byte(`{"id":"a","color":"green","tags":[]}`))).To(MatchError(ContainSubstring("not one of")))
+		Expect(document.Validate(schema, []byte(`{"id":"a","color":"red","tags":[],"count":1.5}`))).To(MatchError(ContainSubstring("expected an integer")))
+		Expect(document.Validate(schema, []byte(`{"id":"a","color":"red","tags":[],"extra":true}`))).To(MatchError(ContainSubstring("undocumented property extra")))
+		Expect(document.Validate(schema, []byte(`{"id":"a","color":"red","tags":[1]}`))).To(MatchError(ContainSubstring("$.tags[0]")))
+	})
+})
+
+var _ = Describe("ValidateProbeOutput", func() {
+	It("Validates probe outputs without the document of the server", func() {
+		endpoint := v1.ProbeEndpointInfo{Type: v1.POD, Name: "a", Namespace: "default"}
+		data, err := json.Marshal(v1.ProbeOutput{Items: []v1.ProbeOutputItem{
+			{Type: v1.PROBE, Source: endpoint, Destination: endpoint, Port: "80", Protocol: "TCP", ExpectedAction: v1.ALLOW, ResultingAction: v1.DENY},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ValidateProbeOutput(data)).To(Succeed())
+
+		invalid := []byte(strings.Replace(string(data), `"Deny"`, `"Maybe"`, 1))
+		Expect(ValidateProbeOutput(invalid)).To(MatchError(ContainSubstring("not one of")))
+	})
+})
@@ -0,0 +1,21 @@
+package openapi
+
+import v1 "kubesonde.io/api/v1"
+
+// ProbeOutputEnums registers the values of the enums of the probe output
+func ProbeOutputEnums(g *Generator) {
+	Enum(g, v1.ALLOW, v1.DENY)
+	// The items of the errors of deleted pods have no type
+	Enum(g, v1.PROBE, v1.INFO, "")
+	Enum(g, v1.POD, v1.SERVICE, v1.INTERNET)
+}
+
+// ValidateProbeOutput checks that data matches the schema of GET /probes. It
+// does not need the results server, e.g. to validate saved result files.
+func ValidateProbeOutput(data []byte) error {
+	g := NewGenerator()
+	ProbeOutputEnums(g)
+	schema := g.SchemaOf(v1.ProbeOutput{})
+	document := &Document{OpenAPI: VERSION, Components: Components{Schemas: g.Schemas()}}
+	return document.Validate(schema, data)
+}
@@ -0,0 +1,117 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// Validate checks a JSON document against a schema of the document. Objects
+// with properties cannot contain undocumented fields.
+func (d *Document) Validate(schema *Schema, data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	return d.validate("$", schema, value)
+}
+
+func (d *Document) resolve(schema *Schema) (*Schema, error) {
+	if schema.Ref == "" {
+		return schema, nil
+	}
+	resolved, ok := d.Components.Schemas[strings.TrimPrefix(schema.Ref, SCHEMA_REF_PREFIX)]
+	if !ok {
+		return nil, fmt.Errorf("unknown schema %s", schema.Ref)
+	}
+	return resolved, nil
+}
+
+func (d *Document) validate(path string, schema *Schema, value any) error {
+	schema, err := d.resolve(schema)
+	if err != nil {
+		return err
+	}
+	if len(schema.AnyOf) > 0 {
+		errs := []string{}
+		for _, candidate := range schema.AnyOf {
+			err := d.validate(path, candidate, value)
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err.Error())
+		}
+		return fmt.Errorf("%s: no schema matches: %s", path, strings.Join(errs, "; "))
+	}
+	if value == nil {
+		if schema.Nullable || schema.Type == "" {
+			return nil
+		}
+		return fmt.Errorf("%s: null is not allowed", path)
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an object", path)
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				return fmt.Errorf("%s: missing required property %s", path, name)
+			}
+		}
+		names := lo.Keys(object)
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := schema.Properties[name]
+			switch {
+			case ok:
+			case schema.AdditionalProperties != nil:
+				property = schema.AdditionalProperties
+			case len(schema.Properties) > 0:
+				return fmt.Errorf("%s: undocumented property %s", path, name)
+			default:
+				continue
+			}
+			if err := d.validate(path+"."+name, property, object[name]); err != nil {
+				return err
+			}
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an array", path)
+		}
+		for i, item := range array {
+			if err := d.validate(fmt.Sprintf("%s[%d]", path, i), schema.Items, item); err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected a string", path)
+		}
+		if len(schema.Enum) > 0 && !lo.Contains(schema.Enum, s) {
+			return fmt.Errorf("%s: %q is not one of %v", path, s, schema.Enum)
+		}
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("%s: expected a number", path)
+		}
+		if schema.Type == "integer" && n != math.Trunc(n) {
+			return fmt.Errorf("%s: expected an integer", path)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected a boolean", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package restapis
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/audit"
+	"kubesonde.io/controllers/baseline"
+	"kubesonde.io/controllers/cloudevents"
+	"kubesonde.io/controllers/dispatcher"
+	"kubesonde.io/controllers/findings"
+	networkpolicy "kubesonde.io/controllers/network-policy"
+	"kubesonde.io/controllers/probe_command"
+	"kubesonde.io/controllers/snapshot"
+	"kubesonde.io/controllers/state"
+	"kubesonde.io/rest_apis/openapi"
+)
+
+var _ = Describe("OpenAPI", func() {
+	document := OpenAPIDocument()
+
+	It("Documents every endpoint", func() {
+		for _, path := range ENDPOINT_PATHS {
+			Expect(document.Paths).To(HaveKey(path))
+		}
+	})
+
+	It("Only references known schemas", func() {
+		data, err := json.Marshal(document)
+		Expect(err).To(BeNil())
+		for _, part := range strings.Split(string(data), `"$ref":"`)[1:] {
+			name := strings.TrimPrefix(part[:strings.Index(part, `"`)], openapi.SCHEMA_REF_PREFIX)
+			Expect(document.Components.Schemas).To(HaveKey(name))
+		}
+	})
+
+	It("Serves the document", func() {
+		w := httptest.NewRecorder()
+		GetOpenAPIHandler().ServeHTTP(w, httptest.NewRequest("GET", "http://localhost:2709/openapi.json", nil))
+		Expect(w.Code).To(Equal(200))
+		var served openapi.Document
+		Expect(json.Unmarshal(w.Body.Bytes(), &served)).To(Succeed())
+		Expect(served.OpenAPI).To(Equal(openapi.VERSION))
+		Expect(served.Paths).To(HaveLen(len(document.Paths)))
+	})
+
+	Describe("Responses match the document", func() {
+		var stateManager *state.StateManager
+		var store *snapshot.Store
+		var provider networkpolicy.EvaluatorProvider
+
+		BeforeEach(func() {
+			stateManager = state.NewStateManager()
+			store = snapshot.NewStore(snapshot.MAX_SNAPSHOTS)
+			src := v1.ProbeEndpointInfo{Type: v1.POD, Name: "src", Namespace: "default", DeploymentName: "src"}
+			dst := v1.ProbeEndpointInfo{Type: v1.POD, Name: "dst", Namespace: "default"}
+			Expect(stateManager.AppendProbes(&[]v1.ProbeOutputItem{
+				{Type: v1.PROBE, ResultingAction: v1.ALLOW, ExpectedAction: v1.ALLOW, Source: src, Destination: dst, Port: "80", Protocol: "TCP", Timestamp: 1},
+				{Type: v1.PROBE, ResultingAction: v1.DENY, Source: dst, Destination: src, Port: "80", Protocol: "TCP"},
+			})).To(Succeed())
+			Expect(stateManager.AppendErrors(&[]v1.ProbeOutputError{{Value: v1.ProbeOutputItem{Type: v1.PROBE}, Reason: "timeout"}})).To(Succeed())
+			Expect(stateManager.SetNetInfoV2("src", &[]v1.PodNetworkingItem{{Port: "80", IP: "0.0.0.0", Protocol: "TCP"}})).To(Succeed())
+			pods := []corev1.Pod{
+				{ObjectMeta: metav1.ObjectMeta{Name: "src", Namespace: "default"}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "dst", Namespace: "default"}},
+			}
+			provider = func() (*networkpolicy.Evaluator, error) {
+				return networkpolicy.NewEvaluator(pods, nil, nil, nil), nil
+			}
+		})
+
+		validate := func(path string, method string, handler http.Handler, url string, body string) {
+			request := httptest.NewRequest(method, url, strings.NewReader(body))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, request)
+			operation := document.Operation(path, method)
+			Expect(operation).NotTo(BeNil())
+			response, ok := operation.Responses[strconv.Itoa(w.Code)]
+			Expect(ok).To(BeTrue(), "undocumented status %d of %s %s", w.Code, method, url)
+			schema := response.Content["application/json"].Schema
+			Expect(document.Validate(schema, w.Body.Bytes())).To(Succeed(), "%s %s", method, url)
+		}
+
+		It("Validates the probes", func() {
+			validate(GET_PROBES_PATH, "GET", GetProbesHandlerWithManager(state.NewStateManager()), "http://localhost:2709/probes", "")
+			validate(GET_PROBES_PATH, "GET", GetProbesHandlerWithManager(stateManager), "http://localhost:2709/probes", "")
+			validate(GET_PROBES_PATH, "GET", GetProbesHandlerWithManager(stateManager), "http://localhost:2709/probes?limit=1&fields=items", "")
+		})
+
+		It("Validates the analyses", func() {
+			validate(GET_GRAPH_PATH, "GET", GetGraphHandlerWithManager(stateManager), "http://localhost:2709/graph?level=workload", "")
+			validate(GET_MISMATCHES_PATH, "GET", GetMismatchesHandlerWithManager(stateManager, provider), "http://localhost:2709/mismatches", "")
+			validate(GET_EXPLAIN_PATH, "GET", GetExplainHandlerWithManager(stateManager, provider), "http://localhost:2709/explain?source=default/src&destination=dst&port=80", "")
+		})
+
+		It("Validates the snapshots", func() {
+			validate(SNAPSHOTS_PATH, "POST", SnapshotsHandlerWithManager(stateManager, store), "http://localhost:2709/snapshots", `{"label":"before"}`)
+			validate(SNAPSHOTS_PATH, "GET", SnapshotsHandlerWithManager(stateManager, store), "http://localhost:2709/snapshots", "")
+			stateManager.Clear()
+			validate(GET_DIFF_PATH, "GET", GetDiffHandlerWithManager(stateManager, store), "http://localhost:2709/diff?from=before", "")
+		})
+
+		It("Validates the dispatcher state", func() {
+			drain := 2.0
+			stats := func() dispatcher.QueueStats {
+				return dispatcher.QueueStats{
+					Pending:               1,
+					ByPriority:            map[string]int{"high": 1},
+					BySourcePod:           map[string]int{"default/src": 1},
+					ByNamespace:           map[string]int{"default": 1},
+					InFlight:              []dispatcher.InFlightProbe{{Source: "default/src", Destination: "dst", DestinationPort: "80", StartedAt: 1}},
+					ThroughputPerSecond:   0.5,
+					EstimatedDrainSeconds: &drain,
+				}
+			}
+			validate(GET_QUEUE_PATH, "GET", GetQueueHandlerWithStats(stats), "http://localhost:2709/queue", "")
+			probes := func() []probe_command.KubesondeCommand {
+				return []probe_command.KubesondeCommand{{SourcePodName: "src", Namespace: "default", Destination: "dst", DestinationPort: "80", Protocol: "TCP"}}
+			}
+			lastExecution := func(probe_command.KubesondeCommand) (time.Time, bool) { return time.Unix(1, 0), true }
+			validate(GET_PLAN_PATH, "GET", GetPlanHandlerWithStorage(probes, lastExecution), "http://localhost:2709/plan", "")
+		})
+
+		It("Validates the drift", func() {
+			current := func() (baseline.Current, bool) {
+				return baseline.Current{
+					Kubesonde: "default/kubesonde",
+					Source:    "default/approved/baseline.yaml",
+					Baseline:  baseline.Baseline{Edges: []baseline.Edge{{From: "default/dst", To: "default/src", Ports: []string{"80/TCP"}}}},
+				}, true
+			}
+			validate(GET_DRIFT_PATH, "GET", GetDriftHandlerWithManager(stateManager, current), "http://localhost:2709/drift", "")
+		})
+
+		It("Validates the audit records", func() {
+			auditLog := audit.NewLog(nil)
+			auditLog.Attribute("default/src", "default/kubesonde")
+			record := audit.Command("default", "src", "debugger", []string{"nc", "-z", "dst", "80"}, time.Unix(1, 0), errors.New("connection refused"))
+			_, err := auditLog.Append(record)
+			Expect(err).To(BeNil())
+			validate(GET_AUDIT_PATH, "GET", GetAuditHandlerWithLog(func() *audit.Log { return auditLog }), "http://localhost:2709/audit", "")
+		})
+
+		It("Validates the SARIF report", func() {
+			w := httptest.NewRecorder()
+			none := func() (baseline.Current, bool) { return baseline.Current{}, false }
+			GetReportHandlerWithManager(stateManager, none).ServeHTTP(w, httptest.NewRequest("GET", "http://localhost:2709/report", nil))
+			Expect(w.Code).To(Equal(200))
+			schema := document.Operation(GET_REPORT_PATH, "GET").Responses["200"].Content[SARIF_CONTENT_TYPE].Schema
+			Expect(document.Validate(schema, w.Body.Bytes())).To(Succeed())
+		})
+
+		It("Validates the CloudEvents", func() {
+			item := stateManager.GetProbeState().Items[0]
+			source := cloudevents.SourceOf("default", "kubesonde")
+			data, err := json.Marshal(cloudevents.NewProbeResultEvent(source, 1700000000, item))
+			Expect(err).To(BeNil())
+			Expect(document.Validate(&openapi.Schema{Ref: openapi.SCHEMA_REF_PREFIX + "cloudevents.ProbeResultEvent"}, data)).To(Succeed())
+
+			finding := findings.Finding{Kind: findings.VERDICT_FLIP, Timestamp: 1700000000, Action: v1.ALLOW, PreviousAction: v1.DENY, Probe: item}
+			data, err = json.Marshal(cloudevents.NewFindingEvent(source, finding))
+			Expect(err).To(BeNil())
+			Expect(document.Validate(&openapi.Schema{Ref: openapi.SCHEMA_REF_PREFIX + "cloudevents.FindingEvent"}, data)).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,76 @@
+package restapis
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"k8s.io/client-go/kubernetes"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/inner"
+	"kubesonde.io/controllers/probe_command"
+	"kubesonde.io/pkg/apitypes"
+)
+
+const POST_PROBES_ADHOC_PATH = apitypes.POST_PROBES_ADHOC_PATH
+
+// ProbeRunner runs a single probe synchronously
+type ProbeRunner func(client kubernetes.Interface, command probe_command.KubesondeCommand) (v1.ProbeOutputItem, error)
+
+func PostProbesAdhocHandler(client kubernetes.Interface) http.Handler {
+	return PostProbesAdhocHandlerWithRunner(client, inner.RunProbe)
+}
+
+// PostProbesAdhocHandlerWithRunner runs the probe described by an
+// inner.AdhocProbe body and returns the probe output item, which is also
+// recorded with the other results. Failed probes answer 502 with the reason.
+func PostProbesAdhocHandlerWithRunner(client kubernetes.Interface, run ProbeRunner) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var request inner.AdhocProbe
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		command, err := inner.BuildAdhocCommand(r.Context(), client, request)
+		switch {
+		case errors.Is(err, inner.ErrInvalidProbe):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, inner.ErrEndpointNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case err != nil:
+			log.Error(err, "[POST /probes/adhoc] Failed to resolve the probe endpoints")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		item, err := run(client, command)
+		switch {
+		case errors.Is(err, inner.ErrSourceNotReady):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		data, err := json.MarshalIndent(item, "", "  ")
+		if err != nil {
+			log.Error(err, "[POST /probes/adhoc] Failed to marshal probe")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(data); err != nil {
+			log.Error(err, "[POST /probes/adhoc] Failed to write response")
+		}
+	})
+}
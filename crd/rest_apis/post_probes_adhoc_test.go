@@ -0,0 +1,71 @@
+package restapis
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/inner"
+	"kubesonde.io/controllers/probe_command"
+)
+
+var _ = Describe("PostProbesAdhoc", func() {
+	client := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "src", Namespace: "default"}, Status: corev1.PodStatus{PodIP: "10.0.0.1"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "dst", Namespace: "default"}, Status: corev1.PodStatus{PodIP: "10.0.0.2"}},
+	)
+	deny := func(_ kubernetes.Interface, command probe_command.KubesondeCommand) (v1.ProbeOutputItem, error) {
+		return v1.ProbeOutputItem{
+			Type:            v1.PROBE,
+			ResultingAction: v1.DENY,
+			Source:          v1.ProbeEndpointInfo{Type: command.SourceType, Name: command.SourcePodName, Namespace: command.Namespace},
+			Destination:     v1.ProbeEndpointInfo{Type: command.DestinationType, Name: command.Destination, IPAddress: command.DestinationIPAddress},
+			Port:            command.DestinationPort,
+			Protocol:        command.Protocol,
+		}, nil
+	}
+	post := func(run ProbeRunner, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "http://localhost:2709/probes/adhoc", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		PostProbesAdhocHandlerWithRunner(client, run).ServeHTTP(w, req)
+		return w
+	}
+
+	It("Returns the verdict of the probe", func() {
+		w := post(deny, `{"source":"default/src","destination":"dst","port":8080}`)
+
+		Expect(w.Code).To(Equal(200))
+		var item v1.ProbeOutputItem
+		Expect(json.Unmarshal(w.Body.Bytes(), &item)).To(Succeed())
+		Expect(item.ResultingAction).To(Equal(v1.DENY))
+		Expect(item.Destination.IPAddress).To(Equal("10.0.0.2"))
+		Expect(item.Port).To(Equal("8080"))
+		Expect(item.Protocol).To(Equal("TCP"))
+	})
+
+	It("Maps the errors to status codes", func() {
+		Expect(post(deny, `{"source":"default/src"`).Code).To(Equal(400))
+		Expect(post(deny, `{"source":"default/src","destination":"dst","port":0}`).Code).To(Equal(400))
+		Expect(post(deny, `{"source":"default/unknown","destination":"dst","port":80}`).Code).To(Equal(404))
+
+		notReady := func(kubernetes.Interface, probe_command.KubesondeCommand) (v1.ProbeOutputItem, error) {
+			return v1.ProbeOutputItem{}, inner.ErrSourceNotReady
+		}
+		Expect(post(notReady, `{"source":"src","destination":"dst","port":80}`).Code).To(Equal(409))
+
+		failed := func(kubernetes.Interface, probe_command.KubesondeCommand) (v1.ProbeOutputItem, error) {
+			return v1.ProbeOutputItem{}, errors.New("exec failed")
+		}
+		w := post(failed, `{"source":"src","destination":"dst","port":80}`)
+		Expect(w.Code).To(Equal(502))
+		Expect(w.Body.String()).To(ContainSubstring("exec failed"))
+	})
+})
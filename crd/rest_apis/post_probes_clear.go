@@ -4,9 +4,10 @@ import (
 	"net/http"
 
 	"kubesonde.io/controllers/state"
+	"kubesonde.io/pkg/apitypes"
 )
 
-const POST_PROBES_CLEAR_PATH = "/probes/clear"
+const POST_PROBES_CLEAR_PATH = apitypes.POST_PROBES_CLEAR_PATH
 
 func PostProbesClearHandler() http.Handler {
 	return PostProbesClearHandlerWithManager(state.GetDefaultManager())
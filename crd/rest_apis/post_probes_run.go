@@ -0,0 +1,51 @@
+package restapis
+
+import (
+	"encoding/json"
+	"net/http"
+
+	recursiveprobing "kubesonde.io/controllers/recursive-probing"
+	"kubesonde.io/pkg/apitypes"
+)
+
+const POST_PROBES_RUN_PATH = apitypes.POST_PROBES_RUN_PATH
+
+// RunProbesResponse tells how many probes were queued
+type RunProbesResponse = apitypes.RunProbesResponse
+
+func PostProbesRunHandler() http.Handler {
+	return PostProbesRunHandlerWithRunner(recursiveprobing.RunScopedProbing)
+}
+
+// PostProbesRunHandlerWithRunner queues a round of probes ahead of the running
+// one, without waiting for the recursive probing timer. The `namespace`,
+// `workload` and `pod` query parameters restrict the round to the probes from
+// or to the matching endpoints. The probes run asynchronously; their results
+// show up in /probes and /probes/stream.
+func PostProbesRunHandlerWithRunner(run func(recursiveprobing.Scope) int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		query := r.URL.Query()
+		scope := recursiveprobing.Scope{
+			Namespace: query.Get("namespace"),
+			Workload:  query.Get("workload"),
+			Pod:       query.Get("pod"),
+		}
+		data, err := json.MarshalIndent(RunProbesResponse{Scope: scope, Queued: run(scope)}, "", "  ")
+		if err != nil {
+			log.Error(err, "[POST /probes/run] Failed to marshal response")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		if _, err := w.Write(data); err != nil {
+			log.Error(err, "[POST /probes/run] Failed to write response")
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package restapis
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	recursiveprobing "kubesonde.io/controllers/recursive-probing"
+)
+
+var _ = Describe("PostProbesRun", func() {
+	var scopes []recursiveprobing.Scope
+	run := func(scope recursiveprobing.Scope) int {
+		scopes = append(scopes, scope)
+		return 3
+	}
+
+	BeforeEach(func() {
+		scopes = nil
+	})
+
+	It("Queues a scoped round", func() {
+		req := httptest.NewRequest("POST", "http://localhost:2709/probes/run?namespace=default&workload=web", nil)
+		w := httptest.NewRecorder()
+		PostProbesRunHandlerWithRunner(run).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(202))
+		var response RunProbesResponse
+		Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+		Expect(response.Queued).To(Equal(3))
+		Expect(scopes).To(Equal([]recursiveprobing.Scope{{Namespace: "default", Workload: "web"}}))
+	})
+
+	It("Rejects other methods", func() {
+		req := httptest.NewRequest("GET", "http://localhost:2709/probes/run", nil)
+		w := httptest.NewRecorder()
+		PostProbesRunHandlerWithRunner(run).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(405))
+		Expect(scopes).To(BeEmpty())
+	})
+})
@@ -0,0 +1,58 @@
+package restapis
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"kubesonde.io/controllers/snapshot"
+	"kubesonde.io/controllers/state"
+	"kubesonde.io/pkg/apitypes"
+)
+
+const SNAPSHOTS_PATH = apitypes.SNAPSHOTS_PATH
+
+type CreateSnapshotRequest = apitypes.CreateSnapshotRequest
+
+func SnapshotsHandler() http.Handler {
+	return SnapshotsHandlerWithManager(state.GetDefaultManager(), snapshot.GetDefaultStore())
+}
+
+// SnapshotsHandlerWithManager lists the snapshots on GET and freezes the
+// current probe output on POST. The POST body optionally sets a label:
+// `{"label": "before-upgrade"}`.
+func SnapshotsHandlerWithManager(sm *state.StateManager, store *snapshot.Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response any
+		status := http.StatusOK
+		switch r.Method {
+		case http.MethodGet:
+			response = store.List()
+		case http.MethodPost:
+			var request CreateSnapshotRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			response = store.Create(request.Label, sm.GetProbeState())
+			status = http.StatusCreated
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		data, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			log.Error(err, "["+r.Method+" /snapshots] Failed to marshal snapshots")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if _, err := w.Write(data); err != nil {
+			log.Error(err, "["+r.Method+" /snapshots] Failed to write response")
+		}
+	})
+}
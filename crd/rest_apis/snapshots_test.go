@@ -0,0 +1,91 @@
+package restapis
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "kubesonde.io/api/v1"
+	"kubesonde.io/controllers/snapshot"
+	"kubesonde.io/controllers/state"
+)
+
+var _ = Describe("Snapshots", func() {
+	var stateManager *state.StateManager
+	var store *snapshot.Store
+	probe := func(action v1.ActionType) v1.ProbeOutputItem {
+		return v1.ProbeOutputItem{
+			Type:            v1.PROBE,
+			ResultingAction: action,
+			Source:          v1.ProbeEndpointInfo{Type: v1.POD, Name: "src", Namespace: "default"},
+			Destination:     v1.ProbeEndpointInfo{Type: v1.POD, Name: "dst", Namespace: "default"},
+			Port:            "80",
+			Protocol:        "TCP",
+		}
+	}
+
+	BeforeEach(func() {
+		stateManager = state.NewStateManager()
+		store = snapshot.NewStore(snapshot.MAX_SNAPSHOTS)
+		Expect(stateManager.AppendProbes(&[]v1.ProbeOutputItem{probe(v1.DENY)})).To(Succeed())
+	})
+
+	It("Creates and lists snapshots", func() {
+		req := httptest.NewRequest("POST", "http://localhost:2709/snapshots", strings.NewReader(`{"label": "before"}`))
+		w := httptest.NewRecorder()
+		SnapshotsHandlerWithManager(stateManager, store).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(201))
+		var created snapshot.Summary
+		Expect(json.Unmarshal(w.Body.Bytes(), &created)).To(Succeed())
+		Expect(created.Label).To(Equal("before"))
+		Expect(created.Items).To(Equal(1))
+
+		req = httptest.NewRequest("GET", "http://localhost:2709/snapshots", nil)
+		w = httptest.NewRecorder()
+		SnapshotsHandlerWithManager(stateManager, store).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(200))
+		var summaries []snapshot.Summary
+		Expect(json.Unmarshal(w.Body.Bytes(), &summaries)).To(Succeed())
+		Expect(summaries).To(Equal([]snapshot.Summary{created}))
+	})
+
+	It("Creates snapshots without a body", func() {
+		req := httptest.NewRequest("POST", "http://localhost:2709/snapshots", nil)
+		w := httptest.NewRecorder()
+		SnapshotsHandlerWithManager(stateManager, store).ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(201))
+	})
+
+	It("Diffs a snapshot against the current state", func() {
+		before := store.Create("before", stateManager.GetProbeState())
+		Expect(stateManager.AppendProbes(&[]v1.ProbeOutputItem{probe(v1.ALLOW)})).To(Succeed())
+
+		req := httptest.NewRequest("GET", "http://localhost:2709/diff?from="+before.ID, nil)
+		w := httptest.NewRecorder()
+		GetDiffHandlerWithManager(stateManager, store).ServeHTTP(w, req)
+
+		Expect(w.Code).To(Equal(200))
+		var diff snapshot.Diff
+		Expect(json.Unmarshal(w.Body.Bytes(), &diff)).To(Succeed())
+		Expect(diff.To).To(Equal("current"))
+		Expect(diff.Workloads).To(HaveLen(1))
+		Expect(diff.Workloads[0].Changed).To(HaveLen(1))
+		Expect(diff.Workloads[0].Changed[0].PreviousAction).To(Equal(v1.DENY))
+	})
+
+	It("Rejects unknown snapshots", func() {
+		req := httptest.NewRequest("GET", "http://localhost:2709/diff?from=before&to=after", nil)
+		w := httptest.NewRecorder()
+		GetDiffHandlerWithManager(stateManager, store).ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(404))
+
+		req = httptest.NewRequest("GET", "http://localhost:2709/diff", nil)
+		w = httptest.NewRecorder()
+		GetDiffHandlerWithManager(stateManager, store).ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(400))
+	})
+})
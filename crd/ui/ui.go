@@ -0,0 +1,20 @@
+// Package ui embeds the results viewer built from the frontend directory.
+// Run `make ui` to copy the frontend build in dist before building the manager.
+package ui
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed all:dist
+var dist embed.FS
+
+// Assets returns the built frontend, dist is the root of the returned file system
+func Assets() fs.FS {
+	assets, err := fs.Sub(dist, "dist")
+	if err != nil {
+		panic(err)
+	}
+	return assets
+}
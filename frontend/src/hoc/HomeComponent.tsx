@@ -1,15 +1,33 @@
 import { FaDownload } from "react-icons/fa";
 import "./Home.scss";
-import React, { useEffect } from "react";
+import React, { useEffect, useState } from "react";
 import { useFilePicker } from "use-file-picker";
 import { useNavigate } from "react-router-dom";
 import { ProbeOutput } from "src/entities/probeOutput";
+import { fetchProbes, getToken, isEmbedded, setToken } from "src/utils/remote";
 
 export const HomeComponent = () => {
   const navigate = useNavigate();
   const [openFileSelector, { filesContent, clear }] = useFilePicker({
     accept: ".json",
   });
+  const [fetchError, setFetchError] = useState<string>();
+  const [token, setTokenState] = useState<string>(getToken());
+
+  const changeToken = (value: string) => {
+    setTokenState(value);
+    setToken(value);
+  };
+
+  const loadFromController = () => {
+    fetchProbes(token)
+      .then((data) =>
+        navigate("/graph/current", {
+          state: { data, title: "Current probes" },
+        })
+      )
+      .catch((error: Error) => setFetchError(error.message));
+  };
 
   // 👇 move navigation into an effect
   useEffect(() => {
@@ -52,6 +70,26 @@ export const HomeComponent = () => {
             >
               Select a file
             </span>
+            {isEmbedded() && (
+              <>
+                <input
+                  id="token-input"
+                  type="password"
+                  placeholder="Bearer token"
+                  autoComplete="off"
+                  value={token}
+                  onChange={(event) => changeToken(event.target.value)}
+                />
+                <span
+                  id="fetch-probes-btn"
+                  className="btn btn-primary"
+                  onClick={loadFromController}
+                >
+                  Load from Kubesonde
+                </span>
+              </>
+            )}
+            {fetchError && <div className="fetch-error">{fetchError}</div>}
           </div>
         </label>
       </div>
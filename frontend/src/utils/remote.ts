@@ -0,0 +1,41 @@
+import { ProbeOutput } from "src/entities/probeOutput";
+
+// The viewer embedded in the controller is built with the /ui/ base
+// (see `make ui` in crd/Makefile) and is served on the same origin as /probes
+export const isEmbedded = (): boolean =>
+  !!import.meta.env.BASE_URL && import.meta.env.BASE_URL !== "/";
+
+const TOKEN_KEY = "kubesonde.token";
+
+// The controller authenticates the requests with a bearer token, e.g. from
+// `kubectl create token`. The token is kept for the session of the tab only.
+export const getToken = (): string => sessionStorage.getItem(TOKEN_KEY) ?? "";
+
+export const setToken = (token: string): void => {
+  if (token) {
+    sessionStorage.setItem(TOKEN_KEY, token);
+  } else {
+    sessionStorage.removeItem(TOKEN_KEY);
+  }
+};
+
+export const fetchProbes = async (
+  token: string = getToken()
+): Promise<ProbeOutput> => {
+  const headers: Record<string, string> = { Accept: "application/json" };
+  if (token) {
+    headers.Authorization = `Bearer ${token}`;
+  }
+  const response = await fetch("/probes", { headers });
+  if (response.status === 401) {
+    throw new Error(
+      "GET /probes failed: 401 Unauthorized, enter a bearer token, e.g. from `kubectl create token`"
+    );
+  }
+  if (!response.ok) {
+    throw new Error(
+      `GET /probes failed: ${response.status} ${response.statusText}`
+    );
+  }
+  return response.json();
+};
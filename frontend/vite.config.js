@@ -5,6 +5,8 @@ import viteTsconfigPaths from 'vite-tsconfig-paths';
 
 export default defineConfig(() => {
   return {
+    // The controller serves the embedded viewer under /ui/
+    base: process.env.KUBESONDE_UI_BASE ?? '/',
     build: {
       outDir: 'build',
     },